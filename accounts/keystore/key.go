@@ -0,0 +1,253 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/TIE-Tech/tie-core/common/crypto"
+	"github.com/TIE-Tech/tie-core/types"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// StandardScryptN and StandardScryptP are the scrypt parameters of the encrypted keys,
+	// taking about a second and 256MB of memory to decrypt a key
+	StandardScryptN = 1 << 18
+	StandardScryptP = 1
+
+	// LightScryptN and LightScryptP are the scrypt parameters of the keys
+	// decrypted quickly, with 4MB of memory
+	LightScryptN = 1 << 12
+	LightScryptP = 6
+
+	keyVersion = 3
+
+	scryptR     = 8
+	scryptDKLen = 32
+
+	kdfScrypt = "scrypt"
+	kdfPBKDF2 = "pbkdf2"
+
+	cipherAES128CTR = "aes-128-ctr"
+)
+
+var (
+	ErrDecrypt = errors.New("could not decrypt key with given passphrase")
+)
+
+// encryptedKey is the json encoding of a key in the Web3 Secret Storage v3 format
+type encryptedKey struct {
+	Address string     `json:"address"`
+	Crypto  cryptoJSON `json:"crypto"`
+	ID      string     `json:"id"`
+	Version int        `json:"version"`
+}
+
+type cryptoJSON struct {
+	Cipher       string                 `json:"cipher"`
+	CipherText   string                 `json:"ciphertext"`
+	CipherParams cipherParamsJSON       `json:"cipherparams"`
+	KDF          string                 `json:"kdf"`
+	KDFParams    map[string]interface{} `json:"kdfparams"`
+	MAC          string                 `json:"mac"`
+}
+
+type cipherParamsJSON struct {
+	IV string `json:"iv"`
+}
+
+// EncryptKey encrypts a key with the passphrase in the Web3 Secret Storage v3 format,
+// the key of the cipher is derived from the passphrase with scrypt
+func EncryptKey(key *ecdsa.PrivateKey, passphrase string, scryptN, scryptP int) ([]byte, error) {
+	salt := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+
+	derivedKey, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, scryptDKLen)
+	if err != nil {
+		return nil, err
+	}
+
+	keyBytes, err := crypto.MarshalPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	iv := make([]byte, aes.BlockSize)
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return nil, err
+	}
+
+	cipherText, err := aesCTRXOR(derivedKey[:16], keyBytes, iv)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(&encryptedKey{
+		Address: hex.EncodeToString(crypto.PubKeyToAddress(&key.PublicKey).Bytes()),
+		Crypto: cryptoJSON{
+			Cipher:     cipherAES128CTR,
+			CipherText: hex.EncodeToString(cipherText),
+			CipherParams: cipherParamsJSON{
+				IV: hex.EncodeToString(iv),
+			},
+			KDF: kdfScrypt,
+			KDFParams: map[string]interface{}{
+				"n":     scryptN,
+				"r":     scryptR,
+				"p":     scryptP,
+				"dklen": scryptDKLen,
+				"salt":  hex.EncodeToString(salt),
+			},
+			MAC: hex.EncodeToString(crypto.Keccak256(derivedKey[16:32], cipherText)),
+		},
+		ID:      uuid.New().String(),
+		Version: keyVersion,
+	})
+}
+
+// DecryptKey decrypts a key in the Web3 Secret Storage v3 format with the passphrase.
+// It returns ErrDecrypt if the passphrase is wrong
+func DecryptKey(data []byte, passphrase string) (*ecdsa.PrivateKey, error) {
+	k := new(encryptedKey)
+	if err := json.Unmarshal(data, k); err != nil {
+		return nil, err
+	}
+
+	if k.Version != keyVersion {
+		return nil, fmt.Errorf("unsupported key version %d", k.Version)
+	}
+
+	if k.Crypto.Cipher != cipherAES128CTR {
+		return nil, fmt.Errorf("unsupported cipher %s", k.Crypto.Cipher)
+	}
+
+	mac, err := hex.DecodeString(k.Crypto.MAC)
+	if err != nil {
+		return nil, err
+	}
+
+	iv, err := hex.DecodeString(k.Crypto.CipherParams.IV)
+	if err != nil {
+		return nil, err
+	}
+
+	cipherText, err := hex.DecodeString(k.Crypto.CipherText)
+	if err != nil {
+		return nil, err
+	}
+
+	derivedKey, err := deriveKey(&k.Crypto, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(crypto.Keccak256(derivedKey[16:32], cipherText), mac) {
+		return nil, ErrDecrypt
+	}
+
+	keyBytes, err := aesCTRXOR(derivedKey[:16], cipherText, iv)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := crypto.ToECDSA(keyBytes, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// the address of the file, which is optional, must be the one of the key
+	if k.Address != "" {
+		if address, err := keyAddress(k); err != nil || address != crypto.PubKeyToAddress(&key.PublicKey) {
+			return nil, errors.New("the key does not match the address of the file")
+		}
+	}
+
+	return key, nil
+}
+
+// keyAddress returns the address of an encrypted key
+func keyAddress(k *encryptedKey) (types.Address, error) {
+	buf, err := hex.DecodeString(k.Address)
+	if err != nil {
+		return types.ZeroAddress, err
+	}
+
+	if len(buf) != types.AddressLength {
+		return types.ZeroAddress, fmt.Errorf("invalid address %s", k.Address)
+	}
+
+	return types.BytesToAddress(buf), nil
+}
+
+// deriveKey derives the key of the cipher from the passphrase, with the kdf of the key
+func deriveKey(c *cryptoJSON, passphrase string) ([]byte, error) {
+	salt, err := hex.DecodeString(paramString(c.KDFParams, "salt"))
+	if err != nil {
+		return nil, err
+	}
+
+	dkLen := paramInt(c.KDFParams, "dklen")
+	if dkLen < 32 {
+		return nil, fmt.Errorf("invalid derived key length %d", dkLen)
+	}
+
+	switch c.KDF {
+	case kdfScrypt:
+		return scrypt.Key(
+			[]byte(passphrase),
+			salt,
+			paramInt(c.KDFParams, "n"),
+			paramInt(c.KDFParams, "r"),
+			paramInt(c.KDFParams, "p"),
+			dkLen,
+		)
+
+	case kdfPBKDF2:
+		if prf := paramString(c.KDFParams, "prf"); prf != "hmac-sha256" {
+			return nil, fmt.Errorf("unsupported pbkdf2 prf %s", prf)
+		}
+
+		return pbkdf2.Key([]byte(passphrase), salt, paramInt(c.KDFParams, "c"), dkLen, sha256.New), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported kdf %s", c.KDF)
+	}
+}
+
+func paramInt(params map[string]interface{}, name string) int {
+	// the numbers of the json params are decoded as floats
+	value, _ := params[name].(float64)
+
+	return int(value)
+}
+
+func paramString(params map[string]interface{}, name string) string {
+	value, _ := params[name].(string)
+
+	return value
+}
+
+func aesCTRXOR(key, in, iv []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, len(in))
+	cipher.NewCTR(block, iv).XORKeyStream(out, in)
+
+	return out, nil
+}
@@ -0,0 +1,349 @@
+package keystore
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/TIE-Tech/go-logger"
+	"github.com/TIE-Tech/tie-core/common/crypto"
+	"github.com/TIE-Tech/tie-core/types"
+)
+
+var (
+	ErrNoAccount = errors.New("no key for the given address")
+	ErrLocked    = errors.New("the account is locked")
+	ErrExists    = errors.New("the account already exists")
+)
+
+// TxSigner signs the transactions with a private key
+type TxSigner interface {
+	SignTx(tx *types.Transaction, priv *ecdsa.PrivateKey) (*types.Transaction, error)
+}
+
+// unlockedKey is the decrypted key of an unlocked account
+type unlockedKey struct {
+	key *ecdsa.PrivateKey
+
+	// expiry is the time the account is locked again, zero if it is unlocked until it is locked
+	expiry time.Time
+}
+
+// KeyStore manages the accounts of the keys encrypted in a directory, one file per key.
+// The keys are decrypted in memory while their account is unlocked
+type KeyStore struct {
+	dir     string
+	scryptN int
+	scryptP int
+
+	lock sync.Mutex
+
+	// files are the key files of the accounts
+	files map[types.Address]string
+
+	// unlocked are the decrypted keys of the unlocked accounts
+	unlocked map[types.Address]*unlockedKey
+
+	// now returns the current time, replaced in the tests
+	now func() time.Time
+}
+
+// NewKeyStore returns the keystore of the keys in the directory, created if it
+// does not exist. The new keys are encrypted with the scrypt parameters
+func NewKeyStore(dir string, scryptN, scryptP int) (*KeyStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	ks := &KeyStore{
+		dir:      dir,
+		scryptN:  scryptN,
+		scryptP:  scryptP,
+		files:    map[types.Address]string{},
+		unlocked: map[types.Address]*unlockedKey{},
+		now:      time.Now,
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		// skip the directories, and the hidden and temporary files
+		if entry.IsDir() || entry.Name()[0] == '.' || filepath.Ext(entry.Name()) == ".tmp" {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+
+		address, err := readAddress(path)
+		if err != nil {
+			logger.Warn("[KEY] skipping the invalid key file", "path", path, "err", err)
+
+			continue
+		}
+
+		ks.files[address] = path
+	}
+
+	return ks, nil
+}
+
+// readAddress returns the address of a key file
+func readAddress(path string) (types.Address, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return types.ZeroAddress, err
+	}
+
+	k := new(encryptedKey)
+	if err := json.Unmarshal(data, k); err != nil {
+		return types.ZeroAddress, err
+	}
+
+	return keyAddress(k)
+}
+
+// Accounts returns the addresses of the keys, sorted
+func (ks *KeyStore) Accounts() []types.Address {
+	ks.lock.Lock()
+	defer ks.lock.Unlock()
+
+	accounts := make([]types.Address, 0, len(ks.files))
+	for address := range ks.files {
+		accounts = append(accounts, address)
+	}
+
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].String() < accounts[j].String()
+	})
+
+	return accounts
+}
+
+// HasAccount returns true if the keystore has the key of the account
+func (ks *KeyStore) HasAccount(address types.Address) bool {
+	ks.lock.Lock()
+	defer ks.lock.Unlock()
+
+	_, ok := ks.files[address]
+
+	return ok
+}
+
+// NewAccount generates a key encrypted with the passphrase, and returns its account
+func (ks *KeyStore) NewAccount(passphrase string) (types.Address, error) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return types.ZeroAddress, err
+	}
+
+	return ks.ImportKey(key, passphrase)
+}
+
+// ImportKey stores the key encrypted with the passphrase, and returns its account
+func (ks *KeyStore) ImportKey(key *ecdsa.PrivateKey, passphrase string) (types.Address, error) {
+	address := crypto.PubKeyToAddress(&key.PublicKey)
+
+	// the key is encrypted without the lock, the signatures are not blocked meanwhile
+	data, err := EncryptKey(key, passphrase, ks.scryptN, ks.scryptP)
+	if err != nil {
+		return types.ZeroAddress, err
+	}
+
+	ks.lock.Lock()
+	defer ks.lock.Unlock()
+
+	if _, ok := ks.files[address]; ok {
+		return types.ZeroAddress, ErrExists
+	}
+
+	path := filepath.Join(ks.dir, keyFileName(address, ks.now()))
+	if err := writeKeyFile(path, data); err != nil {
+		return types.ZeroAddress, err
+	}
+
+	ks.files[address] = path
+
+	return address, nil
+}
+
+// keyFileName returns the name of the file of a key, UTC--<created at>--<address>
+func keyFileName(address types.Address, now time.Time) string {
+	return fmt.Sprintf("UTC--%s--%x", now.UTC().Format("2006-01-02T15-04-05.000000000Z"), address.Bytes())
+}
+
+// writeKeyFile writes a key file atomically, only readable by the user of the node
+func writeKeyFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// Unlock decrypts the key of the account with the passphrase, and keeps it in memory for
+// the duration, 0 to keep it until the account is locked. Unlocking an unlocked account
+// replaces its duration
+func (ks *KeyStore) Unlock(address types.Address, passphrase string, duration time.Duration) error {
+	key, err := ks.decrypt(address, passphrase)
+	if err != nil {
+		return err
+	}
+
+	ks.lock.Lock()
+	defer ks.lock.Unlock()
+
+	unlocked := &unlockedKey{key: key}
+	if duration != 0 {
+		unlocked.expiry = ks.now().Add(duration)
+	}
+
+	ks.unlocked[address] = unlocked
+
+	return nil
+}
+
+// Lock removes the decrypted key of the account from memory
+func (ks *KeyStore) Lock(address types.Address) error {
+	ks.lock.Lock()
+	defer ks.lock.Unlock()
+
+	if _, ok := ks.files[address]; !ok {
+		return ErrNoAccount
+	}
+
+	ks.lockAccount(address)
+
+	return nil
+}
+
+func (ks *KeyStore) lockAccount(address types.Address) {
+	if unlocked, ok := ks.unlocked[address]; ok {
+		zeroKey(unlocked.key)
+		delete(ks.unlocked, address)
+	}
+}
+
+// SignHash signs the hash with the key of an unlocked account
+func (ks *KeyStore) SignHash(address types.Address, hash []byte) ([]byte, error) {
+	ks.lock.Lock()
+	defer ks.lock.Unlock()
+
+	key, err := ks.unlockedKey(address)
+	if err != nil {
+		return nil, err
+	}
+
+	return crypto.Sign(key, hash)
+}
+
+// SignHashWithPassphrase signs the hash with the key of an account decrypted with the passphrase,
+// the account is not unlocked
+func (ks *KeyStore) SignHashWithPassphrase(address types.Address, passphrase string, hash []byte) ([]byte, error) {
+	key, err := ks.decrypt(address, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	defer zeroKey(key)
+
+	return crypto.Sign(key, hash)
+}
+
+// SignTx signs the transaction with the key of an unlocked account
+func (ks *KeyStore) SignTx(address types.Address, tx *types.Transaction, signer TxSigner) (*types.Transaction, error) {
+	ks.lock.Lock()
+	defer ks.lock.Unlock()
+
+	key, err := ks.unlockedKey(address)
+	if err != nil {
+		return nil, err
+	}
+
+	return signer.SignTx(tx, key)
+}
+
+// SignTxWithPassphrase signs the transaction with the key of an account decrypted with the passphrase,
+// the account is not unlocked
+func (ks *KeyStore) SignTxWithPassphrase(
+	address types.Address,
+	passphrase string,
+	tx *types.Transaction,
+	signer TxSigner,
+) (*types.Transaction, error) {
+	key, err := ks.decrypt(address, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	defer zeroKey(key)
+
+	return signer.SignTx(tx, key)
+}
+
+// unlockedKey returns the key of an unlocked account, the account is locked once it expires.
+// The key is only used while the lock is held, as locking the account clears it
+func (ks *KeyStore) unlockedKey(address types.Address) (*ecdsa.PrivateKey, error) {
+	if _, ok := ks.files[address]; !ok {
+		return nil, ErrNoAccount
+	}
+
+	unlocked, ok := ks.unlocked[address]
+	if !ok {
+		return nil, ErrLocked
+	}
+
+	if !unlocked.expiry.IsZero() && !ks.now().Before(unlocked.expiry) {
+		ks.lockAccount(address)
+
+		return nil, ErrLocked
+	}
+
+	return unlocked.key, nil
+}
+
+// decrypt reads and decrypts the key of the account
+func (ks *KeyStore) decrypt(address types.Address, passphrase string) (*ecdsa.PrivateKey, error) {
+	ks.lock.Lock()
+	path, ok := ks.files[address]
+	ks.lock.Unlock()
+
+	if !ok {
+		return nil, ErrNoAccount
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := DecryptKey(data, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	if crypto.PubKeyToAddress(&key.PublicKey) != address {
+		return nil, fmt.Errorf("the key file %s is not the key of %s", path, address)
+	}
+
+	return key, nil
+}
+
+// zeroKey clears the private key from memory
+func zeroKey(key *ecdsa.PrivateKey) {
+	b := key.D.Bits()
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,199 @@
+package keystore
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/TIE-Tech/tie-core/common/crypto"
+	"github.com/TIE-Tech/tie-core/state"
+	"github.com/TIE-Tech/tie-core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// the test vectors of the Web3 Secret Storage definition, without address
+const (
+	vectorPassphrase = "testpassword"
+	vectorKey        = "7a28b5ba57c53603b0b07b56bba752f7784bf506fa95edc395f5cf6c7514fe9d"
+
+	vectorPBKDF2 = `{
+		"crypto": {
+			"cipher": "aes-128-ctr",
+			"cipherparams": {"iv": "6087dab2f9fdbbfaddc31a909735c1e6"},
+			"ciphertext": "5318b4d5bcd28de64ee5559e671353e16f075ecae9f99c7a79a38af5f869aa46",
+			"kdf": "pbkdf2",
+			"kdfparams": {
+				"c": 262144,
+				"dklen": 32,
+				"prf": "hmac-sha256",
+				"salt": "ae3cd4e7013836a3df6bd7241b12db061dbe2c6785853cce422d148a624ce0bd"
+			},
+			"mac": "517ead924a9d0dc3124507e3393d175ce3ff7c1e96529c6c555ce9e51205e9b2"
+		},
+		"id": "3198bc9c-6672-5ab3-d995-4942343ae5b6",
+		"version": 3
+	}`
+
+	vectorScrypt = `{
+		"crypto": {
+			"cipher": "aes-128-ctr",
+			"cipherparams": {"iv": "83dbcc02d8ccb40e466191a123791e0e"},
+			"ciphertext": "d172bf743a674da9cdad04534d56926ef8358534d458fffccd4e6ad2fbde479c",
+			"kdf": "scrypt",
+			"kdfparams": {
+				"dklen": 32,
+				"n": 262144,
+				"r": 1,
+				"p": 8,
+				"salt": "ab0c7876052600dd703518d6fc3fe8984592145b591fc8fb5c6d43190334ba19"
+			},
+			"mac": "2103ac29920d71da29f15d75b4a16dbe95cfd7ff8faea1056c33131d846e3097"
+		},
+		"id": "3198bc9c-6672-5ab3-d995-4942343ae5b6",
+		"version": 3
+	}`
+)
+
+func TestDecryptKey_Vectors(t *testing.T) {
+	for name, vector := range map[string]string{"pbkdf2": vectorPBKDF2, "scrypt": vectorScrypt} {
+		t.Run(name, func(t *testing.T) {
+			key, err := DecryptKey([]byte(vector), vectorPassphrase)
+			assert.NoError(t, err)
+
+			buf, err := crypto.MarshalPrivateKey(key)
+			assert.NoError(t, err)
+			assert.Equal(t, vectorKey, hex.EncodeToString(buf))
+
+			_, err = DecryptKey([]byte(vector), "wrong")
+			assert.ErrorIs(t, err, ErrDecrypt)
+		})
+	}
+}
+
+func TestEncryptKey(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+
+	data, err := EncryptKey(key, "foo", LightScryptN, LightScryptP)
+	assert.NoError(t, err)
+
+	decrypted, err := DecryptKey(data, "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, key.D, decrypted.D)
+
+	_, err = DecryptKey(data, "bar")
+	assert.ErrorIs(t, err, ErrDecrypt)
+}
+
+func TestKeyStore(t *testing.T) {
+	dir := t.TempDir()
+
+	ks, err := NewKeyStore(dir, LightScryptN, LightScryptP)
+	assert.NoError(t, err)
+
+	now := time.Unix(1000, 0)
+	ks.now = func() time.Time {
+		return now
+	}
+
+	address, err := ks.NewAccount("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, []types.Address{address}, ks.Accounts())
+
+	// the file is only readable by the user of the node
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.Equal(t, "-rw-------", files[0].Mode().String())
+
+	// the accounts are loaded from the directory
+	reopened, err := NewKeyStore(dir, LightScryptN, LightScryptP)
+	assert.NoError(t, err)
+	assert.Equal(t, []types.Address{address}, reopened.Accounts())
+
+	hash := crypto.Keccak256([]byte("hello"))
+
+	// sign with the passphrase, the account stays locked
+	_, err = ks.SignHashWithPassphrase(address, "bar", hash)
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	sig, err := ks.SignHashWithPassphrase(address, "foo", hash)
+	assert.NoError(t, err)
+
+	pub, err := crypto.SigToPub(hash, sig)
+	assert.NoError(t, err)
+	assert.Equal(t, address, crypto.PubKeyToAddress(pub))
+
+	_, err = ks.SignHash(address, hash)
+	assert.ErrorIs(t, err, ErrLocked)
+
+	// unlock for a minute
+	assert.ErrorIs(t, ks.Unlock(address, "bar", time.Minute), ErrDecrypt)
+	assert.NoError(t, ks.Unlock(address, "foo", time.Minute))
+
+	_, err = ks.SignHash(address, hash)
+	assert.NoError(t, err)
+
+	signer := state.NewEIP155Signer(100)
+
+	tx, err := ks.SignTx(address, &types.Transaction{
+		To:       &address,
+		Value:    big.NewInt(1),
+		GasPrice: big.NewInt(1),
+	}, signer)
+	assert.NoError(t, err)
+
+	from, err := signer.Sender(tx)
+	assert.NoError(t, err)
+	assert.Equal(t, address, from)
+
+	now = now.Add(time.Minute)
+
+	_, err = ks.SignHash(address, hash)
+	assert.ErrorIs(t, err, ErrLocked)
+
+	// unlock until locked
+	assert.NoError(t, ks.Unlock(address, "foo", 0))
+
+	now = now.Add(time.Hour)
+
+	_, err = ks.SignHash(address, hash)
+	assert.NoError(t, err)
+
+	assert.NoError(t, ks.Lock(address))
+
+	_, err = ks.SignHash(address, hash)
+	assert.ErrorIs(t, err, ErrLocked)
+
+	// unknown accounts
+	unknown := types.StringToAddress("1")
+
+	_, err = ks.SignHash(unknown, hash)
+	assert.ErrorIs(t, err, ErrNoAccount)
+	assert.ErrorIs(t, ks.Unlock(unknown, "foo", 0), ErrNoAccount)
+	assert.ErrorIs(t, ks.Lock(unknown), ErrNoAccount)
+
+	// a key is imported once
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+
+	imported, err := ks.ImportKey(key, "baz")
+	assert.NoError(t, err)
+	assert.Equal(t, crypto.PubKeyToAddress(&key.PublicKey), imported)
+	assert.True(t, ks.HasAccount(imported))
+
+	_, err = ks.ImportKey(key, "baz")
+	assert.ErrorIs(t, err, ErrExists)
+
+	assert.Len(t, ks.Accounts(), 2)
+
+	// the invalid files are skipped
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "invalid"), []byte("{}"), 0600))
+
+	reopened, err = NewKeyStore(dir, LightScryptN, LightScryptP)
+	assert.NoError(t, err)
+	assert.Len(t, reopened.Accounts(), 2)
+}
@@ -8,10 +8,15 @@ import (
 	"github.com/TIE-Tech/tie-core/params"
 	"io/ioutil"
 	"net"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	helperFlags "github.com/TIE-Tech/tie-core/common/flags"
+	"github.com/TIE-Tech/tie-core/rpc"
 	"github.com/TIE-Tech/tie-core/server"
+	itrie "github.com/TIE-Tech/tie-core/state/trie"
 	"github.com/TIE-Tech/tie-core/types"
 	"github.com/hashicorp/hcl"
 	"github.com/imdario/mergo"
@@ -19,26 +24,32 @@ import (
 
 // Config defines the server configuration params
 type Config struct {
-	Chain          string                 `json:"chain_config"`
-	Secrets        string                 `json:"secrets_config"`
-	DataDir        string                 `json:"data_dir"`
-	BlockGasTarget string                 `json:"block_gas_target"`
-	GRPCAddr       string                 `json:"grpc_addr"`
-	JSONRPCAddr    string                 `json:"jsonrpc_addr"`
-	Telemetry      *Telemetry             `json:"telemetry"`
-	Network        *Network               `json:"network"`
-	Seal           bool                   `json:"seal"`
-	TxPool         *TxPool                `json:"tx_pool"`
-	LogLevel       string                 `json:"log_level"`
-	LogPath        string                 `json:"log_path"`
-	EsAddr         string                 `json:"es_addr"`
-	EsIndex        string                 `json:"es_index"`
-	EsOwner        string                 `json:"es_owner"`
-	EsOpen         bool                   `json:"es_open"`
-	Join           string                 `json:"join_addr"`
-	Consensus      map[string]interface{} `json:"consensus"`
-	RestoreFile    string                 `json:"restore_file"`
-	BlockTime      uint64                 `json:"block_time_s"`
+	Chain             string                 `json:"chain_config"`
+	Secrets           string                 `json:"secrets_config"`
+	DataDir           string                 `json:"data_dir"`
+	BlockGasTarget    string                 `json:"block_gas_target"`
+	GRPCAddr          string                 `json:"grpc_addr"`
+	JSONRPCAddr       string                 `json:"jsonrpc_addr"`
+	JSONRPC           *JSONRPC               `json:"jsonrpc"`
+	Telemetry         *Telemetry             `json:"telemetry"`
+	Network           *Network               `json:"network"`
+	Seal              bool                   `json:"seal"`
+	AddressIndex      bool                   `json:"address_index"`
+	InternalTransfers bool                   `json:"internal_transfers"`
+	ParallelExecution uint64                 `json:"parallel_execution"`
+	PendingBlock      bool                   `json:"pending_block"`
+	TxPool            *TxPool                `json:"tx_pool"`
+	LogLevel          string                 `json:"log_level"`
+	LogPath           string                 `json:"log_path"`
+	EsAddr            string                 `json:"es_addr"`
+	EsIndex           string                 `json:"es_index"`
+	EsOwner           string                 `json:"es_owner"`
+	EsOpen            bool                   `json:"es_open"`
+	Join              string                 `json:"join_addr"`
+	Consensus         map[string]interface{} `json:"consensus"`
+	RestoreFile       string                 `json:"restore_file"`
+	BlockTime         uint64                 `json:"block_time_s"`
+	Pruning           *Pruning               `json:"pruning"`
 }
 
 // Telemetry holds the config details for metric services.
@@ -63,6 +74,47 @@ type TxPool struct {
 	MaxSlots   uint64 `json:"max_slots"`
 }
 
+// JSONRPC defines the json rpc configuration params
+type JSONRPC struct {
+	MaxBlockRange uint64       `json:"max_block_range"`
+	MaxLogs       uint64       `json:"max_logs"`
+	IPCPath       string       `json:"ipc_path"`
+	Namespaces    []string     `json:"namespaces"`
+	Methods       []string     `json:"methods"`
+	CorsOrigins   []string     `json:"cors_origins"`
+	VHosts        []string     `json:"vhosts"`
+	Auth          *JSONRPCAuth `json:"auth"`
+
+	MaxRequestSize   int64    `json:"max_request_size"`
+	MaxBatchLength   uint64   `json:"max_batch_length"`
+	CallTimeout      string   `json:"call_timeout"`
+	RateLimit        uint64   `json:"rate_limit"`
+	RateLimitBurst   uint64   `json:"rate_limit_burst"`
+	MethodRateLimits []string `json:"method_rate_limits"`
+
+	SlowRequestThreshold string `json:"slow_request_threshold"`
+
+	GraphQL bool `json:"graphql"`
+
+	Keystore string `json:"keystore"`
+}
+
+// JSONRPCAuth defines the configuration params of the json rpc listener authenticated with a jwt secret
+type JSONRPCAuth struct {
+	Addr       string   `json:"addr"`
+	JWTSecret  string   `json:"jwt_secret"`
+	Namespaces []string `json:"namespaces"`
+	Methods    []string `json:"methods"`
+	VHosts     []string `json:"vhosts"`
+}
+
+// Pruning defines the state retention configuration params
+type Pruning struct {
+	Mode               string `json:"mode"`
+	Retention          uint64 `json:"retention"`
+	CheckpointInterval uint64 `json:"checkpoint_interval"`
+}
+
 // DefaultConfig returns the default server configuration
 func DefaultConfig() *Config {
 	return &Config{
@@ -76,7 +128,20 @@ func DefaultConfig() *Config {
 			MaxInboundPeers:  32,
 		},
 		Telemetry: &Telemetry{},
-		Seal:      false,
+		JSONRPC: &JSONRPC{
+			MaxBlockRange:  rpc.DefaultMaxBlockRange,
+			MaxLogs:        rpc.DefaultMaxLogs,
+			CorsOrigins:    []string{"*"},
+			VHosts:         []string{"*"},
+			MaxRequestSize: rpc.DefaultMaxRequestSize,
+			MaxBatchLength: rpc.DefaultMaxBatchLength,
+			CallTimeout:    rpc.DefaultCallTimeout.String(),
+			Auth: &JSONRPCAuth{
+				JWTSecret: "jwtsecret",
+				VHosts:    []string{"localhost"},
+			},
+		},
+		Seal: false,
 		TxPool: &TxPool{
 			PriceLimit: 0,
 			MaxSlots:   4096,
@@ -89,6 +154,11 @@ func DefaultConfig() *Config {
 		EsOwner:     "tie-node",
 		RestoreFile: "",
 		BlockTime:   types.DefaultBlockTime,
+		Pruning: &Pruning{
+			Mode:               itrie.DefaultPruningConfig().Mode,
+			Retention:          itrie.DefaultPruningConfig().Retention,
+			CheckpointInterval: itrie.DefaultPruningConfig().CheckpointInterval,
+		},
 	}
 }
 
@@ -105,6 +175,10 @@ func (c *Config) BuildConfig() (*server.Config, error) {
 
 	conf.Chain = cc
 	conf.Seal = c.Seal
+	conf.AddressIndex = c.AddressIndex
+	conf.InternalTransfers = c.InternalTransfers
+	conf.ParallelExecution = c.ParallelExecution
+	conf.PendingBlock = c.PendingBlock
 	conf.DataDir = c.DataDir
 	// Set the secrets manager config if it was passed in
 	if c.Secrets != "" {
@@ -162,6 +236,85 @@ func (c *Config) BuildConfig() (*server.Config, error) {
 		conf.Chain = cc
 	}
 
+	// JSON RPC limits
+	{
+		conf.JSONRPC = &server.JSONRPC{
+			MaxBlockRange: c.JSONRPC.MaxBlockRange,
+			MaxLogs:       c.JSONRPC.MaxLogs,
+		}
+
+		// a relative ipc path is in the data directory
+		if ipcPath := c.JSONRPC.IPCPath; ipcPath != "" {
+			if !filepath.IsAbs(ipcPath) {
+				ipcPath = filepath.Join(c.DataDir, ipcPath)
+			}
+
+			conf.JSONRPC.IPCPath = ipcPath
+		}
+
+		methodRateLimits, err := parseMethodRateLimits(c.JSONRPC.MethodRateLimits)
+		if err != nil {
+			return nil, err
+		}
+
+		conf.JSONRPC.Access = rpc.AccessConfig{
+			Namespaces:       c.JSONRPC.Namespaces,
+			Methods:          c.JSONRPC.Methods,
+			CorsOrigins:      c.JSONRPC.CorsOrigins,
+			VHosts:           c.JSONRPC.VHosts,
+			RateLimit:        c.JSONRPC.RateLimit,
+			RateLimitBurst:   c.JSONRPC.RateLimitBurst,
+			MethodRateLimits: methodRateLimits,
+		}
+
+		conf.JSONRPC.MaxRequestSize = c.JSONRPC.MaxRequestSize
+		conf.JSONRPC.MaxBatchLength = c.JSONRPC.MaxBatchLength
+
+		if c.JSONRPC.CallTimeout != "" {
+			if conf.JSONRPC.CallTimeout, err = time.ParseDuration(c.JSONRPC.CallTimeout); err != nil {
+				return nil, fmt.Errorf("failed to parse call timeout %s, %w", c.JSONRPC.CallTimeout, err)
+			}
+		}
+
+		if c.JSONRPC.SlowRequestThreshold != "" {
+			conf.JSONRPC.SlowRequestThreshold, err = time.ParseDuration(c.JSONRPC.SlowRequestThreshold)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"failed to parse slow request threshold %s, %w", c.JSONRPC.SlowRequestThreshold, err,
+				)
+			}
+		}
+
+		conf.JSONRPC.GraphQL = c.JSONRPC.GraphQL
+
+		// a relative keystore directory is in the data directory
+		if keystoreDir := c.JSONRPC.Keystore; keystoreDir != "" {
+			if !filepath.IsAbs(keystoreDir) {
+				keystoreDir = filepath.Join(c.DataDir, keystoreDir)
+			}
+
+			conf.JSONRPC.KeystoreDir = keystoreDir
+		}
+
+		if c.JSONRPC.Auth.Addr != "" {
+			if conf.JSONRPC.AuthAddr, err = resolveAddr(c.JSONRPC.Auth.Addr); err != nil {
+				return nil, err
+			}
+
+			// a relative jwt secret path is in the data directory
+			conf.JSONRPC.JWTSecretPath = c.JSONRPC.Auth.JWTSecret
+			if !filepath.IsAbs(conf.JSONRPC.JWTSecretPath) {
+				conf.JSONRPC.JWTSecretPath = filepath.Join(c.DataDir, conf.JSONRPC.JWTSecretPath)
+			}
+
+			conf.JSONRPC.AuthAccess = rpc.AccessConfig{
+				Namespaces: c.JSONRPC.Auth.Namespaces,
+				Methods:    c.JSONRPC.Auth.Methods,
+				VHosts:     c.JSONRPC.Auth.VHosts,
+			}
+		}
+	}
+
 	// TxPool
 	{
 		conf.PriceLimit = c.TxPool.PriceLimit
@@ -191,9 +344,43 @@ func (c *Config) BuildConfig() (*server.Config, error) {
 		conf.BlockTime = c.BlockTime
 	}
 
+	// State pruning
+	{
+		conf.Pruning = &itrie.PruningConfig{
+			Mode:               c.Pruning.Mode,
+			Retention:          c.Pruning.Retention,
+			CheckpointInterval: c.Pruning.CheckpointInterval,
+		}
+
+		if err := conf.Pruning.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
 	return conf, nil
 }
 
+// parseMethodRateLimits parses the rate limits of the methods, as eth_getLogs=10
+func parseMethodRateLimits(raw []string) (map[string]uint64, error) {
+	limits := make(map[string]uint64, len(raw))
+
+	for _, limit := range raw {
+		parts := strings.SplitN(limit, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid method rate limit '%s', expected method=rate", limit)
+		}
+
+		rate, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid method rate limit '%s': %w", limit, err)
+		}
+
+		limits[parts[0]] = rate
+	}
+
+	return limits, nil
+}
+
 // resolveAddr resolves the passed in TCP address
 func resolveAddr(raw string) (*net.TCPAddr, error) {
 	addr, err := net.ResolveTCPAddr("tcp", raw)
@@ -227,6 +414,22 @@ func (c *Config) mergeConfigWith(otherConfig *Config) error {
 		c.Seal = true
 	}
 
+	if otherConfig.AddressIndex {
+		c.AddressIndex = true
+	}
+
+	if otherConfig.InternalTransfers {
+		c.InternalTransfers = true
+	}
+
+	if otherConfig.ParallelExecution != 0 {
+		c.ParallelExecution = otherConfig.ParallelExecution
+	}
+
+	if otherConfig.PendingBlock {
+		c.PendingBlock = true
+	}
+
 	if otherConfig.LogLevel != "" {
 		c.LogLevel = otherConfig.LogLevel
 	}
@@ -249,6 +452,94 @@ func (c *Config) mergeConfigWith(otherConfig *Config) error {
 		c.JSONRPCAddr = otherConfig.JSONRPCAddr
 	}
 
+	if otherConfig.JSONRPC != nil {
+		if otherConfig.JSONRPC.MaxBlockRange != 0 {
+			c.JSONRPC.MaxBlockRange = otherConfig.JSONRPC.MaxBlockRange
+		}
+
+		if otherConfig.JSONRPC.MaxLogs != 0 {
+			c.JSONRPC.MaxLogs = otherConfig.JSONRPC.MaxLogs
+		}
+
+		if otherConfig.JSONRPC.IPCPath != "" {
+			c.JSONRPC.IPCPath = otherConfig.JSONRPC.IPCPath
+		}
+
+		if len(otherConfig.JSONRPC.Namespaces) != 0 {
+			c.JSONRPC.Namespaces = otherConfig.JSONRPC.Namespaces
+		}
+
+		if len(otherConfig.JSONRPC.Methods) != 0 {
+			c.JSONRPC.Methods = otherConfig.JSONRPC.Methods
+		}
+
+		if len(otherConfig.JSONRPC.CorsOrigins) != 0 {
+			c.JSONRPC.CorsOrigins = otherConfig.JSONRPC.CorsOrigins
+		}
+
+		if len(otherConfig.JSONRPC.VHosts) != 0 {
+			c.JSONRPC.VHosts = otherConfig.JSONRPC.VHosts
+		}
+
+		if otherConfig.JSONRPC.MaxRequestSize != 0 {
+			c.JSONRPC.MaxRequestSize = otherConfig.JSONRPC.MaxRequestSize
+		}
+
+		if otherConfig.JSONRPC.MaxBatchLength != 0 {
+			c.JSONRPC.MaxBatchLength = otherConfig.JSONRPC.MaxBatchLength
+		}
+
+		if otherConfig.JSONRPC.CallTimeout != "" {
+			c.JSONRPC.CallTimeout = otherConfig.JSONRPC.CallTimeout
+		}
+
+		if otherConfig.JSONRPC.RateLimit != 0 {
+			c.JSONRPC.RateLimit = otherConfig.JSONRPC.RateLimit
+		}
+
+		if otherConfig.JSONRPC.RateLimitBurst != 0 {
+			c.JSONRPC.RateLimitBurst = otherConfig.JSONRPC.RateLimitBurst
+		}
+
+		if len(otherConfig.JSONRPC.MethodRateLimits) != 0 {
+			c.JSONRPC.MethodRateLimits = otherConfig.JSONRPC.MethodRateLimits
+		}
+
+		if otherConfig.JSONRPC.SlowRequestThreshold != "" {
+			c.JSONRPC.SlowRequestThreshold = otherConfig.JSONRPC.SlowRequestThreshold
+		}
+
+		if otherConfig.JSONRPC.GraphQL {
+			c.JSONRPC.GraphQL = true
+		}
+
+		if otherConfig.JSONRPC.Keystore != "" {
+			c.JSONRPC.Keystore = otherConfig.JSONRPC.Keystore
+		}
+
+		if otherAuth := otherConfig.JSONRPC.Auth; otherAuth != nil {
+			if otherAuth.Addr != "" {
+				c.JSONRPC.Auth.Addr = otherAuth.Addr
+			}
+
+			if otherAuth.JWTSecret != "" {
+				c.JSONRPC.Auth.JWTSecret = otherAuth.JWTSecret
+			}
+
+			if len(otherAuth.Namespaces) != 0 {
+				c.JSONRPC.Auth.Namespaces = otherAuth.Namespaces
+			}
+
+			if len(otherAuth.Methods) != 0 {
+				c.JSONRPC.Auth.Methods = otherAuth.Methods
+			}
+
+			if len(otherAuth.VHosts) != 0 {
+				c.JSONRPC.Auth.VHosts = otherAuth.VHosts
+			}
+		}
+	}
+
 	if otherConfig.Join != "" {
 		c.Join = otherConfig.Join
 	}
@@ -308,6 +599,21 @@ func (c *Config) mergeConfigWith(otherConfig *Config) error {
 		c.BlockTime = otherConfig.BlockTime
 	}
 
+	if otherConfig.Pruning != nil {
+		// State pruning
+		if otherConfig.Pruning.Mode != "" {
+			c.Pruning.Mode = otherConfig.Pruning.Mode
+		}
+
+		if otherConfig.Pruning.Retention != 0 {
+			c.Pruning.Retention = otherConfig.Pruning.Retention
+		}
+
+		if otherConfig.Pruning.CheckpointInterval != 0 {
+			c.Pruning.CheckpointInterval = otherConfig.Pruning.CheckpointInterval
+		}
+	}
+
 	// elastic config
 	if otherConfig.EsOpen != false {
 		c.EsOpen = otherConfig.EsOpen
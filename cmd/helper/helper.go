@@ -315,6 +315,8 @@ func ReadConfig(baseCommand string, args []string) (*Config, error) {
 		Network:   &Network{},
 		TxPool:    &TxPool{},
 		Telemetry: &Telemetry{},
+		Pruning:   &Pruning{},
+		JSONRPC:   &JSONRPC{Auth: &JSONRPCAuth{}},
 	}
 
 	flags := flag.NewFlagSet(baseCommand, flag.ContinueOnError)
@@ -324,11 +326,36 @@ func ReadConfig(baseCommand string, args []string) (*Config, error) {
 
 	flags.StringVar(&cliConfig.LogLevel, "log-level", "", "")
 	flags.BoolVar(&cliConfig.Seal, "seal", false, "")
+	flags.BoolVar(&cliConfig.AddressIndex, "address-index", false, "")
+	flags.BoolVar(&cliConfig.InternalTransfers, "internal-transfers", false, "")
+	flags.Uint64Var(&cliConfig.ParallelExecution, "parallel-execution", 0, "")
+	flags.BoolVar(&cliConfig.PendingBlock, "pending-block", false, "")
 	flags.StringVar(&configFile, "config", "", "")
 	flags.StringVar(&cliConfig.Chain, "chain", "", "")
 	flags.StringVar(&cliConfig.DataDir, "data-dir", "", "")
 	flags.StringVar(&cliConfig.GRPCAddr, "grpc", "", "")
 	flags.StringVar(&cliConfig.JSONRPCAddr, "jsonrpc", "", "")
+	flags.Uint64Var(&cliConfig.JSONRPC.MaxBlockRange, "jsonrpc-max-block-range", 0, "")
+	flags.Uint64Var(&cliConfig.JSONRPC.MaxLogs, "jsonrpc-max-logs", 0, "")
+	flags.StringVar(&cliConfig.JSONRPC.IPCPath, "jsonrpc-ipc", "", "")
+	flags.Var((*helperFlags.ArrayFlags)(&cliConfig.JSONRPC.Namespaces), "jsonrpc-namespace", "")
+	flags.Var((*helperFlags.ArrayFlags)(&cliConfig.JSONRPC.Methods), "jsonrpc-method", "")
+	flags.Var((*helperFlags.ArrayFlags)(&cliConfig.JSONRPC.CorsOrigins), "jsonrpc-cors-origin", "")
+	flags.Var((*helperFlags.ArrayFlags)(&cliConfig.JSONRPC.VHosts), "jsonrpc-vhost", "")
+	flags.StringVar(&cliConfig.JSONRPC.Auth.Addr, "jsonrpc-auth", "", "")
+	flags.StringVar(&cliConfig.JSONRPC.Auth.JWTSecret, "jsonrpc-auth-jwt-secret", "", "")
+	flags.Var((*helperFlags.ArrayFlags)(&cliConfig.JSONRPC.Auth.Namespaces), "jsonrpc-auth-namespace", "")
+	flags.Var((*helperFlags.ArrayFlags)(&cliConfig.JSONRPC.Auth.Methods), "jsonrpc-auth-method", "")
+	flags.Var((*helperFlags.ArrayFlags)(&cliConfig.JSONRPC.Auth.VHosts), "jsonrpc-auth-vhost", "")
+	flags.Int64Var(&cliConfig.JSONRPC.MaxRequestSize, "jsonrpc-max-request-size", 0, "")
+	flags.Uint64Var(&cliConfig.JSONRPC.MaxBatchLength, "jsonrpc-max-batch-length", 0, "")
+	flags.StringVar(&cliConfig.JSONRPC.CallTimeout, "jsonrpc-call-timeout", "", "")
+	flags.Uint64Var(&cliConfig.JSONRPC.RateLimit, "jsonrpc-rate-limit", 0, "")
+	flags.Uint64Var(&cliConfig.JSONRPC.RateLimitBurst, "jsonrpc-rate-limit-burst", 0, "")
+	flags.Var((*helperFlags.ArrayFlags)(&cliConfig.JSONRPC.MethodRateLimits), "jsonrpc-method-rate-limit", "")
+	flags.StringVar(&cliConfig.JSONRPC.SlowRequestThreshold, "jsonrpc-slow-request-threshold", "", "")
+	flags.BoolVar(&cliConfig.JSONRPC.GraphQL, "jsonrpc-graphql", false, "")
+	flags.StringVar(&cliConfig.JSONRPC.Keystore, "jsonrpc-keystore", "", "")
 	flags.StringVar(&cliConfig.Join, "join", "", "")
 	flags.StringVar(&cliConfig.Network.Addr, "libp2p", "", "")
 	flags.StringVar(&cliConfig.Telemetry.PrometheusAddr, "prometheus", "", "")
@@ -358,6 +385,9 @@ func ReadConfig(baseCommand string, args []string) (*Config, error) {
 	flags.StringVar(&cliConfig.EsAddr, "es-addr", "", "")
 	flags.StringVar(&cliConfig.EsIndex, "es-index", "tie-logs", "")
 	flags.StringVar(&cliConfig.EsOwner, "es-owner", "tie-node", "")
+	flags.StringVar(&cliConfig.Pruning.Mode, "pruning", "", "")
+	flags.Uint64Var(&cliConfig.Pruning.Retention, "pruning-retention", 0, "")
+	flags.Uint64Var(&cliConfig.Pruning.CheckpointInterval, "pruning-checkpoint", 0, "")
 
 	if err := flags.Parse(args); err != nil {
 		return nil, err
@@ -46,6 +46,43 @@ func (c *ServerCommand) DefineFlags() {
 		FlagOptional: true,
 	}
 
+	c.FlagMap["address-index"] = helper.FlagDescriptor{
+		Description: "Sets the flag indicating that the client should index the transactions " +
+			"and the token transfers by address. Default: false",
+		Arguments: []string{
+			"SHOULD_INDEX",
+		},
+		FlagOptional: true,
+	}
+
+	c.FlagMap["internal-transfers"] = helper.FlagDescriptor{
+		Description: "Sets the flag indicating that the client should store the internal value " +
+			"transfers of the new blocks. Default: false",
+		Arguments: []string{
+			"SHOULD_STORE",
+		},
+		FlagOptional: true,
+	}
+
+	c.FlagMap["parallel-execution"] = helper.FlagDescriptor{
+		Description: "Sets the number of transactions of a block executed in parallel, when building " +
+			"and importing blocks. Default: 0 (sequential execution)",
+		Arguments: []string{
+			"PARALLEL_EXECUTION",
+		},
+		FlagOptional: true,
+	}
+
+	c.FlagMap["pending-block"] = helper.FlagDescriptor{
+		Description: "Sets the flag indicating that the client should build the pending block from the " +
+			"executable transactions of the pool, served by the JSON-RPC pending queries. " +
+			"Default: false (the pending queries are served by the latest block)",
+		Arguments: []string{
+			"SHOULD_BUILD_PENDING_BLOCK",
+		},
+		FlagOptional: true,
+	}
+
 	c.FlagMap["block-gas-target"] = helper.FlagDescriptor{
 		Description: "Sets the target block gas limit for the chain. If omitted, the value of the parent block is used",
 		Arguments: []string{
@@ -177,6 +214,210 @@ func (c *ServerCommand) DefineFlags() {
 		FlagOptional: true,
 	}
 
+	c.FlagMap["jsonrpc-max-block-range"] = helper.FlagDescriptor{
+		Description: fmt.Sprintf(
+			"Sets the maximum number of blocks of a log query. Default: %d",
+			helper.DefaultConfig().JSONRPC.MaxBlockRange,
+		),
+		Arguments: []string{
+			"BLOCK_RANGE",
+		},
+		FlagOptional: true,
+	}
+
+	c.FlagMap["jsonrpc-max-logs"] = helper.FlagDescriptor{
+		Description: fmt.Sprintf(
+			"Sets the maximum number of logs returned by a log query. Default: %d",
+			helper.DefaultConfig().JSONRPC.MaxLogs,
+		),
+		Arguments: []string{
+			"LOG_COUNT",
+		},
+		FlagOptional: true,
+	}
+
+	c.FlagMap["jsonrpc-ipc"] = helper.FlagDescriptor{
+		Description: "Sets the path of the unix socket of the JSON-RPC IPC server, relative to the data directory " +
+			"if not absolute. Disabled if not set",
+		Arguments: []string{
+			"IPC_PATH",
+		},
+		FlagOptional: true,
+	}
+
+	c.FlagMap["jsonrpc-namespace"] = helper.FlagDescriptor{
+		Description: "Enables a namespace on the JSON-RPC listener, as eth. " +
+			"Can be repeated. All the namespaces are enabled if neither namespaces nor methods are set",
+		Arguments: []string{
+			"NAMESPACE",
+		},
+		FlagOptional: true,
+	}
+
+	c.FlagMap["jsonrpc-method"] = helper.FlagDescriptor{
+		Description: "Enables a method on the JSON-RPC listener in addition to the namespaces, " +
+			"as txpool_status. Can be repeated",
+		Arguments: []string{
+			"METHOD",
+		},
+		FlagOptional: true,
+	}
+
+	c.FlagMap["jsonrpc-cors-origin"] = helper.FlagDescriptor{
+		Description: "Allows the browsers to call the JSON-RPC listener from an origin, * for any. " +
+			"Can be repeated. Default: *",
+		Arguments: []string{
+			"ORIGIN",
+		},
+		FlagOptional: true,
+	}
+
+	c.FlagMap["jsonrpc-vhost"] = helper.FlagDescriptor{
+		Description: "Accepts a host name in the Host header of the requests to the JSON-RPC listener, " +
+			"* for any. Can be repeated. Default: *",
+		Arguments: []string{
+			"HOST",
+		},
+		FlagOptional: true,
+	}
+
+	c.FlagMap["jsonrpc-auth"] = helper.FlagDescriptor{
+		Description: "Sets the address and port of the JSON-RPC listener authenticated with JWT tokens. " +
+			"Disabled if not set",
+		Arguments: []string{
+			"JSONRPC_AUTH_ADDRESS",
+		},
+		FlagOptional: true,
+	}
+
+	c.FlagMap["jsonrpc-auth-jwt-secret"] = helper.FlagDescriptor{
+		Description: fmt.Sprintf(
+			"Sets the path of the hex encoded HS256 secret of the JWT tokens, relative to the data directory "+
+				"if not absolute. It is generated if it does not exist. Default: %s",
+			helper.DefaultConfig().JSONRPC.Auth.JWTSecret,
+		),
+		Arguments: []string{
+			"JWT_SECRET_PATH",
+		},
+		FlagOptional: true,
+	}
+
+	c.FlagMap["jsonrpc-auth-namespace"] = helper.FlagDescriptor{
+		Description: "Enables a namespace on the authenticated JSON-RPC listener. " +
+			"Can be repeated. All the namespaces are enabled if neither namespaces nor methods are set",
+		Arguments: []string{
+			"NAMESPACE",
+		},
+		FlagOptional: true,
+	}
+
+	c.FlagMap["jsonrpc-auth-method"] = helper.FlagDescriptor{
+		Description: "Enables a method on the authenticated JSON-RPC listener in addition to the namespaces. " +
+			"Can be repeated",
+		Arguments: []string{
+			"METHOD",
+		},
+		FlagOptional: true,
+	}
+
+	c.FlagMap["jsonrpc-auth-vhost"] = helper.FlagDescriptor{
+		Description: "Accepts a host name in the Host header of the requests to the authenticated JSON-RPC " +
+			"listener, * for any. Can be repeated. Default: localhost",
+		Arguments: []string{
+			"HOST",
+		},
+		FlagOptional: true,
+	}
+
+	c.FlagMap["jsonrpc-max-request-size"] = helper.FlagDescriptor{
+		Description: fmt.Sprintf(
+			"Sets the maximum size in bytes of a JSON-RPC request. Default: %d",
+			helper.DefaultConfig().JSONRPC.MaxRequestSize,
+		),
+		Arguments: []string{
+			"BYTES",
+		},
+		FlagOptional: true,
+	}
+
+	c.FlagMap["jsonrpc-max-batch-length"] = helper.FlagDescriptor{
+		Description: fmt.Sprintf(
+			"Sets the maximum number of requests of a JSON-RPC batch. Default: %d",
+			helper.DefaultConfig().JSONRPC.MaxBatchLength,
+		),
+		Arguments: []string{
+			"BATCH_LENGTH",
+		},
+		FlagOptional: true,
+	}
+
+	c.FlagMap["jsonrpc-call-timeout"] = helper.FlagDescriptor{
+		Description: fmt.Sprintf(
+			"Sets the maximum execution time of eth_call, eth_estimateGas and eth_simulateV1, 0s for no limit. "+
+				"Default: %s",
+			helper.DefaultConfig().JSONRPC.CallTimeout,
+		),
+		Arguments: []string{
+			"DURATION",
+		},
+		FlagOptional: true,
+	}
+
+	c.FlagMap["jsonrpc-rate-limit"] = helper.FlagDescriptor{
+		Description: "Sets the number of requests per second allowed to a client IP on the JSON-RPC listener. " +
+			"A websocket message is a request. Disabled if not set",
+		Arguments: []string{
+			"RATE",
+		},
+		FlagOptional: true,
+	}
+
+	c.FlagMap["jsonrpc-rate-limit-burst"] = helper.FlagDescriptor{
+		Description: "Sets the number of requests a client IP can make at once on the JSON-RPC listener. " +
+			"Default: the rate limit",
+		Arguments: []string{
+			"BURST",
+		},
+		FlagOptional: true,
+	}
+
+	c.FlagMap["jsonrpc-method-rate-limit"] = helper.FlagDescriptor{
+		Description: "Sets the number of calls per second allowed to a method for all the clients of the " +
+			"JSON-RPC listener, as eth_getLogs=10. Can be repeated",
+		Arguments: []string{
+			"METHOD=RATE",
+		},
+		FlagOptional: true,
+	}
+
+	c.FlagMap["jsonrpc-slow-request-threshold"] = helper.FlagDescriptor{
+		Description: "Logs the method and the params of the JSON-RPC requests taking longer than the duration, " +
+			"as 500ms. Disabled if not set",
+		Arguments: []string{
+			"DURATION",
+		},
+		FlagOptional: true,
+	}
+
+	c.FlagMap["jsonrpc-graphql"] = helper.FlagDescriptor{
+		Description: "Sets the flag indicating that the JSON-RPC listeners should serve the EIP-1767 " +
+			"GraphQL queries at /graphql. Default: false",
+		Arguments: []string{
+			"SHOULD_SERVE_GRAPHQL",
+		},
+		FlagOptional: true,
+	}
+
+	c.FlagMap["jsonrpc-keystore"] = helper.FlagDescriptor{
+		Description: "Sets the directory of the encrypted keys of the personal_ and signing JSON-RPC methods, " +
+			"relative to the data directory if not absolute. The methods are only served by the authenticated " +
+			"and the IPC listeners. Default: disabled",
+		Arguments: []string{
+			"KEYSTORE_DIR",
+		},
+		FlagOptional: true,
+	}
+
 	c.FlagMap["price-limit"] = helper.FlagDescriptor{
 		Description: fmt.Sprintf(
 			"Sets minimum gas price limit to enforce for acceptance into the pool. Default: %d",
@@ -196,6 +437,37 @@ func (c *ServerCommand) DefineFlags() {
 		FlagOptional: true,
 	}
 
+	c.FlagMap["pruning"] = helper.FlagDescriptor{
+		Description: fmt.Sprintf(
+			"Sets the state storage mode, archive keeps every historic state and pruned only the recent ones. Default: %s",
+			helper.DefaultConfig().Pruning.Mode,
+		),
+		Arguments: []string{
+			"PRUNING_MODE",
+		},
+		FlagOptional: true,
+	}
+
+	c.FlagMap["pruning-retention"] = helper.FlagDescriptor{
+		Description: fmt.Sprintf(
+			"Sets the number of recent block states kept in pruned mode. Default: %d",
+			helper.DefaultConfig().Pruning.Retention,
+		),
+		Arguments: []string{
+			"PRUNING_RETENTION",
+		},
+		FlagOptional: true,
+	}
+
+	c.FlagMap["pruning-checkpoint"] = helper.FlagDescriptor{
+		Description: "Sets the block interval of the checkpoint states kept forever in pruned mode. " +
+			"If omitted, no checkpoints are kept",
+		Arguments: []string{
+			"PRUNING_CHECKPOINT",
+		},
+		FlagOptional: true,
+	}
+
 	c.FlagMap["prometheus"] = helper.FlagDescriptor{
 		Description: "Sets the address and port for the prometheus instrumentation service (address:port)",
 		Arguments: []string{
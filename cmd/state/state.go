@@ -0,0 +1,125 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/TIE-Tech/tie-core/cmd/helper"
+	itrie "github.com/TIE-Tech/tie-core/state/trie"
+	"github.com/TIE-Tech/tie-core/storage/leveldb"
+	"github.com/TIE-Tech/tie-core/types"
+	"github.com/mitchellh/cli"
+)
+
+// StateCommand is the top level command for the offline state tools
+type StateCommand struct {
+}
+
+// Help implements the cli.Command interface
+func (c *StateCommand) Help() string {
+	return c.Synopsis()
+}
+
+func (c *StateCommand) GetBaseCommand() string {
+	return "state"
+}
+
+// Synopsis implements the cli.Command interface
+func (c *StateCommand) Synopsis() string {
+	return "Top level command for dumping, verifying and importing the world state. Only accepts subcommands"
+}
+
+// Run implements the cli.Command interface
+func (c *StateCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
+
+// defineRootFlags defines the flags selecting the state of a data directory
+func defineRootFlags(flagMap map[string]helper.FlagDescriptor) {
+	flagMap["data-dir"] = helper.FlagDescriptor{
+		Description: "Sets the data directory of the stopped node",
+		Arguments: []string{
+			"DATA_DIRECTORY",
+		},
+		ArgumentsOptional: false,
+	}
+
+	flagMap["block"] = helper.FlagDescriptor{
+		Description: "The number of the block whose state is used. Default: latest",
+		Arguments: []string{
+			"BLOCK_NUMBER",
+		},
+		ArgumentsOptional: false,
+		FlagOptional:      true,
+	}
+
+	flagMap["root"] = helper.FlagDescriptor{
+		Description: "The state root to use instead of the one of a block",
+		Arguments: []string{
+			"STATE_ROOT",
+		},
+		ArgumentsOptional: false,
+		FlagOptional:      true,
+	}
+}
+
+// openState opens the trie storage of a data directory
+func openState(dataDir string) (*itrie.State, itrie.Storage, error) {
+	storage, err := itrie.NewLevelDBStorage(filepath.Join(dataDir, "trie"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open the state storage: %w", err)
+	}
+
+	return itrie.NewState(storage), storage, nil
+}
+
+// resolveRoot returns the state root to use, either the given root
+// or the one of the block header read from the data directory
+func resolveRoot(dataDir, rawBlock, rawRoot string) (types.Hash, error) {
+	if rawRoot != "" {
+		if rawBlock != "" {
+			return types.Hash{}, errors.New("only one of block and root can be set")
+		}
+
+		root := types.Hash{}
+		if err := root.UnmarshalText([]byte(rawRoot)); err != nil {
+			return types.Hash{}, fmt.Errorf("failed to decode root: %w", err)
+		}
+
+		return root, nil
+	}
+
+	db, err := leveldb.NewLevelDBStorage(filepath.Join(dataDir, "blockchain"))
+	if err != nil {
+		return types.Hash{}, fmt.Errorf("failed to open the blockchain storage: %w", err)
+	}
+	defer db.Close()
+
+	var (
+		hash types.Hash
+		ok   bool
+	)
+
+	if rawBlock == "" || rawBlock == "latest" {
+		hash, ok = db.ReadHeadHash()
+	} else {
+		number, err := types.ParseUint64orHex(&rawBlock)
+		if err != nil {
+			return types.Hash{}, fmt.Errorf("failed to decode block: %w", err)
+		}
+
+		hash, ok = db.ReadCanonicalHash(number)
+	}
+
+	if !ok {
+		return types.Hash{}, errors.New("block not found")
+	}
+
+	header, err := db.ReadHeader(hash)
+	if err != nil {
+		return types.Hash{}, fmt.Errorf("failed to read header %s: %w", hash, err)
+	}
+
+	return header.StateRoot, nil
+}
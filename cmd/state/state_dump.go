@@ -0,0 +1,176 @@
+package state
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/TIE-Tech/tie-core/cmd/helper"
+	"github.com/TIE-Tech/tie-core/params"
+	itrie "github.com/TIE-Tech/tie-core/state/trie"
+	"github.com/TIE-Tech/tie-core/types"
+)
+
+// StateDump is the command to dump the accounts of a state
+type StateDump struct {
+	helper.Base
+	Formatter *helper.FormatterFlag
+}
+
+// DefineFlags defines the command flags
+func (c *StateDump) DefineFlags() {
+	c.Base.DefineFlags(c.Formatter)
+
+	defineRootFlags(c.FlagMap)
+
+	c.FlagMap["out"] = helper.FlagDescriptor{
+		Description: "The path of the JSONL file to write the accounts to",
+		Arguments: []string{
+			"OUT",
+		},
+		ArgumentsOptional: false,
+	}
+
+	c.FlagMap["chain"] = helper.FlagDescriptor{
+		Description: "The genesis file used to resolve the addresses and storage keys of its allocations",
+		Arguments: []string{
+			"GENESIS_FILE",
+		},
+		ArgumentsOptional: false,
+		FlagOptional:      true,
+	}
+}
+
+// GetHelperText returns a simple description of the command
+func (c *StateDump) GetHelperText() string {
+	return "Dumps the accounts, code and storage of a state to a JSONL file"
+}
+
+func (c *StateDump) GetBaseCommand() string {
+	return "state dump"
+}
+
+// Help implements the cli.Command interface
+func (c *StateDump) Help() string {
+	c.DefineFlags()
+
+	return helper.GenerateHelp(c.Synopsis(), helper.GenerateUsage(c.GetBaseCommand(), c.FlagMap), c.FlagMap)
+}
+
+// Synopsis implements the cli.Command interface
+func (c *StateDump) Synopsis() string {
+	return c.GetHelperText()
+}
+
+// Run implements the cli.Command interface
+func (c *StateDump) Run(args []string) int {
+	flags := c.Base.NewFlagSet(c.GetBaseCommand(), c.Formatter)
+
+	var dataDir, rawBlock, rawRoot, out, chain string
+
+	flags.StringVar(&dataDir, "data-dir", "", "")
+	flags.StringVar(&rawBlock, "block", "", "")
+	flags.StringVar(&rawRoot, "root", "", "")
+	flags.StringVar(&out, "out", "", "")
+	flags.StringVar(&chain, "chain", "", "")
+
+	if err := flags.Parse(args); err != nil {
+		c.Formatter.OutputError(err)
+
+		return 1
+	}
+
+	if dataDir == "" {
+		c.Formatter.OutputError(errors.New("the data directory is required"))
+
+		return 1
+	}
+
+	if out == "" {
+		c.Formatter.OutputError(errors.New("the path of the dump file is required"))
+
+		return 1
+	}
+
+	var preimages *itrie.Preimages
+
+	if chain != "" {
+		cc, err := params.ImportFromFile(chain)
+		if err != nil {
+			c.Formatter.OutputError(fmt.Errorf("failed to load genesis: %w", err))
+
+			return 1
+		}
+
+		preimages = itrie.NewPreimages(cc.Genesis.Alloc)
+	}
+
+	root, err := resolveRoot(dataDir, rawBlock, rawRoot)
+	if err != nil {
+		c.Formatter.OutputError(err)
+
+		return 1
+	}
+
+	st, storage, err := openState(dataDir)
+	if err != nil {
+		c.Formatter.OutputError(err)
+
+		return 1
+	}
+	defer storage.Close()
+
+	count, err := dumpToFile(st, root, preimages, out)
+	if err != nil {
+		c.Formatter.OutputError(err)
+
+		return 1
+	}
+
+	c.Formatter.OutputResult(&StateDumpResult{
+		Root:     root,
+		Accounts: count,
+		Out:      out,
+	})
+
+	return 0
+}
+
+func dumpToFile(st *itrie.State, root types.Hash, preimages *itrie.Preimages, out string) (int, error) {
+	f, err := os.Create(out)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+
+	count, err := itrie.Dump(st, root, preimages, w)
+	if err != nil {
+		return count, err
+	}
+
+	return count, w.Flush()
+}
+
+type StateDumpResult struct {
+	Root     types.Hash `json:"root"`
+	Accounts int        `json:"accounts"`
+	Out      string     `json:"out"`
+}
+
+func (r *StateDumpResult) Output() string {
+	var buffer bytes.Buffer
+
+	buffer.WriteString("\n[STATE DUMP]\n")
+	buffer.WriteString("Dumped state successfully:\n")
+	buffer.WriteString(helper.FormatKV([]string{
+		fmt.Sprintf("File|%s", r.Out),
+		fmt.Sprintf("Root|%s", r.Root),
+		fmt.Sprintf("Accounts|%d", r.Accounts),
+	}))
+
+	return buffer.String()
+}
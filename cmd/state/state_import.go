@@ -0,0 +1,201 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/TIE-Tech/tie-core/cmd/helper"
+	itrie "github.com/TIE-Tech/tie-core/state/trie"
+	"github.com/TIE-Tech/tie-core/types"
+)
+
+// StateImport is the command to build a state from a dump
+type StateImport struct {
+	helper.Base
+	Formatter *helper.FormatterFlag
+}
+
+// DefineFlags defines the command flags
+func (c *StateImport) DefineFlags() {
+	c.Base.DefineFlags(c.Formatter)
+
+	c.FlagMap["in"] = helper.FlagDescriptor{
+		Description: "The path of the JSONL dump to import",
+		Arguments: []string{
+			"IN",
+		},
+		ArgumentsOptional: false,
+	}
+
+	c.FlagMap["data-dir"] = helper.FlagDescriptor{
+		Description: "Sets the data directory to write the state trie to",
+		Arguments: []string{
+			"DATA_DIRECTORY",
+		},
+		ArgumentsOptional: false,
+		FlagOptional:      true,
+	}
+
+	c.FlagMap["genesis"] = helper.FlagDescriptor{
+		Description: "Sets the path of the file to write the accounts to as a genesis allocation. " +
+			"Requires a dump with the address and storage key preimages",
+		Arguments: []string{
+			"ALLOC_FILE",
+		},
+		ArgumentsOptional: false,
+		FlagOptional:      true,
+	}
+}
+
+// GetHelperText returns a simple description of the command
+func (c *StateImport) GetHelperText() string {
+	return "Builds a state or a genesis allocation from a state dump"
+}
+
+func (c *StateImport) GetBaseCommand() string {
+	return "state import"
+}
+
+// Help implements the cli.Command interface
+func (c *StateImport) Help() string {
+	c.DefineFlags()
+
+	return helper.GenerateHelp(c.Synopsis(), helper.GenerateUsage(c.GetBaseCommand(), c.FlagMap), c.FlagMap)
+}
+
+// Synopsis implements the cli.Command interface
+func (c *StateImport) Synopsis() string {
+	return c.GetHelperText()
+}
+
+// Run implements the cli.Command interface
+func (c *StateImport) Run(args []string) int {
+	flags := c.Base.NewFlagSet(c.GetBaseCommand(), c.Formatter)
+
+	var in, dataDir, genesis string
+
+	flags.StringVar(&in, "in", "", "")
+	flags.StringVar(&dataDir, "data-dir", "", "")
+	flags.StringVar(&genesis, "genesis", "", "")
+
+	if err := flags.Parse(args); err != nil {
+		c.Formatter.OutputError(err)
+
+		return 1
+	}
+
+	if in == "" {
+		c.Formatter.OutputError(errors.New("the path of the dump file is required"))
+
+		return 1
+	}
+
+	if dataDir == "" && genesis == "" {
+		c.Formatter.OutputError(errors.New("at least one of data directory and genesis is required"))
+
+		return 1
+	}
+
+	res := &StateImportResult{
+		DataDir: dataDir,
+		Genesis: genesis,
+	}
+
+	if dataDir != "" {
+		root, count, err := importToDataDir(in, dataDir)
+		if err != nil {
+			c.Formatter.OutputError(err)
+
+			return 1
+		}
+
+		res.Root, res.Accounts = &root, count
+	}
+
+	if genesis != "" {
+		count, err := importToGenesis(in, genesis)
+		if err != nil {
+			c.Formatter.OutputError(err)
+
+			return 1
+		}
+
+		res.Accounts = count
+	}
+
+	c.Formatter.OutputResult(res)
+
+	return 0
+}
+
+func importToDataDir(in, dataDir string) (types.Hash, int, error) {
+	f, err := os.Open(in)
+	if err != nil {
+		return types.Hash{}, 0, err
+	}
+	defer f.Close()
+
+	st, storage, err := openState(dataDir)
+	if err != nil {
+		return types.Hash{}, 0, err
+	}
+	defer storage.Close()
+
+	return itrie.Import(st, f)
+}
+
+func importToGenesis(in, out string) (int, error) {
+	f, err := os.Open(in)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	alloc, err := itrie.GenesisAlloc(f)
+	if err != nil {
+		return 0, err
+	}
+
+	data, err := json.MarshalIndent(alloc, "", "    ")
+	if err != nil {
+		return 0, err
+	}
+
+	return len(alloc), ioutil.WriteFile(out, data, 0600)
+}
+
+type StateImportResult struct {
+	Root     *types.Hash `json:"root,omitempty"`
+	Accounts int         `json:"accounts"`
+	DataDir  string      `json:"data_dir,omitempty"`
+	Genesis  string      `json:"genesis,omitempty"`
+}
+
+func (r *StateImportResult) Output() string {
+	var buffer bytes.Buffer
+
+	output := []string{
+		fmt.Sprintf("Accounts|%d", r.Accounts),
+	}
+
+	if r.Root != nil {
+		output = append(output,
+			fmt.Sprintf("Data Dir|%s", r.DataDir),
+			fmt.Sprintf("Root|%s", r.Root),
+		)
+	}
+
+	if r.Genesis != "" {
+		output = append(output, fmt.Sprintf("Genesis Alloc|%s", r.Genesis))
+	}
+
+	buffer.WriteString("\n[STATE IMPORT]\n")
+	buffer.WriteString("Imported state successfully:\n")
+	buffer.WriteString(helper.FormatKV(output))
+
+	return buffer.String()
+}
@@ -0,0 +1,146 @@
+package state
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/TIE-Tech/tie-core/cmd/helper"
+	itrie "github.com/TIE-Tech/tie-core/state/trie"
+	"github.com/TIE-Tech/tie-core/types"
+)
+
+// StateVerify is the command to check the nodes of a state
+type StateVerify struct {
+	helper.Base
+	Formatter *helper.FormatterFlag
+}
+
+// DefineFlags defines the command flags
+func (c *StateVerify) DefineFlags() {
+	c.Base.DefineFlags(c.Formatter)
+
+	defineRootFlags(c.FlagMap)
+}
+
+// GetHelperText returns a simple description of the command
+func (c *StateVerify) GetHelperText() string {
+	return "Walks the trie of a state, re-hashes every node and reports the missing or corrupt ones"
+}
+
+func (c *StateVerify) GetBaseCommand() string {
+	return "state verify"
+}
+
+// Help implements the cli.Command interface
+func (c *StateVerify) Help() string {
+	c.DefineFlags()
+
+	return helper.GenerateHelp(c.Synopsis(), helper.GenerateUsage(c.GetBaseCommand(), c.FlagMap), c.FlagMap)
+}
+
+// Synopsis implements the cli.Command interface
+func (c *StateVerify) Synopsis() string {
+	return c.GetHelperText()
+}
+
+// Run implements the cli.Command interface
+func (c *StateVerify) Run(args []string) int {
+	flags := c.Base.NewFlagSet(c.GetBaseCommand(), c.Formatter)
+
+	var dataDir, rawBlock, rawRoot string
+
+	flags.StringVar(&dataDir, "data-dir", "", "")
+	flags.StringVar(&rawBlock, "block", "", "")
+	flags.StringVar(&rawRoot, "root", "", "")
+
+	if err := flags.Parse(args); err != nil {
+		c.Formatter.OutputError(err)
+
+		return 1
+	}
+
+	if dataDir == "" {
+		c.Formatter.OutputError(errors.New("the data directory is required"))
+
+		return 1
+	}
+
+	root, err := resolveRoot(dataDir, rawBlock, rawRoot)
+	if err != nil {
+		c.Formatter.OutputError(err)
+
+		return 1
+	}
+
+	st, storage, err := openState(dataDir)
+	if err != nil {
+		c.Formatter.OutputError(err)
+
+		return 1
+	}
+	defer storage.Close()
+
+	res := &StateVerifyResult{
+		Root:         root,
+		VerifyResult: itrie.Verify(st, root),
+	}
+	c.Formatter.OutputResult(res)
+
+	if !res.Valid() {
+		return 1
+	}
+
+	return 0
+}
+
+type StateVerifyResult struct {
+	Root types.Hash `json:"root"`
+	*itrie.VerifyResult
+}
+
+func (r *StateVerifyResult) Output() string {
+	var buffer bytes.Buffer
+
+	buffer.WriteString("\n[STATE VERIFY]\n")
+	buffer.WriteString(helper.FormatKV([]string{
+		fmt.Sprintf("Root|%s", r.Root),
+		fmt.Sprintf("Valid|%t", r.Valid()),
+		fmt.Sprintf("Nodes|%d", r.Nodes),
+		fmt.Sprintf("Accounts|%d", r.Accounts),
+	}))
+
+	for _, list := range []struct {
+		title  string
+		hashes []types.Hash
+	}{
+		{"Missing nodes", r.Missing},
+		{"Corrupt nodes", r.Corrupt},
+		{"Missing code", r.MissingCode},
+	} {
+		if len(list.hashes) == 0 {
+			continue
+		}
+
+		buffer.WriteString(fmt.Sprintf("\n\n[%s]\n", list.title))
+
+		for _, hash := range list.hashes {
+			buffer.WriteString(hash.String() + "\n")
+		}
+	}
+
+	if len(r.CorruptAccounts) != 0 {
+		buffer.WriteString("\n\n[Corrupt accounts]\n")
+
+		rows := []string{"Account|Node"}
+		for _, account := range r.CorruptAccounts {
+			rows = append(rows, fmt.Sprintf("%s|%s", account.Account, account.Node))
+		}
+
+		buffer.WriteString(helper.FormatList(rows))
+	}
+
+	buffer.WriteString("\n")
+
+	return buffer.String()
+}
@@ -186,6 +186,7 @@ func (t *TxPoolSubscribeCommand) Run(args []string) int {
 			t.Formatter.OutputResult(&TxPoolEventResult{
 				EventType: streamEvent.Type,
 				TxHash:    streamEvent.TxHash,
+				Reason:    streamEvent.Reason,
 			})
 		}
 
@@ -209,6 +210,7 @@ func (t *TxPoolSubscribeCommand) Run(args []string) int {
 type TxPoolEventResult struct {
 	EventType txpoolProto.EventType `json:"eventType"`
 	TxHash    string                `json:"txHash"`
+	Reason    string                `json:"reason,omitempty"`
 }
 
 func (r *TxPoolEventResult) Output() string {
@@ -216,10 +218,16 @@ func (r *TxPoolEventResult) Output() string {
 
 	// current number & hash
 	buffer.WriteString("\n[TXPOOL EVENT]\n")
-	buffer.WriteString(helper.FormatKV([]string{
+	vals := []string{
 		fmt.Sprintf("TYPE|%s", r.EventType),
 		fmt.Sprintf("HASH|%s", r.TxHash),
-	}))
+	}
+
+	if r.Reason != "" {
+		vals = append(vals, fmt.Sprintf("REASON|%s", r.Reason))
+	}
+
+	buffer.WriteString(helper.FormatKV(vals))
 	buffer.WriteString("\n")
 
 	return buffer.String()
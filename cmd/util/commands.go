@@ -12,6 +12,7 @@ import (
 	"github.com/TIE-Tech/tie-core/cmd/peers"
 	"github.com/TIE-Tech/tie-core/cmd/secrets"
 	"github.com/TIE-Tech/tie-core/cmd/server"
+	"github.com/TIE-Tech/tie-core/cmd/state"
 	"github.com/TIE-Tech/tie-core/cmd/status"
 	"github.com/TIE-Tech/tie-core/cmd/txpool"
 	"github.com/TIE-Tech/tie-core/cmd/version"
@@ -55,6 +56,11 @@ func Commands() map[string]cli.CommandFactory {
 	txPoolStatusCmd := txpool.TxPoolStatus{Base: base, Formatter: formatter, GRPC: grpc}
 	txPoolSubscribeCmd := txpool.TxPoolSubscribeCommand{Base: base, Formatter: formatter, GRPC: grpc}
 
+	stateCmd := state.StateCommand{}
+	stateDumpCmd := state.StateDump{Base: base, Formatter: formatter}
+	stateVerifyCmd := state.StateVerify{Base: base, Formatter: formatter}
+	stateImportCmd := state.StateImport{Base: base, Formatter: formatter}
+
 	loadbotCmd := loadbot.LoadbotCommand{Base: base, Formatter: formatter}
 
 	secretsManagerCmd := secrets.SecretsCommand{}
@@ -130,6 +136,20 @@ func Commands() map[string]cli.CommandFactory {
 			return &backupCmd, nil
 		},
 
+		// STATE COMMANDS //
+		stateCmd.GetBaseCommand(): func() (cli.Command, error) {
+			return &stateCmd, nil
+		},
+		stateDumpCmd.GetBaseCommand(): func() (cli.Command, error) {
+			return &stateDumpCmd, nil
+		},
+		stateVerifyCmd.GetBaseCommand(): func() (cli.Command, error) {
+			return &stateVerifyCmd, nil
+		},
+		stateImportCmd.GetBaseCommand(): func() (cli.Command, error) {
+			return &stateImportCmd, nil
+		},
+
 		// SECRETS MANAGER COMMANDS //
 		secretsManagerCmd.GetBaseCommand(): func() (cli.Command, error) {
 			return &secretsManagerCmd, nil
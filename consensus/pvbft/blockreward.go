@@ -43,8 +43,15 @@ func newBlockReward(chainid int, path string) *BlockReward {
 }
 
 // initReward
-// 1 billion in 20 years, halved every two years
 func (br *BlockReward) initReward() {
+	br.rewardList = append(br.rewardList, rewardList()...)
+	value, _ := json.Marshal(br.rewardList)
+	br.db.Put(rewardListKey, value, nil)
+}
+
+// rewardList returns the reward total of every two years period
+// 1 billion in 20 years, halved every two years
+func rewardList() []*big.Int {
 	total := RewardTotal
 	rewardList := make([]int, 0)
 	for n := uint64(1); n < 10; n++ {
@@ -53,12 +60,12 @@ func (br *BlockReward) initReward() {
 	}
 	rewardList = append(rewardList, total)
 
+	list := make([]*big.Int, 0, len(rewardList))
 	for _, v := range rewardList {
 		bigV := new(big.Int).Mul(big.NewInt(int64(v)), big.NewInt(types.WEI))
-		br.rewardList = append(br.rewardList, bigV)
+		list = append(list, bigV)
 	}
-	value, _ := json.Marshal(br.rewardList)
-	br.db.Put(rewardListKey, value, nil)
+	return list
 }
 
 // calcEpochTotal
@@ -90,6 +97,29 @@ func (br *BlockReward) calcEpochTotal(blockTime, currentEpoch uint64) {
 	logger.Debug("[BFT] Calculate block rewards", "epochTotal", br.epochTotal, "reward", br.reward)
 }
 
+// epochReward returns the reward of each block of the two years period holding the epoch.
+// Unlike GetReward it neither reads nor writes the reward database, so every validator
+// computes the same reward for a block
+func epochReward(blockTime, currentEpoch uint64) *big.Int {
+	list := rewardList()
+
+	// The quota is halved every two years, the last quota is kept afterwards
+	epochTotal := list[len(list)-1]
+	for k := range list {
+		if currentEpoch <= uint64((k+1)*2*types.OneYearEpoch) {
+			epochTotal = list[k]
+			break
+		}
+	}
+
+	outBlock := 86400 * 365 * 2 / (blockTime / 1e9)
+	noWeiTotal := new(big.Int).Div(epochTotal, big.NewInt(types.WEI))
+	reward := new(big.Int).Div(noWeiTotal, new(big.Int).SetUint64(outBlock))
+	reward.Mul(reward, big.NewInt(types.WEI))
+
+	return reward
+}
+
 func (br *BlockReward) GetReward(blockTime, currentEpoch uint64) *big.Int {
 	if br.getRemaining().Cmp(br.reward) < 0 {
 		br.calcEpochTotal(blockTime, currentEpoch)
@@ -132,6 +162,7 @@ func (br *BlockReward) getRemaining() *big.Int {
 }
 
 func (br *BlockReward) rewardTx(prv *ecdsa.PrivateKey, miner, rewardPool types.Address, nonce uint64, amount *big.Int) (*types.Transaction, error) {
+	// the strict system transaction input is the bare method selector
 	data, _ := hex.DecodeHex(types.FixedRewardMethod)
 	tx := &types.Transaction{
 		Nonce:    nonce,
@@ -10,6 +10,7 @@ import (
 	"github.com/TIE-Tech/tie-core/common/crypto/vrf"
 	"github.com/TIE-Tech/tie-core/core/nodekey"
 	"github.com/TIE-Tech/tie-core/metrics"
+	"math/big"
 	"reflect"
 	"time"
 
@@ -252,6 +253,14 @@ func Factory(
 	// Istanbul requires a different header hash function
 	types.HeaderHash = istanbulHeaderHash
 
+	// the chain precompiles read the vrf output of the block from the extra
+	p.executor.GetVrfValue = readVrfValue
+
+	// the executor checks the value of the fixed reward transactions after the SystemTx fork
+	p.executor.GetFixedReward = func(h *types.Header) *big.Int {
+		return epochReward(uint64(p.blockTime), p.GetEpoch(h.Number))
+	}
+
 	p.syncer = syncer.NewSyncer(params.Network, params.Blockchain)
 	return p, nil
 }
@@ -590,6 +599,16 @@ func (i *Ibft) buildBlock(snap *Snapshot, parent *types.Header) (*types.Block, e
 	// we need to include in the extra field the current set of validators
 	putIbftExtraValidators(header, snap.Set)
 
+	// the vrf output is deterministic, once the chain precompiles are active write it
+	// before the execution so the transactions read the same value as the validators
+	// of the sealed block. The blocks before the fork are built as they always were
+	vrfData := i.vrfInfo.GetInfo(header.Number)
+	if i.executor.GetForksInTime(header.Number).ChainPrecompiles {
+		if err := writeVrf(i.validatorKey, header, vrfData); err != nil {
+			return nil, err
+		}
+	}
+
 	transition, err := i.executor.BeginTxn(parent.StateRoot, header, i.validatorKeyAddr)
 	if err != nil {
 		return nil, err
@@ -614,7 +633,6 @@ func (i *Ibft) buildBlock(snap *Snapshot, parent *types.Header) (*types.Block, e
 	})
 
 	// write the seal of the block after all the fields are completed
-	vrfData := i.vrfInfo.GetInfo(header.Number)
 	header, err = writeSeal(i.validatorKey, block.Header, vrfData)
 	if err != nil {
 		return nil, err
@@ -634,6 +652,13 @@ type transitionInterface interface {
 	WriteFailedReceipt(txn *types.Transaction) error
 }
 
+// batchTransition is a transition executing batches of transactions in parallel
+type batchTransition interface {
+	transitionInterface
+	Parallel() bool
+	WriteBatch(txns []*types.Transaction) (int, error)
+}
+
 // writeTransactions writes transactions from the txpool to the transition object
 // and returns transactions that were included in the transition (new block)
 func (i *Ibft) writeTransactions(gasLimit uint64, transition transitionInterface) []*types.Transaction {
@@ -644,46 +669,50 @@ func (i *Ibft) writeTransactions(gasLimit uint64, transition transitionInterface
 
 	i.txpool.Prepare()
 
-	for {
-		tx := i.txpool.Peek()
-		if tx == nil {
-			break
-		}
+	if batch, ok := transition.(batchTransition); ok && batch.Parallel() {
+		transactions, successTxCount, failedTxCount = i.writeTransactionBatches(gasLimit, batch)
+	} else {
+		for {
+			tx := i.txpool.Peek()
+			if tx == nil {
+				break
+			}
+
+			if tx.ExceedsBlockGasLimit(gasLimit) {
+				if err := transition.WriteFailedReceipt(tx); err != nil {
+					failedTxCount++
+
+					i.txpool.Drop(tx)
+
+					continue
+				}
 
-		if tx.ExceedsBlockGasLimit(gasLimit) {
-			if err := transition.WriteFailedReceipt(tx); err != nil {
 				failedTxCount++
 
+				transactions = append(transactions, tx)
 				i.txpool.Drop(tx)
 
 				continue
 			}
 
-			failedTxCount++
+			if err := transition.Write(tx); err != nil {
+				logger.Error("transition.Write err", "hash", tx.Hash, "err", err)
+				if _, ok := err.(*state.GasLimitReachedTransitionApplicationError); ok { // nolint:errorlint
+					break
+					//} else if appErr, ok := err.(*state.TransitionApplicationError); ok && appErr.IsRecoverable { // nolint:errorlint
+					//	i.txpool.Demote(tx)
+				} else {
+					failedTxCount++
+					i.txpool.Drop(tx)
+				}
+				continue
+			}
 
+			// no errors, pop the tx from the pool
+			i.txpool.Pop(tx)
+			successTxCount++
 			transactions = append(transactions, tx)
-			i.txpool.Drop(tx)
-
-			continue
 		}
-
-		if err := transition.Write(tx); err != nil {
-			logger.Error("transition.Write err", "hash", tx.Hash, "err", err)
-			if _, ok := err.(*state.GasLimitReachedTransitionApplicationError); ok { // nolint:errorlint
-				break
-				//} else if appErr, ok := err.(*state.TransitionApplicationError); ok && appErr.IsRecoverable { // nolint:errorlint
-				//	i.txpool.Demote(tx)
-			} else {
-				failedTxCount++
-				i.txpool.Drop(tx)
-			}
-			continue
-		}
-
-		// no errors, pop the tx from the pool
-		i.txpool.Pop(tx)
-		successTxCount++
-		transactions = append(transactions, tx)
 	}
 
 	// Block reward transaction
@@ -696,10 +725,76 @@ func (i *Ibft) writeTransactions(gasLimit uint64, transition transitionInterface
 	return transactions
 }
 
+// writeTransactionBatches writes the transactions from the txpool in batches executed in parallel.
+// A batch holds the executable transaction of every account, the next transactions of the
+// accounts are written in the next batches
+func (i *Ibft) writeTransactionBatches(gasLimit uint64, transition batchTransition) ([]*types.Transaction, int, int) {
+	var transactions []*types.Transaction
+
+	successTxCount := 0
+	failedTxCount := 0
+
+	for {
+		var batch []*types.Transaction
+		for tx := i.txpool.Peek(); tx != nil; tx = i.txpool.Peek() {
+			batch = append(batch, tx)
+		}
+
+		if len(batch) == 0 {
+			return transactions, successTxCount, failedTxCount
+		}
+
+		for len(batch) > 0 {
+			if tx := batch[0]; tx.ExceedsBlockGasLimit(gasLimit) {
+				if err := transition.WriteFailedReceipt(tx); err == nil {
+					transactions = append(transactions, tx)
+				}
+
+				failedTxCount++
+				i.txpool.Drop(tx)
+
+				batch = batch[1:]
+
+				continue
+			}
+
+			// write the transactions up to the next one exceeding the block gas limit
+			n := 1
+			for n < len(batch) && !batch[n].ExceedsBlockGasLimit(gasLimit) {
+				n++
+			}
+
+			written, err := transition.WriteBatch(batch[:n])
+
+			for _, tx := range batch[:written] {
+				i.txpool.Pop(tx)
+				successTxCount++
+				transactions = append(transactions, tx)
+			}
+
+			batch = batch[written:]
+
+			if err != nil {
+				tx := batch[0]
+				logger.Error("transition.Write err", "hash", tx.Hash, "err", err)
+
+				if _, ok := err.(*state.GasLimitReachedTransitionApplicationError); ok { // nolint:errorlint
+					return transactions, successTxCount, failedTxCount
+				}
+
+				failedTxCount++
+				i.txpool.Drop(tx)
+
+				batch = batch[1:]
+			}
+		}
+	}
+}
+
 func (i *Ibft) witeFixedReward(txn transitionInterface) (*types.Transaction, uint64) {
 	header := i.blockchain.Header()
 	block := header.Number + 1
-	reward := i.blockReward.GetReward(uint64(i.blockTime), i.GetEpoch(block))
+	reward := i.fixedReward(block)
 	tx, err := i.executor.BeginTxn(header.StateRoot, header, header.Miner)
 	if err != nil {
 		logger.Error("writeTransactions BeginTxn err", "block", header.Number, "err", err)
@@ -725,6 +820,16 @@ func (i *Ibft) witeFixedReward(txn transitionInterface) (*types.Transaction, uin
 	return rewardTx, block
 }
 
+// fixedReward returns the fixed reward of a block. After the SystemTx fork it is the pure
+// reward of the epoch of the block, which the executor checks on every node
+func (i *Ibft) fixedReward(number uint64) *big.Int {
+	if i.config.Params.Forks.IsSystemTx(number) {
+		return epochReward(uint64(i.blockTime), i.GetEpoch(number))
+	}
+
+	return i.blockReward.GetReward(uint64(i.blockTime), i.GetEpoch(number))
+}
+
 // runAcceptState runs the Accept state loop
 //
 // The Accept state always checks the snapshot, and the validator set. If the current node is not in the validators set,
@@ -12,6 +12,7 @@ import (
 	"github.com/TIE-Tech/tie-core/consensus"
 	"github.com/TIE-Tech/tie-core/consensus/pvbft/proto"
 	"github.com/TIE-Tech/tie-core/core"
+	"github.com/TIE-Tech/tie-core/params"
 	"github.com/TIE-Tech/tie-core/state"
 	"github.com/TIE-Tech/tie-core/types"
 	"github.com/stretchr/testify/assert"
@@ -607,6 +608,26 @@ func TestWriteTransactions(t *testing.T) {
 	}
 }
 
+func TestFixedReward(t *testing.T) {
+	i := &Ibft{
+		config: &consensus.Config{
+			Params: &params.Params{Forks: &params.Forks{SystemTx: params.NewFork(10)}},
+		},
+		epochSize:   types.DefaultEpochSize,
+		blockTime:   2 * time.Second,
+		blockReward: newBlockReward(100, t.TempDir()),
+	}
+
+	// after the fork the reward of the epoch is returned without touching the database
+	reward := i.fixedReward(10)
+	assert.Equal(t, epochReward(uint64(i.blockTime), i.GetEpoch(10)), reward)
+	assert.Equal(t, 0, i.blockReward.getReward().Sign())
+
+	// before the fork the stored reward is used
+	assert.Equal(t, reward, i.fixedReward(1))
+	assert.Equal(t, reward, i.blockReward.getReward())
+}
+
 func TestRunSyncState_NewHeadReceivedFromPeer_CallsTxPoolResetWithHeaders(t *testing.T) {
 	m := newMockIbft(t, []string{"A", "B", "C"}, "A")
 	m.setState(SyncState)
@@ -89,6 +89,22 @@ func writeSeal(prv *ecdsa.PrivateKey, h *types.Header, vrfData []byte) (*types.H
 	return h, nil
 }
 
+// writeVrf writes the vrf output and proof of the block in the extra
+func writeVrf(prv *ecdsa.PrivateKey, h *types.Header, vrfData []byte) error {
+	extra, err := getIbftExtra(h)
+	if err != nil {
+		return err
+	}
+
+	vrfValue, vrfProof, err := vrf.Vrf(prv, vrfData)
+	if err != nil {
+		return err
+	}
+	extra.SetVrfInfo(vrfValue, vrfProof)
+
+	return PutIbftExtra(h, extra)
+}
+
 func writeCommittedSeal(prv *ecdsa.PrivateKey, h *types.Header) ([]byte, error) {
 	return signSealImpl(prv, h, true)
 }
@@ -29,6 +29,16 @@ func getVrfValue(h *types.Header) ([]byte, []byte, error) {
 	return extra.VrfValue, extra.VrfProof, nil
 }
 
+// readVrfValue returns the vrf output of a header, used by the executor
+// to expose the value of the block to the chain precompiles
+func readVrfValue(h *types.Header) []byte {
+	vrfValue, _, err := getVrfValue(h)
+	if err != nil {
+		return nil
+	}
+	return vrfValue
+}
+
 // CalcVrfSeed Calculate vrf seed
 func CalcVrfSeed(h *types.Header) ([]byte, error) {
 	prvVrfValue, _, _ := getVrfValue(h)
@@ -5,6 +5,7 @@ import (
 	"github.com/TIE-Tech/tie-core/common/crypto/keccak"
 	"github.com/TIE-Tech/tie-core/common/hex"
 	"github.com/TIE-Tech/tie-core/params"
+	"math"
 	"math/big"
 
 	"github.com/TIE-Tech/tie-core/types"
@@ -181,3 +182,56 @@ func PredeployStakingSC(validators []types.Address) (*params.GenesisAccount, err
 
 	return stakingAccount, nil
 }
+
+// StorageReader reads the storage of an account
+type StorageReader interface {
+	GetStorage(addr types.Address, key types.Hash) types.Hash
+}
+
+// MaxValidatorsCount is the maximum number of validators read from the staking smart contract.
+// The size of the array is any word in the states overridden by the calls
+const MaxValidatorsCount = 1 << 16
+
+// ReadValidatorsCount returns the size of the validators array of the staking smart contract,
+// math.MaxUint64 if it does not fit in 64 bits
+func ReadValidatorsCount(r StorageReader) uint64 {
+	size := new(big.Int).SetBytes(
+		r.GetStorage(StakingSCAddress, types.BytesToHash(big.NewInt(validatorsSlot).Bytes())).Bytes(),
+	)
+
+	if !size.IsUint64() {
+		return math.MaxUint64
+	}
+
+	return size.Uint64()
+}
+
+// ReadValidators returns the validators of the staking smart contract and their
+// staked amounts, reading the storage slots the contract layout defines.
+// At most MaxValidatorsCount validators are read
+func ReadValidators(r StorageReader) ([]types.Address, []*big.Int) {
+	count := ReadValidatorsCount(r)
+	if count > MaxValidatorsCount {
+		count = MaxValidatorsCount
+	}
+
+	validators := make([]types.Address, 0, count)
+	stakes := make([]*big.Int, 0, count)
+
+	arrayIndex := keccak.Keccak256(nil, PadLeftOrTrim(big.NewInt(validatorsSlot).Bytes(), 32))
+
+	for indx := uint64(0); indx < count; indx++ {
+		validatorIndex := getIndexWithOffset(arrayIndex, int64(indx))
+		validator := types.BytesToAddress(
+			r.GetStorage(StakingSCAddress, types.BytesToHash(validatorIndex)).Bytes(),
+		)
+
+		stakedAmountIndex := getAddressMapping(validator, addressToStakedAmountSlot)
+		stake := r.GetStorage(StakingSCAddress, types.BytesToHash(stakedAmountIndex))
+
+		validators = append(validators, validator)
+		stakes = append(stakes, new(big.Int).SetBytes(stake.Bytes()))
+	}
+
+	return validators, stakes
+}
@@ -0,0 +1,285 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/TIE-Tech/go-logger"
+	"github.com/TIE-Tech/tie-core/storage"
+	"github.com/TIE-Tech/tie-core/types"
+)
+
+var (
+	// TransferEventTopic is the topic of the Transfer(address,address,uint256) event of the tokens
+	TransferEventTopic = types.StringToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+
+	ErrAddressIndexDisabled = errors.New("the address index is disabled")
+)
+
+// addressIndexLogInterval is the number of indexed blocks between two logs of the backfill
+const addressIndexLogInterval = 10000
+
+// addressIndexer indexes the canonical blocks by address in the background
+type addressIndexer struct {
+	// lock is held while indexing a block or reorganizing the canonical chain
+	lock sync.Mutex
+
+	notifyCh chan struct{}
+	closeCh  chan struct{}
+	doneCh   chan struct{}
+}
+
+// notify wakes the indexer up after a new head
+func (a *addressIndexer) notify() {
+	select {
+	case a.notifyCh <- struct{}{}:
+	default:
+	}
+}
+
+// EnableAddressIndex starts indexing the transactions and the token transfers by address.
+// The blocks which are not indexed yet, including the existing ones, are indexed in the background
+func (b *Blockchain) EnableAddressIndex() {
+	if b.addressIndex != nil {
+		return
+	}
+
+	b.addressIndex = &addressIndexer{
+		notifyCh: make(chan struct{}, 1),
+		closeCh:  make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+
+	go b.runAddressIndexer()
+}
+
+func (b *Blockchain) runAddressIndexer() {
+	defer close(b.addressIndex.doneCh)
+
+	for {
+		if err := b.indexAddresses(); err != nil {
+			logger.Error("failed to index the addresses", "err", err)
+		}
+
+		select {
+		case <-b.addressIndex.notifyCh:
+		case <-b.addressIndex.closeCh:
+			return
+		}
+	}
+}
+
+// stopAddressIndexer stops the indexer and waits for the current block to be indexed
+func (b *Blockchain) stopAddressIndexer() {
+	if b.addressIndex == nil {
+		return
+	}
+
+	close(b.addressIndex.closeCh)
+	<-b.addressIndex.doneCh
+}
+
+// GetAddressIndex returns a page of the entries of the index of an address,
+// the newest first, and the total number of entries
+func (b *Blockchain) GetAddressIndex(
+	index storage.AddressIndex,
+	addr types.Address,
+	offset,
+	limit uint64,
+) ([]*storage.AddressIndexEntry, uint64, error) {
+	if b.addressIndex == nil {
+		return nil, 0, ErrAddressIndexDisabled
+	}
+
+	entries, total := storage.ReadAddressIndexPage(b.db, index, addr, offset, limit)
+
+	return entries, total, nil
+}
+
+// indexAddresses indexes the canonical blocks from the last indexed one up to the head
+func (b *Blockchain) indexAddresses() error {
+	for {
+		select {
+		case <-b.addressIndex.closeCh:
+			return nil
+		default:
+		}
+
+		done, err := b.indexNextAddresses()
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// indexNextAddresses indexes the block after the last indexed one,
+// it returns true if there is no block to index
+func (b *Blockchain) indexNextAddresses() (bool, error) {
+	b.addressIndex.lock.Lock()
+	defer b.addressIndex.lock.Unlock()
+
+	number := uint64(0)
+	if head, ok := b.db.ReadAddressIndexHead(); ok {
+		number = head + 1
+	}
+
+	if number > b.Header().Number {
+		return true, nil
+	}
+
+	hash, ok := b.db.ReadCanonicalHash(number)
+	if !ok {
+		return false, fmt.Errorf("canonical hash %d not found", number)
+	}
+
+	entries, ok, err := b.addressIndexEntries(hash, number)
+	if err != nil {
+		return false, err
+	}
+
+	if !ok {
+		// the receipts of the head are not written yet
+		return true, nil
+	}
+
+	// the entries written before a crash, while the block was partially indexed,
+	// are removed so that they are not appended twice
+	if number > 0 {
+		for _, e := range entries {
+			if err := storage.TruncateAddressIndex(b.db, e.index, e.addr, number-1); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	for _, e := range entries {
+		if err := storage.AppendAddressIndex(b.db, e.index, e.addr, e.entry); err != nil {
+			return false, err
+		}
+	}
+
+	if err := b.db.WriteAddressIndexHead(number); err != nil {
+		return false, err
+	}
+
+	if number%addressIndexLogInterval == 0 {
+		logger.Info("[BLK] address index", "block", number)
+	}
+
+	return false, nil
+}
+
+// unindexAddresses removes the blocks of the old chain from the index, the lock of the
+// indexer must be held until the canonical chain is updated
+func (b *Blockchain) unindexAddresses(oldHead, ancestor *types.Header) error {
+	head, ok := b.db.ReadAddressIndexHead()
+	if !ok || head <= ancestor.Number {
+		return nil
+	}
+
+	type key struct {
+		index storage.AddressIndex
+		addr  types.Address
+	}
+
+	touched := map[key]struct{}{}
+
+	for header := oldHead; header.Number > ancestor.Number; {
+		if header.Number <= head {
+			entries, _, err := b.addressIndexEntries(header.Hash, header.Number)
+			if err != nil {
+				return err
+			}
+
+			for _, e := range entries {
+				touched[key{e.index, e.addr}] = struct{}{}
+			}
+		}
+
+		parent, ok := b.readHeader(header.ParentHash)
+		if !ok {
+			return fmt.Errorf("header '%s' not found", header.ParentHash.String())
+		}
+
+		header = parent
+	}
+
+	for k := range touched {
+		if err := storage.TruncateAddressIndex(b.db, k.index, k.addr, ancestor.Number); err != nil {
+			return err
+		}
+	}
+
+	return b.db.WriteAddressIndexHead(ancestor.Number)
+}
+
+type addressIndexEntry struct {
+	index storage.AddressIndex
+	addr  types.Address
+	entry *storage.AddressIndexEntry
+}
+
+// addressIndexEntries returns the index entries of a block,
+// it returns false if the receipts of the block are not found
+func (b *Blockchain) addressIndexEntries(hash types.Hash, number uint64) ([]*addressIndexEntry, bool, error) {
+	// the genesis and the blocks synced without body have no transactions
+	body, err := b.db.ReadBody(hash)
+	if errors.Is(err, storage.ErrNotFound) || (err == nil && len(body.Transactions) == 0) {
+		return nil, true, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	receipts, err := b.db.ReadReceipts(hash)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	if len(receipts) != len(body.Transactions) {
+		return nil, false, fmt.Errorf("receipts of block %d do not match the transactions", number)
+	}
+
+	entries := []*addressIndexEntry{}
+	logIndex := uint64(0)
+
+	for i, txn := range body.Transactions {
+		entry := &storage.AddressIndexEntry{
+			BlockNumber: number,
+			TxHash:      txn.Hash,
+		}
+
+		addrs := []types.Address{txn.From}
+		if txn.To != nil && *txn.To != txn.From {
+			addrs = append(addrs, *txn.To)
+		}
+
+		if txn.To == nil && receipts[i].ContractAddress != types.ZeroAddress {
+			addrs = append(addrs, receipts[i].ContractAddress)
+		}
+
+		for _, addr := range addrs {
+			entries = append(entries, &addressIndexEntry{storage.AddressTransactions, addr, entry})
+		}
+
+		for _, log := range receipts[i].Logs {
+			if len(log.Topics) >= 3 && log.Topics[0] == TransferEventTopic {
+				entries = append(entries, &addressIndexEntry{
+					index: storage.TokenTransfers,
+					addr:  log.Address,
+					entry: &storage.AddressIndexEntry{
+						BlockNumber: number,
+						TxHash:      txn.Hash,
+						LogIndex:    logIndex,
+					},
+				})
+			}
+
+			logIndex++
+		}
+	}
+
+	return entries, true, nil
+}
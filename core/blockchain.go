@@ -10,7 +10,6 @@ import (
 	"github.com/TIE-Tech/tie-core/storage/memory"
 	"math/big"
 	"path/filepath"
-	"sync"
 	"sync/atomic"
 
 	"github.com/TIE-Tech/tie-core/common/common"
@@ -24,6 +23,8 @@ const (
 	BlockGasTargetDivisor uint64 = 1024 // The bound divisor of the gas limit, used in update calculations
 )
 
+var ErrInternalTransfersDisabled = errors.New("the internal transfers are not stored")
+
 // Blockchain is a blockchain reference
 type Blockchain struct {
 	db        storage.Storage // The Storage object (database)
@@ -41,11 +42,13 @@ type Blockchain struct {
 
 	stream *eventStream // Event subscriptions
 
-	// Average gas price (rolling average)
-	averageGasPrice      *big.Int // The average gas price that gets queried
-	averageGasPriceCount *big.Int // Param used in the avg. gas price calculation
+	bloomBits *bloomBitsIndexer // The bloom bits indexer, nil if the index is disabled
+
+	addressIndex *addressIndexer // The address indexer, nil if the index is disabled
+
+	pending *pendingBuilder // The pending block builder, nil if the pending block is disabled
 
-	agpMux sync.Mutex // Mutex for the averageGasPrice calculation
+	internalTransfers bool // Whether the internal transfers of the blocks are stored
 }
 
 type Verifier interface {
@@ -57,25 +60,6 @@ type Executor interface {
 	ProcessBlock(parentRoot types.Hash, block *types.Block, blockCreator types.Address) (*state.BlockResult, error)
 }
 
-// UpdateGasPriceAvg Updates the rolling average value of the gas price
-func (b *Blockchain) UpdateGasPriceAvg(newValue *big.Int) {
-	b.agpMux.Lock()
-
-	b.averageGasPriceCount.Add(b.averageGasPriceCount, big.NewInt(1))
-
-	differential := big.NewInt(0)
-	differential.Div(newValue.Sub(newValue, b.averageGasPrice), b.averageGasPriceCount)
-
-	b.averageGasPrice.Add(b.averageGasPrice, differential)
-
-	b.agpMux.Unlock()
-}
-
-// GetAvgGasPrice returns the average gas price
-func (b *Blockchain) GetAvgGasPrice() *big.Int {
-	return b.averageGasPrice
-}
-
 // NewBlockchain creates a new blockchain object
 func NewBlockchain(
 	dataDir string,
@@ -113,10 +97,6 @@ func NewBlockchain(
 	// Push the initial event to the stream
 	b.stream.push(&Event{})
 
-	// Initialize the average gas price
-	b.averageGasPrice = big.NewInt(0)
-	b.averageGasPriceCount = big.NewInt(0)
-
 	return b, nil
 }
 
@@ -290,9 +270,6 @@ func (b *Blockchain) writeGenesisImpl(header *types.Header) error {
 		return err
 	}
 
-	// Update the average gas price to take into account the genesis block
-	b.UpdateGasPriceAvg(new(big.Int).SetUint64(header.GasUsed))
-
 	// Advance the head
 	if _, err := b.advanceHead(header); err != nil {
 		return err
@@ -610,10 +587,23 @@ func (b *Blockchain) WriteBlock(block *types.Block) error {
 		return err
 	}
 
+	if b.internalTransfers {
+		if err := b.db.WriteInternalTransfers(block.Hash(), res.InternalTransfers); err != nil {
+			return err
+		}
+	}
+
 	b.dispatchEvent(evnt)
 
-	// Update the average gas price
-	b.UpdateGasPriceAvg(new(big.Int).SetUint64(header.GasUsed))
+	if b.bloomBits != nil {
+		b.bloomBits.notify()
+	}
+
+	if b.addressIndex != nil {
+		b.addressIndex.notify()
+	}
+
+	b.notifyPendingHead()
 
 	logArgs := []interface{}{"block", header.Number, "txns", len(block.Transactions)}
 
@@ -653,6 +643,20 @@ func (b *Blockchain) ReadTxLookup(hash types.Hash) (types.Hash, bool) {
 	return v, ok
 }
 
+// EnableInternalTransfers starts storing the internal transfers of the new blocks
+func (b *Blockchain) EnableInternalTransfers() {
+	b.internalTransfers = true
+}
+
+// GetInternalTransfers returns the internal transfers of a block
+func (b *Blockchain) GetInternalTransfers(hash types.Hash) ([]*types.InternalTransfer, error) {
+	if !b.internalTransfers {
+		return nil, ErrInternalTransfersDisabled
+	}
+
+	return b.db.ReadInternalTransfers(hash)
+}
+
 // processBlock Processes the block, and does validation
 func (b *Blockchain) processBlock(block *types.Block) (*state.BlockResult, error) {
 	header := block.Header
@@ -933,6 +937,23 @@ func (b *Blockchain) handleReorg(
 		return fmt.Errorf("failed to write the old header as fork: %w", err)
 	}
 
+	// The blocks of the old chain are removed from the address index, which
+	// waits for the new canonical chain before indexing again
+	if b.addressIndex != nil {
+		b.addressIndex.lock.Lock()
+		defer b.addressIndex.lock.Unlock()
+
+		if err := b.unindexAddresses(oldChainHead, oldHeader); err != nil {
+			return fmt.Errorf("failed to update the address index: %w", err)
+		}
+	}
+
+	// The sections of the bloom bits are not indexed while the canonical chain is updated
+	if b.bloomBits != nil {
+		b.bloomBits.lock.Lock()
+		defer b.bloomBits.lock.Unlock()
+	}
+
 	// Update canonical chain numbers
 	for _, h := range newChain {
 		if err := b.db.WriteCanonicalHash(h.Number, h.Hash); err != nil {
@@ -940,6 +961,13 @@ func (b *Blockchain) handleReorg(
 		}
 	}
 
+	// The sections of the bloom bits from the common ancestor are indexed again
+	if section := (oldHeader.Number + 1) / storage.BloomBitsSectionSize; section < b.db.ReadBloomBitsSections() {
+		if err := b.db.WriteBloomBitsSections(section); err != nil {
+			return err
+		}
+	}
+
 	diff, err := b.advanceHead(newChainHead)
 	if err != nil {
 		return err
@@ -1002,5 +1030,9 @@ func (b *Blockchain) GetBlockByNumber(blockNumber uint64, full bool) (*types.Blo
 
 // Close closes the DB connection
 func (b *Blockchain) Close() error {
+	b.stopBloomBitsIndexer()
+	b.stopAddressIndexer()
+	b.stopPendingBuilder()
+
 	return b.db.Close()
 }
@@ -10,6 +10,10 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/TIE-Tech/tie-core/state"
+	itrie "github.com/TIE-Tech/tie-core/state/trie"
+	"github.com/TIE-Tech/tie-core/tievm/evm/execute"
+	"github.com/TIE-Tech/tie-core/tievm/evm/precompiled"
 	"github.com/TIE-Tech/tie-core/types"
 	"github.com/stretchr/testify/assert"
 )
@@ -619,3 +623,258 @@ func TestCalculateGasLimit(t *testing.T) {
 		})
 	}
 }
+
+func TestBloomBitsIndex(t *testing.T) {
+	addr := types.StringToAddress("1")
+
+	headers := NewTestHeaderChain(storage.BloomBitsSectionSize)
+	headers[10].LogsBloom = types.CreateBloom([]*types.Receipt{
+		{Logs: []*types.Log{{Address: addr}}},
+	})
+
+	// the section is not complete
+	b := NewTestBlockchain(t, headers[:len(headers)-1])
+
+	// index synchronously
+	b.bloomBits = &bloomBitsIndexer{closeCh: make(chan struct{})}
+
+	// the test chain only sets the first header as head
+	assert.NoError(t, b.db.WriteHeader(headers[0]))
+
+	assert.NoError(t, b.indexBloomBits())
+	assert.Equal(t, uint64(0), b.BloomBitsSections())
+
+	assert.NoError(t, b.WriteHeaders(headers[len(headers)-1:]))
+	assert.NoError(t, b.indexBloomBits())
+	assert.Equal(t, uint64(1), b.BloomBitsSections())
+
+	bits, err := b.MatchBloomBits(0, [][][]byte{{addr.Bytes()}})
+	assert.NoError(t, err)
+
+	for i := 0; i < storage.BloomBitsSectionSize; i++ {
+		assert.Equal(t, i == 10, bits[i/8]&(1<<(7-i%8)) != 0, "block %d", i)
+	}
+}
+
+func TestAddressIndex(t *testing.T) {
+	var (
+		sender   = types.StringToAddress("1")
+		receiver = types.StringToAddress("2")
+		token    = types.StringToAddress("3")
+		created  = types.StringToAddress("4")
+	)
+
+	headers := NewTestHeaderChain(4)
+	b := NewTestBlockchain(t, headers)
+
+	// index synchronously
+	b.addressIndex = &addressIndexer{closeCh: make(chan struct{})}
+
+	writeBlock := func(header *types.Header, txn *types.Transaction, receipt *types.Receipt) {
+		assert.NoError(t, b.db.WriteBody(header.Hash, &types.Body{Transactions: []*types.Transaction{txn}}))
+		assert.NoError(t, b.db.WriteReceipts(header.Hash, []*types.Receipt{receipt}))
+	}
+
+	// a transfer of a token, then the creation of a contract
+	writeBlock(
+		headers[1],
+		&types.Transaction{Hash: types.StringToHash("1"), From: sender, To: &token},
+		&types.Receipt{Logs: []*types.Log{
+			{Address: token, Topics: []types.Hash{types.StringToHash("5")}},
+			{Address: token, Topics: []types.Hash{TransferEventTopic, types.BytesToHash(sender.Bytes()), types.BytesToHash(receiver.Bytes())}},
+		}},
+	)
+	writeBlock(
+		headers[3],
+		&types.Transaction{Hash: types.StringToHash("3"), From: sender},
+		&types.Receipt{ContractAddress: created},
+	)
+
+	// the first block is partially indexed, as if the node crashed before the head was written
+	assert.NoError(t, storage.AppendAddressIndex(b.db, storage.AddressTransactions, sender, &storage.AddressIndexEntry{
+		BlockNumber: 1,
+		TxHash:      types.StringToHash("1"),
+	}))
+
+	assert.NoError(t, b.indexAddresses())
+
+	head, _ := b.db.ReadAddressIndexHead()
+	assert.Equal(t, uint64(3), head)
+
+	blocks := func(index storage.AddressIndex, addr types.Address) []uint64 {
+		entries, total, err := b.GetAddressIndex(index, addr, 0, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(len(entries)), total)
+
+		found := []uint64{}
+		for _, entry := range entries {
+			found = append(found, entry.BlockNumber)
+		}
+
+		return found
+	}
+
+	assert.Equal(t, []uint64{3, 1}, blocks(storage.AddressTransactions, sender))
+	assert.Equal(t, []uint64{1}, blocks(storage.AddressTransactions, token))
+	assert.Equal(t, []uint64{3}, blocks(storage.AddressTransactions, created))
+	assert.Equal(t, []uint64{}, blocks(storage.AddressTransactions, receiver))
+
+	transfers, _, err := b.GetAddressIndex(storage.TokenTransfers, token, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, transfers, 1)
+	assert.Equal(t, uint64(1), transfers[0].BlockNumber)
+	assert.Equal(t, uint64(1), transfers[0].LogIndex)
+
+	// the blocks after 1 are replaced by a heavier fork
+	fork := NewTestHeaderFromChainWithSeed(headers[:2], 3, 10)
+	for _, header := range fork[2:] {
+		header.Difficulty += 10
+		header.ComputeHash()
+	}
+
+	for i := 3; i < len(fork); i++ {
+		fork[i].ParentHash = fork[i-1].Hash
+		fork[i].ComputeHash()
+	}
+
+	assert.NoError(t, b.WriteHeaders(fork[2:]))
+	assert.Equal(t, fork[len(fork)-1].Hash, b.Header().Hash)
+
+	assert.Equal(t, []uint64{1}, blocks(storage.AddressTransactions, sender))
+	assert.Equal(t, []uint64{}, blocks(storage.AddressTransactions, created))
+
+	assert.NoError(t, b.indexAddresses())
+
+	head, _ = b.db.ReadAddressIndexHead()
+	assert.Equal(t, uint64(4), head)
+	assert.Equal(t, []uint64{1}, blocks(storage.AddressTransactions, sender))
+}
+
+type mockPendingTxPool struct {
+	txs []*types.Transaction
+}
+
+func (m *mockPendingTxPool) GetExecutableTxs() []*types.Transaction {
+	return m.txs
+}
+
+func TestPendingBlock(t *testing.T) {
+	var (
+		sender    = types.StringToAddress("1001")
+		receiver  = types.StringToAddress("1002")
+		poor      = types.StringToAddress("1003")
+		validator = types.StringToAddress("1004")
+	)
+
+	config := &params.Chain{
+		Genesis: &params.Genesis{
+			GasLimit: defaultBlockGasTarget,
+			Alloc: map[types.Address]*params.GenesisAccount{
+				sender:        {Balance: big.NewInt(1000000000)},
+				state.FeePool: {Balance: big.NewInt(1000000000)},
+				validator:     {Balance: big.NewInt(1000000000)},
+			},
+		},
+		Params: &params.Params{
+			Forks:          params.AllForksEnabled,
+			BlockGasTarget: defaultBlockGasTarget,
+		},
+	}
+
+	executor := state.NewExecutor(config.Params, itrie.NewState(itrie.NewMemoryStorage()))
+	executor.SetRuntime(precompiled.NewPrecompiled())
+	executor.SetRuntime(execute.NewEVM())
+	config.Genesis.StateRoot = executor.WriteGenesis(config.Genesis.Alloc)
+
+	b, err := newBlockChain(config, executor)
+	assert.NoError(t, err)
+
+	executor.GetHash = b.GetHashHelper
+
+	transfer := func(from types.Address, nonce uint64) *types.Transaction {
+		txn := &types.Transaction{
+			From:     from,
+			To:       &receiver,
+			Nonce:    nonce,
+			Value:    big.NewInt(10),
+			Gas:      21000,
+			GasPrice: big.NewInt(1),
+		}
+		txn.ComputeHash()
+
+		return txn
+	}
+
+	// the validator withdraws its fees
+	fees := state.NewValidatorFee()
+	fees.SetValidatorFee(validator, big.NewInt(100))
+
+	withdraw := &types.Transaction{
+		From:     validator,
+		To:       &types.TxFeePoolAddress,
+		Value:    big.NewInt(100),
+		Input:    []byte{0x07, 0x0f, 0x46, 0x8d},
+		Gas:      30000,
+		GasPrice: big.NewInt(0),
+	}
+	withdraw.ComputeHash()
+
+	// the transactions of the poor account fail, the next one is skipped
+	pool := &mockPendingTxPool{txs: []*types.Transaction{
+		transfer(sender, 0),
+		transfer(poor, 0),
+		withdraw,
+		transfer(poor, 1),
+		transfer(sender, 1),
+	}}
+
+	// build synchronously
+	b.pending = &pendingBuilder{executor: executor, pool: pool}
+
+	_, _, ok := b.GetPendingBlock()
+	assert.False(t, ok)
+	assert.ErrorIs(t, b.PendingState(nil), ErrNoPendingBlock)
+
+	taximeter := new(big.Int).Set(fees.GetTaximeter())
+
+	assert.NoError(t, b.buildPendingBlock())
+
+	// the system transactions are not written to the pending block
+	assert.Equal(t, taximeter, fees.GetTaximeter())
+
+	block, receipts, ok := b.GetPendingBlock()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1), block.Number())
+	assert.Equal(t, b.Header().Hash, block.ParentHash())
+	assert.Equal(t, []*types.Transaction{pool.txs[0], pool.txs[4]}, block.Transactions)
+	assert.Len(t, receipts, 2)
+	assert.Equal(t, uint64(42000), block.Header.GasUsed)
+
+	readState := func() (balance *big.Int, nonce uint64) {
+		assert.NoError(t, b.PendingState(func(header *types.Header, transition *state.Transition) error {
+			balance, nonce = transition.GetBalance(receiver), transition.GetNonce(sender)
+
+			// the changes of a call are discarded
+			_, err := transition.Apply(transfer(sender, 2))
+
+			return err
+		}))
+
+		return
+	}
+
+	for i := 0; i < 2; i++ {
+		balance, nonce := readState()
+		assert.Equal(t, big.NewInt(20), balance)
+		assert.Equal(t, uint64(2), nonce)
+	}
+
+	// the pending state is read while a call is running on it
+	assert.NoError(t, b.PendingState(func(header *types.Header, transition *state.Transition) error {
+		balance, nonce := readState()
+		assert.Equal(t, big.NewInt(20), balance)
+		assert.Equal(t, uint64(2), nonce)
+
+		return nil
+	}))
+}
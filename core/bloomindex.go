@@ -0,0 +1,137 @@
+package blockchain
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/TIE-Tech/go-logger"
+	"github.com/TIE-Tech/tie-core/storage"
+	"github.com/TIE-Tech/tie-core/types"
+)
+
+// bloomBitsIndexer indexes the sections of the bloom bits of the canonical chain in the background
+type bloomBitsIndexer struct {
+	// lock is held while indexing a section or reorganizing the canonical chain
+	lock sync.Mutex
+
+	notifyCh chan struct{}
+	closeCh  chan struct{}
+	doneCh   chan struct{}
+}
+
+// notify wakes the indexer up after a new head
+func (i *bloomBitsIndexer) notify() {
+	select {
+	case i.notifyCh <- struct{}{}:
+	default:
+	}
+}
+
+// EnableBloomBitsIndex starts indexing the bloom bits of the complete sections of the canonical chain.
+// The sections which are not indexed yet, including the existing ones, are indexed in the background
+func (b *Blockchain) EnableBloomBitsIndex() {
+	if b.bloomBits != nil {
+		return
+	}
+
+	b.bloomBits = &bloomBitsIndexer{
+		notifyCh: make(chan struct{}, 1),
+		closeCh:  make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+
+	go b.runBloomBitsIndexer()
+}
+
+func (b *Blockchain) runBloomBitsIndexer() {
+	defer close(b.bloomBits.doneCh)
+
+	for {
+		// the index is only used to speed up the log queries, a failure is not fatal
+		if err := b.indexBloomBits(); err != nil {
+			logger.Error("failed to index the bloom bits", "err", err)
+		}
+
+		select {
+		case <-b.bloomBits.notifyCh:
+		case <-b.bloomBits.closeCh:
+			return
+		}
+	}
+}
+
+// stopBloomBitsIndexer stops the indexer and waits for the current section to be indexed
+func (b *Blockchain) stopBloomBitsIndexer() {
+	if b.bloomBits == nil {
+		return
+	}
+
+	close(b.bloomBits.closeCh)
+	<-b.bloomBits.doneCh
+}
+
+// BloomBitsSections returns the number of sections of the bloom bits index
+func (b *Blockchain) BloomBitsSections() uint64 {
+	return b.db.ReadBloomBitsSections()
+}
+
+// MatchBloomBits returns the bit set of the blocks of an indexed section whose
+// bloom possibly contains a value of every group
+func (b *Blockchain) MatchBloomBits(section uint64, groups [][][]byte) ([]byte, error) {
+	return storage.MatchBloomBits(b.db, section, groups)
+}
+
+// indexBloomBits indexes the complete sections of the canonical chain from the last indexed one
+func (b *Blockchain) indexBloomBits() error {
+	for {
+		select {
+		case <-b.bloomBits.closeCh:
+			return nil
+		default:
+		}
+
+		done, err := b.indexNextBloomBits()
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// indexNextBloomBits indexes the section after the last indexed one,
+// it returns true if the canonical chain does not have all its blocks yet
+func (b *Blockchain) indexNextBloomBits() (bool, error) {
+	b.bloomBits.lock.Lock()
+	defer b.bloomBits.lock.Unlock()
+
+	section := b.db.ReadBloomBitsSections()
+	if (section+1)*storage.BloomBitsSectionSize > b.Header().Number+1 {
+		return true, nil
+	}
+
+	blooms := make([]types.Bloom, storage.BloomBitsSectionSize)
+
+	for i := range blooms {
+		number := section*storage.BloomBitsSectionSize + uint64(i)
+
+		// the headers are read from the storage not to evict the recent ones from the cache
+		hash, ok := b.db.ReadCanonicalHash(number)
+		if !ok {
+			return false, fmt.Errorf("canonical hash %d not found", number)
+		}
+
+		header, err := b.db.ReadHeader(hash)
+		if err != nil {
+			return false, fmt.Errorf("header %d not found: %w", number, err)
+		}
+
+		blooms[i] = header.LogsBloom
+	}
+
+	if err := b.db.WriteBloomBitsSection(section, storage.GenerateBloomBits(blooms)); err != nil {
+		return false, err
+	}
+
+	logger.Info("[BLK] bloom bits index", "section", section)
+
+	return false, nil
+}
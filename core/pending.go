@@ -0,0 +1,278 @@
+package blockchain
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/TIE-Tech/go-logger"
+	"github.com/TIE-Tech/tie-core/state"
+	"github.com/TIE-Tech/tie-core/types"
+	"github.com/TIE-Tech/tie-core/types/calcroot"
+)
+
+// pendingRecommitInterval is the delay before the pending block is rebuilt after changes of the pool
+const pendingRecommitInterval = time.Second
+
+var ErrNoPendingBlock = errors.New("the pending block is not built")
+
+// PendingExecutor begins the execution of the pending blocks
+type PendingExecutor interface {
+	BeginTxn(parentRoot types.Hash, header *types.Header, coinbaseReceiver types.Address) (*state.Transition, error)
+}
+
+// PendingTxPool provides the transactions of the pending blocks
+type PendingTxPool interface {
+	// GetExecutableTxs returns the promoted transactions in the order they are written to a block
+	GetExecutableTxs() []*types.Transaction
+}
+
+// pendingBuilder builds the pending block in the background, a speculative block on the head
+// of the chain with the executable transactions of the pool. It is never sealed nor written
+type pendingBuilder struct {
+	executor PendingExecutor
+	pool     PendingTxPool
+
+	// lock guards the pending block and the forks of its state, the
+	// transition is not written once the block is built
+	lock       sync.Mutex
+	block      *types.Block
+	receipts   []*types.Receipt
+	transition *state.Transition
+
+	headCh  chan struct{}
+	txsCh   chan struct{}
+	closeCh chan struct{}
+	doneCh  chan struct{}
+}
+
+// EnablePendingBlock starts building the pending block, after every new head
+// and after the changes of the pool notified by NotifyPendingTxs
+func (b *Blockchain) EnablePendingBlock(executor PendingExecutor, pool PendingTxPool) {
+	if b.pending != nil {
+		return
+	}
+
+	b.pending = &pendingBuilder{
+		executor: executor,
+		pool:     pool,
+		headCh:   make(chan struct{}, 1),
+		txsCh:    make(chan struct{}, 1),
+		closeCh:  make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+
+	go b.runPendingBuilder()
+}
+
+// NotifyPendingTxs notifies the pending block builder of new executable transactions in the pool
+func (b *Blockchain) NotifyPendingTxs() {
+	if b.pending == nil {
+		return
+	}
+
+	select {
+	case b.pending.txsCh <- struct{}{}:
+	default:
+	}
+}
+
+// notifyPendingHead wakes the pending block builder up after a new head
+func (b *Blockchain) notifyPendingHead() {
+	if b.pending == nil {
+		return
+	}
+
+	select {
+	case b.pending.headCh <- struct{}{}:
+	default:
+	}
+}
+
+func (b *Blockchain) runPendingBuilder() {
+	defer close(b.pending.doneCh)
+
+	// the changes of the pool are batched until the recommit
+	var recommitCh <-chan time.Time
+
+	for {
+		if err := b.buildPendingBlock(); err != nil {
+			logger.Error("failed to build the pending block", "err", err)
+		}
+
+		for wait := true; wait; {
+			select {
+			case <-b.pending.headCh:
+				recommitCh, wait = nil, false
+			case <-b.pending.txsCh:
+				if recommitCh == nil {
+					recommitCh = time.After(pendingRecommitInterval)
+				}
+			case <-recommitCh:
+				recommitCh, wait = nil, false
+			case <-b.pending.closeCh:
+				return
+			}
+		}
+	}
+}
+
+// stopPendingBuilder stops the builder and waits for the current block to be built
+func (b *Blockchain) stopPendingBuilder() {
+	if b.pending == nil {
+		return
+	}
+
+	close(b.pending.closeCh)
+	<-b.pending.doneCh
+}
+
+// buildPendingBlock builds the pending block on the head, the transactions of the pool are
+// written like the ones of a sealed block: a transaction exceeding the block gas limit has
+// a failed receipt, and the next transactions of the account of a failed one are skipped.
+// The system transactions are skipped, as they update the fee and reward pools of the node
+func (b *Blockchain) buildPendingBlock() error {
+	parent := b.Header()
+
+	// the creator of the head receives the fees, the next one is not known yet
+	coinbase := types.ZeroAddress
+
+	if b.consensus != nil {
+		if creator, err := b.consensus.GetBlockCreator(parent); err == nil {
+			coinbase = creator
+		}
+	}
+
+	gasLimit, err := b.CalculateGasLimit(parent.Number + 1)
+	if err != nil {
+		return err
+	}
+
+	timestamp := uint64(time.Now().Unix())
+	if timestamp <= parent.Timestamp {
+		timestamp = parent.Timestamp + 1
+	}
+
+	header := &types.Header{
+		ParentHash: parent.Hash,
+		Number:     parent.Number + 1,
+		Miner:      coinbase,
+		Difficulty: parent.Number + 1,
+		Sha3Uncles: types.EmptyUncleHash,
+		GasLimit:   gasLimit,
+		Timestamp:  timestamp,
+	}
+
+	transition, err := b.pending.executor.BeginTxn(parent.StateRoot, header, coinbase)
+	if err != nil {
+		return err
+	}
+
+	txns := []*types.Transaction{}
+	failed := map[types.Address]struct{}{}
+
+	for _, txn := range b.pending.pool.GetExecutableTxs() {
+		if _, ok := failed[txn.From]; ok {
+			continue
+		}
+
+		// the fee and reward pools are shared with the sealed blocks
+		if transition.IsSystemTx(txn) {
+			failed[txn.From] = struct{}{}
+
+			continue
+		}
+
+		if txn.ExceedsBlockGasLimit(gasLimit) {
+			failed[txn.From] = struct{}{}
+
+			if err := transition.WriteFailedReceipt(txn); err == nil {
+				txns = append(txns, txn)
+			}
+
+			continue
+		}
+
+		if err := transition.Write(txn); err != nil {
+			var gasLimitErr *state.GasLimitReachedTransitionApplicationError
+			if errors.As(err, &gasLimitErr) {
+				break
+			}
+
+			failed[txn.From] = struct{}{}
+
+			continue
+		}
+
+		txns = append(txns, txn)
+	}
+
+	receipts := transition.Receipts()
+
+	// the state is not committed, the pending block has no state root nor hash
+	header.GasUsed = transition.TotalGas()
+	header.LogsBloom = types.CreateBloom(receipts)
+	header.TxRoot = types.EmptyRootHash
+	header.ReceiptsRoot = types.EmptyRootHash
+
+	if len(txns) != 0 {
+		header.TxRoot = calcroot.CalculateTransactionsRoot(txns)
+		header.ReceiptsRoot = calcroot.CalculateReceiptsRoot(receipts)
+	}
+
+	b.pending.lock.Lock()
+	defer b.pending.lock.Unlock()
+
+	b.pending.block = &types.Block{
+		Header:       header,
+		Transactions: txns,
+	}
+	b.pending.receipts = receipts
+	b.pending.transition = transition
+
+	return nil
+}
+
+// GetPendingBlock returns the pending block and its receipts, false if the pending block is not built
+func (b *Blockchain) GetPendingBlock() (*types.Block, []*types.Receipt, bool) {
+	if b.pending == nil {
+		return nil, nil, false
+	}
+
+	b.pending.lock.Lock()
+	defer b.pending.lock.Unlock()
+
+	if b.pending.block == nil {
+		return nil, nil, false
+	}
+
+	return b.pending.block, b.pending.receipts, true
+}
+
+// PendingState runs f on a fork of the state of the pending block, which is discarded afterwards.
+// The lock is only held to fork the state, so the calls run concurrently with each other and
+// with the builder. It returns ErrNoPendingBlock if the pending block is not built
+func (b *Blockchain) PendingState(f func(header *types.Header, transition *state.Transition) error) error {
+	if b.pending == nil {
+		return ErrNoPendingBlock
+	}
+
+	header, transition, ok := b.forkPendingState()
+	if !ok {
+		return ErrNoPendingBlock
+	}
+
+	return f(header, transition)
+}
+
+// forkPendingState returns a copy of the header of the pending block and a fork of its state
+func (b *Blockchain) forkPendingState() (*types.Header, *state.Transition, bool) {
+	b.pending.lock.Lock()
+	defer b.pending.lock.Unlock()
+
+	if b.pending.block == nil {
+		return nil, nil, false
+	}
+
+	return b.pending.block.Header.Copy(), b.pending.transition.Fork(), true
+}
@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+// RPCMetrics represents the json rpc metrics
+type RPCMetrics struct {
+	// Requests rejected by the limits, by reason
+	RejectedRequests metrics.Counter
+
+	// Requests handled, by method and transport
+	Requests metrics.Counter
+
+	// Requests which returned an error, by method, transport and error code
+	Errors metrics.Counter
+
+	// Time to handle a request in seconds, by method and transport
+	RequestDuration metrics.Histogram
+
+	// No.of open websocket connections
+	WSConnections metrics.Gauge
+
+	// No.of installed filters, by type
+	Filters metrics.Gauge
+}
+
+// GetRPCPrometheusMetrics return the json rpc metrics instance
+func GetRPCPrometheusMetrics(namespace string, labelsWithValues ...string) *RPCMetrics {
+	labels := []string{}
+
+	for i := 0; i < len(labelsWithValues); i += 2 {
+		labels = append(labels, labelsWithValues[i])
+	}
+
+	return &RPCMetrics{
+		RejectedRequests: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "jsonrpc",
+			Name:      "rejected_requests",
+			Help:      "Requests rejected by the limits, by reason.",
+		}, withLabels(labels, "reason")).With(labelsWithValues...),
+		Requests: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "jsonrpc",
+			Name:      "requests",
+			Help:      "Requests handled, by method and transport.",
+		}, withLabels(labels, "method", "transport")).With(labelsWithValues...),
+		Errors: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "jsonrpc",
+			Name:      "errors",
+			Help:      "Requests which returned an error, by method, transport and error code.",
+		}, withLabels(labels, "method", "transport", "code")).With(labelsWithValues...),
+		RequestDuration: prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "jsonrpc",
+			Name:      "request_duration_seconds",
+			Help:      "Time to handle a request in seconds, by method and transport.",
+			Buckets:   []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+		}, withLabels(labels, "method", "transport")).With(labelsWithValues...),
+		WSConnections: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "jsonrpc",
+			Name:      "ws_connections",
+			Help:      "Number of open websocket connections.",
+		}, labels).With(labelsWithValues...),
+		Filters: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "jsonrpc",
+			Name:      "filters",
+			Help:      "Number of installed filters, by type.",
+		}, withLabels(labels, "type")).With(labelsWithValues...),
+	}
+}
+
+// withLabels returns a copy of the labels, followed by the names of the labels of a metric
+func withLabels(labels []string, names ...string) []string {
+	return append(append([]string{}, labels...), names...)
+}
+
+// NewRPCMetrics will return the non operational json rpc metrics
+func NewRPCMetrics() *RPCMetrics {
+	return &RPCMetrics{
+		RejectedRequests: discard.NewCounter(),
+		Requests:         discard.NewCounter(),
+		Errors:           discard.NewCounter(),
+		RequestDuration:  discard.NewHistogram(),
+		WSConnections:    discard.NewGauge(),
+		Filters:          discard.NewGauge(),
+	}
+}
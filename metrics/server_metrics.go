@@ -5,6 +5,7 @@ type ServerMetrics struct {
 	Consensus *CosMetrics
 	Network   *P2PMetrics
 	Txpool    *TxMetrics
+	JSONRPC   *RPCMetrics
 }
 
 // metricProvider serverMetric instance for the given ChainID and nameSpace
@@ -14,6 +15,7 @@ func MetricProvider(nameSpace string, chainID string, metricsRequired bool) *Ser
 			Consensus: GetCosPrometheusMetrics(nameSpace, "chain_id", chainID),
 			Network:   GetP2PPrometheusMetrics(nameSpace, "chain_id", chainID),
 			Txpool:    GetTxPrometheusMetrics(nameSpace, "chain_id", chainID),
+			JSONRPC:   GetRPCPrometheusMetrics(nameSpace, "chain_id", chainID),
 		}
 	}
 
@@ -21,5 +23,6 @@ func MetricProvider(nameSpace string, chainID string, metricsRequired bool) *Ser
 		Consensus: NewCosMetrics(),
 		Network:   NewP2PMetrics(),
 		Txpool:    NewTxMetrics(),
+		JSONRPC:   NewRPCMetrics(),
 	}
 }
@@ -31,6 +31,14 @@ type Forks struct {
 	EIP150         *Fork `json:"EIP150,omitempty"`
 	EIP158         *Fork `json:"EIP158,omitempty"`
 	EIP155         *Fork `json:"EIP155,omitempty"`
+
+	// SystemTx switches the fee withdrawal and block reward transactions from the
+	// legacy selector matching to the strict system transaction rules
+	SystemTx *Fork `json:"systemTx,omitempty"`
+
+	// ChainPrecompiles enables the precompiled contracts reading the block vrf
+	// value, verifying vrf proofs and reading the validator set
+	ChainPrecompiles *Fork `json:"chainPrecompiles,omitempty"`
 }
 
 func (f *Forks) active(ff *Fork, block uint64) bool {
@@ -69,6 +77,14 @@ func (f *Forks) IsEIP155(block uint64) bool {
 	return f.active(f.EIP155, block)
 }
 
+func (f *Forks) IsSystemTx(block uint64) bool {
+	return f.active(f.SystemTx, block)
+}
+
+func (f *Forks) IsChainPrecompiles(block uint64) bool {
+	return f.active(f.ChainPrecompiles, block)
+}
+
 func (f *Forks) At(block uint64) ForksInTime {
 	return ForksInTime{
 		Homestead:      f.active(f.Homestead, block),
@@ -79,6 +95,9 @@ func (f *Forks) At(block uint64) ForksInTime {
 		EIP150:         f.active(f.EIP150, block),
 		EIP158:         f.active(f.EIP158, block),
 		EIP155:         f.active(f.EIP155, block),
+		SystemTx:       f.active(f.SystemTx, block),
+
+		ChainPrecompiles: f.active(f.ChainPrecompiles, block),
 	}
 }
 
@@ -106,7 +125,9 @@ type ForksInTime struct {
 	Istanbul,
 	EIP150,
 	EIP158,
-	EIP155 bool
+	EIP155,
+	SystemTx,
+	ChainPrecompiles bool
 }
 
 var AllForksEnabled = &Forks{
@@ -118,4 +139,7 @@ var AllForksEnabled = &Forks{
 	Constantinople: NewFork(0),
 	Petersburg:     NewFork(0),
 	Istanbul:       NewFork(0),
+	SystemTx:       NewFork(0),
+
+	ChainPrecompiles: NewFork(0),
 }
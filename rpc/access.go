@@ -0,0 +1,125 @@
+package rpc
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// AccessConfig restricts the methods served by a listener and the clients allowed to call them
+type AccessConfig struct {
+	// Namespaces are the enabled namespaces, all the namespaces are enabled if both
+	// the namespaces and the methods are empty
+	Namespaces []string
+
+	// Methods are the methods enabled in addition to the namespaces, as txpool_status
+	Methods []string
+
+	// CorsOrigins are the origins allowed to call the listener from a browser, "*" for any
+	CorsOrigins []string
+
+	// VHosts are the host names accepted in the Host header of the requests, "*" for any.
+	// The requests to an IP address are always accepted
+	VHosts []string
+
+	// RateLimit is the number of requests per second allowed to a client IP, 0 for no limit.
+	// A websocket message is a request
+	RateLimit uint64
+
+	// RateLimitBurst is the number of requests a client IP can make at once, the rate limit if 0
+	RateLimitBurst uint64
+
+	// MethodRateLimits are the numbers of calls per second allowed to the methods, for all the clients
+	MethodRateLimits map[string]uint64
+}
+
+// methodAccess is the set of the methods enabled on a listener
+type methodAccess struct {
+	namespaces map[string]struct{}
+	methods    map[string]struct{}
+}
+
+// newMethodAccess returns the methods enabled by the config, nil if all are enabled
+func newMethodAccess(config AccessConfig) *methodAccess {
+	if len(config.Namespaces) == 0 && len(config.Methods) == 0 {
+		return nil
+	}
+
+	return &methodAccess{
+		namespaces: toSet(config.Namespaces),
+		methods:    toSet(config.Methods),
+	}
+}
+
+// allowed returns true if the method is enabled
+func (a *methodAccess) allowed(method string) bool {
+	if a == nil {
+		return true
+	}
+
+	if _, ok := a.methods[method]; ok {
+		return true
+	}
+
+	namespace := strings.SplitN(method, "_", 2)[0]
+	_, ok := a.namespaces[namespace]
+
+	return ok
+}
+
+// originAllowed returns the value of the Access-Control-Allow-Origin header for an origin,
+// empty if it is not allowed
+func (c *AccessConfig) originAllowed(origin string) string {
+	for _, allowed := range c.CorsOrigins {
+		if allowed == "*" {
+			return "*"
+		}
+
+		if origin != "" && strings.EqualFold(allowed, origin) {
+			return origin
+		}
+	}
+
+	return ""
+}
+
+// hostAllowed returns true if the host of a request is accepted
+func (c *AccessConfig) hostAllowed(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		// no port
+		host = strings.Trim(r.Host, "[]")
+	}
+
+	if net.ParseIP(host) != nil {
+		return true
+	}
+
+	for _, allowed := range c.VHosts {
+		if allowed == "*" || strings.EqualFold(allowed, host) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isAccountMethod returns true if the method uses the keys of the node, these methods are
+// only served by the authenticated and the ipc listeners whatever their access config
+func isAccountMethod(method string) bool {
+	switch method {
+	case "eth_accounts", "eth_sign", "eth_signTransaction", "eth_sendTransaction":
+		return true
+	}
+
+	return strings.HasPrefix(method, "personal_")
+}
+
+func toSet(values []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+
+	return set
+}
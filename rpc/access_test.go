@@ -0,0 +1,90 @@
+package rpc
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMethodAccess(t *testing.T) {
+	access := newMethodAccess(AccessConfig{
+		Namespaces: []string{"eth", "net"},
+		Methods:    []string{"txpool_status"},
+	})
+
+	assert.True(t, access.allowed("eth_blockNumber"))
+	assert.True(t, access.allowed("net_version"))
+	assert.True(t, access.allowed("txpool_status"))
+	assert.False(t, access.allowed("txpool_content"))
+	assert.False(t, access.allowed("web3_clientVersion"))
+
+	// all the methods are enabled by default
+	access = newMethodAccess(AccessConfig{})
+	assert.True(t, access.allowed("txpool_content"))
+}
+
+func TestDispatcherWithAccess(t *testing.T) {
+	store := newMockStore()
+	dispatcher := newDispatcher(store, &dispatcherParams{})
+
+	restricted := dispatcher.withAccess(AccessConfig{
+		Namespaces: []string{"web3"},
+	})
+
+	var version string
+
+	resp, err := restricted.Handle([]byte(`{"id":1,"method":"web3_clientVersion"}`))
+	assert.NoError(t, err)
+	assert.NoError(t, expectJSONResult(resp, &version))
+
+	resp, err = restricted.Handle([]byte(`{"id":1,"method":"txpool_status"}`))
+	assert.NoError(t, err)
+	assert.Error(t, expectJSONResult(resp, &version))
+
+	// the subscriptions are in the eth namespace
+	resp, err = restricted.HandleWs([]byte(`{"id":1,"method":"eth_subscribe","params":["newHeads"]}`), &mockWsConn{})
+	assert.NoError(t, err)
+	assert.Error(t, expectJSONResult(resp, &version))
+
+	// the dispatcher is not restricted
+	resp, err = dispatcher.Handle([]byte(`{"id":1,"method":"txpool_status"}`))
+	assert.NoError(t, err)
+	assert.NoError(t, expectJSONResult(resp, &map[string]interface{}{}))
+}
+
+func TestAccessConfig_Origins(t *testing.T) {
+	config := &AccessConfig{
+		CorsOrigins: []string{"https://explorer.example"},
+	}
+
+	assert.Equal(t, "https://explorer.example", config.originAllowed("https://explorer.example"))
+	assert.Equal(t, "", config.originAllowed("https://other.example"))
+	assert.Equal(t, "", config.originAllowed(""))
+
+	config.CorsOrigins = []string{"*"}
+	assert.Equal(t, "*", config.originAllowed("https://other.example"))
+}
+
+func TestAccessConfig_VHosts(t *testing.T) {
+	config := &AccessConfig{
+		VHosts: []string{"localhost", "node.example"},
+	}
+
+	cases := []struct {
+		host    string
+		allowed bool
+	}{
+		{"localhost:8545", true},
+		{"NODE.example", true},
+		{"other.example:8545", false},
+		{"127.0.0.1:8545", true},
+		{"[::1]:8545", true},
+		{"[::1]", true},
+	}
+
+	for _, c := range cases {
+		req := &http.Request{Host: c.host}
+		assert.Equal(t, c.allowed, config.hostAllowed(req), c.host)
+	}
+}
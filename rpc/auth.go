@@ -0,0 +1,146 @@
+package rpc
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/TIE-Tech/go-logger"
+	"github.com/TIE-Tech/tie-core/common/hex"
+)
+
+const (
+	// jwtSecretLength is the length of the shared secret of the authenticated listener
+	jwtSecretLength = 32
+
+	// jwtMaxClockSkew is the maximum difference between the issue time of a token and the local time
+	jwtMaxClockSkew = 60 * time.Second
+)
+
+var (
+	errMissingToken  = errors.New("missing token")
+	errInvalidToken  = errors.New("invalid token")
+	errTokenIssuedAt = errors.New("token issued at a stale or future time")
+)
+
+// ObtainJWTSecret reads the hex encoded shared secret of the authenticated listener.
+// A random secret is generated and written to the file if it does not exist
+func ObtainJWTSecret(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err == nil {
+		secret, err := hex.DecodeHex(strings.TrimSpace(string(data)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid jwt secret in %s: %w", path, err)
+		}
+
+		if len(secret) != jwtSecretLength {
+			return nil, fmt.Errorf("invalid jwt secret in %s: expected %d bytes", path, jwtSecretLength)
+		}
+
+		return secret, nil
+	}
+
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	secret := make([]byte, jwtSecretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+
+	if err := ioutil.WriteFile(path, []byte(hex.EncodeToHex(secret)), 0600); err != nil {
+		return nil, err
+	}
+
+	logger.Info("[RPC] generated jwt secret", "path", path)
+
+	return secret, nil
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+}
+
+type jwtClaims struct {
+	IssuedAt  *int64 `json:"iat"`
+	ExpiresAt *int64 `json:"exp"`
+}
+
+// authenticate checks the HS256 bearer token of a request, signed with the shared secret.
+// The token must hold its issue time, close to the local time
+func authenticate(r *http.Request, secret []byte, now time.Time) error {
+	auth := r.Header.Get("Authorization")
+	if auth == "" {
+		return errMissingToken
+	}
+
+	token := strings.TrimPrefix(auth, "Bearer ")
+	if token == auth {
+		return errInvalidToken
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return errInvalidToken
+	}
+
+	var header jwtHeader
+	if err := decodeJWTPart(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return errInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return errInvalidToken
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return errInvalidToken
+	}
+
+	var claims jwtClaims
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return errInvalidToken
+	}
+
+	if claims.IssuedAt == nil {
+		return errTokenIssuedAt
+	}
+
+	if skew := now.Sub(time.Unix(*claims.IssuedAt, 0)); skew > jwtMaxClockSkew || skew < -jwtMaxClockSkew {
+		return errTokenIssuedAt
+	}
+
+	if claims.ExpiresAt != nil && now.Unix() >= *claims.ExpiresAt {
+		return errInvalidToken
+	}
+
+	return nil
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
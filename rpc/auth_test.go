@@ -0,0 +1,163 @@
+package rpc
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/TIE-Tech/tie-core/common/tests"
+	"github.com/stretchr/testify/assert"
+)
+
+// newJWT returns an HS256 token with the claims, signed with the secret
+func newJWT(secret []byte, alg, claims string) string {
+	enc := base64.RawURLEncoding
+
+	unsigned := enc.EncodeToString([]byte(fmt.Sprintf(`{"alg":"%s","typ":"JWT"}`, alg))) + "." +
+		enc.EncodeToString([]byte(claims))
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+
+	return unsigned + "." + enc.EncodeToString(mac.Sum(nil))
+}
+
+func TestAuthenticate(t *testing.T) {
+	secret := bytes.Repeat([]byte{1}, jwtSecretLength)
+	now := time.Unix(1700000000, 0)
+
+	iat := func(ts time.Time) string {
+		return fmt.Sprintf(`{"iat":%d}`, ts.Unix())
+	}
+
+	cases := []struct {
+		name   string
+		header string
+		err    error
+	}{
+		{"valid", "Bearer " + newJWT(secret, "HS256", iat(now)), nil},
+		{"clock skew", "Bearer " + newJWT(secret, "HS256", iat(now.Add(30*time.Second))), nil},
+		{"missing", "", errMissingToken},
+		{"not a bearer token", newJWT(secret, "HS256", iat(now)), errInvalidToken},
+		{"malformed", "Bearer abc.def", errInvalidToken},
+		{"wrong secret", "Bearer " + newJWT([]byte("other"), "HS256", iat(now)), errInvalidToken},
+		{"wrong algorithm", "Bearer " + newJWT(secret, "none", iat(now)), errInvalidToken},
+		{"no issue time", "Bearer " + newJWT(secret, "HS256", `{}`), errTokenIssuedAt},
+		{"stale", "Bearer " + newJWT(secret, "HS256", iat(now.Add(-2*time.Minute))), errTokenIssuedAt},
+		{"future", "Bearer " + newJWT(secret, "HS256", iat(now.Add(2*time.Minute))), errTokenIssuedAt},
+		{
+			"expired",
+			"Bearer " + newJWT(secret, "HS256", fmt.Sprintf(`{"iat":%d,"exp":%d}`, now.Unix(), now.Unix()-1)),
+			errInvalidToken,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, "http://localhost", nil)
+			if c.header != "" {
+				req.Header.Set("Authorization", c.header)
+			}
+
+			assert.Equal(t, c.err, authenticate(req, secret, now))
+		})
+	}
+}
+
+func TestObtainJWTSecret(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwtsecret")
+
+	// generated on the first call
+	secret, err := ObtainJWTSecret(path)
+	assert.NoError(t, err)
+	assert.Len(t, secret, jwtSecretLength)
+
+	read, err := ObtainJWTSecret(path)
+	assert.NoError(t, err)
+	assert.Equal(t, secret, read)
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte("0x1234"), 0600))
+
+	_, err = ObtainJWTSecret(path)
+	assert.Error(t, err)
+}
+
+func TestAuthenticatedListener(t *testing.T) {
+	ports := make([]int, 2)
+
+	for i := range ports {
+		port, err := tests.GetFreePort()
+		if err != nil {
+			t.Fatalf("Unable to fetch free port, %v", err)
+		}
+
+		ports[i] = port
+	}
+
+	secret := bytes.Repeat([]byte{2}, jwtSecretLength)
+
+	_, err := NewJSONRPC(&Config{
+		Store:    newMockStore(),
+		Addr:     &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: ports[0]},
+		Access:   AccessConfig{Namespaces: []string{"web3"}, VHosts: []string{"*"}},
+		AuthAddr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: ports[1]},
+		AuthAccess: AccessConfig{
+			VHosts: []string{"localhost"},
+		},
+		JWTSecret: secret,
+	})
+	assert.NoError(t, err)
+
+	call := func(port int, host, method, token string) (int, []byte) {
+		body := []byte(fmt.Sprintf(`{"id":1,"method":"%s"}`, method))
+
+		req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://127.0.0.1:%d", port), bytes.NewReader(body))
+		assert.NoError(t, err)
+
+		req.Host = host
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+
+		defer resp.Body.Close()
+
+		data, err := ioutil.ReadAll(resp.Body)
+		assert.NoError(t, err)
+
+		return resp.StatusCode, data
+	}
+
+	token := newJWT(secret, "HS256", fmt.Sprintf(`{"iat":%d}`, time.Now().Unix()))
+
+	var res interface{}
+
+	// the public listener only serves the web3 namespace
+	status, data := call(ports[0], "node.example", "web3_clientVersion", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.NoError(t, expectJSONResult(data, &res))
+
+	_, data = call(ports[0], "node.example", "txpool_status", "")
+	assert.Error(t, expectJSONResult(data, &res))
+
+	// the authenticated listener serves all the namespaces to the authenticated requests
+	status, _ = call(ports[1], "localhost", "txpool_status", "")
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	status, data = call(ports[1], "localhost", "txpool_status", token)
+	assert.Equal(t, http.StatusOK, status)
+	assert.NoError(t, expectJSONResult(data, &res))
+
+	status, _ = call(ports[1], "node.example", "txpool_status", token)
+	assert.Equal(t, http.StatusForbidden, status)
+}
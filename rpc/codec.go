@@ -201,9 +201,15 @@ func NewRPCErrorResponse(id interface{}, errCode int, err string, jsonrpcver str
 // NewRPCResponse returns Success/Error response object
 func NewRPCResponse(id interface{}, jsonrpcver string, reply []byte, err Error) Response {
 	var response Response
-	switch err.(type) {
+	switch dataErr := err.(type) {
 	case nil:
 		response = &SuccessResponse{JSONRPC: jsonrpcver, ID: id, Result: reply}
+	case DataError:
+		response = &ErrorResponse{
+			JSONRPC: jsonrpcver,
+			ID:      id,
+			Error:   &ObjectError{dataErr.ErrorCode(), dataErr.Error(), dataErr.ErrorData()},
+		}
 	default:
 		response = NewRPCErrorResponse(id, err.ErrorCode(), err.Error(), jsonrpcver)
 	}
@@ -8,8 +8,12 @@ import (
 	"github.com/TIE-Tech/go-logger"
 	"math"
 	"reflect"
+	"strconv"
 	"strings"
+	"time"
 	"unicode"
+
+	"github.com/TIE-Tech/tie-core/metrics"
 )
 
 type serviceData struct {
@@ -29,10 +33,12 @@ func (f *funcData) numParams() int {
 }
 
 type endpoints struct {
-	Eth    *Eth
-	Web3   *Web3
-	Net    *Net
-	TxPool *TxPool
+	Eth      *Eth
+	Web3     *Web3
+	Net      *Net
+	TxPool   *TxPool
+	Tie      *Tie
+	Personal *Personal
 }
 
 // Dispatcher handles all json rpc requests by delegating
@@ -41,16 +47,57 @@ type Dispatcher struct {
 	serviceMap    map[string]*serviceData
 	filterManager *FilterManager
 	endpoints     endpoints
-	chainID       uint64
+	params        *dispatcherParams
+
+	// access is the set of the enabled methods, nil if all are enabled
+	access *methodAccess
+
+	// limiter limits the calls of the methods, nil if they are not limited
+	limiter *methodLimiter
+
+	// accountMethods serves the methods using the keys of the node,
+	// only on the authenticated and the ipc listeners
+	accountMethods bool
+
+	// transport is the transport of the requests in the metrics
+	transport serverType
+}
+
+type dispatcherParams struct {
+	chainID uint64
+
+	// maxBlockRange is the maximum number of blocks of a log query, 0 for no limit
+	maxBlockRange uint64
+
+	// maxLogs is the maximum number of logs returned by a log query, 0 for no limit
+	maxLogs uint64
+
+	// maxBatchLength is the maximum number of requests of a batch, 0 for no limit
+	maxBatchLength uint64
+
+	// callTimeout is the maximum execution time of eth_call, eth_estimateGas and eth_simulateV1, 0 for no limit
+	callTimeout time.Duration
+
+	// slowRequestThreshold is the duration above which the requests are logged, 0 to disable
+	slowRequestThreshold time.Duration
+
+	// accounts signs with the keys of the node, nil if the keystore is disabled
+	accounts accountStore
+
+	metrics *metrics.RPCMetrics
 }
 
-func newDispatcher(store JSONRPCStore, chainID uint64) *Dispatcher {
+func newDispatcher(store JSONRPCStore, params *dispatcherParams) *Dispatcher {
+	if params.metrics == nil {
+		params.metrics = metrics.NewRPCMetrics()
+	}
+
 	d := &Dispatcher{
-		chainID: chainID,
+		params: params,
 	}
 
 	if store != nil {
-		d.filterManager = NewFilterManager(store)
+		d.filterManager = NewFilterManager(store, params.metrics)
 		go d.filterManager.Run()
 	}
 
@@ -59,16 +106,65 @@ func newDispatcher(store JSONRPCStore, chainID uint64) *Dispatcher {
 	return d
 }
 
+// withAccess returns a dispatcher serving the methods enabled by the config, within
+// their rate limits. It shares the endpoints and the filters
+func (d *Dispatcher) withAccess(config AccessConfig) *Dispatcher {
+	restricted := *d
+	restricted.access = newMethodAccess(config)
+	restricted.limiter = newMethodLimiter(config.MethodRateLimits)
+
+	return &restricted
+}
+
+// withAccountMethods returns a dispatcher serving the methods using the keys of the node.
+// It shares the endpoints and the filters
+func (d *Dispatcher) withAccountMethods() *Dispatcher {
+	dispatcher := *d
+	dispatcher.accountMethods = true
+
+	return &dispatcher
+}
+
+// withTransport returns a dispatcher reporting its requests under the transport in the metrics.
+// It shares the endpoints and the filters
+func (d *Dispatcher) withTransport(transport serverType) *Dispatcher {
+	dispatcher := *d
+	dispatcher.transport = transport
+
+	return &dispatcher
+}
+
 func (d *Dispatcher) registerEndpoints(store JSONRPCStore) {
-	d.endpoints.Eth = &Eth{store, d.chainID, d.filterManager}
-	d.endpoints.Net = &Net{store, d.chainID}
+	d.endpoints.Eth = &Eth{
+		store:          store,
+		chainID:        d.params.chainID,
+		filterManager:  d.filterManager,
+		gasPriceOracle: newGasPriceOracle(store),
+		maxBlockRange:  d.params.maxBlockRange,
+		maxLogs:        d.params.maxLogs,
+		callTimeout:    d.params.callTimeout,
+		accounts:       d.params.accounts,
+		metrics:        d.params.metrics,
+	}
+	d.endpoints.Net = &Net{store, d.params.chainID}
 	d.endpoints.Web3 = &Web3{}
 	d.endpoints.TxPool = &TxPool{store}
+	d.endpoints.Tie = &Tie{store}
 
 	d.registerService("eth", d.endpoints.Eth)
 	d.registerService("net", d.endpoints.Net)
 	d.registerService("web3", d.endpoints.Web3)
 	d.registerService("txpool", d.endpoints.TxPool)
+	d.registerService("tie", d.endpoints.Tie)
+
+	if d.params.accounts != nil {
+		d.endpoints.Personal = &Personal{
+			eth:      d.endpoints.Eth,
+			accounts: d.params.accounts,
+		}
+
+		d.registerService("personal", d.endpoints.Personal)
+	}
 }
 
 func (d *Dispatcher) getFnHandler(req Request) (*serviceData, *funcData, Error) {
@@ -139,6 +235,16 @@ func (d *Dispatcher) handleSubscribe(req Request, conn wsConn) (string, Error) {
 			return "", NewInternalError(err.Error())
 		}
 		filterID = d.filterManager.NewLogFilter(logFilter, conn)
+	} else if subscribeMethod == "newPendingTransactions" {
+		fullTx := false
+		if len(params) > 1 {
+			if fullTx, ok = params[1].(bool); !ok {
+				return "", NewInvalidParamsError("Invalid params")
+			}
+		}
+		filterID = d.filterManager.NewPendingTxFilter(fullTx, conn)
+	} else if subscribeMethod == "syncing" {
+		filterID = d.filterManager.NewSyncingFilter(conn)
 	} else {
 		return "", NewSubscriptionNotFoundError(subscribeMethod)
 	}
@@ -170,13 +276,19 @@ func (d *Dispatcher) HandleWs(reqBody []byte, conn wsConn) ([]byte, error) {
 		return NewRPCResponse(req.ID, "2.0", nil, NewInvalidRequestError("Invalid json request")).Bytes()
 	}
 
+	// the other requests are checked by handleReq
+	if req.Method == "eth_subscribe" || req.Method == "eth_unsubscribe" {
+		if err := d.checkAccess(req.Method); err != nil {
+			return NewRPCResponse(req.ID, "2.0", nil, err).Bytes()
+		}
+	}
+
 	// if the request method is eth_subscribe we need to create a
 	// new filter with ws connection
 	if req.Method == "eth_subscribe" {
 		filterID, err := d.handleSubscribe(req, conn)
 		if err != nil {
-			//nolint
-			NewRPCResponse(req.ID, "2.0", nil, err).Bytes()
+			return NewRPCResponse(req.ID, "2.0", nil, err).Bytes()
 		}
 
 		resp, err := formatFilterResponse(req.ID, filterID)
@@ -208,38 +320,81 @@ func (d *Dispatcher) HandleWs(reqBody []byte, conn wsConn) ([]byte, error) {
 	}
 	// its a normal query that we handle with the dispatcher
 	resp, err := d.handleReq(req)
-	if err != nil {
-		return nil, err
-	}
 
 	return NewRPCResponse(req.ID, "2.0", resp, err).Bytes()
 }
 
 func (d *Dispatcher) Handle(reqBody []byte) ([]byte, error) {
+	resp, _, err := d.HandleRequest(reqBody, nil)
+
+	return resp, err
+}
+
+// HandleRequest handles a request body as Handle does. It also returns true if the body calls
+// an account method, or cannot be decoded and so may do it, not to log the body and its response.
+// A batch is rejected when allowBatch, if set, returns false for its number of calls
+func (d *Dispatcher) HandleRequest(reqBody []byte, allowBatch func(calls int) bool) ([]byte, bool, error) {
 	x := bytes.TrimLeft(reqBody, " \t\r\n")
 	if len(x) == 0 {
-		return NewRPCResponse(nil, "2.0", nil, NewInvalidRequestError("Invalid json request")).Bytes()
+		resp, err := NewRPCResponse(nil, "2.0", nil, NewInvalidRequestError("Invalid json request")).Bytes()
+
+		return resp, false, err
 	}
 
 	if x[0] == '{' {
 		var req Request
 		if err := json.Unmarshal(reqBody, &req); err != nil {
-			return NewRPCResponse(nil, "2.0", nil, NewInvalidRequestError("Invalid json request")).Bytes()
+			resp, err := NewRPCResponse(nil, "2.0", nil, NewInvalidRequestError("Invalid json request")).Bytes()
+
+			return resp, true, err
 		}
 
+		redact := isAccountMethod(req.Method)
+
 		if req.Method == "" {
-			return NewRPCResponse(req.ID, "2.0", nil, NewInvalidRequestError("Invalid json request")).Bytes()
+			resp, err := NewRPCResponse(req.ID, "2.0", nil, NewInvalidRequestError("Invalid json request")).Bytes()
+
+			return resp, redact, err
 		}
 
 		resp, err := d.handleReq(req)
+		respBytes, bytesErr := NewRPCResponse(req.ID, "2.0", resp, err).Bytes()
 
-		return NewRPCResponse(req.ID, "2.0", resp, err).Bytes()
+		return respBytes, redact, bytesErr
 	}
 
 	// handle batch requests
 	var requests []Request
 	if err := json.Unmarshal(reqBody, &requests); err != nil {
-		return NewRPCResponse(nil, "2.0", nil, NewInvalidRequestError("Invalid json request")).Bytes()
+		resp, err := NewRPCResponse(nil, "2.0", nil, NewInvalidRequestError("Invalid json request")).Bytes()
+
+		return resp, true, err
+	}
+
+	redact := false
+
+	for _, req := range requests {
+		if isAccountMethod(req.Method) {
+			redact = true
+
+			break
+		}
+	}
+
+	if d.params.maxBatchLength != 0 && uint64(len(requests)) > d.params.maxBatchLength {
+		d.params.metrics.RejectedRequests.With("reason", rejectedBatchLength).Add(1)
+
+		resp, err := NewRPCResponse(nil, "2.0", nil, NewLimitExceededError(
+			fmt.Sprintf("batch too large, the maximum is %d requests", d.params.maxBatchLength),
+		)).Bytes()
+
+		return resp, redact, err
+	}
+
+	if allowBatch != nil && !allowBatch(len(requests)) {
+		resp, err := NewRPCResponse(nil, "2.0", nil, NewLimitExceededError("rate limit exceeded")).Bytes()
+
+		return resp, redact, err
 	}
 
 	responses := make([]Response, 0)
@@ -259,15 +414,80 @@ func (d *Dispatcher) Handle(reqBody []byte) ([]byte, error) {
 
 	respBytes, err := json.Marshal(responses)
 	if err != nil {
-		return NewRPCResponse(nil, "2.0", nil, NewInternalError("Internal error")).Bytes()
+		resp, err := NewRPCResponse(nil, "2.0", nil, NewInternalError("Internal error")).Bytes()
+
+		return resp, redact, err
 	}
 
-	return respBytes, nil
+	return respBytes, redact, nil
 }
 
 func (d *Dispatcher) handleReq(req Request) ([]byte, Error) {
 	logger.Debug("[DSP] handleReq request", "method", req.Method, "id", req.ID)
 
+	start := time.Now()
+	data, err := d.serveReq(req)
+
+	d.observeReq(req, time.Since(start), err)
+
+	return data, err
+}
+
+// slowRequestMaxParamsLength is the maximum length of the params logged for a slow request
+const slowRequestMaxParamsLength = 1024
+
+// observeReq reports a request in the metrics, and logs it if it is slow
+func (d *Dispatcher) observeReq(req Request, duration time.Duration, err Error) {
+	// the unknown methods are reported together, not to create a metric per name
+	method := req.Method
+	if _, _, ferr := d.getFnHandler(req); ferr != nil {
+		method = "unknown"
+	}
+
+	transport := d.transport.String()
+
+	d.params.metrics.Requests.With("method", method, "transport", transport).Add(1)
+	d.params.metrics.RequestDuration.With("method", method, "transport", transport).Observe(duration.Seconds())
+
+	if err != nil {
+		d.params.metrics.Errors.With(
+			"method", method,
+			"transport", transport,
+			"code", strconv.Itoa(err.ErrorCode()),
+		).Add(1)
+	}
+
+	if d.params.slowRequestThreshold != 0 && duration >= d.params.slowRequestThreshold {
+		logger.Warn("[RPC] slow request",
+			"method", req.Method,
+			"transport", transport,
+			"duration", duration,
+			"params", slowRequestParams(req),
+		)
+	}
+}
+
+// slowRequestParams returns the params of a slow request to log. The params of the account
+// methods, which include passphrases and private keys, are redacted
+func slowRequestParams(req Request) string {
+	if isAccountMethod(req.Method) {
+		return "<redacted>"
+	}
+
+	params := string(req.Params)
+	if len(params) > slowRequestMaxParamsLength {
+		params = params[:slowRequestMaxParamsLength] + "..."
+	}
+
+	return params
+}
+
+// serveReq calls the method of a request
+func (d *Dispatcher) serveReq(req Request) ([]byte, Error) {
+	if err := d.checkAccess(req.Method); err != nil {
+		return nil, err
+	}
+
 	service, fd, ferr := d.getFnHandler(req)
 	if ferr != nil {
 		return nil, ferr
@@ -292,6 +512,12 @@ func (d *Dispatcher) handleReq(req Request) ([]byte, Error) {
 
 	output := fd.fv.Call(inArgs)
 	if err := getError(output[1]); err != nil {
+		// the errors with a code, as a revert, are returned as they are
+		var rpcErr Error
+		if errors.As(err, &rpcErr) {
+			return nil, rpcErr
+		}
+
 		d.logInternalError(req.Method, err)
 
 		return nil, NewInvalidRequestError(err.Error())
@@ -314,6 +540,21 @@ func (d *Dispatcher) handleReq(req Request) ([]byte, Error) {
 	return data, nil
 }
 
+// checkAccess returns an error if the method is not enabled, or its rate limit is exceeded
+func (d *Dispatcher) checkAccess(method string) Error {
+	if !d.access.allowed(method) || !d.accountMethods && isAccountMethod(method) {
+		return NewMethodNotFoundError(method)
+	}
+
+	if !d.limiter.allow(method, time.Now()) {
+		d.params.metrics.RejectedRequests.With("reason", rejectedMethodRateLimit).Add(1)
+
+		return NewLimitExceededError(fmt.Sprintf("rate limit of the method %s exceeded", method))
+	}
+
+	return nil
+}
+
 func (d *Dispatcher) logInternalError(method string, err error) {
 	logger.Error("failed to dispatch", "method", method, "err", err)
 }
@@ -4,10 +4,14 @@ import (
 	"encoding/json"
 	"math/big"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/TIE-Tech/tie-core/metrics"
 	"github.com/TIE-Tech/tie-core/types"
+	gometrics "github.com/go-kit/kit/metrics"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -56,7 +60,7 @@ func expectBatchJSONResult(data []byte, v interface{}) error {
 
 func TestDispatcherWebsocket(t *testing.T) {
 	store := newMockStore()
-	dispatcher := newDispatcher(store, 0)
+	dispatcher := newDispatcher(store, &dispatcherParams{})
 
 	mock := &mockWsConn{
 		msgCh: make(chan []byte, 1),
@@ -87,9 +91,44 @@ func TestDispatcherWebsocket(t *testing.T) {
 	}
 }
 
+func TestDispatcherWebsocketSubscriptions(t *testing.T) {
+	store := newMockStore()
+	dispatcher := newDispatcher(store, &dispatcherParams{})
+
+	mock := &mockWsConn{
+		msgCh: make(chan []byte, 1),
+	}
+
+	cases := []struct {
+		params string
+		valid  bool
+	}{
+		{`["newPendingTransactions"]`, true},
+		{`["newPendingTransactions", true]`, true},
+		{`["newPendingTransactions", "full"]`, false},
+		{`["syncing"]`, true},
+		{`["unknown"]`, false},
+	}
+
+	for _, c := range cases {
+		req := []byte(`{"id":1,"method":"eth_subscribe","params":` + c.params + `}`)
+
+		resp, err := dispatcher.HandleWs(req, mock)
+		assert.NoError(t, err)
+
+		var filterID string
+		if c.valid {
+			assert.NoError(t, expectJSONResult(resp, &filterID), c.params)
+			assert.NotEmpty(t, filterID)
+		} else {
+			assert.Error(t, expectJSONResult(resp, &filterID), c.params)
+		}
+	}
+}
+
 func TestDispatcherWebsocketRequestFormats(t *testing.T) {
 	store := newMockStore()
-	dispatcher := newDispatcher(store, 0)
+	dispatcher := newDispatcher(store, &dispatcherParams{})
 
 	mock := &mockWsConn{
 		msgCh: make(chan []byte, 1),
@@ -193,7 +232,7 @@ func (m *mockService) Filter(f LogFilter) (interface{}, error) {
 func TestDispatcherFuncDecode(t *testing.T) {
 	srv := &mockService{msgCh: make(chan interface{}, 10)}
 
-	dispatcher := newDispatcher(newMockStore(), 0)
+	dispatcher := newDispatcher(newMockStore(), &dispatcherParams{})
 	dispatcher.registerService("mock", srv)
 
 	handleReq := func(typ string, msg string) interface{} {
@@ -258,7 +297,7 @@ func TestDispatcherFuncDecode(t *testing.T) {
 }
 
 func TestDispatcherBatchRequest(t *testing.T) {
-	dispatcher := newDispatcher(newMockStore(), 0)
+	dispatcher := newDispatcher(newMockStore(), &dispatcherParams{})
 
 	// test with leading whitespace ("  \t\n\n\r")
 	leftBytes := []byte{0x20, 0x20, 0x09, 0x0A, 0x0A, 0x0D}
@@ -280,3 +319,89 @@ func TestDispatcherBatchRequest(t *testing.T) {
 	assert.Equal(t, res[0].Error, jsonerr)
 	assert.Nil(t, res[3].Error)
 }
+
+// mockMetric records the values of a counter or a gauge by labels
+type mockMetric struct {
+	lock   *sync.Mutex
+	values map[string]float64
+	labels []string
+}
+
+func newMockMetric() *mockMetric {
+	return &mockMetric{
+		lock:   &sync.Mutex{},
+		values: map[string]float64{},
+	}
+}
+
+func (m *mockMetric) With(labelValues ...string) gometrics.Counter {
+	return &mockMetric{
+		lock:   m.lock,
+		values: m.values,
+		labels: append(append([]string{}, m.labels...), labelValues...),
+	}
+}
+
+func (m *mockMetric) Add(delta float64) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.values[strings.Join(m.labels, ",")] += delta
+}
+
+func (m *mockMetric) value(labelValues ...string) float64 {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.values[strings.Join(labelValues, ",")]
+}
+
+func TestDispatcherMetrics(t *testing.T) {
+	requests, errs := newMockMetric(), newMockMetric()
+
+	rpcMetrics := metrics.NewRPCMetrics()
+	rpcMetrics.Requests = requests
+	rpcMetrics.Errors = errs
+
+	dispatcher := newDispatcher(newMockStore(), &dispatcherParams{
+		metrics:              rpcMetrics,
+		slowRequestThreshold: time.Nanosecond,
+	}).withTransport(serverHTTP)
+
+	_, err := dispatcher.Handle([]byte(`[
+		{"id":1,"method":"web3_clientVersion"},
+		{"id":2,"method":"web3_clientVersion"},
+		{"id":3,"method":"eth_none"},
+		{"id":4,"method":"none"}
+	]`))
+	assert.NoError(t, err)
+
+	assert.Equal(t, float64(2), requests.value("method", "web3_clientVersion", "transport", "http"))
+	assert.Equal(t, float64(0), errs.value("method", "web3_clientVersion", "transport", "http", "code", "-32601"))
+
+	// the unknown methods are reported together
+	assert.Equal(t, float64(2), requests.value("method", "unknown", "transport", "http"))
+	assert.Equal(t, float64(2), errs.value("method", "unknown", "transport", "http", "code", "-32601"))
+}
+
+func TestDispatcher_SlowRequestParams(t *testing.T) {
+	params := func(method, params string) string {
+		return slowRequestParams(Request{Method: method, Params: json.RawMessage(params)})
+	}
+
+	assert.Equal(t, `["0x1",true]`, params("eth_getBlockByNumber", `["0x1",true]`))
+
+	// the long params are truncated
+	long := params("eth_call", `["`+strings.Repeat("a", 2*slowRequestMaxParamsLength)+`"]`)
+	assert.Len(t, long, slowRequestMaxParamsLength+len("..."))
+
+	// the passphrases and the keys of the account methods are never logged
+	for _, method := range []string{
+		"personal_unlockAccount",
+		"personal_importRawKey",
+		"personal_sendTransaction",
+		"eth_sign",
+	} {
+		assert.Equal(t, "<redacted>", params(method, `["0x1","secret"]`), method)
+	}
+}
@@ -3,6 +3,9 @@ package rpc
 import (
 	"errors"
 	"fmt"
+
+	"github.com/TIE-Tech/tie-core/common/hex"
+	"github.com/TIE-Tech/tie-core/tievm/evm"
 )
 
 var (
@@ -13,6 +16,13 @@ type Error interface {
 	Error() string
 	ErrorCode() int
 }
+
+// DataError is an error with additional data, returned in the data field of the error object
+type DataError interface {
+	Error
+	ErrorData() interface{}
+}
+
 type invalidParamsError struct {
 	err string
 }
@@ -73,6 +83,35 @@ func (e *methodNotFoundError) ErrorCode() int {
 	return -32601
 }
 
+type limitExceededError struct {
+	err string
+}
+
+func (e *limitExceededError) Error() string {
+	return e.err
+}
+
+func (e *limitExceededError) ErrorCode() int {
+	return -32005
+}
+
+type revertError struct {
+	err    string
+	reason string
+}
+
+func (e *revertError) Error() string {
+	return e.err
+}
+
+func (e *revertError) ErrorCode() int {
+	return errCodeReverted
+}
+
+func (e *revertError) ErrorData() interface{} {
+	return e.reason
+}
+
 func NewMethodNotFoundError(method string) *methodNotFoundError {
 	return &methodNotFoundError{fmt.Sprintf("the method %s does not exist/is not available", method)}
 }
@@ -87,6 +126,21 @@ func NewInternalError(msg string) *internalError {
 	return &internalError{msg}
 }
 
+func NewLimitExceededError(msg string) *limitExceededError {
+	return &limitExceededError{msg}
+}
+
 func NewSubscriptionNotFoundError(method string) *subscriptionNotFoundError {
 	return &subscriptionNotFoundError{fmt.Sprintf("subscribe method %s not found", method)}
 }
+
+// NewRevertError returns the error of a reverted execution, with its reason in
+// the message if it can be decoded and the raw revert data as the error data
+func NewRevertError(res *evm.ExecutionResult) *revertError {
+	msg := "execution reverted"
+	if reason, ok := evm.UnpackRevertReason(res.ReturnValue); ok {
+		msg = fmt.Sprintf("%s: %s", msg, reason)
+	}
+
+	return &revertError{msg, hex.EncodeToHex(res.ReturnValue)}
+}
@@ -1,6 +1,8 @@
 package rpc
 
 import (
+	"github.com/TIE-Tech/tie-core/storage"
+	"github.com/TIE-Tech/tie-core/storage/memory"
 	"github.com/TIE-Tech/tie-core/types"
 	"github.com/stretchr/testify/assert"
 	"math/big"
@@ -179,10 +181,14 @@ func TestEth_Block_GetLogs(t *testing.T) {
 	store.topics = []types.Hash{topic1, topic2, topic3}
 
 	for i := 0; i < 5; i++ {
+		hash := types.StringToHash(strconv.Itoa(i))
+		receipts, _ := store.GetReceiptsByHash(hash)
+
 		store.add(&types.Block{
 			Header: &types.Header{
-				Number: uint64(i),
-				Hash:   types.StringToHash(strconv.Itoa(i)),
+				Number:    uint64(i),
+				Hash:      hash,
+				LogsBloom: types.CreateBloom(receipts),
 			},
 			Transactions: []*types.Transaction{
 				{
@@ -218,6 +224,122 @@ func TestEth_Block_GetLogs(t *testing.T) {
 	}
 }
 
+func TestEth_Block_GetLogs_BloomBits(t *testing.T) {
+	db, err := memory.NewMemoryStorage()
+	assert.NoError(t, err)
+
+	store := &mockBloomBitsStore{db: db, receipts: map[types.Hash][]*types.Receipt{}}
+
+	// the blocks 5 and 4097 have a log of addr1, the block 7 a log of addr2
+	logs := map[uint64]types.Address{
+		5:                                addr1,
+		7:                                addr2,
+		storage.BloomBitsSectionSize + 1: addr1,
+	}
+
+	blooms := make([]types.Bloom, 0, storage.BloomBitsSectionSize)
+
+	for i := uint64(0); i < storage.BloomBitsSectionSize+3; i++ {
+		header := &types.Header{Number: i, Hash: types.BytesToHash(big.NewInt(int64(i) + 1).Bytes())}
+
+		if addr, ok := logs[i]; ok {
+			store.receipts[header.Hash] = []*types.Receipt{{Logs: []*types.Log{{Address: addr}}}}
+			header.LogsBloom = types.CreateBloom(store.receipts[header.Hash])
+		}
+
+		if i < storage.BloomBitsSectionSize {
+			blooms = append(blooms, header.LogsBloom)
+		}
+
+		store.headers = append(store.headers, header)
+	}
+
+	// only the first section is indexed
+	assert.NoError(t, db.WriteBloomBitsSection(0, storage.GenerateBloomBits(blooms)))
+
+	eth := newTestEthEndpoint(store)
+
+	res, err := eth.GetLogs(&LogFilter{
+		fromBlock: EarliestBlockNumber,
+		toBlock:   LatestBlockNumber,
+		Addresses: []types.Address{addr1},
+	})
+	assert.NoError(t, err)
+
+	found, ok := res.([]*Log)
+	assert.True(t, ok)
+	assert.Len(t, found, 2)
+	assert.Equal(t, argUint64(5), found[0].BlockNumber)
+	assert.Equal(t, argUint64(storage.BloomBitsSectionSize+1), found[1].BlockNumber)
+
+	// the headers are only read for the blocks after the indexed section
+	assert.Equal(t, 3, store.headerReads)
+
+	// the range and the logs are limited
+	eth.maxBlockRange = 100
+
+	_, err = eth.GetLogs(&LogFilter{fromBlock: 0, toBlock: 100})
+	assert.Error(t, err)
+
+	_, err = eth.GetLogs(&LogFilter{fromBlock: 0, toBlock: 99})
+	assert.NoError(t, err)
+
+	eth.maxLogs = 1
+
+	_, err = eth.GetLogs(&LogFilter{fromBlock: 0, toBlock: 99})
+	assert.Error(t, err)
+}
+
+type mockBloomBitsStore struct {
+	ethStore
+
+	db          storage.Storage
+	headers     []*types.Header
+	receipts    map[types.Hash][]*types.Receipt
+	headerReads int
+}
+
+func (m *mockBloomBitsStore) Header() *types.Header {
+	return m.headers[len(m.headers)-1]
+}
+
+func (m *mockBloomBitsStore) GetHeaderByNumber(num uint64) (*types.Header, bool) {
+	if num >= uint64(len(m.headers)) {
+		return nil, false
+	}
+
+	m.headerReads++
+
+	return m.headers[num], true
+}
+
+func (m *mockBloomBitsStore) GetBlockByNumber(num uint64, full bool) (*types.Block, bool) {
+	if num >= uint64(len(m.headers)) {
+		return nil, false
+	}
+
+	header := m.headers[num]
+	block := &types.Block{Header: header}
+
+	for range m.receipts[header.Hash] {
+		block.Transactions = append(block.Transactions, &types.Transaction{})
+	}
+
+	return block, true
+}
+
+func (m *mockBloomBitsStore) GetReceiptsByHash(hash types.Hash) ([]*types.Receipt, error) {
+	return m.receipts[hash], nil
+}
+
+func (m *mockBloomBitsStore) BloomBitsSections() uint64 {
+	return m.db.ReadBloomBitsSections()
+}
+
+func (m *mockBloomBitsStore) MatchBloomBits(section uint64, groups [][][]byte) ([]byte, error) {
+	return storage.MatchBloomBits(m.db, section, groups)
+}
+
 type mockBlockStore struct {
 	ethStore
 	blocks []*types.Block
@@ -301,6 +423,10 @@ func (m *mockBlockStore) GetReceiptsByHash(hash types.Hash) ([]*types.Receipt, e
 	return nil, nil
 }
 
+func (m *mockBlockStore) BloomBitsSections() uint64 {
+	return 0
+}
+
 func (m *mockBlockStore) GetHeaderByNumber(blockNumber uint64) (*types.Header, bool) {
 	b, ok := m.GetBlockByNumber(blockNumber, false)
 	if !ok {
@@ -333,3 +459,168 @@ func (m *mockBlockStore) GetBlockByHash(hash types.Hash, full bool) (*types.Bloc
 func (m *mockBlockStore) Header() *types.Header {
 	return m.blocks[len(m.blocks)-1].Header
 }
+
+func TestEth_Block_GetBlockReceipts(t *testing.T) {
+	store := newMockReceiptStore()
+	eth := newTestEthEndpoint(store)
+
+	block := store.blocks[1]
+	number := BlockNumber(1)
+
+	for _, filter := range []BlockNumberOrHash{
+		{BlockNumber: &number},
+		{BlockHash: &block.Header.Hash},
+	} {
+		res, err := eth.GetBlockReceipts(filter)
+		assert.NoError(t, err)
+
+		receipts, ok := res.([]*receipt)
+		assert.True(t, ok)
+		assert.Len(t, receipts, 3)
+
+		for i, receipt := range receipts {
+			txn := block.Transactions[i]
+
+			assert.Equal(t, txn.Hash, receipt.TxHash)
+			assert.Equal(t, argUint64(i), receipt.TxIndex)
+			assert.Equal(t, block.Hash(), receipt.BlockHash)
+			assert.Equal(t, txn.From, receipt.FromAddr)
+			assert.Equal(t, txn.To, receipt.ToAddr)
+			assert.Equal(t, argBig(*txn.GasPrice), receipt.EffectiveGasPrice)
+		}
+
+		// the logs are indexed in the block
+		assert.Len(t, receipts[0].Logs, 2)
+		assert.Equal(t, argUint64(0), receipts[0].Logs[0].LogIndex)
+		assert.Equal(t, argUint64(1), receipts[0].Logs[1].LogIndex)
+		assert.Len(t, receipts[1].Logs, 0)
+		assert.Len(t, receipts[2].Logs, 1)
+		assert.Equal(t, argUint64(2), receipts[2].Logs[0].LogIndex)
+		assert.Equal(t, argUint64(2), receipts[2].Logs[0].TxIndex)
+	}
+
+	// a block without transactions has no receipts
+	number = 0
+
+	res, err := eth.GetBlockReceipts(BlockNumberOrHash{BlockNumber: &number})
+	assert.NoError(t, err)
+	assert.Equal(t, []*receipt{}, res)
+
+	// an unknown block
+	number = 5
+
+	_, err = eth.GetBlockReceipts(BlockNumberOrHash{BlockNumber: &number})
+	assert.Error(t, err)
+}
+
+func TestEth_Block_LogIndexes(t *testing.T) {
+	store := newMockReceiptStore()
+	eth := newTestEthEndpoint(store)
+
+	block := store.blocks[1]
+
+	// the receipt of a transaction indexes its logs in the block
+	res, err := eth.GetTransactionReceipt(block.Transactions[2].Hash)
+	assert.NoError(t, err)
+
+	receipt, ok := res.(*receipt)
+	assert.True(t, ok)
+	assert.Equal(t, argUint64(2), receipt.TxIndex)
+	assert.Len(t, receipt.Logs, 1)
+	assert.Equal(t, argUint64(2), receipt.Logs[0].LogIndex)
+	assert.Equal(t, argUint64(2), receipt.Logs[0].TxIndex)
+
+	// the logs of a query too
+	res, err = eth.GetLogs(&LogFilter{BlockHash: &block.Header.Hash})
+	assert.NoError(t, err)
+
+	logs, ok := res.([]*Log)
+	assert.True(t, ok)
+	assert.Len(t, logs, 3)
+
+	for i, log := range logs {
+		assert.Equal(t, argUint64(i), log.LogIndex)
+	}
+
+	assert.Equal(t, argUint64(2), logs[2].TxIndex)
+}
+
+type mockReceiptStore struct {
+	ethStore
+	blocks   []*types.Block
+	receipts map[types.Hash][]*types.Receipt
+}
+
+func newMockReceiptStore() *mockReceiptStore {
+	store := &mockReceiptStore{
+		blocks: []*types.Block{
+			{Header: &types.Header{Number: 0, Hash: types.StringToHash("10")}},
+			{Header: &types.Header{Number: 1, Hash: types.StringToHash("11")}},
+		},
+		receipts: map[types.Hash][]*types.Receipt{},
+	}
+
+	block := store.blocks[1]
+	receipts := []*types.Receipt{}
+
+	for i := 0; i < 3; i++ {
+		to := types.StringToAddress(strconv.Itoa(i + 1))
+		block.Transactions = append(block.Transactions, &types.Transaction{
+			Nonce:    uint64(i),
+			GasPrice: big.NewInt(int64(i + 1)),
+			To:       &to,
+			Value:    big.NewInt(0),
+			From:     addr1,
+			Hash:     types.StringToHash(strconv.Itoa(20 + i)),
+		})
+
+		status := types.ReceiptSuccess
+		receipts = append(receipts, &types.Receipt{Status: &status, TxHash: block.Transactions[i].Hash})
+	}
+
+	receipts[0].Logs = []*types.Log{{Address: addr1}, {Address: addr2}}
+	receipts[2].Logs = []*types.Log{{Address: addr1}}
+
+	block.Header.LogsBloom = types.CreateBloom(receipts)
+	store.receipts[block.Hash()] = receipts
+
+	return store
+}
+
+func (m *mockReceiptStore) Header() *types.Header {
+	return m.blocks[len(m.blocks)-1].Header
+}
+
+func (m *mockReceiptStore) GetHeaderByNumber(num uint64) (*types.Header, bool) {
+	if num >= uint64(len(m.blocks)) {
+		return nil, false
+	}
+
+	return m.blocks[num].Header, true
+}
+
+func (m *mockReceiptStore) GetBlockByHash(hash types.Hash, full bool) (*types.Block, bool) {
+	for _, b := range m.blocks {
+		if b.Hash() == hash {
+			return b, true
+		}
+	}
+
+	return nil, false
+}
+
+func (m *mockReceiptStore) ReadTxLookup(hash types.Hash) (types.Hash, bool) {
+	for _, b := range m.blocks {
+		for _, txn := range b.Transactions {
+			if txn.Hash == hash {
+				return b.Hash(), true
+			}
+		}
+	}
+
+	return types.Hash{}, false
+}
+
+func (m *mockReceiptStore) GetReceiptsByHash(hash types.Hash) ([]*types.Receipt, error) {
+	return m.receipts[hash], nil
+}
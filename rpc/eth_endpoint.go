@@ -1,15 +1,21 @@
 package rpc
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/TIE-Tech/go-logger"
 	"github.com/TIE-Tech/tie-core/params"
 	"math/big"
+	"sort"
+	"time"
 
 	"github.com/TIE-Tech/tie-core/common/hex"
 	"github.com/TIE-Tech/tie-core/common/progress"
+	"github.com/TIE-Tech/tie-core/core"
+	"github.com/TIE-Tech/tie-core/metrics"
 	"github.com/TIE-Tech/tie-core/state"
+	"github.com/TIE-Tech/tie-core/storage"
 	"github.com/TIE-Tech/tie-core/tievm/evm"
 	"github.com/TIE-Tech/tie-core/types"
 	"github.com/umbracle/fastrlp"
@@ -24,6 +30,9 @@ type ethTxPoolStore interface {
 
 	// GetPendingTx gets the pending transaction from the transaction pool, if it's present
 	GetPendingTx(txHash types.Hash) (*types.Transaction, bool)
+
+	// GetPriceLimit returns the minimum gas price accepted by the tx pool
+	GetPriceLimit() uint64
 }
 
 type ethStateStore interface {
@@ -52,11 +61,42 @@ type ethBlockchainStore interface {
 	// GetReceiptsByHash returns the receipts for a block hash
 	GetReceiptsByHash(hash types.Hash) ([]*types.Receipt, error)
 
-	// GetAvgGasPrice returns the average gas price
-	GetAvgGasPrice() *big.Int
-
-	// ApplyTxn applies a transaction object to the blockchain
-	ApplyTxn(header *types.Header, txn *types.Transaction) (*evm.ExecutionResult, error)
+	// BloomBitsSections returns the number of sections of the bloom bits index
+	BloomBitsSections() uint64
+
+	// MatchBloomBits returns the bit set of the blocks of an indexed section whose
+	// bloom possibly contains a value of every group
+	MatchBloomBits(section uint64, groups [][][]byte) ([]byte, error)
+
+	// ApplyTxn applies a transaction object to the blockchain, with the optional
+	// state and block overrides which are not persisted. The execution is
+	// cancelled when the context is done
+	ApplyTxn(
+		ctx context.Context,
+		header *types.Header,
+		txn *types.Transaction,
+		stateOverride state.StateOverride,
+		blockOverride *state.BlockOverride,
+	) (*evm.ExecutionResult, error)
+
+	// BeginSimulation returns a transition on the state of a header, which is never committed
+	BeginSimulation(header *types.Header) (*state.Transition, error)
+
+	// GetPendingBlock returns the pending block and its receipts, false if the pending block is not built
+	GetPendingBlock() (*types.Block, []*types.Receipt, bool)
+
+	// PendingState runs f on a fork of the state of the pending block, which is discarded afterwards.
+	// It returns ErrNoPendingBlock if the pending block is not built
+	PendingState(f func(header *types.Header, transition *state.Transition) error) error
+
+	// ApplyPendingTxn applies a transaction on the state of the pending block like ApplyTxn.
+	// It returns ErrNoPendingBlock if the pending block is not built
+	ApplyPendingTxn(
+		ctx context.Context,
+		txn *types.Transaction,
+		stateOverride state.StateOverride,
+		blockOverride *state.BlockOverride,
+	) (*evm.ExecutionResult, error)
 
 	// GetSyncProgression retrieves the current sync progression, if any
 	GetSyncProgression() *progress.Progression
@@ -71,9 +111,24 @@ type ethStore interface {
 
 // Eth is the eth jsonrpc endpoint
 type Eth struct {
-	store         ethStore
-	chainID       uint64
-	filterManager *FilterManager
+	store          ethStore
+	chainID        uint64
+	filterManager  *FilterManager
+	gasPriceOracle *gasPriceOracle
+
+	// maxBlockRange is the maximum number of blocks of a log query, 0 for no limit
+	maxBlockRange uint64
+
+	// maxLogs is the maximum number of logs returned by a log query, 0 for no limit
+	maxLogs uint64
+
+	// callTimeout is the maximum execution time of eth_call, eth_estimateGas and eth_simulateV1, 0 for no limit
+	callTimeout time.Duration
+
+	// accounts signs with the keys of the node, nil if the keystore is disabled
+	accounts accountStore
+
+	metrics *metrics.RPCMetrics
 }
 
 // ChainId returns the chain id of the client
@@ -106,18 +161,23 @@ func (e *Eth) getHeaderFromBlockNumberOrHash(bnh *BlockNumberOrHash) (*types.Hea
 }
 
 func (e *Eth) Syncing() (interface{}, error) {
-	if syncProgression := e.store.GetSyncProgression(); syncProgression != nil {
+	return toSyncingResult(e.store.GetSyncProgression()), nil
+}
+
+// toSyncingResult returns the status of the sync, false if the node is not syncing
+func toSyncingResult(syncProgression *progress.Progression) interface{} {
+	if syncProgression != nil {
 		// Node is bulk syncing, return the status
 		return progression{
 			Type:          string(syncProgression.SyncType),
 			StartingBlock: hex.EncodeUint64(syncProgression.StartingBlock),
 			CurrentBlock:  hex.EncodeUint64(syncProgression.CurrentBlock),
 			HighestBlock:  hex.EncodeUint64(syncProgression.HighestBlock),
-		}, nil
+		}
 	}
 
 	// Node is not bulk syncing
-	return false, nil
+	return false
 }
 
 func GetNumericBlockNumber(number BlockNumber, e *Eth) (uint64, error) {
@@ -142,13 +202,11 @@ func GetNumericBlockNumber(number BlockNumber, e *Eth) (uint64, error) {
 
 // GetBlockByNumber returns information about a block by block number
 func (e *Eth) GetBlockByNumber(number BlockNumber, fullTx bool) (interface{}, error) {
-	num, err := GetNumericBlockNumber(number, e)
+	block, ok, err := e.getBlockByNumber(number)
 	if err != nil {
 		return nil, err
 	}
 
-	block, ok := e.store.GetBlockByNumber(num, true)
-
 	if !ok {
 		return nil, nil
 	}
@@ -156,6 +214,27 @@ func (e *Eth) GetBlockByNumber(number BlockNumber, fullTx bool) (interface{}, er
 	return toBlock(block, fullTx), nil
 }
 
+// getBlockByNumber returns a block by number, the pending block is
+// the latest block while it is not built
+func (e *Eth) getBlockByNumber(number BlockNumber) (*types.Block, bool, error) {
+	if number == PendingBlockNumber {
+		if block, _, ok := e.store.GetPendingBlock(); ok {
+			return block, true, nil
+		}
+
+		number = LatestBlockNumber
+	}
+
+	num, err := GetNumericBlockNumber(number, e)
+	if err != nil {
+		return nil, false, err
+	}
+
+	block, ok := e.store.GetBlockByNumber(num, true)
+
+	return block, ok, nil
+}
+
 // GetBlockByHash returns information about a block by hash
 func (e *Eth) GetBlockByHash(hash types.Hash, fullTx bool) (interface{}, error) {
 	block, ok := e.store.GetBlockByHash(hash, true)
@@ -167,13 +246,11 @@ func (e *Eth) GetBlockByHash(hash types.Hash, fullTx bool) (interface{}, error)
 }
 
 func (e *Eth) GetBlockTransactionCountByNumber(number BlockNumber) (interface{}, error) {
-	num, err := GetNumericBlockNumber(number, e)
+	block, ok, err := e.getBlockByNumber(number)
 	if err != nil {
 		return nil, err
 	}
 
-	block, ok := e.store.GetBlockByNumber(num, true)
-
 	if !ok {
 		return nil, nil
 	}
@@ -210,8 +287,21 @@ func (e *Eth) SendRawTransaction(input string) (interface{}, error) {
 }
 
 // SendTransaction creates new message call transaction or a contract creation, if the data field contains code.
+// The transaction is signed with the key of the sender if the keystore is enabled, the account must be unlocked
 func (e *Eth) SendTransaction(arg *txnArgs) (interface{}, error) {
-	transaction, err := e.decodeTxn(arg)
+	var (
+		transaction *types.Transaction
+		err         error
+	)
+
+	if e.accounts != nil {
+		transaction, err = e.signTxnArgs(arg, func(txn *types.Transaction) (*types.Transaction, error) {
+			return e.accounts.SignTx(txn.From, txn, e.signer())
+		})
+	} else {
+		transaction, err = e.decodeTxn(arg)
+	}
+
 	if err != nil {
 		return nil, err
 	}
@@ -223,6 +313,101 @@ func (e *Eth) SendTransaction(arg *txnArgs) (interface{}, error) {
 	return transaction.Hash.String(), nil
 }
 
+// Accounts returns the accounts of the keystore
+func (e *Eth) Accounts() (interface{}, error) {
+	if e.accounts == nil {
+		return []types.Address{}, nil
+	}
+
+	return e.accounts.Accounts(), nil
+}
+
+// Sign signs keccak256("\x19Ethereum Signed Message:\n" + len(message) + message)
+// with the key of an unlocked account
+func (e *Eth) Sign(address types.Address, data argBytes) (interface{}, error) {
+	if e.accounts == nil {
+		return nil, ErrKeystoreDisabled
+	}
+
+	sig, err := e.accounts.SignHash(address, signHash(data))
+	if err != nil {
+		return nil, err
+	}
+
+	return toMessageSignature(sig), nil
+}
+
+// SignTransaction signs the transaction with the key of the sender, which must be unlocked,
+// and returns it without adding it to the pool
+func (e *Eth) SignTransaction(arg *txnArgs) (interface{}, error) {
+	if e.accounts == nil {
+		return nil, ErrKeystoreDisabled
+	}
+
+	txn, err := e.signTxnArgs(arg, func(txn *types.Transaction) (*types.Transaction, error) {
+		return e.accounts.SignTx(txn.From, txn, e.signer())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toSignTransactionResult(txn), nil
+}
+
+// signer returns the signer of the transactions of the chain
+func (e *Eth) signer() state.TxSigner {
+	return state.NewSigner(e.chainID)
+}
+
+// signTxnArgs signs a transaction with sign. The fields which are not set are filled: the nonce
+// is the next one of the sender in the pool, the gas price is the suggested one and the gas is
+// estimated on the pending block
+func (e *Eth) signTxnArgs(
+	arg *txnArgs,
+	sign func(txn *types.Transaction) (*types.Transaction, error),
+) (*types.Transaction, error) {
+	if arg.From == nil {
+		return nil, errors.New("the sender of the transaction is not set")
+	}
+
+	if arg.Nonce == nil {
+		arg.Nonce = argUintPtr(e.store.GetNonce(*arg.From))
+	}
+
+	if arg.GasPrice == nil {
+		arg.GasPrice = argBytesPtr(e.gasPriceOracle.SuggestPrice().Bytes())
+	}
+
+	if arg.Gas == nil {
+		// the transaction is estimated with the nonce of the sender in the pending state
+		estimateArg := *arg
+		estimateArg.Nonce = nil
+
+		pending := PendingBlockNumber
+
+		gas, err := e.estimateGas(&estimateArg, &pending, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to estimate the gas of the transaction: %w", err)
+		}
+
+		arg.Gas = argUintPtr(gas)
+	}
+
+	txn, err := e.decodeTxn(arg)
+	if err != nil {
+		return nil, err
+	}
+
+	signed, err := sign(txn)
+	if err != nil {
+		return nil, err
+	}
+
+	signed.ComputeHash()
+
+	return signed, nil
+}
+
 // GetTransactionByHash returns a transaction by its hash.
 // If the transaction is still pending -> return the txn with some fields omitted
 // If the transaction is sealed into a block -> return the whole txn with all fields
@@ -340,38 +525,56 @@ func (e *Eth) GetTransactionReceipt(hash types.Hash) (interface{}, error) {
 		return nil, nil
 	}
 
-	txn := block.Transactions[indx]
-	raw := receipts[indx]
-
-	logs := make([]*Log, len(raw.Logs))
-	for indx, elem := range raw.Logs {
-		logs[indx] = &Log{
-			Address:     elem.Address,
-			Topics:      elem.Topics,
-			Data:        argBytes(elem.Data),
-			BlockHash:   block.Hash(),
-			BlockNumber: argUint64(block.Number()),
-			TxHash:      txn.Hash,
-			TxIndex:     argUint64(indx),
-			LogIndex:    argUint64(indx),
-			Removed:     false,
-		}
-	}
-
-	res := &receipt{
-		Root:              raw.Root,
-		CumulativeGasUsed: argUint64(raw.CumulativeGasUsed),
-		LogsBloom:         raw.LogsBloom,
-		Status:            argUint64(*raw.Status),
-		TxHash:            txn.Hash,
-		TxIndex:           argUint64(indx),
-		BlockHash:         block.Hash(),
-		BlockNumber:       argUint64(block.Number()),
-		GasUsed:           argUint64(raw.GasUsed),
-		ContractAddress:   raw.ContractAddress,
-		FromAddr:          txn.From,
-		ToAddr:            txn.To,
-		Logs:              logs,
+	if indx >= len(receipts) {
+		// receipt not written yet on the db
+		return nil, nil
+	}
+
+	return toReceipt(
+		receipts[indx],
+		block.Transactions[indx],
+		indx,
+		block.Header,
+		firstLogIndex(receipts, indx),
+	), nil
+}
+
+// GetBlockReceipts returns the receipts of all the transactions of a block
+func (e *Eth) GetBlockReceipts(filter BlockNumberOrHash) (interface{}, error) {
+	header, err := e.getHeaderFromBlockNumberOrHash(&filter)
+	if err != nil {
+		return nil, err
+	}
+
+	block, ok := e.store.GetBlockByHash(header.Hash, true)
+	if !ok {
+		return nil, nil
+	}
+
+	res := make([]*receipt, 0, len(block.Transactions))
+	if len(block.Transactions) == 0 {
+		return res, nil
+	}
+
+	receipts, err := e.store.GetReceiptsByHash(block.Hash())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(receipts) != len(block.Transactions) {
+		// receipts not written yet on the db
+		logger.Warn(
+			fmt.Sprintf("No receipts found for block with hash [%s]", block.Hash().String()),
+		)
+
+		return nil, nil
+	}
+
+	logIndex := uint64(0)
+
+	for indx, raw := range receipts {
+		res = append(res, toReceipt(raw, block.Transactions[indx], indx, block.Header, logIndex))
+		logIndex += uint64(len(raw.Logs))
 	}
 
 	return res, nil
@@ -393,6 +596,16 @@ func (e *Eth) GetStorageAt(
 		filter.BlockNumber, _ = createBlockNumberPointer("latest")
 	}
 
+	var value types.Hash
+
+	if ok, err := e.readPendingState(&filter, func(transition *state.Transition) error {
+		value = transition.GetStorage(address, index)
+
+		return nil
+	}); ok {
+		return argBytesPtr(value.Bytes()), err
+	}
+
 	header, err = e.getHeaderFromBlockNumberOrHash(&filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get header from block hash or block number")
@@ -424,18 +637,159 @@ func (e *Eth) GetStorageAt(
 	return argBytesPtr(data), nil
 }
 
-// GasPrice returns the average gas price based on the last x blocks
+// GasPrice returns the suggested gas price based on the last blocks
 func (e *Eth) GasPrice() (interface{}, error) {
-	// Grab the average gas price and convert it to a hex value
-	avgGasPrice := hex.EncodeBig(e.store.GetAvgGasPrice())
+	return hex.EncodeBig(e.gasPriceOracle.SuggestPrice()), nil
+}
+
+// MaxPriorityFeePerGas returns the suggested priority fee. There is no base fee,
+// the whole gas price is paid to the block creator
+func (e *Eth) MaxPriorityFeePerGas() (interface{}, error) {
+	return hex.EncodeBig(e.gasPriceOracle.SuggestPrice()), nil
+}
+
+// FeeHistory returns the gas used ratio of a range of blocks ending at newestBlock,
+// and the gas prices at the given percentiles of the gas used by each block
+func (e *Eth) FeeHistory(
+	blockCount argUint64,
+	newestBlock BlockNumber,
+	rewardPercentiles []float64,
+) (interface{}, error) {
+	for i, percentile := range rewardPercentiles {
+		if percentile < 0 || percentile > 100 {
+			return nil, fmt.Errorf("invalid reward percentile %f", percentile)
+		}
+
+		if i > 0 && percentile < rewardPercentiles[i-1] {
+			return nil, fmt.Errorf("reward percentiles are not increasing: %f > %f", rewardPercentiles[i-1], percentile)
+		}
+	}
+
+	// There is no pending block, use the latest one
+	if newestBlock == PendingBlockNumber {
+		newestBlock = LatestBlockNumber
+	}
+
+	header, err := e.getBlockHeader(newestBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	count := uint64(blockCount)
+	if count > maxFeeHistory {
+		count = maxFeeHistory
+	}
+
+	if count > header.Number+1 {
+		count = header.Number + 1
+	}
+
+	history := &feeHistory{
+		OldestBlock:   argUint64(header.Number + 1 - count),
+		BaseFeePerGas: make([]argBig, 0, count+1),
+		GasUsedRatio:  make([]float64, 0, count),
+	}
+
+	if len(rewardPercentiles) > 0 {
+		history.Reward = make([][]argBig, 0, count)
+	}
+
+	for number := uint64(history.OldestBlock); number <= header.Number; number++ {
+		block, ok := e.store.GetBlockByNumber(number, true)
+		if !ok {
+			return nil, fmt.Errorf("error fetching block number %d", number)
+		}
+
+		ratio := float64(0)
+		if block.Header.GasLimit > 0 {
+			ratio = float64(block.Header.GasUsed) / float64(block.Header.GasLimit)
+		}
 
-	return avgGasPrice, nil
+		history.BaseFeePerGas = append(history.BaseFeePerGas, argBig{})
+		history.GasUsedRatio = append(history.GasUsedRatio, ratio)
+
+		if len(rewardPercentiles) > 0 {
+			rewards, err := e.blockRewards(block, rewardPercentiles)
+			if err != nil {
+				return nil, err
+			}
+
+			history.Reward = append(history.Reward, rewards)
+		}
+	}
+
+	// the base fee of the block following the newest one
+	if count > 0 {
+		history.BaseFeePerGas = append(history.BaseFeePerGas, argBig{})
+	}
+
+	return history, nil
+}
+
+// blockRewards returns the gas prices of a block at the percentiles of its gas used,
+// the transactions being sorted by gas price
+func (e *Eth) blockRewards(block *types.Block, percentiles []float64) ([]argBig, error) {
+	rewards := make([]argBig, len(percentiles))
+
+	receipts, err := e.store.GetReceiptsByHash(block.Hash())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(receipts) != len(block.Transactions) {
+		return nil, fmt.Errorf("missing receipts of block %d", block.Number())
+	}
+
+	type txnGas struct {
+		gasUsed uint64
+		price   *big.Int
+	}
+
+	var (
+		forks   = e.store.GetForksInTime(block.Number())
+		txns    = make([]txnGas, 0, len(block.Transactions))
+		gasUsed = uint64(0)
+	)
+
+	for i, txn := range block.Transactions {
+		if isSystemTx(txn, forks) {
+			continue
+		}
+
+		txns = append(txns, txnGas{receipts[i].GasUsed, txn.GasPrice})
+		gasUsed += receipts[i].GasUsed
+	}
+
+	if len(txns) == 0 {
+		return rewards, nil
+	}
+
+	sort.SliceStable(txns, func(i, j int) bool {
+		return txns[i].price.Cmp(txns[j].price) < 0
+	})
+
+	index, sum := 0, txns[0].gasUsed
+
+	for i, percentile := range percentiles {
+		threshold := uint64(float64(gasUsed) * percentile / 100)
+
+		for sum < threshold && index < len(txns)-1 {
+			index++
+			sum += txns[index].gasUsed
+		}
+
+		rewards[i] = argBig(*txns[index].price)
+	}
+
+	return rewards, nil
 }
 
 // Call executes a smart contract call using the transaction object data
 func (e *Eth) Call(
 	arg *txnArgs,
 	filter BlockNumberOrHash,
+	overrides stateOverride,
+	blockOverrides *blockOverride,
 ) (interface{}, error) {
 	var (
 		header *types.Header
@@ -452,6 +806,16 @@ func (e *Eth) Call(
 		return nil, fmt.Errorf("failed to get header from block hash or block number")
 	}
 
+	pending := filter.BlockNumber != nil && *filter.BlockNumber == PendingBlockNumber
+
+	overrides.fillNonce(arg)
+
+	if pending {
+		if err := e.fillPendingNonce(arg); err != nil {
+			return nil, err
+		}
+	}
+
 	transaction, err := e.decodeTxn(arg)
 	if err != nil {
 		return nil, err
@@ -460,14 +824,25 @@ func (e *Eth) Call(
 	// If the caller didn't supply the gas limit in the message, then we set it to maximum possible => block gas limit
 	if transaction.Gas == 0 {
 		transaction.Gas = header.GasLimit
+
+		if blockOverrides != nil && blockOverrides.GasLimit != nil {
+			transaction.Gas = uint64(*blockOverrides.GasLimit)
+		}
 	}
 
+	ctx, cancel := e.callContext()
+	defer cancel()
+
 	// The return value of the execution is saved in the transition (returnValue field)
-	result, err := e.store.ApplyTxn(header, transaction)
+	result, err := e.applyTxn(ctx, header, pending, transaction, overrides.toState(), blockOverrides.toState())
 	if err != nil {
 		return nil, err
 	}
 
+	if result.Reverted() {
+		return nil, NewRevertError(result)
+	}
+
 	if result.Failed() {
 		return nil, fmt.Errorf("unable to execute call: %w", result.Err)
 	}
@@ -479,24 +854,50 @@ func (e *Eth) Call(
 func (e *Eth) EstimateGas(
 	arg *txnArgs,
 	rawNum *BlockNumber,
+	overrides stateOverride,
+	blockOverrides *blockOverride,
 ) (interface{}, error) {
-	transaction, err := e.decodeTxn(arg)
+	gas, err := e.estimateGas(arg, rawNum, overrides, blockOverrides)
 	if err != nil {
 		return nil, err
 	}
 
+	return hex.EncodeUint64(gas), nil
+}
+
+func (e *Eth) estimateGas(
+	arg *txnArgs,
+	rawNum *BlockNumber,
+	overrides stateOverride,
+	blockOverrides *blockOverride,
+) (uint64, error) {
 	number := LatestBlockNumber
 	if rawNum != nil {
 		number = *rawNum
 	}
 
+	pending := number == PendingBlockNumber
+
+	overrides.fillNonce(arg)
+
+	if pending {
+		if err := e.fillPendingNonce(arg); err != nil {
+			return 0, err
+		}
+	}
+
+	transaction, err := e.decodeTxn(arg)
+	if err != nil {
+		return 0, err
+	}
+
 	// Fetch the requested header
 	header, err := e.getBlockHeader(number)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
-	forksInTime := e.store.GetForksInTime(uint64(number))
+	forksInTime := e.store.GetForksInTime(header.Number)
 
 	var standardGas uint64
 	if transaction.IsContractCreation() && forksInTime.Homestead {
@@ -517,6 +918,10 @@ func (e *Eth) EstimateGas(
 	} else {
 		// If not, use the referenced block number
 		highEnd = header.GasLimit
+
+		if blockOverrides != nil && blockOverrides.GasLimit != nil {
+			highEnd = uint64(*blockOverrides.GasLimit)
+		}
 	}
 
 	gasPriceInt := new(big.Int).Set(transaction.GasPrice)
@@ -527,23 +932,21 @@ func (e *Eth) EstimateGas(
 		// Get the account balance
 		// If the account is not initialized yet in state,
 		// assume it's an empty account
-		accountBalance := big.NewInt(0)
-		acc, err := e.store.GetAccount(header.StateRoot, transaction.From)
+		accountBalance, err := e.getBalance(header, pending, transaction.From)
+		if err != nil {
+			return 0, err
+		}
 
-		if err != nil && !errors.As(err, &ErrStateNotFound) {
-			// An unrelated error occurred, return it
-			return nil, err
-		} else if err == nil {
-			// No error when fetching the account,
-			// read the balance from state
-			accountBalance = acc.Balance
+		// The balance of the sender may be overridden
+		if account, ok := overrides[transaction.From]; ok && account.Balance != nil {
+			accountBalance = (*big.Int)(account.Balance)
 		}
 
 		available := new(big.Int).Set(accountBalance)
 
 		if transaction.Value != nil {
 			if valueInt.Cmp(available) >= 0 {
-				return nil, fmt.Errorf("insufficient funds for execution")
+				return 0, fmt.Errorf("insufficient funds for execution")
 			}
 
 			available.Sub(available, valueInt)
@@ -564,31 +967,31 @@ func (e *Eth) EstimateGas(
 
 	gasCap = highEnd
 
+	stateOverride, blockOverride := overrides.toState(), blockOverrides.toState()
+
+	// the call timeout applies to the whole estimation
+	ctx, cancel := e.callContext()
+	defer cancel()
+
 	// Run the transaction with the estimated gas
-	testTransaction := func(gas uint64) (bool, error) {
+	testTransaction := func(gas uint64) (*evm.ExecutionResult, error) {
 		// Create a dummy transaction with the new gas
 		txn := transaction.Copy()
 		txn.Gas = gas
 
-		result, err := e.store.ApplyTxn(header, txn)
-
-		if err != nil {
-			return true, err
-		}
-
-		return result.Failed(), nil
+		return e.applyTxn(ctx, header, pending, txn, stateOverride, blockOverride)
 	}
 
 	// Start the binary search for the lowest possible gas price
 	for lowEnd <= highEnd {
 		mid := (lowEnd + highEnd) / 2
 
-		failed, err := testTransaction(mid)
+		result, err := testTransaction(mid)
 		if err != nil {
 			return 0, err
 		}
 
-		if failed {
+		if result.Failed() {
 			// If the transaction failed => increase the gas
 			lowEnd = mid + 1
 		} else {
@@ -603,18 +1006,75 @@ func (e *Eth) EstimateGas(
 
 	// Check the case if even the highest cap is not enough to complete the transaction
 	if highEnd == gasCap {
-		failed, err := testTransaction(gasCap)
+		result, err := testTransaction(gasCap)
 
 		if err != nil {
 			return 0, err
 		}
 
-		if failed {
+		// the transaction reverts with any gas, return the reason
+		if result.Reverted() {
+			return 0, NewRevertError(result)
+		}
+
+		if result.Failed() {
 			return 0, fmt.Errorf("gas required exceeds allowance (%d)", gasCap)
 		}
 	}
 
-	return hex.EncodeUint64(highEnd), nil
+	return highEnd, nil
+}
+
+// callContext returns the context of an execution, done after the call timeout
+func (e *Eth) callContext() (context.Context, context.CancelFunc) {
+	if e.callTimeout == 0 {
+		return context.WithCancel(context.Background())
+	}
+
+	return context.WithTimeout(context.Background(), e.callTimeout)
+}
+
+// applyTxn applies a transaction on the state of the header, or on the state of the pending
+// block if pending is set. It returns an error if the execution timed out
+func (e *Eth) applyTxn(
+	ctx context.Context,
+	header *types.Header,
+	pending bool,
+	txn *types.Transaction,
+	stateOverride state.StateOverride,
+	blockOverride *state.BlockOverride,
+) (*evm.ExecutionResult, error) {
+	var (
+		result *evm.ExecutionResult
+		err    = blockchain.ErrNoPendingBlock
+	)
+
+	if pending {
+		result, err = e.store.ApplyPendingTxn(ctx, txn, stateOverride, blockOverride)
+	}
+
+	// the header is the latest one while the pending block is not built
+	if errors.Is(err, blockchain.ErrNoPendingBlock) {
+		result, err = e.store.ApplyTxn(ctx, header, txn, stateOverride, blockOverride)
+	}
+
+	if err := e.timeoutError(ctx); err != nil {
+		return nil, err
+	}
+
+	return result, err
+}
+
+// timeoutError returns the error of an execution aborted by the call timeout,
+// nil if the context did not time out
+func (e *Eth) timeoutError(ctx context.Context) error {
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil
+	}
+
+	e.metrics.RejectedRequests.With("reason", rejectedTimeout).Add(1)
+
+	return fmt.Errorf("execution aborted (timeout = %v)", e.callTimeout)
 }
 
 // GetLogs returns an array of logs matching the filter options
@@ -626,8 +1086,10 @@ func (e *Eth) GetLogs(filterOptions *LogFilter) (interface{}, error) {
 			return err
 		}
 
+		logIndex := uint64(0)
+
 		for indx, receipt := range receipts {
-			for logIndx, log := range receipt.Logs {
+			for _, log := range receipt.Logs {
 				if filterOptions.Match(log) {
 					result = append(result, &Log{
 						Address:     log.Address,
@@ -637,9 +1099,11 @@ func (e *Eth) GetLogs(filterOptions *LogFilter) (interface{}, error) {
 						BlockHash:   block.Header.Hash,
 						TxHash:      block.Transactions[indx].Hash,
 						TxIndex:     argUint64(indx),
-						LogIndex:    argUint64(logIndx),
+						LogIndex:    argUint64(logIndex),
 					})
 				}
+
+				logIndex++
 			}
 		}
 
@@ -689,8 +1153,17 @@ func (e *Eth) GetLogs(filterOptions *LogFilter) (interface{}, error) {
 		return nil, fmt.Errorf("incorrect range")
 	}
 
-	for i := from; i <= to; i++ {
-		block, ok := e.store.GetBlockByNumber(i, true)
+	if e.maxBlockRange > 0 && to-from >= e.maxBlockRange {
+		return nil, fmt.Errorf("block range too large, the maximum is %d blocks", e.maxBlockRange)
+	}
+
+	numbers, err := e.filterBlocks(filterOptions, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, number := range numbers {
+		block, ok := e.store.GetBlockByNumber(number, true)
 		if !ok {
 			break
 		}
@@ -703,11 +1176,64 @@ func (e *Eth) GetLogs(filterOptions *LogFilter) (interface{}, error) {
 		if err := parseReceipts(block); err != nil {
 			return nil, err
 		}
+
+		if e.maxLogs > 0 && uint64(len(result)) > e.maxLogs {
+			return nil, fmt.Errorf("too many logs, the maximum is %d logs", e.maxLogs)
+		}
 	}
 
 	return result, nil
 }
 
+// filterBlocks returns the numbers of the blocks of a range whose bloom possibly matches
+// the filter. The indexed sections are matched with the bloom bits, the other blocks
+// with the bloom of their header
+func (e *Eth) filterBlocks(filter *LogFilter, from, to uint64) ([]uint64, error) {
+	var (
+		numbers  = []uint64{}
+		sections = e.store.BloomBitsSections()
+		groups   = filter.bloomGroups()
+	)
+
+	for number := from; number <= to; {
+		section := number / storage.BloomBitsSectionSize
+
+		// the filters without values match any block with logs, only the headers tell it
+		if section >= sections || len(groups) == 0 {
+			header, ok := e.store.GetHeaderByNumber(number)
+			if !ok {
+				break
+			}
+
+			if filter.MatchBloom(&header.LogsBloom) {
+				numbers = append(numbers, number)
+			}
+
+			number++
+
+			continue
+		}
+
+		bits, err := e.store.MatchBloomBits(section, groups)
+		if err != nil {
+			return nil, err
+		}
+
+		last := (section+1)*storage.BloomBitsSectionSize - 1
+		if last > to {
+			last = to
+		}
+
+		for ; number <= last; number++ {
+			if i := number % storage.BloomBitsSectionSize; bits[i/8]&(1<<(7-i%8)) != 0 {
+				numbers = append(numbers, number)
+			}
+		}
+	}
+
+	return numbers, nil
+}
+
 // GetBalance returns the account's balance at the referenced block.
 func (e *Eth) GetBalance(address types.Address, filter BlockNumberOrHash) (interface{}, error) {
 	var (
@@ -720,6 +1246,16 @@ func (e *Eth) GetBalance(address types.Address, filter BlockNumberOrHash) (inter
 		filter.BlockNumber, _ = createBlockNumberPointer("latest")
 	}
 
+	var balance *big.Int
+
+	if ok, err := e.readPendingState(&filter, func(transition *state.Transition) error {
+		balance = transition.GetBalance(address)
+
+		return nil
+	}); ok {
+		return argBigPtr(balance), err
+	}
+
 	header, err = e.getHeaderFromBlockNumberOrHash(&filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get header from block hash or block number")
@@ -737,6 +1273,32 @@ func (e *Eth) GetBalance(address types.Address, filter BlockNumberOrHash) (inter
 	return argBigPtr(acc.Balance), nil
 }
 
+// getBalance returns the balance of an account at a header, or in the pending state if pending is set.
+// The balance of an account which is not initialized is zero
+func (e *Eth) getBalance(header *types.Header, pending bool, address types.Address) (*big.Int, error) {
+	if pending {
+		var balance *big.Int
+
+		err := e.store.PendingState(func(_ *types.Header, transition *state.Transition) error {
+			balance = transition.GetBalance(address)
+
+			return nil
+		})
+		if !errors.Is(err, blockchain.ErrNoPendingBlock) {
+			return balance, err
+		}
+	}
+
+	acc, err := e.store.GetAccount(header.StateRoot, address)
+	if errors.Is(err, ErrStateNotFound) {
+		return big.NewInt(0), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return acc.Balance, nil
+}
+
 // GetFeeReward returns the account's fee reward.
 func (e *Eth) GetFeeReward(address types.Address) (interface{}, error) {
 	return state.NewValidatorFee().GetFeeReward(address), nil
@@ -790,6 +1352,16 @@ func (e *Eth) GetCode(address types.Address, filter BlockNumberOrHash) (interfac
 		filter.BlockNumber, _ = createBlockNumberPointer("latest")
 	}
 
+	var pendingCode []byte
+
+	if ok, err := e.readPendingState(&filter, func(transition *state.Transition) error {
+		pendingCode = transition.GetCode(address)
+
+		return nil
+	}); ok {
+		return argBytesPtr(pendingCode), err
+	}
+
 	header, err = e.getHeaderFromBlockNumberOrHash(&filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get header from block hash or block number")
@@ -824,6 +1396,11 @@ func (e *Eth) NewBlockFilter() (interface{}, error) {
 	return e.filterManager.NewBlockFilter(nil), nil
 }
 
+// NewPendingTransactionFilter creates a filter in the node, to notify when new transactions are pending in the pool
+func (e *Eth) NewPendingTransactionFilter() (interface{}, error) {
+	return e.filterManager.NewPendingTxFilter(false, nil), nil
+}
+
 // GetFilterChanges is a polling method for a filter, which returns an array of logs which occurred since last poll.
 func (e *Eth) GetFilterChanges(id string) (interface{}, error) {
 	return e.filterManager.GetFilterChanges(id)
@@ -857,7 +1434,12 @@ func (e *Eth) getBlockHeader(number BlockNumber) (*types.Header, error) {
 		return header, nil
 
 	case PendingBlockNumber:
-		return nil, fmt.Errorf("fetching the pending header is not supported")
+		// the pending block has no state root, its state is read with PendingState
+		if block, _, ok := e.store.GetPendingBlock(); ok {
+			return block.Header, nil
+		}
+
+		return e.store.Header(), nil
 
 	default:
 		// Convert the block number from hex to uint64
@@ -870,15 +1452,68 @@ func (e *Eth) getBlockHeader(number BlockNumber) (*types.Header, error) {
 	}
 }
 
+// readPendingState runs f on the state of the pending block if the filter is the pending block.
+// It returns false if the state of the filter is read from the chain: the filter is not the
+// pending block, or the pending block is not built and the filter is set to the latest block
+func (e *Eth) readPendingState(filter *BlockNumberOrHash, f func(transition *state.Transition) error) (bool, error) {
+	if filter.BlockNumber == nil || *filter.BlockNumber != PendingBlockNumber {
+		return false, nil
+	}
+
+	err := e.store.PendingState(func(_ *types.Header, transition *state.Transition) error {
+		return f(transition)
+	})
+	if errors.Is(err, blockchain.ErrNoPendingBlock) {
+		filter.BlockNumber, _ = createBlockNumberPointer("latest")
+
+		return false, nil
+	}
+
+	return true, err
+}
+
+// fillPendingNonce sets the nonce of a call on the pending block which has no nonce,
+// to the nonce of the sender in the pending state
+func (e *Eth) fillPendingNonce(arg *txnArgs) error {
+	if arg.From == nil || arg.Nonce != nil {
+		return nil
+	}
+
+	err := e.store.PendingState(func(_ *types.Header, transition *state.Transition) error {
+		arg.Nonce = argUintPtr(transition.GetNonce(*arg.From))
+
+		return nil
+	})
+	if errors.Is(err, blockchain.ErrNoPendingBlock) {
+		return nil
+	}
+
+	return err
+}
+
 // getNextNonce returns the next nonce for the account for the specified block
 func (e *Eth) getNextNonce(address types.Address, number BlockNumber) (uint64, error) {
 	if number == PendingBlockNumber {
-		// Grab the latest pending nonce from the TxPool
+		// Grab the latest pending nonce from the TxPool, it includes the executables
+		// which do not fit in the pending block
 		//
 		// If the account is not initialized in the local TxPool,
 		// return the latest nonce from the world state
 		res := e.store.GetNonce(address)
 
+		// the nonce in the pending state may be ahead of the pool, if
+		// the pool has not processed the head yet
+		err := e.store.PendingState(func(_ *types.Header, transition *state.Transition) error {
+			if nonce := transition.GetNonce(address); nonce > res {
+				res = nonce
+			}
+
+			return nil
+		})
+		if err != nil && !errors.Is(err, blockchain.ErrNoPendingBlock) {
+			return 0, err
+		}
+
 		return res, nil
 	}
 
@@ -242,5 +242,5 @@ func TestEth_GetNextNonce(t *testing.T) {
 }
 
 func newTestEthEndpoint(store ethStore) *Eth {
-	return &Eth{store, 100, nil}
+	return &Eth{store: store, chainID: 100, gasPriceOracle: newGasPriceOracle(store)}
 }
@@ -0,0 +1,366 @@
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/TIE-Tech/tie-core/common/crypto"
+	"github.com/TIE-Tech/tie-core/core"
+	"github.com/TIE-Tech/tie-core/state"
+	"github.com/TIE-Tech/tie-core/tievm/evm"
+	"github.com/TIE-Tech/tie-core/types"
+)
+
+const (
+	// maxSimulateBlocks is the maximum number of blocks of a simulation
+	maxSimulateBlocks = 256
+
+	// maxSimulateCalls is the maximum number of calls of a simulation, in all the blocks
+	maxSimulateCalls = 1000
+
+	// errCodeReverted is the error code of a reverted call
+	errCodeReverted = 3
+
+	// errCodeVMError is the error code of a call failed with another error than a revert
+	errCodeVMError = -32015
+)
+
+// transferLogAddress is the address of the Transfer logs of the traced value transfers
+var transferLogAddress = types.StringToAddress("0xEeeeeEeeeEeEeeEeEeEeeEEEeeeeEeeeeeeeEEeE")
+
+var (
+	ErrSimulateTooManyBlocks = fmt.Errorf("too many blocks to simulate, the maximum is %d", maxSimulateBlocks)
+	ErrSimulateTooManyCalls  = fmt.Errorf("too many calls to simulate, the maximum is %d", maxSimulateCalls)
+	ErrSimulateBlockNumber   = errors.New("the simulated block numbers must be increasing")
+	ErrSimulateSender        = errors.New("the sender of the signed transaction does not match from")
+)
+
+// simulateOpts are the options of a simulation
+type simulateOpts struct {
+	BlockStateCalls []*simulateBlock `json:"blockStateCalls"`
+
+	// Validation enables the signature and nonce validation of the calls
+	Validation bool `json:"validation"`
+
+	// TraceTransfers adds a Transfer log for every value transfer of the calls
+	TraceTransfers bool `json:"traceTransfers"`
+}
+
+// simulateBlock are the calls of a simulated block, with the overrides applied before them
+type simulateBlock struct {
+	BlockOverrides *blockOverride  `json:"blockOverrides"`
+	StateOverrides stateOverride   `json:"stateOverrides"`
+	Calls          []*simulateCall `json:"calls"`
+}
+
+// simulateCall is a call of a simulation, either a message or a signed transaction
+type simulateCall struct {
+	txnArgs
+
+	// Raw is a signed transaction, which replaces the other fields
+	Raw *argBytes `json:"raw"`
+}
+
+type simulatedBlock struct {
+	Number    argUint64        `json:"number"`
+	Timestamp argUint64        `json:"timestamp"`
+	GasLimit  argUint64        `json:"gasLimit"`
+	GasUsed   argUint64        `json:"gasUsed"`
+	Miner     types.Address    `json:"miner"`
+	Calls     []*simulatedCall `json:"calls"`
+}
+
+type simulatedCall struct {
+	ReturnData argBytes        `json:"returnData"`
+	Logs       []*Log          `json:"logs"`
+	GasUsed    argUint64       `json:"gasUsed"`
+	Status     argUint64       `json:"status"`
+	Error      *simulatedError `json:"error,omitempty"`
+}
+
+type simulatedError struct {
+	Code    int       `json:"code"`
+	Message string    `json:"message"`
+	Data    *argBytes `json:"data,omitempty"`
+}
+
+// SimulateV1 executes the calls of a sequence of blocks on top of a block. The calls are
+// chained, each one sees the state left by the previous ones, and nothing is persisted
+func (e *Eth) SimulateV1(opts *simulateOpts, filter BlockNumberOrHash) (interface{}, error) {
+	if opts == nil {
+		return nil, errors.New("missing the simulation options")
+	}
+
+	if len(opts.BlockStateCalls) > maxSimulateBlocks {
+		return nil, ErrSimulateTooManyBlocks
+	}
+
+	calls := 0
+	for _, block := range opts.BlockStateCalls {
+		calls += len(block.Calls)
+	}
+
+	if calls > maxSimulateCalls {
+		return nil, ErrSimulateTooManyCalls
+	}
+
+	// The filter is empty, use the latest block by default. The blocks are not
+	// simulated on the pending block, which has no state root
+	if filter.BlockNumber == nil && filter.BlockHash == nil ||
+		filter.BlockNumber != nil && *filter.BlockNumber == PendingBlockNumber {
+		filter.BlockNumber, _ = createBlockNumberPointer("latest")
+	}
+
+	header, err := e.getHeaderFromBlockNumberOrHash(&filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get header from block hash or block number")
+	}
+
+	transition, err := e.store.BeginSimulation(header)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := e.callContext()
+	defer cancel()
+
+	// cancel the execution when the call timeout expires
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			transition.Cancel()
+		case <-done:
+		}
+	}()
+
+	var (
+		signer  = state.NewSigner(e.chainID)
+		results = make([]*simulatedBlock, 0, len(opts.BlockStateCalls))
+
+		// the simulated blocks follow the base block
+		number    = header.Number
+		timestamp = header.Timestamp
+		gasLimit  = header.GasLimit
+		miner     = transition.GetTxContext().Coinbase
+	)
+
+	for i, block := range opts.BlockStateCalls {
+		number++
+		timestamp++
+
+		override := block.BlockOverrides.toState()
+		if override == nil {
+			override = &state.BlockOverride{}
+		}
+
+		if override.Number != nil {
+			if *override.Number < number {
+				return nil, fmt.Errorf("block %d: %w", i, ErrSimulateBlockNumber)
+			}
+
+			number = *override.Number
+		}
+
+		if override.Timestamp != nil {
+			timestamp = *override.Timestamp
+		}
+
+		if override.GasLimit != nil {
+			gasLimit = *override.GasLimit
+		}
+
+		if override.Coinbase != nil {
+			miner = *override.Coinbase
+		}
+
+		// every block starts with a full gas pool
+		override.Number, override.Timestamp, override.GasLimit = &number, &timestamp, &gasLimit
+
+		if err := transition.ApplyOverrides(block.StateOverrides.toState(), override); err != nil {
+			return nil, fmt.Errorf("block %d: %w", i, err)
+		}
+
+		result := &simulatedBlock{
+			Number:    argUint64(number),
+			Timestamp: argUint64(timestamp),
+			GasLimit:  argUint64(gasLimit),
+			Miner:     miner,
+			Calls:     make([]*simulatedCall, 0, len(block.Calls)),
+		}
+
+		logIndex := uint64(0)
+		forks := e.store.GetForksInTime(number)
+
+		for j, call := range block.Calls {
+			msg, err := e.simulateMessage(transition, signer, call, opts.Validation, gasLimit-uint64(result.GasUsed))
+			if err != nil {
+				return nil, fmt.Errorf("block %d call %d: %w", i, j, err)
+			}
+
+			res, err := transition.Apply(msg)
+			if err := e.timeoutError(ctx); err != nil {
+				return nil, err
+			}
+
+			if err != nil {
+				return nil, fmt.Errorf("block %d call %d: %w", i, j, err)
+			}
+
+			// the suicided accounts, and the empty ones since EIP158, are deleted for the next calls
+			transition.Txn().CleanDeleteObjects(forks.EIP158)
+
+			callResult := &simulatedCall{
+				ReturnData: argBytes(res.ReturnValue),
+				Logs:       []*Log{},
+				GasUsed:    argUint64(res.GasUsed),
+				Status:     argUint64(types.ReceiptSuccess),
+			}
+
+			if res.Failed() {
+				callResult.Status = argUint64(types.ReceiptFailed)
+				callResult.Error = newSimulatedError(res)
+			}
+
+			logs := transition.Txn().Logs()
+			if opts.TraceTransfers && !res.Failed() {
+				logs = append(transferLogs(msg, transition.LastInternalTransfers()), logs...)
+			}
+
+			for _, log := range logs {
+				callResult.Logs = append(callResult.Logs, &Log{
+					Address:     log.Address,
+					Topics:      log.Topics,
+					Data:        argBytes(log.Data),
+					BlockNumber: argUint64(number),
+					TxHash:      msg.Hash,
+					TxIndex:     argUint64(j),
+					LogIndex:    argUint64(logIndex),
+				})
+
+				logIndex++
+			}
+
+			result.GasUsed += argUint64(res.GasUsed)
+			result.Calls = append(result.Calls, callResult)
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// simulateMessage returns the message of a simulated call. The nonce of the sender is
+// taken from the simulated state, unless the validation is enabled and the call has one
+func (e *Eth) simulateMessage(
+	transition *state.Transition,
+	signer state.TxSigner,
+	call *simulateCall,
+	validation bool,
+	gasLeft uint64,
+) (*types.Transaction, error) {
+	if call.Raw != nil {
+		msg := &types.Transaction{}
+		if err := msg.UnmarshalRLP(*call.Raw); err != nil {
+			return nil, err
+		}
+
+		if validation || call.From == nil {
+			from, err := signer.Sender(msg)
+			if err != nil {
+				return nil, err
+			}
+
+			if call.From != nil && *call.From != from {
+				return nil, ErrSimulateSender
+			}
+
+			msg.From = from
+		} else {
+			msg.From = *call.From
+		}
+
+		if !validation {
+			msg.Nonce = transition.GetNonce(msg.From)
+		}
+
+		msg.ComputeHash()
+
+		return msg, nil
+	}
+
+	arg := call.txnArgs
+	if arg.From == nil {
+		arg.From = &types.ZeroAddress
+	}
+
+	if arg.Nonce == nil || !validation {
+		arg.Nonce = argUintPtr(transition.GetNonce(*arg.From))
+	}
+
+	msg, err := e.decodeTxn(&arg)
+	if err != nil {
+		return nil, err
+	}
+
+	// the calls without a gas limit use the gas left in the block
+	if msg.Gas == 0 {
+		msg.Gas = gasLeft
+	}
+
+	return msg, nil
+}
+
+func newSimulatedError(res *evm.ExecutionResult) *simulatedError {
+	if !res.Reverted() {
+		return &simulatedError{
+			Code:    errCodeVMError,
+			Message: res.Err.Error(),
+		}
+	}
+
+	err := NewRevertError(res)
+
+	return &simulatedError{
+		Code:    err.ErrorCode(),
+		Message: err.Error(),
+		Data:    argBytesPtr(res.ReturnValue),
+	}
+}
+
+// transferLogs returns the Transfer logs of the value of a message and of its internal
+// transfers, which precede the logs emitted by the call
+func transferLogs(msg *types.Transaction, transfers []*types.InternalTransfer) []*types.Log {
+	logs := []*types.Log{}
+
+	transferLog := func(from, to types.Address, value *big.Int) *types.Log {
+		return &types.Log{
+			Address: transferLogAddress,
+			Topics: []types.Hash{
+				blockchain.TransferEventTopic,
+				types.BytesToHash(from.Bytes()),
+				types.BytesToHash(to.Bytes()),
+			},
+			Data: types.BytesToHash(value.Bytes()).Bytes(),
+		}
+	}
+
+	if msg.Value != nil && msg.Value.Sign() > 0 {
+		to := crypto.CreateAddress(msg.From, msg.Nonce)
+		if msg.To != nil {
+			to = *msg.To
+		}
+
+		logs = append(logs, transferLog(msg.From, to, msg.Value))
+	}
+
+	for _, transfer := range transfers {
+		logs = append(logs, transferLog(transfer.From, transfer.To, transfer.Value))
+	}
+
+	return logs
+}
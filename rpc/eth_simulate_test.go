@@ -0,0 +1,256 @@
+package rpc
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/TIE-Tech/tie-core/common/crypto"
+	"github.com/TIE-Tech/tie-core/common/hex"
+	"github.com/TIE-Tech/tie-core/core"
+	"github.com/TIE-Tech/tie-core/metrics"
+	"github.com/TIE-Tech/tie-core/params"
+	"github.com/TIE-Tech/tie-core/state"
+	itrie "github.com/TIE-Tech/tie-core/state/trie"
+	"github.com/TIE-Tech/tie-core/tievm/evm/execute"
+	"github.com/TIE-Tech/tie-core/tievm/evm/precompiled"
+	"github.com/TIE-Tech/tie-core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	// storeAddr stores the calldata in the slot 0 with a log, or returns the slot 0 without calldata
+	storeAddr = types.StringToAddress("1000")
+	storeCode = "0x36156015576000358060005560005260206000a0005b60005460005260206000f3"
+
+	// revertAddr copies the Error("no") data after its code in memory and reverts with it
+	revertAddr = types.StringToAddress("2000")
+	revertCode = "0x6064600c600039" + "60646000fd" +
+		"08c379a0" +
+		"0000000000000000000000000000000000000000000000000000000000000020" +
+		"0000000000000000000000000000000000000000000000000000000000000002" +
+		"6e6f000000000000000000000000000000000000000000000000000000000000"
+
+	// forwardAddr forwards the value it receives to storeAddr
+	forwardAddr = types.StringToAddress("3000")
+	forwardCode = "0x6000600060006000346110005af100"
+
+	// loopAddr loops until it runs out of gas
+	loopAddr = types.StringToAddress("4000")
+	loopCode = "0x5b600056"
+)
+
+type mockSimulateStore struct {
+	ethStore
+
+	header   *types.Header
+	executor *state.Executor
+}
+
+func newMockSimulateStore(t *testing.T) *mockSimulateStore {
+	t.Helper()
+
+	executor := state.NewExecutor(&params.Params{
+		Forks:   params.AllForksEnabled,
+		ChainID: 100,
+	}, itrie.NewState(itrie.NewMemoryStorage()))
+
+	executor.SetRuntime(precompiled.NewPrecompiled())
+	executor.SetRuntime(execute.NewEVM())
+
+	executor.GetHash = func(*types.Header) state.GetHashByNumber {
+		return func(uint64) types.Hash {
+			return types.Hash{}
+		}
+	}
+
+	root := executor.WriteGenesis(map[types.Address]*params.GenesisAccount{
+		storeAddr:   {Code: hex.MustDecodeHex(storeCode)},
+		revertAddr:  {Code: hex.MustDecodeHex(revertCode)},
+		forwardAddr: {Code: hex.MustDecodeHex(forwardCode)},
+		loopAddr:    {Code: hex.MustDecodeHex(loopCode)},
+	})
+
+	return &mockSimulateStore{
+		header: &types.Header{
+			Number:    10,
+			Timestamp: 100,
+			GasLimit:  1000000,
+			StateRoot: root,
+		},
+		executor: executor,
+	}
+}
+
+func (m *mockSimulateStore) Header() *types.Header {
+	return m.header
+}
+
+func (m *mockSimulateStore) GetForksInTime(blockNumber uint64) params.ForksInTime {
+	return params.AllForksEnabled.At(blockNumber)
+}
+
+func (m *mockSimulateStore) BeginSimulation(header *types.Header) (*state.Transition, error) {
+	return m.executor.BeginTxn(header.StateRoot, header, types.ZeroAddress)
+}
+
+func simulate(t *testing.T, eth *Eth, input string) ([]*simulatedBlock, error) {
+	t.Helper()
+
+	opts := &simulateOpts{}
+	if err := json.Unmarshal([]byte(input), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := eth.SimulateV1(opts, BlockNumberOrHash{})
+	if err != nil {
+		return nil, err
+	}
+
+	blocks, ok := res.([]*simulatedBlock)
+	if !ok {
+		t.Fatal("invalid type assertion")
+	}
+
+	return blocks, nil
+}
+
+func TestEth_SimulateV1(t *testing.T) {
+	eth := newTestEthEndpoint(newMockSimulateStore(t))
+
+	blocks, err := simulate(t, eth, `{"blockStateCalls": [
+		{"calls": [
+			{"to": "0x0000000000000000000000000000000000001000", "data": "0x000000000000000000000000000000000000000000000000000000000000002a"},
+			{"to": "0x0000000000000000000000000000000000001000"}
+		]},
+		{
+			"blockOverrides": {"time": "0x3e8"},
+			"stateOverrides": {
+				"0x0000000000000000000000000000000000001000": {"stateDiff": {
+					"0x0000000000000000000000000000000000000000000000000000000000000000": "0x0000000000000000000000000000000000000000000000000000000000000007"
+				}}
+			},
+			"calls": [
+				{"to": "0x0000000000000000000000000000000000001000"},
+				{"to": "0x0000000000000000000000000000000000002000"}
+			]
+		}
+	]}`)
+	assert.NoError(t, err)
+	assert.Len(t, blocks, 2)
+
+	// the second call reads the value stored by the first one
+	first := blocks[0]
+	assert.Equal(t, argUint64(11), first.Number)
+	assert.Equal(t, argUint64(101), first.Timestamp)
+	assert.Len(t, first.Calls, 2)
+	assert.Equal(t, argUint64(types.ReceiptSuccess), first.Calls[0].Status)
+	assert.Len(t, first.Calls[0].Logs, 1)
+	assert.Equal(t, argUint64(11), first.Calls[0].Logs[0].BlockNumber)
+	assert.Equal(t, types.StringToHash("2a").Bytes(), []byte(first.Calls[1].ReturnData))
+	assert.Equal(t, first.GasUsed, first.Calls[0].GasUsed+first.Calls[1].GasUsed)
+
+	// the overrides of the second block apply on top of the first one
+	second := blocks[1]
+	assert.Equal(t, argUint64(12), second.Number)
+	assert.Equal(t, argUint64(1000), second.Timestamp)
+	assert.Equal(t, types.StringToHash("7").Bytes(), []byte(second.Calls[0].ReturnData))
+
+	reverted := second.Calls[1]
+	assert.Equal(t, argUint64(types.ReceiptFailed), reverted.Status)
+	assert.Equal(t, errCodeReverted, reverted.Error.Code)
+	assert.Equal(t, "execution reverted: no", reverted.Error.Message)
+}
+
+func TestEth_SimulateV1_TraceTransfers(t *testing.T) {
+	eth := newTestEthEndpoint(newMockSimulateStore(t))
+
+	blocks, err := simulate(t, eth, `{"traceTransfers": true, "blockStateCalls": [{
+		"stateOverrides": {"0x0000000000000000000000000000000000000000": {"balance": "0x100"}},
+		"calls": [{"to": "0x0000000000000000000000000000000000003000", "value": "0x10"}]
+	}]}`)
+	assert.NoError(t, err)
+
+	call := blocks[0].Calls[0]
+	assert.Equal(t, argUint64(types.ReceiptSuccess), call.Status)
+	assert.Len(t, call.Logs, 2)
+
+	// the value of the call, then the value forwarded by the contract
+	for i, transfer := range [][2]types.Address{
+		{types.ZeroAddress, forwardAddr},
+		{forwardAddr, storeAddr},
+	} {
+		log := call.Logs[i]
+
+		assert.Equal(t, transferLogAddress, log.Address)
+		assert.Equal(t, []types.Hash{
+			blockchain.TransferEventTopic,
+			types.BytesToHash(transfer[0].Bytes()),
+			types.BytesToHash(transfer[1].Bytes()),
+		}, log.Topics)
+		assert.Equal(t, types.StringToHash("10").Bytes(), []byte(log.Data))
+		assert.Equal(t, argUint64(i), log.LogIndex)
+	}
+}
+
+func TestEth_SimulateV1_Validation(t *testing.T) {
+	eth := newTestEthEndpoint(newMockSimulateStore(t))
+
+	// the nonce is taken from the simulated state without validation
+	blocks, err := simulate(t, eth, `{"blockStateCalls": [{"calls": [
+		{"to": "0x0000000000000000000000000000000000001000", "nonce": "0x5"},
+		{"to": "0x0000000000000000000000000000000000001000", "nonce": "0x5"}
+	]}]}`)
+	assert.NoError(t, err)
+	assert.Len(t, blocks[0].Calls, 2)
+
+	// the nonce must match with the validation
+	_, err = simulate(t, eth, `{"validation": true, "blockStateCalls": [{"calls": [
+		{"to": "0x0000000000000000000000000000000000001000", "nonce": "0x5"}
+	]}]}`)
+	assert.Error(t, err)
+
+	// the sender of a signed transaction is recovered from its signature
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+
+	signed, err := state.NewEIP155Signer(100).SignTx(&types.Transaction{
+		To:       &storeAddr,
+		Gas:      100000,
+		GasPrice: big.NewInt(0),
+		Value:    big.NewInt(0),
+	}, key)
+	assert.NoError(t, err)
+
+	raw := hex.EncodeToHex(signed.MarshalRLP())
+
+	blocks, err = simulate(t, eth, `{"validation": true, "blockStateCalls": [{"calls": [{"raw": "`+raw+`"}]}]}`)
+	assert.NoError(t, err)
+	assert.Equal(t, argUint64(types.ReceiptSuccess), blocks[0].Calls[0].Status)
+
+	_, err = simulate(t, eth, `{"validation": true, "blockStateCalls": [{"calls": [
+		{"raw": "`+raw+`", "from": "0x0000000000000000000000000000000000000001"}
+	]}]}`)
+	assert.ErrorIs(t, err, ErrSimulateSender)
+
+	// the simulated block numbers must be increasing
+	_, err = simulate(t, eth, `{"blockStateCalls": [
+		{"blockOverrides": {"number": "0x14"}},
+		{"blockOverrides": {"number": "0x14"}}
+	]}`)
+	assert.ErrorIs(t, err, ErrSimulateBlockNumber)
+}
+
+func TestEth_SimulateV1_CallTimeout(t *testing.T) {
+	eth := newTestEthEndpoint(newMockSimulateStore(t))
+	eth.callTimeout = 50 * time.Millisecond
+	eth.metrics = metrics.NewRPCMetrics()
+
+	// the loop runs out of gas long after the timeout
+	_, err := simulate(t, eth, `{"blockStateCalls": [{
+		"blockOverrides": {"gasLimit": "0x174876e800"},
+		"calls": [{"to": "0x0000000000000000000000000000000000004000", "gas": "0x174876e800"}]
+	}]}`)
+	assert.EqualError(t, err, "execution aborted (timeout = 50ms)")
+}
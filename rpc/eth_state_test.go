@@ -2,13 +2,22 @@ package rpc
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/TIE-Tech/tie-core/common/hex"
+	"github.com/TIE-Tech/tie-core/core"
+	"github.com/TIE-Tech/tie-core/metrics"
+	"github.com/TIE-Tech/tie-core/params"
 	"github.com/TIE-Tech/tie-core/state"
+	"github.com/TIE-Tech/tie-core/tievm/evm"
 	"github.com/TIE-Tech/tie-core/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/umbracle/fastrlp"
 	"math/big"
 	"testing"
+	"time"
 )
 
 var (
@@ -639,3 +648,355 @@ func (m *mockSpecialStore) GetCode(hash types.Hash) ([]byte, error) {
 
 	return nil, fmt.Errorf("code not found")
 }
+
+type mockCallStore struct {
+	ethStore
+
+	stateOverride state.StateOverride
+	blockOverride *state.BlockOverride
+	result        *evm.ExecutionResult
+
+	// block makes the execution run until it is cancelled
+	block bool
+}
+
+func (m *mockCallStore) Header() *types.Header {
+	return &types.Header{GasLimit: 100000}
+}
+
+func (m *mockCallStore) ApplyTxn(
+	ctx context.Context,
+	header *types.Header,
+	txn *types.Transaction,
+	stateOverride state.StateOverride,
+	blockOverride *state.BlockOverride,
+) (*evm.ExecutionResult, error) {
+	m.stateOverride = stateOverride
+	m.blockOverride = blockOverride
+
+	if m.block {
+		<-ctx.Done()
+
+		return nil, ctx.Err()
+	}
+
+	if m.result != nil {
+		return m.result, nil
+	}
+
+	return &evm.ExecutionResult{ReturnValue: []byte{0x1}}, nil
+}
+
+func TestEth_State_CallOverrides(t *testing.T) {
+	var (
+		overrides      stateOverride
+		blockOverrides *blockOverride
+	)
+
+	err := json.Unmarshal([]byte(`{
+		"0x0100000000000000000000000000000000000000": {
+			"nonce": "0x5",
+			"balance": "0x64",
+			"code": "0x010203",
+			"state": {}
+		},
+		"0x9900000000000000000000000000000000000000": {
+			"stateDiff": {
+				"0x0000000000000000000000000000000000000000000000000000000000000001": "0x0000000000000000000000000000000000000000000000000000000000000002"
+			}
+		}
+	}`), &overrides)
+	assert.NoError(t, err)
+
+	err = json.Unmarshal([]byte(`{"number": "0xa", "time": "0x14", "coinbase": "0x9900000000000000000000000000000000000000", "gasLimit": "0x1e"}`), &blockOverrides)
+	assert.NoError(t, err)
+
+	store := &mockCallStore{}
+	eth := newTestEthEndpoint(store)
+
+	res, err := eth.Call(&txnArgs{
+		From: &addr0,
+		To:   &uninitializedAddress,
+	}, BlockNumberOrHash{}, overrides, blockOverrides)
+	assert.NoError(t, err)
+	assert.Equal(t, argBytesPtr([]byte{0x1}), res)
+
+	// the overrides are converted for the execution
+	account := store.stateOverride[addr0]
+	assert.Equal(t, uint64(5), *account.Nonce)
+	assert.Equal(t, big.NewInt(100), account.Balance)
+	assert.Equal(t, code0, account.Code)
+	assert.NotNil(t, account.State)
+	assert.Len(t, account.State, 0)
+	assert.Nil(t, account.StateDiff)
+
+	diff := store.stateOverride[uninitializedAddress].StateDiff
+	assert.Equal(t, types.StringToHash("2"), diff[types.StringToHash("1")])
+
+	assert.Equal(t, uint64(10), *store.blockOverride.Number)
+	assert.Equal(t, uint64(20), *store.blockOverride.Timestamp)
+	assert.Equal(t, uninitializedAddress, *store.blockOverride.Coinbase)
+	assert.Equal(t, uint64(30), *store.blockOverride.GasLimit)
+}
+
+func TestEth_State_CallRevert(t *testing.T) {
+	// Error("no")
+	data := hex.MustDecodeHex("0x08c379a0" +
+		"0000000000000000000000000000000000000000000000000000000000000020" +
+		"0000000000000000000000000000000000000000000000000000000000000002" +
+		"6e6f000000000000000000000000000000000000000000000000000000000000")
+
+	store := &mockCallStore{
+		result: &evm.ExecutionResult{ReturnValue: data, Err: evm.ErrExecutionReverted},
+	}
+	eth := newTestEthEndpoint(store)
+
+	_, err := eth.Call(&txnArgs{
+		From:  &addr0,
+		To:    &uninitializedAddress,
+		Nonce: argUintPtr(0),
+	}, BlockNumberOrHash{}, nil, nil)
+
+	var dataErr DataError
+	assert.True(t, errors.As(err, &dataErr))
+	assert.Equal(t, 3, dataErr.ErrorCode())
+	assert.Equal(t, "execution reverted: no", dataErr.Error())
+
+	// the raw revert data is returned in the error object
+	resp, err := NewRPCResponse(1, "2.0", nil, dataErr).Bytes()
+	assert.NoError(t, err)
+
+	var obj ErrorResponse
+	assert.NoError(t, json.Unmarshal(resp, &obj))
+	assert.Equal(t, 3, obj.Error.Code)
+	assert.Equal(t, hex.EncodeToHex(data), obj.Error.Data)
+}
+
+func TestEth_State_CallTimeout(t *testing.T) {
+	store := &mockCallStore{block: true}
+	eth := newTestEthEndpoint(store)
+	eth.callTimeout = 50 * time.Millisecond
+	eth.metrics = metrics.NewRPCMetrics()
+
+	_, err := eth.Call(&txnArgs{
+		From:  &addr0,
+		To:    &uninitializedAddress,
+		Nonce: argUintPtr(0),
+	}, BlockNumberOrHash{}, nil, nil)
+	assert.EqualError(t, err, "execution aborted (timeout = 50ms)")
+}
+
+type mockPendingStore struct {
+	*mockSimulateStore
+
+	// block is the pending block, nil while it is not built
+	block      *types.Block
+	transition *state.Transition
+}
+
+// build writes the transactions to the pending block
+func (m *mockPendingStore) build(t *testing.T, txns ...*types.Transaction) {
+	t.Helper()
+
+	header := &types.Header{
+		ParentHash: m.header.Hash,
+		Number:     m.header.Number + 1,
+		GasLimit:   m.header.GasLimit,
+		Timestamp:  m.header.Timestamp + 1,
+	}
+
+	transition, err := m.executor.BeginTxn(m.header.StateRoot, header, types.ZeroAddress)
+	assert.NoError(t, err)
+
+	for _, txn := range txns {
+		assert.NoError(t, transition.Write(txn))
+	}
+
+	m.block = &types.Block{Header: header, Transactions: txns}
+	m.transition = transition
+}
+
+func (m *mockPendingStore) GetPendingBlock() (*types.Block, []*types.Receipt, bool) {
+	if m.block == nil {
+		return nil, nil, false
+	}
+
+	return m.block, m.transition.Receipts(), true
+}
+
+func (m *mockPendingStore) PendingState(f func(header *types.Header, transition *state.Transition) error) error {
+	if m.block == nil {
+		return blockchain.ErrNoPendingBlock
+	}
+
+	return f(m.block.Header, m.transition.Fork())
+}
+
+func (m *mockPendingStore) ApplyPendingTxn(
+	ctx context.Context,
+	txn *types.Transaction,
+	stateOverride state.StateOverride,
+	blockOverride *state.BlockOverride,
+) (result *evm.ExecutionResult, err error) {
+	err = m.PendingState(func(_ *types.Header, transition *state.Transition) error {
+		result, err = transition.Apply(txn)
+
+		return err
+	})
+
+	return result, err
+}
+
+func (m *mockPendingStore) ApplyTxn(
+	ctx context.Context,
+	header *types.Header,
+	txn *types.Transaction,
+	stateOverride state.StateOverride,
+	blockOverride *state.BlockOverride,
+) (*evm.ExecutionResult, error) {
+	transition, err := m.BeginSimulation(header)
+	if err != nil {
+		return nil, err
+	}
+
+	return transition.Apply(txn)
+}
+
+func (m *mockPendingStore) GetAccount(root types.Hash, addr types.Address) (*state.Account, error) {
+	if root != m.header.StateRoot {
+		return nil, ErrStateNotFound
+	}
+
+	transition, err := m.BeginSimulation(m.header)
+	if err != nil {
+		return nil, err
+	}
+
+	return &state.Account{
+		Balance: transition.GetBalance(addr),
+		Nonce:   transition.GetNonce(addr),
+	}, nil
+}
+
+func (m *mockPendingStore) GetStorage(root types.Hash, addr types.Address, slot types.Hash) ([]byte, error) {
+	if root != m.header.StateRoot {
+		return nil, ErrStateNotFound
+	}
+
+	transition, err := m.BeginSimulation(m.header)
+	if err != nil {
+		return nil, err
+	}
+
+	value := transition.GetStorage(addr, slot)
+	if value == types.ZeroHash {
+		return nil, ErrStateNotFound
+	}
+
+	return (&fastrlp.Arena{}).NewBytes(value.Bytes()).MarshalTo(nil), nil
+}
+
+func (m *mockPendingStore) GetForksInTime(blockNumber uint64) params.ForksInTime {
+	return params.AllForksEnabled.At(blockNumber)
+}
+
+func (m *mockPendingStore) GetBlockByNumber(num uint64, full bool) (*types.Block, bool) {
+	if num != m.header.Number {
+		return nil, false
+	}
+
+	return &types.Block{Header: m.header}, true
+}
+
+func (m *mockPendingStore) GetNonce(addr types.Address) uint64 {
+	return 0
+}
+
+func TestEth_State_Pending(t *testing.T) {
+	var (
+		sender   = types.StringToAddress("4000")
+		receiver = types.StringToAddress("5000")
+	)
+
+	store := newMockSimulateStore(t)
+	store.header.StateRoot = store.executor.WriteGenesis(map[types.Address]*params.GenesisAccount{
+		sender:    {Balance: big.NewInt(1000)},
+		storeAddr: {Code: hex.MustDecodeHex(storeCode)},
+	})
+
+	pendingStore := &mockPendingStore{mockSimulateStore: store}
+	eth := newTestEthEndpoint(pendingStore)
+
+	pending := PendingBlockNumber
+	pendingFilter := func() BlockNumberOrHash {
+		return BlockNumberOrHash{BlockNumber: &pending}
+	}
+
+	slot := types.StringToHash("2a")
+
+	assertState := func(balance int64, nonce uint64, value types.Hash, number uint64) {
+		t.Helper()
+
+		res, err := eth.GetBalance(receiver, pendingFilter())
+		assert.NoError(t, err)
+		assert.Equal(t, argBigPtr(big.NewInt(balance)), res)
+
+		res, err = eth.GetTransactionCount(sender, pendingFilter())
+		assert.NoError(t, err)
+		assert.Equal(t, argUintPtr(nonce), res)
+
+		res, err = eth.GetStorageAt(storeAddr, types.ZeroHash, pendingFilter())
+		assert.NoError(t, err)
+		assert.Equal(t, argBytesPtr(value.Bytes()), res)
+
+		// the call without calldata returns the slot 0
+		res, err = eth.Call(&txnArgs{From: &sender, To: &storeAddr}, pendingFilter(), nil, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, argBytesPtr(value.Bytes()), res)
+
+		res, err = eth.GetBlockByNumber(pending, false)
+		assert.NoError(t, err)
+		assert.Equal(t, argUint64(number), res.(*block).Number)
+	}
+
+	// the latest block is used while the pending block is not built
+	assertState(0, 0, types.ZeroHash, 10)
+
+	pendingStore.build(t,
+		&types.Transaction{
+			From:     sender,
+			To:       &receiver,
+			Value:    big.NewInt(10),
+			Gas:      21000,
+			GasPrice: big.NewInt(0),
+		},
+		&types.Transaction{
+			From:     sender,
+			To:       &storeAddr,
+			Nonce:    1,
+			Value:    big.NewInt(0),
+			Input:    slot.Bytes(),
+			Gas:      100000,
+			GasPrice: big.NewInt(0),
+		},
+	)
+
+	// the calls on the pending state are discarded
+	for i := 0; i < 2; i++ {
+		assertState(10, 2, slot, 11)
+	}
+
+	// the latest state is unchanged
+	res, err := eth.GetBalance(receiver, BlockNumberOrHash{})
+	assert.NoError(t, err)
+	assert.Equal(t, argBigPtr(big.NewInt(0)), res)
+
+	// the sender has 990 left in the pending state
+	_, err = eth.EstimateGas(&txnArgs{
+		From:     &sender,
+		To:       &receiver,
+		Value:    argBytesPtr(big.NewInt(995).Bytes()),
+		GasPrice: argBytesPtr(big.NewInt(1).Bytes()),
+	}, &pending, nil, nil)
+	assert.ErrorContains(t, err, "insufficient funds")
+}
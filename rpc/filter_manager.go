@@ -9,7 +9,10 @@ import (
 	"sync"
 	"time"
 
+	"github.com/TIE-Tech/tie-core/common/progress"
 	"github.com/TIE-Tech/tie-core/core"
+	"github.com/TIE-Tech/tie-core/metrics"
+	"github.com/TIE-Tech/tie-core/storage"
 	"github.com/TIE-Tech/tie-core/types"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
@@ -27,6 +30,15 @@ type Filter struct {
 	// log filter
 	logFilter *LogFilter
 
+	// pending transaction filter, the transactions are returned in full if fullTx is set
+	pendingTx bool
+	fullTx    bool
+	txHashes  []types.Hash
+	txs       []*types.Transaction
+
+	// syncing filter
+	syncing bool
+
 	// index of the filter in the timer array
 	index int
 
@@ -50,6 +62,19 @@ func (f *Filter) getFilterUpdates() (string, error) {
 
 		return fmt.Sprintf("[\"%s\"]", strings.Join(updates, "\",\"")), nil
 	}
+
+	if f.isPendingTxFilter() {
+		// pending transaction filter
+		res, err := json.Marshal(f.txHashes)
+		if err != nil {
+			return "", err
+		}
+
+		f.txHashes = []types.Hash{}
+
+		return string(res), nil
+	}
+
 	// log filter
 	res, err := json.Marshal(f.logs)
 	if err != nil {
@@ -99,7 +124,28 @@ func (f *Filter) flush() error {
 				return err
 			}
 		}
-	} else {
+	} else if f.isPendingTxFilter() {
+		// send each transaction independently
+		for _, hash := range f.txHashes {
+			if err := f.sendMessage(fmt.Sprintf("\"%s\"", hash.String())); err != nil {
+				return err
+			}
+		}
+
+		for _, txn := range f.txs {
+			res, err := json.Marshal(toPendingTransaction(txn))
+			if err != nil {
+				return err
+			}
+
+			if err := f.sendMessage(string(res)); err != nil {
+				return err
+			}
+		}
+
+		f.txHashes = []types.Hash{}
+		f.txs = []*types.Transaction{}
+	} else if f.isLogFilter() {
 		// log filter
 		for _, log := range f.logs {
 			res, err := json.Marshal(log)
@@ -124,8 +170,33 @@ func (f *Filter) isBlockFilter() bool {
 	return f.block != nil
 }
 
+func (f *Filter) isPendingTxFilter() bool {
+	return f.pendingTx
+}
+
+func (f *Filter) isSyncingFilter() bool {
+	return f.syncing
+}
+
+// filterType returns the type of the filter in the metrics
+func (f *Filter) filterType() string {
+	switch {
+	case f.isBlockFilter():
+		return "block"
+	case f.isPendingTxFilter():
+		return "pending_tx"
+	case f.isSyncingFilter():
+		return "syncing"
+	default:
+		return "log"
+	}
+}
+
 var defaultTimeout = 1 * time.Minute
 
+// syncingPollInterval is the interval between two checks of the sync status for the syncing filters
+var syncingPollInterval = 1 * time.Second
+
 // filterManagerStore provides methods required by FilterManager
 type filterManagerStore interface {
 	// Header returns the current header of the chain (genesis if empty)
@@ -136,6 +207,23 @@ type filterManagerStore interface {
 
 	// GetReceiptsByHash returns the receipts for a block hash
 	GetReceiptsByHash(hash types.Hash) ([]*types.Receipt, error)
+
+	// SubscribePendingTxs subscribes for the hashes of the transactions promoted in the pool,
+	// the returned function cancels the subscription
+	SubscribePendingTxs() (<-chan types.Hash, func())
+
+	// GetPendingTx gets the pending transaction from the transaction pool, if it's present
+	GetPendingTx(txHash types.Hash) (*types.Transaction, bool)
+
+	// GetSyncProgression retrieves the current sync progression, if any
+	GetSyncProgression() *progress.Progression
+
+	// BloomBitsSections returns the number of sections of the bloom bits index
+	BloomBitsSections() uint64
+
+	// MatchBloomBits returns the bit set of the blocks of an indexed section whose
+	// bloom possibly contains a value of every group
+	MatchBloomBits(section uint64, groups [][][]byte) ([]byte, error)
 }
 
 type FilterManager struct {
@@ -144,6 +232,13 @@ type FilterManager struct {
 
 	subscription blockchain.Subscription
 
+	// pendingTxCh receives the hashes of the transactions promoted in the pool
+	pendingTxCh       <-chan types.Hash
+	cancelPendingTxCh func()
+
+	// syncing is the last sync status sent to the syncing filters
+	syncing bool
+
 	filters map[string]*Filter
 	lock    sync.Mutex
 
@@ -152,11 +247,14 @@ type FilterManager struct {
 	timeout  time.Duration
 
 	blockStream *blockStream
+
+	metrics *metrics.RPCMetrics
 }
 
-func NewFilterManager(store filterManagerStore) *FilterManager {
+func NewFilterManager(store filterManagerStore, metrics *metrics.RPCMetrics) *FilterManager {
 	m := &FilterManager{
 		store:       store,
+		metrics:     metrics,
 		closeCh:     make(chan struct{}),
 		filters:     map[string]*Filter{},
 		updateCh:    make(chan struct{}),
@@ -172,6 +270,11 @@ func NewFilterManager(store filterManagerStore) *FilterManager {
 	// start the head watcher
 	m.subscription = store.SubscribeEvents()
 
+	// start the pending transaction watcher
+	m.pendingTxCh, m.cancelPendingTxCh = store.SubscribePendingTxs()
+
+	m.syncing = store.GetSyncProgression() != nil
+
 	return m
 }
 
@@ -189,6 +292,9 @@ func (f *FilterManager) Run() {
 		}
 	}()
 
+	syncTicker := time.NewTicker(syncingPollInterval)
+	defer syncTicker.Stop()
+
 	var timeoutCh <-chan time.Time
 
 	for {
@@ -207,6 +313,20 @@ func (f *FilterManager) Run() {
 				logger.Error("failed to dispatch event", "err", err)
 			}
 
+		case hash, ok := <-f.pendingTxCh:
+			if !ok {
+				// the pool is closed
+				f.pendingTxCh = nil
+
+				continue
+			}
+
+			// new pending transaction
+			f.dispatchPendingTx(hash)
+
+		case <-syncTicker.C:
+			f.dispatchSyncing()
+
 		case <-timeoutCh:
 			// timeout for filter
 			if !f.Uninstall(filter.id) {
@@ -247,19 +367,47 @@ func (f *FilterManager) dispatchEvent(evnt *blockchain.Event) error {
 		f.blockStream.push(header)
 	}
 
+	matcher := newHeaderMatcher(f.store)
+
 	processBlock := func(h *types.Header, removed bool) error {
+		// skip the blocks without logs for the filters
+		match := false
+
+		for _, filter := range f.filters {
+			if !filter.isLogFilter() {
+				continue
+			}
+
+			ok, err := matcher.match(filter.logFilter, h, removed)
+			if err != nil {
+				return err
+			}
+
+			if ok {
+				match = true
+
+				break
+			}
+		}
+
+		if !match {
+			return nil
+		}
+
 		// get the logs from the transaction
 		receipts, err := f.store.GetReceiptsByHash(h.Hash)
 		if err != nil {
 			return err
 		}
 
+		logIndex := uint64(0)
+
 		for indx, receipt := range receipts {
 			// check the logs with the filters
 			for _, log := range receipt.Logs {
-				for _, f := range f.filters {
-					if f.isLogFilter() {
-						if f.logFilter.Match(log) {
+				for _, filter := range f.filters {
+					if filter.isLogFilter() {
+						if filter.logFilter.Match(log) {
 							nn := &Log{
 								Address:     log.Address,
 								Topics:      log.Topics,
@@ -268,12 +416,15 @@ func (f *FilterManager) dispatchEvent(evnt *blockchain.Event) error {
 								BlockHash:   h.Hash,
 								TxHash:      receipt.TxHash,
 								TxIndex:     argUint64(indx),
+								LogIndex:    argUint64(logIndex),
 								Removed:     removed,
 							}
-							f.logs = append(f.logs, nn)
+							filter.logs = append(filter.logs, nn)
 						}
 					}
 				}
+
+				logIndex++
 			}
 		}
 
@@ -305,6 +456,122 @@ func (f *FilterManager) dispatchEvent(evnt *blockchain.Event) error {
 	return nil
 }
 
+// headerMatcher matches the headers of a chain event with the log filters. The index only
+// covers the canonical chain, so the headers of the new chain in the indexed sections, such
+// as the ones of the large batches of a sync or of a deep reorg, are matched with the bloom
+// bits index and the other headers with their bloom
+type headerMatcher struct {
+	store    filterManagerStore
+	sections uint64
+
+	// bits are the bit sets of the sections matched by each filter
+	bits map[*LogFilter]map[uint64][]byte
+}
+
+func newHeaderMatcher(store filterManagerStore) *headerMatcher {
+	return &headerMatcher{
+		store:    store,
+		sections: store.BloomBitsSections(),
+		bits:     map[*LogFilter]map[uint64][]byte{},
+	}
+}
+
+// match returns whether the logs of a header possibly match a filter
+func (m *headerMatcher) match(filter *LogFilter, header *types.Header, removed bool) (bool, error) {
+	section := header.Number / storage.BloomBitsSectionSize
+	groups := filter.bloomGroups()
+
+	// the filters without values match any block with logs, only the headers tell it
+	if removed || section >= m.sections || len(groups) == 0 {
+		return filter.MatchBloom(&header.LogsBloom), nil
+	}
+
+	sections, ok := m.bits[filter]
+	if !ok {
+		sections = map[uint64][]byte{}
+		m.bits[filter] = sections
+	}
+
+	bits, ok := sections[section]
+	if !ok {
+		var err error
+		if bits, err = m.store.MatchBloomBits(section, groups); err != nil {
+			return false, err
+		}
+
+		sections[section] = bits
+	}
+
+	i := header.Number % storage.BloomBitsSectionSize
+
+	return bits[i/8]&(1<<(7-i%8)) != 0, nil
+}
+
+// dispatchPendingTx adds a transaction promoted in the pool to the pending transaction filters
+func (f *FilterManager) dispatchPendingTx(hash types.Hash) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	var txn *types.Transaction
+
+	for _, filter := range f.filters {
+		if !filter.isPendingTxFilter() {
+			continue
+		}
+
+		if filter.fullTx {
+			if txn == nil {
+				pendingTx, ok := f.store.GetPendingTx(hash)
+				if !ok {
+					// the transaction already left the pool
+					continue
+				}
+
+				txn = pendingTx
+			}
+
+			filter.txs = append(filter.txs, txn)
+		} else {
+			filter.txHashes = append(filter.txHashes, hash)
+		}
+
+		if filter.isWS() {
+			if flushErr := filter.flush(); flushErr != nil {
+				logger.Error("Unable to process flush", "err", flushErr)
+			}
+		}
+	}
+}
+
+// dispatchSyncing notifies the syncing filters when the node starts or stops syncing
+func (f *FilterManager) dispatchSyncing() {
+	syncProgression := f.store.GetSyncProgression()
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if syncing := syncProgression != nil; syncing == f.syncing {
+		return
+	}
+
+	f.syncing = !f.syncing
+
+	res, err := json.Marshal(toSyncingResult(syncProgression))
+	if err != nil {
+		logger.Error("Unable to encode sync status", "err", err)
+
+		return
+	}
+
+	for _, filter := range f.filters {
+		if filter.isSyncingFilter() {
+			if sendErr := filter.sendMessage(string(res)); sendErr != nil {
+				logger.Error("Unable to send sync status", "err", sendErr)
+			}
+		}
+	}
+}
+
 func (f *FilterManager) Exists(id string) bool {
 	f.lock.Lock()
 	_, ok := f.filters[id]
@@ -342,12 +609,16 @@ func (f *FilterManager) Uninstall(id string) bool {
 
 	item, ok := f.filters[id]
 	if !ok {
+		f.lock.Unlock()
+
 		return false
 	}
 
 	delete(f.filters, id)
 	heap.Remove(&f.timer, item.index)
 
+	f.metrics.Filters.With("type", item.filterType()).Add(-1)
+
 	f.lock.Unlock()
 
 	return true
@@ -361,24 +632,49 @@ func (f *FilterManager) NewLogFilter(logFilter *LogFilter, ws wsConn) string {
 	return f.addFilter(logFilter, ws)
 }
 
-func (f *FilterManager) addFilter(logFilter *LogFilter, ws wsConn) string {
-	f.lock.Lock()
+// NewPendingTxFilter adds a filter of the transactions promoted in the pool
+func (f *FilterManager) NewPendingTxFilter(fullTx bool, ws wsConn) string {
+	return f.installFilter(&Filter{
+		pendingTx: true,
+		fullTx:    fullTx,
+		ws:        ws,
+	})
+}
 
+// NewSyncingFilter adds a filter notified when the node starts or stops syncing
+func (f *FilterManager) NewSyncingFilter(ws wsConn) string {
+	return f.installFilter(&Filter{
+		syncing: true,
+		ws:      ws,
+	})
+}
+
+func (f *FilterManager) addFilter(logFilter *LogFilter, ws wsConn) string {
 	filter := &Filter{
-		id: uuid.New().String(),
 		ws: ws,
 	}
 
 	if logFilter == nil {
 		// block filter
 		// take the reference from the stream
+		f.lock.Lock()
 		filter.block = f.blockStream.Head()
+		f.lock.Unlock()
 	} else {
 		// log filter
 		filter.logFilter = logFilter
 	}
 
+	return f.installFilter(filter)
+}
+
+func (f *FilterManager) installFilter(filter *Filter) string {
+	f.lock.Lock()
+
+	filter.id = uuid.New().String()
+
 	f.filters[filter.id] = filter
+	f.metrics.Filters.With("type", filter.filterType()).Add(1)
 	filter.timestamp = time.Now().Add(f.timeout)
 	heap.Push(&f.timer, filter)
 
@@ -394,6 +690,7 @@ func (f *FilterManager) addFilter(logFilter *LogFilter, ws wsConn) string {
 
 func (f *FilterManager) Close() {
 	close(f.closeCh)
+	f.cancelPendingTxCh()
 }
 
 type timeHeapImpl []*Filter
@@ -1,9 +1,16 @@
 package rpc
 
 import (
+	"encoding/json"
+	"math/big"
 	"testing"
 	"time"
 
+	"github.com/TIE-Tech/tie-core/common/progress"
+	"github.com/TIE-Tech/tie-core/core"
+	"github.com/TIE-Tech/tie-core/metrics"
+	"github.com/TIE-Tech/tie-core/storage"
+	"github.com/TIE-Tech/tie-core/storage/memory"
 	"github.com/TIE-Tech/tie-core/types"
 	"github.com/stretchr/testify/assert"
 )
@@ -11,7 +18,7 @@ import (
 func TestFilterLog(t *testing.T) {
 	store := newMockStore()
 
-	m := NewFilterManager(store)
+	m := NewFilterManager(store, metrics.NewRPCMetrics())
 	go m.Run()
 
 	id := m.addFilter(&LogFilter{
@@ -66,10 +73,53 @@ func TestFilterLog(t *testing.T) {
 	}
 }
 
+func TestFilterLog_BloomBits(t *testing.T) {
+	db, err := memory.NewMemoryStorage()
+	assert.NoError(t, err)
+
+	store := newMockStore()
+	store.bloomBits = db
+	store.receipts = map[types.Hash][]*types.Receipt{}
+
+	// the block 5 of the indexed section has a log of addr1
+	blooms := make([]types.Bloom, storage.BloomBitsSectionSize)
+	receipts := []*types.Receipt{{Logs: []*types.Log{{Address: addr1}}}}
+	blooms[5] = types.CreateBloom(receipts)
+
+	assert.NoError(t, db.WriteBloomBitsSection(0, storage.GenerateBloomBits(blooms)))
+
+	m := NewFilterManager(store, metrics.NewRPCMetrics())
+	id := m.addFilter(&LogFilter{Addresses: []types.Address{addr1}}, nil)
+
+	// the headers of the new chain in the indexed section are matched with the index,
+	// their bloom is left empty not to match it
+	header := &types.Header{Number: 5, Hash: hash1}
+	store.receipts[header.Hash] = receipts
+
+	assert.NoError(t, m.dispatchEvent(&blockchain.Event{
+		NewChain: []*types.Header{header, {Number: 6, Hash: hash2}},
+	}))
+
+	// the removed headers are matched with their bloom, the index only covers the canonical chain
+	assert.NoError(t, m.dispatchEvent(&blockchain.Event{
+		OldChain: []*types.Header{header},
+	}))
+
+	res, err := m.GetFilterChanges(id)
+	assert.NoError(t, err)
+
+	var logs []*Log
+	assert.NoError(t, json.Unmarshal([]byte(res), &logs))
+
+	assert.Len(t, logs, 1)
+	assert.Equal(t, hash1, logs[0].BlockHash)
+	assert.False(t, logs[0].Removed)
+}
+
 func TestFilterBlock(t *testing.T) {
 	store := newMockStore()
 
-	m := NewFilterManager(store)
+	m := NewFilterManager(store, metrics.NewRPCMetrics())
 	go m.Run()
 
 	// add block filter
@@ -130,7 +180,7 @@ func TestFilterBlock(t *testing.T) {
 func TestFilterTimeout(t *testing.T) {
 	store := newMockStore()
 
-	m := NewFilterManager(store)
+	m := NewFilterManager(store, metrics.NewRPCMetrics())
 	m.timeout = 2 * time.Second
 
 	go m.Run()
@@ -150,7 +200,7 @@ func TestFilterWebsocket(t *testing.T) {
 		msgCh: make(chan []byte, 1),
 	}
 
-	m := NewFilterManager(store)
+	m := NewFilterManager(store, metrics.NewRPCMetrics())
 	go m.Run()
 
 	id := m.NewBlockFilter(mock)
@@ -177,6 +227,146 @@ func TestFilterWebsocket(t *testing.T) {
 	}
 }
 
+func TestFilterPendingTx(t *testing.T) {
+	store := newMockStore()
+
+	m := NewFilterManager(store, metrics.NewRPCMetrics())
+	go m.Run()
+
+	id := m.NewPendingTxFilter(false, nil)
+
+	txn := &types.Transaction{Nonce: 1, Gas: 21000, Hash: hash1}
+	store.emitPendingTx(txn)
+
+	// the channel is unbuffered, the second send waits for the first one to be handled
+	store.emitPendingTx(&types.Transaction{Nonce: 2, Hash: hash2})
+
+	time.Sleep(100 * time.Millisecond)
+
+	res, err := m.GetFilterChanges(id)
+	assert.NoError(t, err)
+
+	var hashes []types.Hash
+	assert.NoError(t, json.Unmarshal([]byte(res), &hashes))
+	assert.Equal(t, []types.Hash{hash1, hash2}, hashes)
+
+	// the hashes are returned once
+	res, err = m.GetFilterChanges(id)
+	assert.NoError(t, err)
+	assert.Equal(t, "[]", res)
+}
+
+func TestFilterPendingTxWebsocket(t *testing.T) {
+	store := newMockStore()
+
+	m := NewFilterManager(store, metrics.NewRPCMetrics())
+	go m.Run()
+
+	hashes := &mockWsConn{msgCh: make(chan []byte, 1)}
+	m.NewPendingTxFilter(false, hashes)
+
+	full := &mockWsConn{msgCh: make(chan []byte, 1)}
+	m.NewPendingTxFilter(true, full)
+
+	store.emitPendingTx(&types.Transaction{
+		Nonce:    1,
+		Gas:      21000,
+		GasPrice: big.NewInt(1),
+		Value:    big.NewInt(0),
+		V:        big.NewInt(0),
+		R:        big.NewInt(0),
+		S:        big.NewInt(0),
+		Hash:     hash1,
+	})
+
+	var notification struct {
+		Params struct {
+			Result json.RawMessage
+		}
+	}
+
+	readResult := func(mock *mockWsConn) json.RawMessage {
+		select {
+		case msg := <-mock.msgCh:
+			assert.NoError(t, json.Unmarshal(msg, &notification))
+
+			return notification.Params.Result
+		case <-time.After(2 * time.Second):
+			t.Fatal("no notification")
+		}
+
+		return nil
+	}
+
+	var hash types.Hash
+	assert.NoError(t, json.Unmarshal(readResult(hashes), &hash))
+	assert.Equal(t, hash1, hash)
+
+	var txn transaction
+	assert.NoError(t, json.Unmarshal(readResult(full), &txn))
+	assert.Equal(t, hash1, txn.Hash)
+	assert.Equal(t, argUint64(1), txn.Nonce)
+	assert.Nil(t, txn.BlockHash)
+}
+
+func TestFilterSyncing(t *testing.T) {
+	store := newMockStore()
+
+	defaultPollInterval := syncingPollInterval
+	syncingPollInterval = 50 * time.Millisecond
+
+	t.Cleanup(func() {
+		syncingPollInterval = defaultPollInterval
+	})
+
+	m := NewFilterManager(store, metrics.NewRPCMetrics())
+	go m.Run()
+
+	mock := &mockWsConn{msgCh: make(chan []byte, 1)}
+	m.NewSyncingFilter(mock)
+
+	var notification struct {
+		Params struct {
+			Result json.RawMessage
+		}
+	}
+
+	readResult := func() string {
+		select {
+		case msg := <-mock.msgCh:
+			assert.NoError(t, json.Unmarshal(msg, &notification))
+
+			return string(notification.Params.Result)
+		case <-time.After(2 * time.Second):
+			t.Fatal("no notification")
+		}
+
+		return ""
+	}
+
+	// the status is sent when the node starts syncing
+	store.setSyncProgression(&progress.Progression{
+		SyncType:     progress.ChainSyncBulk,
+		CurrentBlock: 1,
+		HighestBlock: 10,
+	})
+
+	var status progression
+	assert.NoError(t, json.Unmarshal([]byte(readResult()), &status))
+	assert.Equal(t, "0xa", status.HighestBlock)
+
+	// and when it stops
+	store.setSyncProgression(nil)
+	assert.Equal(t, "false", readResult())
+
+	// the status is not sent again while it does not change
+	select {
+	case <-mock.msgCh:
+		t.Fatal("unexpected notification")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
 type mockWsConn struct {
 	msgCh chan []byte
 }
@@ -0,0 +1,123 @@
+package rpc
+
+import (
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/TIE-Tech/tie-core/params"
+	"github.com/TIE-Tech/tie-core/types"
+)
+
+const (
+	// gasPriceBlocks is the number of blocks sampled by the gas price oracle
+	gasPriceBlocks = 20
+
+	// gasPricePercentile is the percentile of the sampled prices suggested by the oracle
+	gasPricePercentile = 60
+
+	// maxFeeHistory is the maximum number of blocks of a fee history
+	maxFeeHistory = 1024
+)
+
+// gasPriceStore provides access to the methods needed by the gas price oracle
+type gasPriceStore interface {
+	// Header returns the current header of the chain (genesis if empty)
+	Header() *types.Header
+
+	// GetBlockByNumber returns a block using the provided number
+	GetBlockByNumber(num uint64, full bool) (*types.Block, bool)
+
+	// GetForksInTime returns the active forks at the given block height
+	GetForksInTime(blockNumber uint64) params.ForksInTime
+
+	// GetPriceLimit returns the minimum gas price accepted by the tx pool
+	GetPriceLimit() uint64
+}
+
+// gasPriceOracle suggests a gas price from the prices of the transactions included
+// in the last blocks. The suggestion is computed once per head of the chain
+type gasPriceOracle struct {
+	store gasPriceStore
+
+	lock      sync.Mutex
+	lastHead  types.Hash
+	lastPrice *big.Int
+}
+
+func newGasPriceOracle(store gasPriceStore) *gasPriceOracle {
+	return &gasPriceOracle{
+		store: store,
+	}
+}
+
+// SuggestPrice returns the percentile of the gas prices of the last blocks. It is
+// never lower than the price limit of the tx pool, the transactions under it being
+// rejected
+func (o *gasPriceOracle) SuggestPrice() *big.Int {
+	head := o.store.Header()
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if o.lastPrice != nil && o.lastHead == head.Hash {
+		return new(big.Int).Set(o.lastPrice)
+	}
+
+	prices := make([]*big.Int, 0)
+
+	for i := uint64(0); i < gasPriceBlocks && i <= head.Number; i++ {
+		block, ok := o.store.GetBlockByNumber(head.Number-i, true)
+		if !ok {
+			break
+		}
+
+		prices = append(prices, blockGasPrices(block, o.store.GetForksInTime(block.Number()))...)
+	}
+
+	price := big.NewInt(0)
+
+	if len(prices) > 0 {
+		sort.Slice(prices, func(i, j int) bool {
+			return prices[i].Cmp(prices[j]) < 0
+		})
+
+		price = prices[(len(prices)-1)*gasPricePercentile/100]
+	}
+
+	if limit := new(big.Int).SetUint64(o.store.GetPriceLimit()); price.Cmp(limit) < 0 {
+		price = limit
+	}
+
+	o.lastHead = head.Hash
+	o.lastPrice = new(big.Int).Set(price)
+
+	return price
+}
+
+// blockGasPrices returns the gas prices of the transactions of a block,
+// without the system transactions which are free
+func blockGasPrices(block *types.Block, forks params.ForksInTime) []*big.Int {
+	prices := make([]*big.Int, 0, len(block.Transactions))
+
+	for _, txn := range block.Transactions {
+		if isSystemTx(txn, forks) {
+			continue
+		}
+
+		prices = append(prices, txn.GasPrice)
+	}
+
+	return prices
+}
+
+// isSystemTx returns true if txn is a fee withdrawal or a block reward transaction
+func isSystemTx(txn *types.Transaction, forks params.ForksInTime) bool {
+	if !forks.SystemTx {
+		return txn.IsWithdrawFee() || txn.IsFixedRewardTx()
+	}
+
+	systemTx, err := txn.SystemTxType()
+
+	return err == nil && systemTx != types.SystemTxNone
+}
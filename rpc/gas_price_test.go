@@ -0,0 +1,155 @@
+package rpc
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/TIE-Tech/tie-core/params"
+	"github.com/TIE-Tech/tie-core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockGasPriceStore struct {
+	ethStore
+
+	blocks     []*types.Block
+	receipts   map[types.Hash][]*types.Receipt
+	priceLimit uint64
+}
+
+// add adds a block with a transaction of each price, using 21000 gas
+func (m *mockGasPriceStore) add(gasLimit uint64, prices ...int64) {
+	number := uint64(len(m.blocks))
+	block := &types.Block{
+		Header: &types.Header{
+			Number:   number,
+			Hash:     types.BytesToHash(big.NewInt(int64(number) + 1).Bytes()),
+			GasLimit: gasLimit,
+		},
+	}
+
+	receipts := make([]*types.Receipt, 0, len(prices))
+
+	for _, price := range prices {
+		block.Transactions = append(block.Transactions, &types.Transaction{GasPrice: big.NewInt(price)})
+		block.Header.GasUsed += 21000
+
+		receipts = append(receipts, &types.Receipt{GasUsed: 21000})
+	}
+
+	if m.receipts == nil {
+		m.receipts = map[types.Hash][]*types.Receipt{}
+	}
+
+	m.blocks = append(m.blocks, block)
+	m.receipts[block.Hash()] = receipts
+}
+
+func (m *mockGasPriceStore) Header() *types.Header {
+	return m.blocks[len(m.blocks)-1].Header
+}
+
+func (m *mockGasPriceStore) GetHeaderByNumber(num uint64) (*types.Header, bool) {
+	if num >= uint64(len(m.blocks)) {
+		return nil, false
+	}
+
+	return m.blocks[num].Header, true
+}
+
+func (m *mockGasPriceStore) GetBlockByNumber(num uint64, full bool) (*types.Block, bool) {
+	if num >= uint64(len(m.blocks)) {
+		return nil, false
+	}
+
+	return m.blocks[num], true
+}
+
+func (m *mockGasPriceStore) GetReceiptsByHash(hash types.Hash) ([]*types.Receipt, error) {
+	return m.receipts[hash], nil
+}
+
+func (m *mockGasPriceStore) GetForksInTime(uint64) params.ForksInTime {
+	return params.ForksInTime{SystemTx: true}
+}
+
+func (m *mockGasPriceStore) GetPriceLimit() uint64 {
+	return m.priceLimit
+}
+
+func TestGasPriceOracle_SuggestPrice(t *testing.T) {
+	store := &mockGasPriceStore{}
+	store.add(100000)
+	store.add(100000, 1, 2, 3, 4, 5)
+	store.add(100000, 6, 7, 8, 9, 10)
+
+	// the fixed reward transaction is free and not sampled
+	reward := &types.Transaction{
+		To:       &types.RewardPoolAddress,
+		Input:    []byte{0x57, 0x30, 0x59, 0x20},
+		GasPrice: big.NewInt(0),
+	}
+	store.blocks[2].Transactions = append(store.blocks[2].Transactions, reward)
+
+	oracle := newGasPriceOracle(store)
+	assert.Equal(t, big.NewInt(6), oracle.SuggestPrice())
+
+	// the suggestion is cached for the head
+	store.blocks[2].Transactions[0].GasPrice = big.NewInt(100)
+	assert.Equal(t, big.NewInt(6), oracle.SuggestPrice())
+
+	// the suggestion is never lower than the price limit
+	store.priceLimit = 50
+
+	assert.Equal(t, big.NewInt(50), newGasPriceOracle(store).SuggestPrice())
+}
+
+func TestGasPriceOracle_NoTransactions(t *testing.T) {
+	store := &mockGasPriceStore{priceLimit: 3}
+	store.add(100000)
+
+	assert.Equal(t, big.NewInt(3), newGasPriceOracle(store).SuggestPrice())
+}
+
+func TestEth_FeeHistory(t *testing.T) {
+	store := &mockGasPriceStore{}
+	store.add(84000)
+	store.add(84000, 4, 1, 3, 2)
+	store.add(84000, 5)
+
+	eth := newTestEthEndpoint(store)
+
+	res, err := eth.FeeHistory(2, LatestBlockNumber, []float64{0, 50, 100})
+	assert.NoError(t, err)
+
+	history, ok := res.(*feeHistory)
+	assert.True(t, ok)
+
+	assert.Equal(t, argUint64(1), history.OldestBlock)
+	assert.Len(t, history.BaseFeePerGas, 3)
+	assert.Equal(t, []float64{1, 0.25}, history.GasUsedRatio)
+
+	prices := func(values ...int64) []argBig {
+		res := make([]argBig, 0, len(values))
+		for _, value := range values {
+			res = append(res, argBig(*big.NewInt(value)))
+		}
+
+		return res
+	}
+
+	assert.Equal(t, [][]argBig{prices(1, 2, 4), prices(5, 5, 5)}, history.Reward)
+
+	// the block count is limited by the chain
+	res, err = eth.FeeHistory(10, BlockNumber(1), nil)
+	assert.NoError(t, err)
+
+	history, ok = res.(*feeHistory)
+	assert.True(t, ok)
+	assert.Equal(t, argUint64(0), history.OldestBlock)
+	assert.Len(t, history.GasUsedRatio, 2)
+	assert.Nil(t, history.Reward)
+
+	_, err = eth.FeeHistory(1, LatestBlockNumber, []float64{50, 10})
+	assert.Error(t, err)
+}
@@ -0,0 +1,318 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/TIE-Tech/go-logger"
+	"github.com/TIE-Tech/tie-core/common/hex"
+	"github.com/TIE-Tech/tie-core/types"
+	"github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+)
+
+// gqlMaxDepth is the maximum nesting of the selection sets of a query
+const gqlMaxDepth = 32
+
+// graphQL serves the graphql queries with the eth endpoint
+type graphQL struct {
+	eth *Eth
+
+	// checkAccess returns an error if a method backing a field is not enabled on the listener
+	checkAccess func(method string) Error
+
+	schema *graphql.Schema
+}
+
+// newGraphQL parses the schema and binds it to the resolvers over the eth endpoint
+func newGraphQL(eth *Eth, checkAccess func(method string) Error) *graphQL {
+	g := &graphQL{
+		eth:         eth,
+		checkAccess: checkAccess,
+	}
+
+	g.schema = graphql.MustParseSchema(
+		gqlSchema,
+		&gqlRoot{g: g},
+		graphql.MaxDepth(gqlMaxDepth),
+		graphql.Logger(gqlPanicLogger{}),
+	)
+
+	return g
+}
+
+// gqlPanicLogger logs the panics recovered by the engine, such as the ones of the int
+// literals overflowing 32 bits, without their stack
+type gqlPanicLogger struct{}
+
+func (gqlPanicLogger) LogPanic(_ context.Context, value interface{}) {
+	logger.Debug("[RPC] graphql query panic", "err", value)
+}
+
+// gqlRequest is the body of a graphql request
+type gqlRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// execute runs the query of a request
+func (g *graphQL) execute(ctx context.Context, req *gqlRequest) *graphql.Response {
+	return g.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+}
+
+// gqlLong is the Long scalar, a 64 bit unsigned integer
+type gqlLong uint64
+
+func (gqlLong) ImplementsGraphQLType(name string) bool {
+	return name == "Long"
+}
+
+func (l *gqlLong) UnmarshalGraphQL(input interface{}) error {
+	switch input := input.(type) {
+	case string:
+		value, err := types.ParseUint64orHex(&input)
+		if err != nil {
+			return err
+		}
+
+		*l = gqlLong(value)
+	case int32:
+		if input < 0 {
+			return fmt.Errorf("negative long %d", input)
+		}
+
+		*l = gqlLong(input)
+	case float64:
+		if input < 0 || input != float64(uint64(input)) {
+			return fmt.Errorf("invalid long %v", input)
+		}
+
+		*l = gqlLong(input)
+	default:
+		return fmt.Errorf("unexpected type %T for Long", input)
+	}
+
+	return nil
+}
+
+func (l gqlLong) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.FormatUint(uint64(l), 10)), nil
+}
+
+// gqlBigInt is the BigInt scalar
+type gqlBigInt big.Int
+
+func toGQLBigInt(value *big.Int) gqlBigInt {
+	if value == nil {
+		return gqlBigInt{}
+	}
+
+	return gqlBigInt(*value)
+}
+
+func (gqlBigInt) ImplementsGraphQLType(name string) bool {
+	return name == "BigInt"
+}
+
+func (b *gqlBigInt) UnmarshalGraphQL(input interface{}) error {
+	switch input := input.(type) {
+	case string:
+		if _, ok := (*big.Int)(b).SetString(input, 0); !ok {
+			return fmt.Errorf("invalid big int %q", input)
+		}
+	case int32:
+		(*big.Int)(b).SetInt64(int64(input))
+	case float64:
+		if input != float64(int64(input)) {
+			return fmt.Errorf("invalid big int %v", input)
+		}
+
+		(*big.Int)(b).SetInt64(int64(input))
+	default:
+		return fmt.Errorf("unexpected type %T for BigInt", input)
+	}
+
+	return nil
+}
+
+func (b gqlBigInt) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeBig((*big.Int)(&b))), nil
+}
+
+// gqlBytes is the Bytes scalar
+type gqlBytes []byte
+
+func (gqlBytes) ImplementsGraphQLType(name string) bool {
+	return name == "Bytes"
+}
+
+func (b *gqlBytes) UnmarshalGraphQL(input interface{}) error {
+	raw, ok := input.(string)
+	if !ok {
+		return fmt.Errorf("unexpected type %T for Bytes", input)
+	}
+
+	data, err := hex.DecodeHex(raw)
+	if err != nil {
+		return err
+	}
+
+	*b = data
+
+	return nil
+}
+
+func (b gqlBytes) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToHex(b)), nil
+}
+
+// gqlBytes32 is the Bytes32 scalar
+type gqlBytes32 types.Hash
+
+func (gqlBytes32) ImplementsGraphQLType(name string) bool {
+	return name == "Bytes32"
+}
+
+func (b *gqlBytes32) UnmarshalGraphQL(input interface{}) error {
+	raw, ok := input.(string)
+	if !ok {
+		return fmt.Errorf("unexpected type %T for Bytes32", input)
+	}
+
+	return (*types.Hash)(b).UnmarshalText([]byte(raw))
+}
+
+func (b gqlBytes32) MarshalText() ([]byte, error) {
+	return []byte(types.Hash(b).String()), nil
+}
+
+// gqlAddress is the Address scalar
+type gqlAddress types.Address
+
+func (gqlAddress) ImplementsGraphQLType(name string) bool {
+	return name == "Address"
+}
+
+func (a *gqlAddress) UnmarshalGraphQL(input interface{}) error {
+	raw, ok := input.(string)
+	if !ok {
+		return fmt.Errorf("unexpected type %T for Address", input)
+	}
+
+	return (*types.Address)(a).UnmarshalText([]byte(raw))
+}
+
+func (a gqlAddress) MarshalText() ([]byte, error) {
+	return []byte(types.Address(a).String()), nil
+}
+
+// handleGraphQL serves the graphql queries, sent in the body of a post request
+// or in the query parameters of a get request
+func (h *httpHandler) handleGraphQL(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if origin := h.access.originAllowed(req.Header.Get("Origin")); origin != "" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Add("Vary", "Origin")
+	}
+
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set(
+		"Access-Control-Allow-Headers",
+		"Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization",
+	)
+
+	gqlReq := &gqlRequest{}
+
+	switch req.Method {
+	case http.MethodOptions:
+		return
+
+	case http.MethodGet:
+		query := req.URL.Query()
+		gqlReq.Query = query.Get("query")
+		gqlReq.OperationName = query.Get("operationName")
+
+		if variables := query.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &gqlReq.Variables); err != nil {
+				writeGraphQLError(w, http.StatusBadRequest, fmt.Errorf("invalid variables: %w", err))
+
+				return
+			}
+		}
+
+	case http.MethodPost:
+		body := io.Reader(req.Body)
+		if h.maxRequestSize != 0 {
+			body = io.LimitReader(req.Body, h.maxRequestSize+1)
+		}
+
+		data, err := ioutil.ReadAll(body)
+		if err != nil {
+			writeGraphQLError(w, http.StatusBadRequest, err)
+
+			return
+		}
+
+		if h.maxRequestSize != 0 && int64(len(data)) > h.maxRequestSize {
+			h.metrics.RejectedRequests.With("reason", rejectedBodySize).Add(1)
+			writeGraphQLError(w, http.StatusRequestEntityTooLarge,
+				fmt.Errorf("request body too large, the maximum is %d bytes", h.maxRequestSize))
+
+			return
+		}
+
+		if err := json.Unmarshal(data, gqlReq); err != nil {
+			writeGraphQLError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+
+			return
+		}
+
+	default:
+		writeGraphQLError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", req.Method))
+
+		return
+	}
+
+	start := time.Now()
+
+	resp := h.graphql.execute(req.Context(), gqlReq)
+
+	h.metrics.Requests.With("method", "graphql", "transport", serverHTTP.String()).Add(1)
+	h.metrics.RequestDuration.With("method", "graphql", "transport", serverHTTP.String()).
+		Observe(time.Since(start).Seconds())
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		writeGraphQLError(w, http.StatusInternalServerError, err)
+
+		return
+	}
+
+	// the queries which cannot be parsed or validated have no data
+	if resp.Data == nil {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+
+	//nolint
+	w.Write(data)
+}
+
+// writeGraphQLError writes the response of a graphql request which cannot be executed
+func writeGraphQLError(w http.ResponseWriter, status int, err error) {
+	data, _ := json.Marshal(&graphql.Response{
+		Errors: []*gqlerrors.QueryError{{Message: err.Error()}},
+	})
+
+	w.WriteHeader(status)
+	//nolint
+	w.Write(data)
+}
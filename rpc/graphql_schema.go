@@ -0,0 +1,1074 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/TIE-Tech/tie-core/common/hex"
+	"github.com/TIE-Tech/tie-core/state"
+	"github.com/TIE-Tech/tie-core/types"
+)
+
+// gqlSchema is the EIP-1767 schema, without the pending state
+const gqlSchema = `
+	# Bytes32 is a 32 byte binary string, represented as 0x-prefixed hexadecimal.
+	scalar Bytes32
+	# Address is a 20 byte address, represented as 0x-prefixed hexadecimal.
+	scalar Address
+	# Bytes is an arbitrary length binary string, represented as 0x-prefixed hexadecimal.
+	# An empty byte string is represented as '0x'.
+	scalar Bytes
+	# BigInt is a large integer. Input is accepted as either a JSON number or as a string.
+	# Strings may be either decimal or 0x-prefixed hexadecimal. Output values are all
+	# 0x-prefixed hexadecimal.
+	scalar BigInt
+	# Long is a 64 bit unsigned integer. Input is accepted as either a JSON number or as
+	# a string, decimal or 0x-prefixed hexadecimal. Output values are JSON numbers.
+	scalar Long
+
+	schema {
+		query: Query
+		mutation: Mutation
+	}
+
+	# Account is an account at a particular block.
+	type Account {
+		# Address is the address owning the account.
+		address: Address!
+		# Balance is the balance of the account, in wei.
+		balance: BigInt!
+		# TransactionCount is the nonce of the account.
+		transactionCount: Long!
+		# Code contains the smart contract code for this account, if the account is a contract.
+		code: Bytes!
+		# Storage provides access to the storage of a contract account, indexed by its 32 byte slot.
+		storage(slot: Bytes32!): Bytes32!
+	}
+
+	# Log is an event log.
+	type Log {
+		# Index is the index of this log in the block.
+		index: Int!
+		# Account is the contract which generated this log.
+		account(block: Long): Account!
+		# Topics is a list of 0-4 indexed topics for the log.
+		topics: [Bytes32!]!
+		# Data is unindexed data for this log.
+		data: Bytes!
+		# Transaction is the transaction that generated this log entry.
+		transaction: Transaction!
+	}
+
+	# Transaction is a transaction.
+	type Transaction {
+		# Hash is the hash of this transaction.
+		hash: Bytes32!
+		# Nonce is the nonce of the account this transaction was generated with.
+		nonce: Long!
+		# Index is the index of this transaction in the parent block, null if it is pending.
+		index: Int
+		# From is the account that sent this transaction.
+		from(block: Long): Account!
+		# To is the account the transaction was sent to, null for contract creations.
+		to(block: Long): Account
+		# Value is the value, in wei, sent along with this transaction.
+		value: BigInt!
+		# GasPrice is the price offered for gas, in wei per unit.
+		gasPrice: BigInt!
+		# Gas is the maximum amount of gas this transaction can consume.
+		gas: Long!
+		# InputData is the data supplied to the target of the transaction.
+		inputData: Bytes!
+		# Block is the block this transaction was sealed in, null if it is pending.
+		block: Block
+		# Status is 1 if the transaction succeeded, or 0 if it failed, null if it is pending.
+		status: Long
+		# GasUsed is the amount of gas that was used by this transaction, null if it is pending.
+		gasUsed: Long
+		# CumulativeGasUsed is the total gas used in the block up to and including this
+		# transaction, null if it is pending.
+		cumulativeGasUsed: Long
+		# CreatedContract is the account created by a contract creation transaction,
+		# null for the other transactions and if it is pending.
+		createdContract(block: Long): Account
+		# Logs is a list of log entries emitted by this transaction, null if it is pending.
+		logs: [Log!]
+		r: BigInt!
+		s: BigInt!
+		v: BigInt!
+	}
+
+	# BlockFilterCriteria encapsulates log filter criteria for a filter applied to a single block.
+	input BlockFilterCriteria {
+		# Addresses is list of addresses that are of interest, all the addresses if empty.
+		addresses: [Address!]
+		# Topics restricts matches to a prefix of the topics of the logs. An empty
+		# element matches any topic, the other ones any of the contained topics.
+		topics: [[Bytes32!]!]
+	}
+
+	# Block is a block.
+	type Block {
+		# Number is the number of this block, starting at 0 for the genesis block.
+		number: Long!
+		# Hash is the block hash of this block.
+		hash: Bytes32!
+		# Parent is the parent block of this block.
+		parent: Block
+		# Nonce is the block nonce.
+		nonce: Bytes!
+		# TransactionsRoot is the hash of the root of the trie of transactions in this block.
+		transactionsRoot: Bytes32!
+		# TransactionCount is the number of transactions in this block.
+		transactionCount: Int
+		# StateRoot is the hash of the state trie after this block was processed.
+		stateRoot: Bytes32!
+		# ReceiptsRoot is the hash of the trie of transaction receipts in this block.
+		receiptsRoot: Bytes32!
+		# Miner is the account that sealed this block.
+		miner(block: Long): Account!
+		# ExtraData is an arbitrary data field supplied by the miner.
+		extraData: Bytes!
+		# GasLimit is the maximum amount of gas that was available to transactions in this block.
+		gasLimit: Long!
+		# GasUsed is the amount of gas that was used executing transactions in this block.
+		gasUsed: Long!
+		# Timestamp is the unix timestamp at which this block was sealed.
+		timestamp: Long!
+		# LogsBloom is a bloom filter of the log entries of the block.
+		logsBloom: Bytes!
+		# MixHash is the mix hash of the block.
+		mixHash: Bytes32!
+		# Difficulty is the difficulty of this block.
+		difficulty: BigInt!
+		# TotalDifficulty is the sum of all difficulty values up to and including this block.
+		totalDifficulty: BigInt!
+		# OmmerCount is the number of ommers of this block, always 0.
+		ommerCount: Int
+		# Ommers is the list of ommers of this block, always empty.
+		ommers: [Block]
+		# OmmerAt returns the ommer at the specified index, always null.
+		ommerAt(index: Int!): Block
+		# OmmerHash is the hash of all the ommers of this block.
+		ommerHash: Bytes32!
+		# Transactions is a list of transactions associated with this block.
+		transactions: [Transaction!]
+		# TransactionAt returns the transaction at the specified index, null if out of bounds.
+		transactionAt(index: Int!): Transaction
+		# Logs returns a filtered set of logs from this block.
+		logs(filter: BlockFilterCriteria!): [Log!]!
+		# Account fetches an account at the current block's state.
+		account(address: Address!): Account!
+		# Call executes a local call operation at the current block's state.
+		call(data: CallData!): CallResult
+		# EstimateGas estimates the amount of gas required to execute a transaction
+		# at the current block's state.
+		estimateGas(data: CallData!): Long!
+	}
+
+	# CallData represents the data associated with a local contract call. All fields are optional.
+	input CallData {
+		# From is the address making the call.
+		from: Address
+		# To is the address the call is sent to.
+		to: Address
+		# Gas is the amount of gas sent with the call.
+		gas: Long
+		# GasPrice is the price, in wei, offered for each unit of gas.
+		gasPrice: BigInt
+		# Value is the value, in wei, sent along with the call.
+		value: BigInt
+		# Data is the data sent to the callee.
+		data: Bytes
+	}
+
+	# CallResult is the result of a local call operation.
+	type CallResult {
+		# Data is the return data of the called contract.
+		data: Bytes!
+		# GasUsed is the amount of gas used by the call, after any refunds.
+		gasUsed: Long!
+		# Status is the result of the call - 1 for success or 0 for failure.
+		status: Long!
+	}
+
+	# FilterCriteria encapsulates log filter criteria for searching log entries.
+	input FilterCriteria {
+		# FromBlock is the block at which to start searching, inclusive. Defaults to the latest block.
+		fromBlock: Long
+		# ToBlock is the block at which to stop searching, inclusive. Defaults to the latest block.
+		toBlock: Long
+		# Addresses is a list of addresses that are of interest, all the addresses if empty.
+		addresses: [Address!]
+		# Topics restricts matches to a prefix of the topics of the logs. An empty
+		# element matches any topic, the other ones any of the contained topics.
+		topics: [[Bytes32!]!]
+	}
+
+	# SyncState contains the current synchronisation state of the client.
+	type SyncState {
+		# StartingBlock is the block number at which synchronisation started.
+		startingBlock: Long!
+		# CurrentBlock is the point at which synchronisation has presently reached.
+		currentBlock: Long!
+		# HighestBlock is the latest known block number.
+		highestBlock: Long!
+		# PulledStates is the number of state entries fetched so far, null if unknown.
+		pulledStates: Long
+		# KnownStates is the number of states the node knows of so far, null if unknown.
+		knownStates: Long
+	}
+
+	type Query {
+		# Block fetches a block by number or by hash. If neither is supplied,
+		# the most recent known block is returned.
+		block(number: Long, hash: Bytes32): Block
+		# Blocks returns all the blocks between two numbers, inclusive. If
+		# to is not supplied, it defaults to the most recent known block.
+		blocks(from: Long!, to: Long): [Block!]!
+		# Transaction returns a transaction specified by its hash.
+		transaction(hash: Bytes32!): Transaction
+		# Logs returns log entries matching the provided filter.
+		logs(filter: FilterCriteria!): [Log!]!
+		# GasPrice returns the node's estimate of a gas price sufficient to
+		# ensure a transaction is sealed in a timely fashion.
+		gasPrice: BigInt!
+		# Syncing returns information on the current synchronisation state.
+		syncing: SyncState
+		# ChainID returns the current chain ID for transaction replay protection.
+		chainID: BigInt!
+	}
+
+	type Mutation {
+		# SendRawTransaction sends an RLP-encoded transaction to the network.
+		sendRawTransaction(data: Bytes!): Bytes32!
+	}
+`
+
+// gqlRoot resolves the fields of the queries and of the mutations
+type gqlRoot struct {
+	g *graphQL
+}
+
+func (r *gqlRoot) Block(args struct {
+	Number *gqlLong
+	Hash   *gqlBytes32
+}) (*gqlBlock, error) {
+	g := r.g
+
+	if err := g.checkAccess("eth_getBlockByNumber"); err != nil {
+		return nil, err
+	}
+
+	var (
+		block *types.Block
+		ok    bool
+	)
+
+	switch {
+	case args.Hash != nil:
+		block, ok = g.eth.store.GetBlockByHash(types.Hash(*args.Hash), true)
+	case args.Number != nil:
+		block, ok = g.eth.store.GetBlockByNumber(uint64(*args.Number), true)
+	default:
+		block, ok = g.eth.store.GetBlockByNumber(g.eth.store.Header().Number, true)
+	}
+
+	if !ok {
+		return nil, nil
+	}
+
+	return g.block(block), nil
+}
+
+func (r *gqlRoot) Blocks(args struct {
+	From gqlLong
+	To   *gqlLong
+}) ([]*gqlBlock, error) {
+	g := r.g
+
+	if err := g.checkAccess("eth_getBlockByNumber"); err != nil {
+		return nil, err
+	}
+
+	from, to := uint64(args.From), g.eth.store.Header().Number
+	if args.To != nil {
+		to = uint64(*args.To)
+	}
+
+	if to < from {
+		return nil, fmt.Errorf("incorrect range")
+	}
+
+	if g.eth.maxBlockRange > 0 && to-from >= g.eth.maxBlockRange {
+		return nil, fmt.Errorf("block range too large, the maximum is %d blocks", g.eth.maxBlockRange)
+	}
+
+	blocks := []*gqlBlock{}
+
+	for number := from; number <= to; number++ {
+		block, ok := g.eth.store.GetBlockByNumber(number, true)
+		if !ok {
+			break
+		}
+
+		blocks = append(blocks, g.block(block))
+	}
+
+	return blocks, nil
+}
+
+func (r *gqlRoot) Transaction(args struct{ Hash gqlBytes32 }) (*gqlTransaction, error) {
+	if err := r.g.checkAccess("eth_getTransactionByHash"); err != nil {
+		return nil, err
+	}
+
+	return r.g.transaction(types.Hash(args.Hash)), nil
+}
+
+func (r *gqlRoot) Logs(args struct{ Filter gqlFilterCriteria }) ([]*gqlLog, error) {
+	if err := r.g.checkAccess("eth_getLogs"); err != nil {
+		return nil, err
+	}
+
+	filter := &LogFilter{
+		fromBlock: LatestBlockNumber,
+		toBlock:   LatestBlockNumber,
+	}
+
+	if args.Filter.FromBlock != nil {
+		filter.fromBlock = BlockNumber(*args.Filter.FromBlock)
+	}
+
+	if args.Filter.ToBlock != nil {
+		filter.toBlock = BlockNumber(*args.Filter.ToBlock)
+	}
+
+	return r.g.logs(filter, args.Filter.Addresses, args.Filter.Topics)
+}
+
+func (r *gqlRoot) GasPrice() (gqlBigInt, error) {
+	if err := r.g.checkAccess("eth_gasPrice"); err != nil {
+		return gqlBigInt{}, err
+	}
+
+	return toGQLBigInt(r.g.eth.gasPriceOracle.SuggestPrice()), nil
+}
+
+func (r *gqlRoot) ChainID() (gqlBigInt, error) {
+	if err := r.g.checkAccess("eth_chainId"); err != nil {
+		return gqlBigInt{}, err
+	}
+
+	return toGQLBigInt(new(big.Int).SetUint64(r.g.eth.chainID)), nil
+}
+
+func (r *gqlRoot) Syncing() (*gqlSyncState, error) {
+	if err := r.g.checkAccess("eth_syncing"); err != nil {
+		return nil, err
+	}
+
+	progression := r.g.eth.store.GetSyncProgression()
+	if progression == nil {
+		return nil, nil
+	}
+
+	return &gqlSyncState{
+		startingBlock: progression.StartingBlock,
+		currentBlock:  progression.CurrentBlock,
+		highestBlock:  progression.HighestBlock,
+	}, nil
+}
+
+func (r *gqlRoot) SendRawTransaction(args struct{ Data gqlBytes }) (gqlBytes32, error) {
+	if err := r.g.checkAccess("eth_sendRawTransaction"); err != nil {
+		return gqlBytes32{}, err
+	}
+
+	res, err := r.g.eth.SendRawTransaction(hex.EncodeToHex(args.Data))
+	if err != nil {
+		return gqlBytes32{}, err
+	}
+
+	hash, _ := res.(string)
+
+	return gqlBytes32(types.StringToHash(hash)), nil
+}
+
+// gqlFilterCriteria is the FilterCriteria input
+type gqlFilterCriteria struct {
+	FromBlock *gqlLong
+	ToBlock   *gqlLong
+	Addresses *[]gqlAddress
+	Topics    *[][]gqlBytes32
+}
+
+// gqlBlockFilterCriteria is the BlockFilterCriteria input
+type gqlBlockFilterCriteria struct {
+	Addresses *[]gqlAddress
+	Topics    *[][]gqlBytes32
+}
+
+// gqlCallData is the CallData input
+type gqlCallData struct {
+	From     *gqlAddress
+	To       *gqlAddress
+	Gas      *gqlLong
+	GasPrice *gqlBigInt
+	Value    *gqlBigInt
+	Data     *gqlBytes
+}
+
+// txnArgs returns the call data as the arguments of a call
+func (c *gqlCallData) txnArgs() *txnArgs {
+	arg := &txnArgs{}
+
+	if c.From != nil {
+		from := types.Address(*c.From)
+		arg.From = &from
+	}
+
+	if c.To != nil {
+		to := types.Address(*c.To)
+		arg.To = &to
+	}
+
+	if c.Gas != nil {
+		arg.Gas = argUintPtr(uint64(*c.Gas))
+	}
+
+	if c.GasPrice != nil {
+		arg.GasPrice = argBytesPtr((*big.Int)(c.GasPrice).Bytes())
+	}
+
+	if c.Value != nil {
+		arg.Value = argBytesPtr((*big.Int)(c.Value).Bytes())
+	}
+
+	if c.Data != nil {
+		arg.Data = argBytesPtr(*c.Data)
+	}
+
+	return arg
+}
+
+// block returns the resolver of a sealed block
+func (g *graphQL) block(block *types.Block) *gqlBlock {
+	return &gqlBlock{g: g, block: block}
+}
+
+// transaction returns a sealed or a pending transaction, nil if it is not found
+func (g *graphQL) transaction(hash types.Hash) *gqlTransaction {
+	if blockHash, ok := g.eth.store.ReadTxLookup(hash); ok {
+		if block, ok := g.eth.store.GetBlockByHash(blockHash, true); ok {
+			b := g.block(block)
+
+			for idx, txn := range block.Transactions {
+				if txn.Hash == hash {
+					return &gqlTransaction{g: g, txn: txn, block: b, index: idx}
+				}
+			}
+		}
+	}
+
+	if txn, ok := g.eth.store.GetPendingTx(hash); ok {
+		return &gqlTransaction{g: g, txn: txn}
+	}
+
+	return nil
+}
+
+// logs returns the logs matching a filter, with the addresses and the topics of a filter criteria
+func (g *graphQL) logs(filter *LogFilter, addresses *[]gqlAddress, topics *[][]gqlBytes32) ([]*gqlLog, error) {
+	if addresses != nil {
+		for _, addr := range *addresses {
+			filter.Addresses = append(filter.Addresses, types.Address(addr))
+		}
+	}
+
+	if topics != nil {
+		for _, set := range *topics {
+			raw := make([]string, len(set))
+			for i, topic := range set {
+				raw[i] = types.Hash(topic).String()
+			}
+
+			if err := filter.addTopicSet(raw...); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	res, err := g.eth.GetLogs(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	logs, _ := res.([]*Log)
+	objs := make([]*gqlLog, len(logs))
+
+	for i, log := range logs {
+		objs[i] = &gqlLog{g: g, log: log}
+	}
+
+	return objs, nil
+}
+
+// account returns an account at the state of a header, or at the state of a block number if set
+func (g *graphQL) account(address types.Address, header *types.Header, number *gqlLong) (*gqlAccount, error) {
+	if number != nil {
+		h, ok := g.eth.store.GetHeaderByNumber(uint64(*number))
+		if !ok {
+			return nil, fmt.Errorf("block %d not found", uint64(*number))
+		}
+
+		header = h
+	}
+
+	return &gqlAccount{g: g, address: address, header: header}, nil
+}
+
+// gqlBlock is a sealed block
+type gqlBlock struct {
+	g     *graphQL
+	block *types.Block
+
+	// receipts are loaded with the first field requiring them, the fields are resolved concurrently
+	receiptsLock sync.Mutex
+	receipts     []*types.Receipt
+}
+
+func (b *gqlBlock) Number() gqlLong {
+	return gqlLong(b.block.Header.Number)
+}
+
+func (b *gqlBlock) Hash() gqlBytes32 {
+	return gqlBytes32(b.block.Header.Hash)
+}
+
+func (b *gqlBlock) Parent() *gqlBlock {
+	h := b.block.Header
+	if h.Number == 0 {
+		return nil
+	}
+
+	parent, ok := b.g.eth.store.GetBlockByHash(h.ParentHash, true)
+	if !ok {
+		return nil
+	}
+
+	return b.g.block(parent)
+}
+
+func (b *gqlBlock) Nonce() gqlBytes {
+	return gqlBytes(b.block.Header.Nonce[:])
+}
+
+func (b *gqlBlock) TransactionsRoot() gqlBytes32 {
+	return gqlBytes32(b.block.Header.TxRoot)
+}
+
+func (b *gqlBlock) TransactionCount() *int32 {
+	count := int32(len(b.block.Transactions))
+
+	return &count
+}
+
+func (b *gqlBlock) StateRoot() gqlBytes32 {
+	return gqlBytes32(b.block.Header.StateRoot)
+}
+
+func (b *gqlBlock) ReceiptsRoot() gqlBytes32 {
+	return gqlBytes32(b.block.Header.ReceiptsRoot)
+}
+
+func (b *gqlBlock) Miner(args struct{ Block *gqlLong }) (*gqlAccount, error) {
+	return b.g.account(b.block.Header.Miner, b.block.Header, args.Block)
+}
+
+func (b *gqlBlock) ExtraData() gqlBytes {
+	return gqlBytes(b.block.Header.ExtraData)
+}
+
+func (b *gqlBlock) GasLimit() gqlLong {
+	return gqlLong(b.block.Header.GasLimit)
+}
+
+func (b *gqlBlock) GasUsed() gqlLong {
+	return gqlLong(b.block.Header.GasUsed)
+}
+
+func (b *gqlBlock) Timestamp() gqlLong {
+	return gqlLong(b.block.Header.Timestamp)
+}
+
+func (b *gqlBlock) LogsBloom() gqlBytes {
+	return gqlBytes(b.block.Header.LogsBloom[:])
+}
+
+func (b *gqlBlock) MixHash() gqlBytes32 {
+	return gqlBytes32(b.block.Header.MixHash)
+}
+
+func (b *gqlBlock) Difficulty() gqlBigInt {
+	return toGQLBigInt(new(big.Int).SetUint64(b.block.Header.Difficulty))
+}
+
+func (b *gqlBlock) TotalDifficulty() gqlBigInt {
+	return b.Difficulty()
+}
+
+func (b *gqlBlock) OmmerCount() *int32 {
+	count := int32(0)
+
+	return &count
+}
+
+func (b *gqlBlock) Ommers() *[]*gqlBlock {
+	return &[]*gqlBlock{}
+}
+
+func (b *gqlBlock) OmmerAt(args struct{ Index int32 }) *gqlBlock {
+	return nil
+}
+
+func (b *gqlBlock) OmmerHash() gqlBytes32 {
+	return gqlBytes32(b.block.Header.Sha3Uncles)
+}
+
+func (b *gqlBlock) Transactions() *[]*gqlTransaction {
+	txns := make([]*gqlTransaction, len(b.block.Transactions))
+	for idx, txn := range b.block.Transactions {
+		txns[idx] = &gqlTransaction{g: b.g, txn: txn, block: b, index: idx}
+	}
+
+	return &txns
+}
+
+func (b *gqlBlock) TransactionAt(args struct{ Index int32 }) *gqlTransaction {
+	if args.Index < 0 || int(args.Index) >= len(b.block.Transactions) {
+		return nil
+	}
+
+	index := int(args.Index)
+
+	return &gqlTransaction{g: b.g, txn: b.block.Transactions[index], block: b, index: index}
+}
+
+func (b *gqlBlock) Logs(args struct{ Filter gqlBlockFilterCriteria }) ([]*gqlLog, error) {
+	if err := b.g.checkAccess("eth_getLogs"); err != nil {
+		return nil, err
+	}
+
+	hash := b.block.Hash()
+
+	return b.g.logs(&LogFilter{BlockHash: &hash}, args.Filter.Addresses, args.Filter.Topics)
+}
+
+func (b *gqlBlock) Account(args struct{ Address gqlAddress }) (*gqlAccount, error) {
+	return b.g.account(types.Address(args.Address), b.block.Header, nil)
+}
+
+func (b *gqlBlock) Call(ctx context.Context, args struct{ Data gqlCallData }) (*gqlCallResult, error) {
+	if err := b.g.checkAccess("eth_call"); err != nil {
+		return nil, err
+	}
+
+	txn, err := b.g.eth.decodeTxn(args.Data.txnArgs())
+	if err != nil {
+		return nil, err
+	}
+
+	if txn.Gas == 0 {
+		txn.Gas = b.block.Header.GasLimit
+	}
+
+	callCtx, cancel := b.g.eth.callContext()
+	defer cancel()
+
+	result, err := b.g.eth.applyTxn(callCtx, b.block.Header, false, txn, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	status := gqlLong(1)
+	if result.Failed() {
+		status = 0
+	}
+
+	return &gqlCallResult{
+		data:    result.ReturnValue,
+		gasUsed: gqlLong(result.GasUsed),
+		status:  status,
+	}, nil
+}
+
+func (b *gqlBlock) EstimateGas(args struct{ Data gqlCallData }) (gqlLong, error) {
+	if err := b.g.checkAccess("eth_estimateGas"); err != nil {
+		return 0, err
+	}
+
+	number := BlockNumber(b.block.Header.Number)
+
+	res, err := b.g.eth.EstimateGas(args.Data.txnArgs(), &number, nil, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	gas, _ := res.(string)
+
+	value, err := types.ParseUint64orHex(&gas)
+	if err != nil {
+		return 0, err
+	}
+
+	return gqlLong(value), nil
+}
+
+// receipt returns the receipt of a transaction of the block, and the receipts of the block
+func (b *gqlBlock) receipt(index int) (*types.Receipt, []*types.Receipt, error) {
+	b.receiptsLock.Lock()
+	defer b.receiptsLock.Unlock()
+
+	if b.receipts == nil {
+		receipts, err := b.g.eth.store.GetReceiptsByHash(b.block.Hash())
+		if err != nil {
+			return nil, nil, err
+		}
+
+		b.receipts = receipts
+	}
+
+	if index >= len(b.receipts) {
+		return nil, b.receipts, nil
+	}
+
+	return b.receipts[index], b.receipts, nil
+}
+
+// gqlTransaction is a sealed transaction, or a pending one without a block
+type gqlTransaction struct {
+	g     *graphQL
+	txn   *types.Transaction
+	block *gqlBlock
+	index int
+}
+
+func (t *gqlTransaction) Hash() gqlBytes32 {
+	return gqlBytes32(t.txn.Hash)
+}
+
+func (t *gqlTransaction) Nonce() gqlLong {
+	return gqlLong(t.txn.Nonce)
+}
+
+func (t *gqlTransaction) Index() *int32 {
+	if t.block == nil {
+		return nil
+	}
+
+	index := int32(t.index)
+
+	return &index
+}
+
+func (t *gqlTransaction) From(args struct{ Block *gqlLong }) (*gqlAccount, error) {
+	return t.g.account(t.txn.From, t.header(), args.Block)
+}
+
+func (t *gqlTransaction) To(args struct{ Block *gqlLong }) (*gqlAccount, error) {
+	if t.txn.To == nil {
+		return nil, nil
+	}
+
+	return t.g.account(*t.txn.To, t.header(), args.Block)
+}
+
+func (t *gqlTransaction) Value() gqlBigInt {
+	return toGQLBigInt(t.txn.Value)
+}
+
+func (t *gqlTransaction) GasPrice() gqlBigInt {
+	return toGQLBigInt(t.txn.GasPrice)
+}
+
+func (t *gqlTransaction) Gas() gqlLong {
+	return gqlLong(t.txn.Gas)
+}
+
+func (t *gqlTransaction) InputData() gqlBytes {
+	return gqlBytes(t.txn.Input)
+}
+
+func (t *gqlTransaction) Block() *gqlBlock {
+	return t.block
+}
+
+func (t *gqlTransaction) Status() (*gqlLong, error) {
+	receipt, err := t.receipt()
+	if err != nil || receipt == nil || receipt.Status == nil {
+		return nil, err
+	}
+
+	status := gqlLong(*receipt.Status)
+
+	return &status, nil
+}
+
+func (t *gqlTransaction) GasUsed() (*gqlLong, error) {
+	receipt, err := t.receipt()
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+
+	gasUsed := gqlLong(receipt.GasUsed)
+
+	return &gasUsed, nil
+}
+
+func (t *gqlTransaction) CumulativeGasUsed() (*gqlLong, error) {
+	receipt, err := t.receipt()
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+
+	gasUsed := gqlLong(receipt.CumulativeGasUsed)
+
+	return &gasUsed, nil
+}
+
+func (t *gqlTransaction) CreatedContract(args struct{ Block *gqlLong }) (*gqlAccount, error) {
+	receipt, err := t.receipt()
+	if err != nil || receipt == nil || t.txn.To != nil {
+		return nil, err
+	}
+
+	return t.g.account(receipt.ContractAddress, t.header(), args.Block)
+}
+
+func (t *gqlTransaction) Logs() (*[]*gqlLog, error) {
+	if t.block == nil {
+		return nil, nil
+	}
+
+	receipt, receipts, err := t.block.receipt(t.index)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+
+	logIndex := firstLogIndex(receipts, t.index)
+	block := t.block.block
+
+	logs := make([]*gqlLog, len(receipt.Logs))
+	for i, log := range receipt.Logs {
+		logs[i] = &gqlLog{
+			g: t.g,
+			log: &Log{
+				Address:     log.Address,
+				Topics:      log.Topics,
+				Data:        argBytes(log.Data),
+				BlockNumber: argUint64(block.Number()),
+				TxHash:      t.txn.Hash,
+				TxIndex:     argUint64(t.index),
+				BlockHash:   block.Hash(),
+				LogIndex:    argUint64(logIndex + uint64(i)),
+			},
+		}
+	}
+
+	return &logs, nil
+}
+
+func (t *gqlTransaction) R() gqlBigInt {
+	return toGQLBigInt(t.txn.R)
+}
+
+func (t *gqlTransaction) S() gqlBigInt {
+	return toGQLBigInt(t.txn.S)
+}
+
+func (t *gqlTransaction) V() gqlBigInt {
+	return toGQLBigInt(t.txn.V)
+}
+
+// header returns the header of the block of the transaction, the latest one if it is pending
+func (t *gqlTransaction) header() *types.Header {
+	if t.block == nil {
+		return t.g.eth.store.Header()
+	}
+
+	return t.block.block.Header
+}
+
+// receipt returns the receipt of the transaction, nil if it is pending
+func (t *gqlTransaction) receipt() (*types.Receipt, error) {
+	if t.block == nil {
+		return nil, nil
+	}
+
+	receipt, _, err := t.block.receipt(t.index)
+
+	return receipt, err
+}
+
+// gqlAccount is an account at the state of a block
+type gqlAccount struct {
+	g       *graphQL
+	address types.Address
+	header  *types.Header
+}
+
+func (a *gqlAccount) Address() gqlAddress {
+	return gqlAddress(a.address)
+}
+
+func (a *gqlAccount) Balance() (gqlBigInt, error) {
+	acc, err := a.account()
+	if err != nil {
+		return gqlBigInt{}, err
+	}
+
+	return toGQLBigInt(acc.Balance), nil
+}
+
+func (a *gqlAccount) TransactionCount() (gqlLong, error) {
+	acc, err := a.account()
+	if err != nil {
+		return 0, err
+	}
+
+	return gqlLong(acc.Nonce), nil
+}
+
+func (a *gqlAccount) Code() (gqlBytes, error) {
+	acc, err := a.account()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(acc.CodeHash) == 0 {
+		return gqlBytes{}, nil
+	}
+
+	code, err := a.g.eth.store.GetCode(types.BytesToHash(acc.CodeHash))
+	if err != nil {
+		return gqlBytes{}, nil
+	}
+
+	return gqlBytes(code), nil
+}
+
+func (a *gqlAccount) Storage(args struct{ Slot gqlBytes32 }) (gqlBytes32, error) {
+	res, err := a.g.eth.GetStorageAt(a.address, types.Hash(args.Slot), BlockNumberOrHash{BlockHash: &a.header.Hash})
+	if err != nil {
+		return gqlBytes32{}, err
+	}
+
+	value, _ := res.(*argBytes)
+
+	return gqlBytes32(types.BytesToHash(*value)), nil
+}
+
+// account reads the account at the state of the block, the accounts which do not exist are empty
+func (a *gqlAccount) account() (*state.Account, error) {
+	acc, err := a.g.eth.store.GetAccount(a.header.StateRoot, a.address)
+	if errors.Is(err, ErrStateNotFound) {
+		return &state.Account{Balance: big.NewInt(0)}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return acc, nil
+}
+
+// gqlLog is a log of a sealed transaction
+type gqlLog struct {
+	g   *graphQL
+	log *Log
+}
+
+func (l *gqlLog) Index() int32 {
+	return int32(l.log.LogIndex)
+}
+
+func (l *gqlLog) Account(args struct{ Block *gqlLong }) (*gqlAccount, error) {
+	header, ok := l.g.eth.store.GetHeaderByNumber(uint64(l.log.BlockNumber))
+	if !ok {
+		return nil, fmt.Errorf("block %d not found", uint64(l.log.BlockNumber))
+	}
+
+	return l.g.account(l.log.Address, header, args.Block)
+}
+
+func (l *gqlLog) Topics() []gqlBytes32 {
+	topics := make([]gqlBytes32, len(l.log.Topics))
+	for i, topic := range l.log.Topics {
+		topics[i] = gqlBytes32(topic)
+	}
+
+	return topics
+}
+
+func (l *gqlLog) Data() gqlBytes {
+	return gqlBytes(l.log.Data)
+}
+
+func (l *gqlLog) Transaction() (*gqlTransaction, error) {
+	txn := l.g.transaction(l.log.TxHash)
+	if txn == nil {
+		return nil, fmt.Errorf("transaction %s not found", l.log.TxHash)
+	}
+
+	return txn, nil
+}
+
+// gqlCallResult is the result of a call
+type gqlCallResult struct {
+	data    []byte
+	gasUsed gqlLong
+	status  gqlLong
+}
+
+func (c *gqlCallResult) Data() gqlBytes {
+	return gqlBytes(c.data)
+}
+
+func (c *gqlCallResult) GasUsed() gqlLong {
+	return c.gasUsed
+}
+
+func (c *gqlCallResult) Status() gqlLong {
+	return c.status
+}
+
+// gqlSyncState is the progression of the sync
+type gqlSyncState struct {
+	startingBlock uint64
+	currentBlock  uint64
+	highestBlock  uint64
+}
+
+func (s *gqlSyncState) StartingBlock() gqlLong {
+	return gqlLong(s.startingBlock)
+}
+
+func (s *gqlSyncState) CurrentBlock() gqlLong {
+	return gqlLong(s.currentBlock)
+}
+
+func (s *gqlSyncState) HighestBlock() gqlLong {
+	return gqlLong(s.highestBlock)
+}
+
+// PulledStates is not tracked by the syncer
+func (s *gqlSyncState) PulledStates() *gqlLong {
+	return nil
+}
+
+// KnownStates is not tracked by the syncer
+func (s *gqlSyncState) KnownStates() *gqlLong {
+	return nil
+}
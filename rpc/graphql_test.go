@@ -0,0 +1,352 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/TIE-Tech/tie-core/metrics"
+	"github.com/TIE-Tech/tie-core/state"
+	"github.com/TIE-Tech/tie-core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockGraphQLStore struct {
+	ethStore
+	blocks   []*types.Block
+	receipts map[types.Hash][]*types.Receipt
+	accounts map[types.Address]*state.Account
+	pending  map[types.Hash]*types.Transaction
+}
+
+func newMockGraphQLStore() *mockGraphQLStore {
+	status := types.ReceiptSuccess
+	txn := &types.Transaction{
+		Nonce:    3,
+		GasPrice: big.NewInt(10),
+		Gas:      21000,
+		To:       &addr1,
+		Value:    big.NewInt(100),
+		Input:    []byte{0x1},
+		From:     addr0,
+		Hash:     hash1,
+	}
+
+	store := &mockGraphQLStore{
+		receipts: map[types.Hash][]*types.Receipt{},
+		accounts: map[types.Address]*state.Account{
+			addr0: {Nonce: 4, Balance: big.NewInt(1000)},
+		},
+		pending: map[types.Hash]*types.Transaction{},
+	}
+
+	for i := uint64(0); i < 3; i++ {
+		block := &types.Block{
+			Header: &types.Header{
+				Number:     i,
+				Hash:       types.BytesToHash([]byte{0xb, byte(i)}),
+				ParentHash: types.BytesToHash([]byte{0xb, byte(i - 1)}),
+				GasLimit:   1000000,
+			},
+		}
+
+		store.blocks = append(store.blocks, block)
+	}
+
+	block := store.blocks[2]
+	block.Transactions = []*types.Transaction{txn}
+	block.Header.LogsBloom = types.CreateBloom([]*types.Receipt{{Logs: []*types.Log{{Address: addr1}}}})
+	store.receipts[block.Hash()] = []*types.Receipt{
+		{
+			Status:            &status,
+			GasUsed:           21000,
+			CumulativeGasUsed: 21000,
+			Logs: []*types.Log{
+				{Address: addr1, Topics: []types.Hash{hash2}, Data: []byte{0x2}},
+			},
+		},
+	}
+
+	return store
+}
+
+func (m *mockGraphQLStore) Header() *types.Header {
+	return m.blocks[len(m.blocks)-1].Header
+}
+
+func (m *mockGraphQLStore) GetHeaderByNumber(num uint64) (*types.Header, bool) {
+	if num >= uint64(len(m.blocks)) {
+		return nil, false
+	}
+
+	return m.blocks[num].Header, true
+}
+
+func (m *mockGraphQLStore) GetBlockByNumber(num uint64, full bool) (*types.Block, bool) {
+	if num >= uint64(len(m.blocks)) {
+		return nil, false
+	}
+
+	return m.blocks[num], true
+}
+
+func (m *mockGraphQLStore) GetBlockByHash(hash types.Hash, full bool) (*types.Block, bool) {
+	for _, block := range m.blocks {
+		if block.Hash() == hash {
+			return block, true
+		}
+	}
+
+	return nil, false
+}
+
+func (m *mockGraphQLStore) ReadTxLookup(hash types.Hash) (types.Hash, bool) {
+	for _, block := range m.blocks {
+		for _, txn := range block.Transactions {
+			if txn.Hash == hash {
+				return block.Hash(), true
+			}
+		}
+	}
+
+	return types.Hash{}, false
+}
+
+func (m *mockGraphQLStore) GetReceiptsByHash(hash types.Hash) ([]*types.Receipt, error) {
+	return m.receipts[hash], nil
+}
+
+func (m *mockGraphQLStore) GetPendingTx(hash types.Hash) (*types.Transaction, bool) {
+	txn, ok := m.pending[hash]
+
+	return txn, ok
+}
+
+func (m *mockGraphQLStore) GetAccount(root types.Hash, addr types.Address) (*state.Account, error) {
+	if acc, ok := m.accounts[addr]; ok {
+		return acc, nil
+	}
+
+	return nil, ErrStateNotFound
+}
+
+func (m *mockGraphQLStore) BloomBitsSections() uint64 {
+	return 0
+}
+
+func newTestGraphQL(store ethStore, checkAccess func(string) Error) *graphQL {
+	if checkAccess == nil {
+		checkAccess = func(string) Error { return nil }
+	}
+
+	return newGraphQL(newTestEthEndpoint(store), checkAccess)
+}
+
+func executeGraphQL(t *testing.T, g *graphQL, req *gqlRequest) string {
+	t.Helper()
+
+	data, err := json.Marshal(g.execute(context.Background(), req))
+	assert.NoError(t, err)
+
+	return string(data)
+}
+
+func TestGraphQL_Schema(t *testing.T) {
+	g := newTestGraphQL(newMockGraphQLStore(), nil)
+
+	// introspection
+	res := executeGraphQL(t, g, &gqlRequest{
+		Query: `{
+			__schema { queryType { name } mutationType { name } }
+			__type(name: "CallResult") { fields { name } }
+		}`,
+	})
+
+	assert.JSONEq(t, `{"data":{
+		"__schema":{"queryType":{"name":"Query"},"mutationType":{"name":"Mutation"}},
+		"__type":{"fields":[{"name":"data"},{"name":"gasUsed"},{"name":"status"}]}
+	}}`, res)
+
+	// the nesting of the queries is limited
+	nested := func(depth int) *gqlRequest {
+		return &gqlRequest{
+			Query: "{ block {" + strings.Repeat(" parent {", depth-2) + " number" + strings.Repeat(" }", depth-1) + " }",
+		}
+	}
+
+	assert.NotContains(t, executeGraphQL(t, g, nested(gqlMaxDepth)), "errors")
+	assert.Contains(t, executeGraphQL(t, g, nested(gqlMaxDepth+1)), "errors")
+}
+
+func TestGraphQL_Block(t *testing.T) {
+	g := newTestGraphQL(newMockGraphQLStore(), nil)
+
+	res := executeGraphQL(t, g, &gqlRequest{
+		Query: `{
+			block(number: 2) {
+				number
+				parent { number }
+				transactionCount
+				transactions {
+					hash
+					index
+					from { address balance transactionCount }
+					to { balance }
+					value
+					status
+					gasUsed
+					logs { index topics data }
+				}
+				account(address: "` + addr1.String() + `") { balance }
+			}
+		}`,
+	})
+
+	assert.JSONEq(t, `{"data":{"block":{
+		"number":2,
+		"parent":{"number":1},
+		"transactionCount":1,
+		"transactions":[{
+			"hash":"`+hash1.String()+`",
+			"index":0,
+			"from":{"address":"`+addr0.String()+`","balance":"0x3e8","transactionCount":4},
+			"to":{"balance":"0x0"},
+			"value":"0x64",
+			"status":1,
+			"gasUsed":21000,
+			"logs":[{"index":0,"topics":["`+hash2.String()+`"],"data":"0x02"}]
+		}],
+		"account":{"balance":"0x0"}
+	}}}`, res)
+
+	// the fields are in the order of the query
+	assert.True(t, strings.HasPrefix(res, `{"data":{"block":{"number":2,"parent":`))
+}
+
+func TestGraphQL_Query(t *testing.T) {
+	store := newMockGraphQLStore()
+	store.pending[hash3] = &types.Transaction{Hash: hash3, From: addr0}
+
+	g := newTestGraphQL(store, nil)
+
+	// variables, aliases, fragments and directives
+	res := executeGraphQL(t, g, &gqlRequest{
+		Query: `
+			query Q($hash: Bytes32!, $skip: Boolean!) {
+				sealed: transaction(hash: $hash) { ...tx }
+				pending: transaction(hash: "` + hash3.String() + `") { ...tx block { number } }
+				unknown: transaction(hash: "` + types.StringToHash("4").String() + `") { hash }
+				blocks(from: 1) { number, hash @skip(if: $skip) }
+				__typename
+			}
+
+			fragment tx on Transaction { __typename hash block { number } }
+		`,
+		Variables: map[string]interface{}{"hash": hash1.String(), "skip": true},
+	})
+
+	assert.JSONEq(t, `{"data":{
+		"sealed":{"__typename":"Transaction","hash":"`+hash1.String()+`","block":{"number":2}},
+		"pending":{"__typename":"Transaction","hash":"`+hash3.String()+`","block":null},
+		"unknown":null,
+		"blocks":[{"number":1},{"number":2}],
+		"__typename":"Query"
+	}}`, res)
+
+	// logs of a range
+	res = executeGraphQL(t, g, &gqlRequest{
+		Query: `{ logs(filter: {fromBlock: 0, toBlock: "0x2", addresses: ["` + addr1.String() + `"]}) {
+			account { address }
+			transaction { hash }
+		} }`,
+	})
+
+	assert.JSONEq(t, `{"data":{"logs":[{
+		"account":{"address":"`+addr1.String()+`"},
+		"transaction":{"hash":"`+hash1.String()+`"}
+	}]}}`, res)
+}
+
+func TestGraphQL_Errors(t *testing.T) {
+	// the fields of the methods which are not enabled are denied
+	g := newTestGraphQL(newMockGraphQLStore(), func(method string) Error {
+		if method == "eth_getTransactionByHash" {
+			return NewMethodNotFoundError(method)
+		}
+
+		return nil
+	})
+
+	res := executeGraphQL(t, g, &gqlRequest{
+		Query: `{ block { number } transaction(hash: "` + hash1.String() + `") { hash } }`,
+	})
+
+	assert.JSONEq(t, `{
+		"data":{"block":{"number":2},"transaction":null},
+		"errors":[{"message":"the method eth_getTransactionByHash does not exist/is not available","path":["transaction"]}]
+	}`, res)
+
+	// the errors of the non null fields are propagated to the parent
+	res = executeGraphQL(t, g, &gqlRequest{
+		Query: `{ block { number } blocks(from: 2, to: 1) { number } }`,
+	})
+
+	assert.JSONEq(t, `{"data":null,"errors":[{"message":"incorrect range","path":["blocks"]}]}`, res)
+
+	// the requests which cannot be executed
+	for _, req := range []*gqlRequest{
+		{Query: `{ block { number }`},
+		{Query: `{ block { number unknown } }`},
+		{Query: `{ block(number: 99999999999) { number } }`},
+		{Query: `{ block(hash: 1) { number } }`},
+		{Query: `query A { chainID } query B { chainID }`},
+		{Query: `{ chainID }`, OperationName: "A"},
+		{Query: `subscription { chainID }`},
+	} {
+		assert.NotEmpty(t, g.execute(context.Background(), req).Errors, req.Query)
+	}
+}
+
+func TestHTTPHandler_GraphQL(t *testing.T) {
+	srv := &JSONRPC{
+		config: &Config{GraphQL: true},
+		dispatcher: newDispatcher(nil, &dispatcherParams{
+			metrics: metrics.NewRPCMetrics(),
+		}),
+	}
+	srv.dispatcher.endpoints.Eth = newTestEthEndpoint(newMockGraphQLStore())
+
+	h := srv.newHTTPHandler(AccessConfig{Methods: []string{"eth_getBlockByNumber"}}, nil)
+
+	call := func(req *http.Request) (int, string) {
+		rec := httptest.NewRecorder()
+		h.handleGraphQL(rec, req)
+
+		return rec.Code, rec.Body.String()
+	}
+
+	code, body := call(httptest.NewRequest(http.MethodPost, "/graphql",
+		strings.NewReader(`{"query":"query($n: Long) { block(number: $n) { number } }","variables":{"n":1}}`)))
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"data":{"block":{"number":1}}}`, body)
+
+	code, body = call(httptest.NewRequest(http.MethodGet,
+		"/graphql?query="+url.QueryEscape("{ block { number } syncing { currentBlock } }"), nil))
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{
+		"data":{"block":{"number":2},"syncing":null},
+		"errors":[{"message":"the method eth_syncing does not exist/is not available","path":["syncing"]}]
+	}`, body)
+
+	code, _ = call(httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ block"}`)))
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// the endpoint is disabled by default
+	srv.config.GraphQL = false
+	assert.Nil(t, srv.newHTTPHandler(AccessConfig{}, nil).graphql)
+}
@@ -0,0 +1,196 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/TIE-Tech/go-logger"
+	"github.com/gorilla/websocket"
+)
+
+// ipcServer serves the json rpc requests on a unix domain socket. The requests and the
+// responses are json values, written one after the other on the connection
+type ipcServer struct {
+	path       string
+	dispatcher dispatcher
+	listener   net.Listener
+
+	lock  sync.Mutex
+	conns map[*ipcConn]struct{}
+}
+
+// setupIPC starts the ipc server on the socket path of the config
+func (j *JSONRPC) setupIPC() error {
+	srv := &ipcServer{
+		path:       j.config.IPCPath,
+		dispatcher: j.dispatcher.withAccountMethods().withTransport(serverIPC),
+		conns:      map[*ipcConn]struct{}{},
+	}
+
+	if err := srv.listen(); err != nil {
+		return err
+	}
+
+	logger.Info("[RPC] ipc server started", "path", srv.path)
+
+	go srv.serve()
+
+	j.ipc = srv
+
+	return nil
+}
+
+func (s *ipcServer) listen() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+
+	// remove the socket left by a node which was not closed
+	if info, err := os.Stat(s.path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return fmt.Errorf("ipc path %s exists and is not a socket", s.path)
+		}
+
+		if err := os.Remove(s.path); err != nil {
+			return err
+		}
+	}
+
+	lis, err := net.Listen("unix", s.path)
+	if err != nil {
+		return err
+	}
+
+	// the access to the socket is restricted to the user running the node
+	if err := os.Chmod(s.path, 0600); err != nil {
+		lis.Close()
+
+		return err
+	}
+
+	s.listener = lis
+
+	return nil
+}
+
+func (s *ipcServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				logger.Error("closed ipc listener", "err", err)
+			}
+
+			return
+		}
+
+		c := &ipcConn{conn: conn}
+
+		s.lock.Lock()
+		s.conns[c] = struct{}{}
+		s.lock.Unlock()
+
+		go func() {
+			s.handle(c)
+
+			s.lock.Lock()
+			delete(s.conns, c)
+			s.lock.Unlock()
+		}()
+	}
+}
+
+// handle serves the requests of a connection until it is closed
+func (s *ipcServer) handle(c *ipcConn) {
+	defer c.conn.Close()
+
+	dec := json.NewDecoder(c.conn)
+
+	for {
+		var msg json.RawMessage
+		if err := dec.Decode(&msg); err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				logger.Error("Unable to read IPC message", "err", err)
+
+				// the stream cannot be read further
+				resp, _ := NewRPCResponse(nil, "2.0", nil, NewInvalidRequestError("Invalid json request")).Bytes()
+				_ = c.WriteMessage(websocket.TextMessage, resp)
+			}
+
+			return
+		}
+
+		var (
+			resp []byte
+			err  error
+		)
+
+		// the subscriptions are handled as on a websocket connection
+		if x := bytes.TrimLeft(msg, " \t\r\n"); len(x) > 0 && x[0] == '[' {
+			resp, err = s.dispatcher.Handle(msg)
+		} else {
+			resp, err = s.dispatcher.HandleWs(msg, c)
+		}
+
+		if err != nil {
+			logger.Error(fmt.Sprintf("Unable to handle IPC request, %s", err.Error()))
+
+			resp, _ = NewRPCResponse(nil, "2.0", nil, NewInternalError(err.Error())).Bytes()
+		}
+
+		if err := c.WriteMessage(websocket.TextMessage, resp); err != nil {
+			return
+		}
+	}
+}
+
+// close stops the server, closes the connections and removes the socket file
+func (s *ipcServer) close() error {
+	err := s.listener.Close()
+
+	s.lock.Lock()
+	for c := range s.conns {
+		c.conn.Close()
+	}
+	s.lock.Unlock()
+
+	if rmErr := os.Remove(s.path); rmErr != nil && !os.IsNotExist(rmErr) && err == nil {
+		err = rmErr
+	}
+
+	return err
+}
+
+// ipcConn is an ipc connection, it implements wsConn for the subscriptions
+type ipcConn struct {
+	conn      net.Conn
+	writeLock sync.Mutex
+}
+
+// WriteMessage writes out a message to the ipc peer on a single line
+func (c *ipcConn) WriteMessage(_ int, data []byte) error {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		// the message is written as it is if it is not json
+		buf.Reset()
+		buf.Write(data)
+	}
+
+	buf.WriteByte('\n')
+
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	if _, err := c.conn.Write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package rpc
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/TIE-Tech/tie-core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestIPCServer(t *testing.T, store *mockStore) (*JSONRPC, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "tie.ipc")
+
+	srv := &JSONRPC{
+		config:     &Config{IPCPath: path},
+		dispatcher: newDispatcher(store, &dispatcherParams{}),
+	}
+
+	if err := srv.setupIPC(); err != nil {
+		t.Fatal(err)
+	}
+
+	return srv, path
+}
+
+func TestIPCServer(t *testing.T) {
+	store := newMockStore()
+	srv, path := newTestIPCServer(t, store)
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+
+	readResponse := func() []byte {
+		assert.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return line
+	}
+
+	// two requests in a single write
+	_, err = conn.Write([]byte(`{"id":1,"method":"web3_clientVersion"}{"id":2,"method":"net_version"}`))
+	assert.NoError(t, err)
+
+	var version string
+	assert.NoError(t, expectJSONResult(readResponse(), &version))
+	assert.NotEmpty(t, version)
+
+	var netVersion string
+	assert.NoError(t, expectJSONResult(readResponse(), &netVersion))
+
+	// batch request
+	_, err = conn.Write([]byte(`[{"id":1,"method":"web3_clientVersion"},{"id":2,"method":"eth_none"}]`))
+	assert.NoError(t, err)
+
+	var batch []*SuccessResponse
+	assert.NoError(t, json.Unmarshal(readResponse(), &batch))
+	assert.Len(t, batch, 2)
+	assert.Nil(t, batch[0].Error)
+	assert.NotNil(t, batch[1].Error)
+
+	// subscription
+	_, err = conn.Write([]byte(`{"id":3,"method":"eth_subscribe","params":["newHeads"]}`))
+	assert.NoError(t, err)
+
+	var filterID string
+	assert.NoError(t, expectJSONResult(readResponse(), &filterID))
+
+	store.emitEvent(&mockEvent{
+		NewChain: []*mockHeader{
+			{
+				header: &types.Header{
+					Hash: types.StringToHash("1"),
+				},
+			},
+		},
+	})
+
+	var notification struct {
+		Method string
+		Params struct {
+			Subscription string
+		}
+	}
+
+	assert.NoError(t, json.Unmarshal(readResponse(), &notification))
+	assert.Equal(t, "eth_subscription", notification.Method)
+	assert.Equal(t, filterID, notification.Params.Subscription)
+
+	// the socket is removed when the server is closed
+	assert.NoError(t, srv.Close())
+
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestIPCServer_StaleSocket(t *testing.T) {
+	srv, path := newTestIPCServer(t, newMockStore())
+
+	// a socket left by a node which was not closed is replaced
+	assert.NoError(t, srv.ipc.listener.Close())
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		lis, err := net.Listen("unix", path)
+		assert.NoError(t, err)
+
+		// keep the file when closing the listener
+		lis.(*net.UnixListener).SetUnlinkOnClose(false)
+		assert.NoError(t, lis.Close())
+	}
+
+	srv2 := &JSONRPC{
+		config:     &Config{IPCPath: path},
+		dispatcher: srv.dispatcher,
+	}
+	assert.NoError(t, srv2.setupIPC())
+	assert.NoError(t, srv2.Close())
+
+	// a file which is not a socket is not removed
+	assert.NoError(t, os.WriteFile(path, []byte{}, 0600))
+
+	srv3 := &JSONRPC{
+		config:     &Config{IPCPath: path},
+		dispatcher: srv.dispatcher,
+	}
+	assert.Error(t, srv3.setupIPC())
+}
@@ -1,12 +1,18 @@
 package rpc
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/TIE-Tech/go-logger"
+	"github.com/TIE-Tech/tie-core/accounts/keystore"
+	"github.com/TIE-Tech/tie-core/metrics"
+	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -34,13 +40,19 @@ func (s serverType) String() string {
 
 // JSONRPC is an API backend
 type JSONRPC struct {
-	config     *Config
-	dispatcher dispatcher
+	config *Config
+
+	// dispatcher serves all the methods, the listeners restrict it to their methods
+	dispatcher *Dispatcher
+
+	// ipc is the ipc server, nil if disabled
+	ipc *ipcServer
 }
 
 type dispatcher interface {
 	HandleWs(reqBody []byte, conn wsConn) ([]byte, error)
 	Handle(reqBody []byte) ([]byte, error)
+	HandleRequest(reqBody []byte, allowBatch func(calls int) bool) ([]byte, bool, error)
 }
 
 // JSONRPCStore defines all the methods required
@@ -49,44 +61,151 @@ type JSONRPCStore interface {
 	ethStore
 	networkStore
 	txPoolStore
+	tieStore
 	filterManagerStore
 }
 
+const (
+	// DefaultMaxBlockRange is the default maximum number of blocks of a log query
+	DefaultMaxBlockRange = 10000
+
+	// DefaultMaxLogs is the default maximum number of logs returned by a log query
+	DefaultMaxLogs = 10000
+)
+
 type Config struct {
 	Store   JSONRPCStore
 	Addr    *net.TCPAddr
 	ChainID uint64
+
+	// MaxBlockRange is the maximum number of blocks of a log query, 0 for no limit
+	MaxBlockRange uint64
+
+	// MaxLogs is the maximum number of logs returned by a log query, 0 for no limit
+	MaxLogs uint64
+
+	// IPCPath is the path of the unix socket of the ipc server, empty to disable it
+	IPCPath string
+
+	// Access restricts the methods and the clients of the http listener
+	Access AccessConfig
+
+	// AuthAddr is the address of the http listener authenticated with the jwt secret, nil to disable it
+	AuthAddr *net.TCPAddr
+
+	// AuthAccess restricts the methods and the clients of the authenticated listener
+	AuthAccess AccessConfig
+
+	// JWTSecret is the shared secret signing the tokens of the authenticated listener
+	JWTSecret []byte
+
+	// MaxRequestSize is the maximum size of the body of an http request or of a websocket
+	// message, 0 for no limit
+	MaxRequestSize int64
+
+	// MaxBatchLength is the maximum number of requests of a batch, 0 for no limit
+	MaxBatchLength uint64
+
+	// CallTimeout is the maximum execution time of eth_call, eth_estimateGas and eth_simulateV1, 0 for no limit
+	CallTimeout time.Duration
+
+	// SlowRequestThreshold is the duration above which the requests are logged, 0 to disable
+	SlowRequestThreshold time.Duration
+
+	// GraphQL enables the graphql endpoint of the http listeners
+	GraphQL bool
+
+	// Keystore signs with the keys of the node in the account methods, which are only served by
+	// the authenticated and the ipc listeners. Nil to disable the account methods
+	Keystore *keystore.KeyStore
+
+	Metrics *metrics.RPCMetrics
 }
 
 // NewJSONRPC returns the JsonRPC http server
 func NewJSONRPC(config *Config) (*JSONRPC, error) {
+	params := &dispatcherParams{
+		chainID:        config.ChainID,
+		maxBlockRange:  config.MaxBlockRange,
+		maxLogs:        config.MaxLogs,
+		maxBatchLength: config.MaxBatchLength,
+		callTimeout:    config.CallTimeout,
+		metrics:        config.Metrics,
+
+		slowRequestThreshold: config.SlowRequestThreshold,
+	}
+
+	if config.Keystore != nil {
+		if config.AuthAddr == nil && config.IPCPath == "" {
+			return nil, errors.New("the keystore requires the authenticated or the ipc listener")
+		}
+
+		params.accounts = config.Keystore
+	}
+
 	srv := &JSONRPC{
 		config:     config,
-		dispatcher: newDispatcher(config.Store, config.ChainID),
+		dispatcher: newDispatcher(config.Store, params),
 	}
 
 	// start http server
-	if err := srv.setupHTTP(); err != nil {
+	if err := srv.setupHTTP(config.Addr, srv.newHTTPHandler(config.Access, nil)); err != nil {
 		return nil, err
 	}
 
+	// start authenticated http server
+	if config.AuthAddr != nil {
+		if len(config.JWTSecret) == 0 {
+			return nil, errors.New("the authenticated listener requires a jwt secret")
+		}
+
+		handler := srv.newHTTPHandler(config.AuthAccess, config.JWTSecret)
+		if err := srv.setupHTTP(config.AuthAddr, handler); err != nil {
+			return nil, err
+		}
+	}
+
+	// start ipc server
+	if config.IPCPath != "" {
+		if err := srv.setupIPC(); err != nil {
+			return nil, err
+		}
+	}
+
 	return srv, nil
 }
 
-func (j *JSONRPC) setupHTTP() error {
-	logger.Info("[RPC] http server started", "addr", j.config.Addr.String())
+// Close stops the filters and the ipc server, and removes its socket file
+func (j *JSONRPC) Close() error {
+	if j.dispatcher.filterManager != nil {
+		j.dispatcher.filterManager.Close()
+	}
+
+	if j.ipc == nil {
+		return nil
+	}
+
+	return j.ipc.close()
+}
+
+func (j *JSONRPC) setupHTTP(addr *net.TCPAddr, h *httpHandler) error {
+	logger.Info("[RPC] http server started", "addr", addr.String(), "auth", h.jwtSecret != nil)
 
-	lis, err := net.Listen("tcp", j.config.Addr.String())
+	lis, err := net.Listen("tcp", addr.String())
 	if err != nil {
 		return err
 	}
 
-	mux := http.DefaultServeMux
-	mux.HandleFunc("/", j.handle)
-	mux.HandleFunc("/ws", j.handleWs)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", h.handle)
+	mux.HandleFunc("/ws", h.handleWs)
+
+	if h.graphql != nil {
+		mux.HandleFunc("/graphql", h.handleGraphQL)
+	}
 
 	srv := http.Server{
-		Handler: mux,
+		Handler: h.guard(mux),
 	}
 
 	go func() {
@@ -98,6 +217,105 @@ func (j *JSONRPC) setupHTTP() error {
 	return nil
 }
 
+// httpHandler serves the json rpc requests of an http listener, over http and websocket
+type httpHandler struct {
+	dispatcher   dispatcher
+	wsDispatcher dispatcher
+	access       AccessConfig
+
+	// jwtSecret authenticates the requests, nil if the listener is not authenticated
+	jwtSecret []byte
+
+	// clients limits the requests of the client IPs, nil if they are not limited
+	clients *clientLimiter
+
+	maxRequestSize int64
+	metrics        *metrics.RPCMetrics
+
+	// graphql serves the graphql queries, nil if the endpoint is disabled
+	graphql *graphQL
+
+	upgrader websocket.Upgrader
+}
+
+func (j *JSONRPC) newHTTPHandler(access AccessConfig, jwtSecret []byte) *httpHandler {
+	dispatcher := j.dispatcher.withAccess(access)
+
+	// the keys of the node are only used by the authenticated clients
+	if jwtSecret != nil {
+		dispatcher = dispatcher.withAccountMethods()
+	}
+
+	h := &httpHandler{
+		dispatcher:     dispatcher.withTransport(serverHTTP),
+		wsDispatcher:   dispatcher.withTransport(serverWS),
+		access:         access,
+		jwtSecret:      jwtSecret,
+		clients:        newClientLimiter(access.RateLimit, access.RateLimitBurst),
+		maxRequestSize: j.config.MaxRequestSize,
+		metrics:        j.dispatcher.params.metrics,
+		upgrader:       wsUpgrader,
+	}
+
+	if j.config.GraphQL {
+		h.graphql = newGraphQL(dispatcher.endpoints.Eth, dispatcher.checkAccess)
+	}
+
+	// the connections from the browsers are accepted from the allowed origins
+	h.upgrader.CheckOrigin = func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+
+		return origin == "" || h.access.originAllowed(origin) != ""
+	}
+
+	return h
+}
+
+// guard rejects the requests to a virtual host which is not allowed, the requests
+// which are not authenticated and the ones exceeding the rate limit of their client
+func (h *httpHandler) guard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !h.access.hostAllowed(req) {
+			http.Error(w, "invalid host specified", http.StatusForbidden)
+
+			return
+		}
+
+		if h.jwtSecret != nil {
+			if err := authenticate(req, h.jwtSecret, time.Now()); err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+
+				return
+			}
+		}
+
+		if !h.allowClient(req, 1) {
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+
+			return
+		}
+
+		next.ServeHTTP(w, req)
+	})
+}
+
+// allowClient returns true if the client of a request is within its rate limit for
+// a number of calls, every call of a batch is charged
+func (h *httpHandler) allowClient(req *http.Request, calls int) bool {
+	ip, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		ip = req.RemoteAddr
+	}
+
+	if !h.clients.allowN(ip, calls, time.Now()) {
+		h.metrics.RejectedRequests.With("reason", rejectedRateLimit).Add(1)
+
+		return false
+	}
+
+	return true
+}
+
 // wsUpgrader defines upgrade parameters for the WS connection
 var wsUpgrader = websocket.Upgrader{
 	// Uses the default HTTP buffer sizes for Read / Write buffers.
@@ -138,12 +356,9 @@ func isSupportedWSType(messageType int) bool {
 		messageType == websocket.BinaryMessage
 }
 
-func (j *JSONRPC) handleWs(w http.ResponseWriter, req *http.Request) {
-	// CORS rule - Allow requests from anywhere
-	wsUpgrader.CheckOrigin = func(r *http.Request) bool { return true }
-
+func (h *httpHandler) handleWs(w http.ResponseWriter, req *http.Request) {
 	// Upgrade the connection to a WS one
-	ws, err := wsUpgrader.Upgrade(w, req, nil)
+	ws, err := h.upgrader.Upgrade(w, req, nil)
 	if err != nil {
 		logger.Error(fmt.Sprintf("Unable to upgrade to a WS connection, %s", err.Error()))
 		return
@@ -159,8 +374,18 @@ func (j *JSONRPC) handleWs(w http.ResponseWriter, req *http.Request) {
 		}
 	}(ws)
 
+	if h.maxRequestSize != 0 {
+		ws.SetReadLimit(h.maxRequestSize)
+	}
+
 	wrapConn := &wsWrapper{ws: ws}
 
+	h.metrics.WSConnections.Add(1)
+	defer h.metrics.WSConnections.Add(-1)
+
+	// requests bounds the number of requests of the connection handled at once
+	requests := make(chan struct{}, wsMaxConcurrentRequests)
+
 	logger.Info("[RPC] Websocket connection established")
 	// Run the listen loop
 	for {
@@ -174,6 +399,9 @@ func (j *JSONRPC) handleWs(w http.ResponseWriter, req *http.Request) {
 			) {
 				// Accepted close codes
 				logger.Warn("[RPC] Closing WS connection gracefully")
+			} else if errors.Is(err, websocket.ErrReadLimit) {
+				h.metrics.RejectedRequests.With("reason", rejectedBodySize).Add(1)
+				logger.Warn("[RPC] Closing WS connection, message too large")
 			} else {
 				logger.Error("Unable to read WS message", "err", err)
 			}
@@ -181,8 +409,18 @@ func (j *JSONRPC) handleWs(w http.ResponseWriter, req *http.Request) {
 		}
 
 		if isSupportedWSType(msgType) {
+			if !h.allowClient(req, 1) {
+				_ = wrapConn.WriteMessage(msgType, rateLimitedResponse(message))
+
+				continue
+			}
+
+			requests <- struct{}{}
+
 			go func() {
-				resp, handleErr := j.dispatcher.HandleWs(message, wrapConn)
+				defer func() { <-requests }()
+
+				resp, handleErr := h.wsDispatcher.HandleWs(message, wrapConn)
 				if handleErr != nil {
 					logger.Error(fmt.Sprintf("Unable to handle WS request, %s", handleErr.Error()))
 					_ = wrapConn.WriteMessage(
@@ -197,9 +435,14 @@ func (j *JSONRPC) handleWs(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
-func (j *JSONRPC) handle(w http.ResponseWriter, req *http.Request) {
+func (h *httpHandler) handle(w http.ResponseWriter, req *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if origin := h.access.originAllowed(req.Header.Get("Origin")); origin != "" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Add("Vary", "Origin")
+	}
+
 	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
 	w.Header().Set(
 		"Access-Control-Allow-Headers",
@@ -221,7 +464,12 @@ func (j *JSONRPC) handle(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	data, err := ioutil.ReadAll(req.Body)
+	body := io.Reader(req.Body)
+	if h.maxRequestSize != 0 {
+		body = io.LimitReader(req.Body, h.maxRequestSize+1)
+	}
+
+	data, err := ioutil.ReadAll(body)
 	if err != nil {
 		//nolint
 		w.Write([]byte(err.Error()))
@@ -229,10 +477,20 @@ func (j *JSONRPC) handle(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	// log request
-	logger.Debug("[RPC] handle ReadAll", "request", string(data))
+	if h.maxRequestSize != 0 && int64(len(data)) > h.maxRequestSize {
+		h.metrics.RejectedRequests.With("reason", rejectedBodySize).Add(1)
+		http.Error(w, fmt.Sprintf("request body too large, the maximum is %d bytes", h.maxRequestSize),
+			http.StatusRequestEntityTooLarge)
 
-	resp, err := j.dispatcher.Handle(data)
+		return
+	}
+
+	// the bodies of the account methods include passphrases and private keys,
+	// the dispatcher tells them while decoding the request. The guard charged
+	// the request as one call, the other calls of a batch are charged here
+	resp, redact, err := h.dispatcher.HandleRequest(data, func(calls int) bool {
+		return h.allowClient(req, calls-1)
+	})
 	if err != nil {
 		//nolint
 		w.Write([]byte(err.Error()))
@@ -240,5 +498,31 @@ func (j *JSONRPC) handle(w http.ResponseWriter, req *http.Request) {
 		//nolint
 		w.Write(resp)
 	}
-	logger.Debug("[RPC] handle Handle", "response", string(resp))
+
+	debugLog("[RPC] handle ReadAll", "request", debugBody(data, redact))
+	debugLog("[RPC] handle Handle", "response", debugBody(resp, redact))
+}
+
+// debugLog logs the bodies of the http requests and responses
+var debugLog = logger.Debug
+
+// debugBody returns the body of a request or of a response to log
+func debugBody(data []byte, redact bool) string {
+	if redact {
+		return "<redacted>"
+	}
+
+	return string(data)
+}
+
+// rateLimitedResponse returns the response to a websocket request exceeding the rate limit of its client
+func rateLimitedResponse(message []byte) []byte {
+	var req Request
+
+	// the id is not set if the request cannot be decoded
+	_ = json.Unmarshal(message, &req)
+
+	resp, _ := NewRPCResponse(req.ID, "2.0", nil, NewLimitExceededError("rate limit exceeded")).Bytes()
+
+	return resp
 }
@@ -1,9 +1,17 @@
 package rpc
 
 import (
+	"fmt"
+	"github.com/TIE-Tech/go-logger"
 	"github.com/TIE-Tech/tie-core/common/tests"
+	"github.com/TIE-Tech/tie-core/metrics"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestHTTPServer(t *testing.T) {
@@ -24,3 +32,51 @@ func TestHTTPServer(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestHTTPHandler_DebugLogRedacted(t *testing.T) {
+	var logged []string
+
+	debugLog = func(f interface{}, v ...interface{}) {
+		logged = append(logged, fmt.Sprint(v...))
+	}
+
+	defer func() {
+		debugLog = logger.Debug
+	}()
+
+	srv := &JSONRPC{
+		config: &Config{},
+		dispatcher: newDispatcher(newMockStore(), &dispatcherParams{
+			metrics: metrics.NewRPCMetrics(),
+		}),
+	}
+
+	h := srv.newHTTPHandler(AccessConfig{VHosts: []string{"*"}}, nil)
+
+	call := func(body string) {
+		rec := httptest.NewRecorder()
+		h.handle(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	}
+
+	// the bodies of the other methods are logged
+	call(`{"id":1,"method":"web3_clientVersion"}`)
+	assert.Len(t, logged, 2)
+	assert.Contains(t, logged[0], "web3_clientVersion")
+
+	// the passphrases and the keys of the account methods never reach the logger
+	for _, body := range []string{
+		`{"id":1,"method":"personal_unlockAccount","params":["0x1","secret",0]}`,
+		`{"id":1,"method":"personal_importRawKey","params":["secret","pass"]}`,
+		`[{"id":1,"method":"web3_clientVersion"},{"id":2,"method":"personal_sendTransaction","params":[{},"secret"]}]`,
+		`{"id":1,"method":"personal_unlockAccount","params":["0x1","secret"`,
+	} {
+		logged = logged[:0]
+		call(body)
+
+		assert.Len(t, logged, 2)
+
+		for _, line := range logged {
+			assert.NotContains(t, line, "secret", body)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package rpc
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxRequestSize is the default maximum size of the body of a request
+	DefaultMaxRequestSize = 5 * 1024 * 1024
+
+	// DefaultMaxBatchLength is the default maximum number of requests of a batch
+	DefaultMaxBatchLength = 1000
+
+	// DefaultCallTimeout is the default maximum execution time of eth_call, eth_estimateGas and eth_simulateV1
+	DefaultCallTimeout = 5 * time.Second
+
+	// wsMaxConcurrentRequests is the number of requests of a websocket connection handled at once,
+	// the next messages are read once a request is handled
+	wsMaxConcurrentRequests = 16
+
+	// rateLimiterSweepInterval is the interval between two removals of the idle client buckets
+	rateLimiterSweepInterval = time.Minute
+)
+
+// the reasons of the rejected requests in the metrics
+const (
+	rejectedBodySize        = "body_size"
+	rejectedBatchLength     = "batch_length"
+	rejectedRateLimit       = "rate_limit"
+	rejectedMethodRateLimit = "method_rate_limit"
+	rejectedTimeout         = "timeout"
+)
+
+// tokenBucket allows a number of events per second, and bursts of events up to its capacity
+type tokenBucket struct {
+	rate     float64
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+func newTokenBucket(rate, burst uint64, now time.Time) *tokenBucket {
+	if burst == 0 {
+		burst = rate
+	}
+
+	return &tokenBucket{
+		rate:     float64(rate),
+		capacity: float64(burst),
+		tokens:   float64(burst),
+		last:     now,
+	}
+}
+
+// take takes a token, it returns false if there is none left
+func (b *tokenBucket) take(now time.Time) bool {
+	return b.takeN(1, now)
+}
+
+// takeN takes n tokens at once, it returns false and takes none if there are fewer left
+func (b *tokenBucket) takeN(n int, now time.Time) bool {
+	b.refill(now)
+
+	if b.tokens < float64(n) {
+		return false
+	}
+
+	b.tokens -= float64(n)
+
+	return true
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.capacity, b.tokens+elapsed*b.rate)
+		b.last = now
+	}
+}
+
+// full returns true if the bucket is full, as the bucket of a new client
+func (b *tokenBucket) full(now time.Time) bool {
+	b.refill(now)
+
+	return b.tokens >= b.capacity
+}
+
+// clientLimiter limits the number of requests per second of every client IP
+type clientLimiter struct {
+	rate  uint64
+	burst uint64
+
+	lock      sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// newClientLimiter returns a limiter of the requests of the clients, nil if the rate is not limited
+func newClientLimiter(rate, burst uint64) *clientLimiter {
+	if rate == 0 {
+		return nil
+	}
+
+	return &clientLimiter{
+		rate:      rate,
+		burst:     burst,
+		buckets:   map[string]*tokenBucket{},
+		lastSweep: time.Now(),
+	}
+}
+
+// allow returns true if a client can make a request
+func (l *clientLimiter) allow(ip string, now time.Time) bool {
+	return l.allowN(ip, 1, now)
+}
+
+// allowN returns true if a client can make n calls at once, as the calls of a batch
+func (l *clientLimiter) allowN(ip string, n int, now time.Time) bool {
+	if l == nil || n <= 0 {
+		return true
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if now.Sub(l.lastSweep) > rateLimiterSweepInterval {
+		l.sweep(now)
+	}
+
+	bucket, ok := l.buckets[ip]
+	if !ok {
+		bucket = newTokenBucket(l.rate, l.burst, now)
+		l.buckets[ip] = bucket
+	}
+
+	return bucket.takeN(n, now)
+}
+
+// sweep removes the buckets of the clients which did not make requests for a while
+func (l *clientLimiter) sweep(now time.Time) {
+	for ip, bucket := range l.buckets {
+		if bucket.full(now) {
+			delete(l.buckets, ip)
+		}
+	}
+
+	l.lastSweep = now
+}
+
+// methodLimiter limits the number of calls per second of the methods, for all the clients
+type methodLimiter struct {
+	lock    sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// newMethodLimiter returns a limiter of the calls of the methods, nil if no method is limited
+func newMethodLimiter(limits map[string]uint64) *methodLimiter {
+	if len(limits) == 0 {
+		return nil
+	}
+
+	l := &methodLimiter{
+		buckets: make(map[string]*tokenBucket, len(limits)),
+	}
+
+	now := time.Now()
+
+	for method, rate := range limits {
+		if rate != 0 {
+			l.buckets[method] = newTokenBucket(rate, rate, now)
+		}
+	}
+
+	return l
+}
+
+// allow returns true if a method can be called
+func (l *methodLimiter) allow(method string, now time.Time) bool {
+	if l == nil {
+		return true
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	bucket, ok := l.buckets[method]
+	if !ok {
+		return true
+	}
+
+	return bucket.take(now)
+}
@@ -0,0 +1,198 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TIE-Tech/tie-core/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Now()
+	bucket := newTokenBucket(2, 3, now)
+
+	// the burst is allowed at once
+	for i := 0; i < 3; i++ {
+		assert.True(t, bucket.take(now))
+	}
+	assert.False(t, bucket.take(now))
+
+	// the tokens are refilled at the rate
+	assert.True(t, bucket.take(now.Add(500*time.Millisecond)))
+	assert.False(t, bucket.take(now.Add(500*time.Millisecond)))
+
+	// up to the capacity
+	later := now.Add(time.Hour)
+	assert.True(t, bucket.full(later))
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, bucket.take(later))
+	}
+	assert.False(t, bucket.take(later))
+
+	// several tokens are taken at once, or none
+	much := later.Add(time.Hour)
+	assert.False(t, bucket.takeN(4, much))
+	assert.True(t, bucket.takeN(3, much))
+	assert.False(t, bucket.take(much))
+}
+
+func TestClientLimiter(t *testing.T) {
+	// no limit
+	var limiter *clientLimiter
+	assert.Nil(t, newClientLimiter(0, 10))
+	assert.True(t, limiter.allow("127.0.0.1", time.Now()))
+
+	now := time.Now()
+	limiter = newClientLimiter(1, 0)
+
+	// the clients are limited separately
+	assert.True(t, limiter.allow("10.0.0.1", now))
+	assert.False(t, limiter.allow("10.0.0.1", now))
+	assert.True(t, limiter.allow("10.0.0.2", now))
+
+	// the buckets of the idle clients are removed
+	later := now.Add(2 * rateLimiterSweepInterval)
+	assert.True(t, limiter.allow("10.0.0.3", later))
+	assert.Len(t, limiter.buckets, 1)
+}
+
+func TestMethodLimiter(t *testing.T) {
+	var limiter *methodLimiter
+	assert.Nil(t, newMethodLimiter(nil))
+	assert.True(t, limiter.allow("eth_call", time.Now()))
+
+	now := time.Now()
+	limiter = newMethodLimiter(map[string]uint64{"eth_getLogs": 1, "eth_call": 0})
+
+	assert.True(t, limiter.allow("eth_getLogs", now))
+	assert.False(t, limiter.allow("eth_getLogs", now))
+
+	// the methods without a rate are not limited
+	for i := 0; i < 10; i++ {
+		assert.True(t, limiter.allow("eth_call", now))
+		assert.True(t, limiter.allow("eth_blockNumber", now))
+	}
+}
+
+func TestDispatcher_MethodRateLimit(t *testing.T) {
+	dispatcher := newDispatcher(newMockStore(), &dispatcherParams{}).withAccess(AccessConfig{
+		MethodRateLimits: map[string]uint64{"web3_clientVersion": 1},
+	})
+
+	req := []byte(`{"id":1,"method":"web3_clientVersion"}`)
+
+	resp, err := dispatcher.Handle(req)
+	assert.NoError(t, err)
+
+	var version string
+	assert.NoError(t, expectJSONResult(resp, &version))
+
+	resp, err = dispatcher.Handle(req)
+	assert.NoError(t, err)
+
+	var obj ErrorResponse
+	assert.NoError(t, json.Unmarshal(resp, &obj))
+	assert.Equal(t, -32005, obj.Error.Code)
+}
+
+func TestDispatcher_MaxBatchLength(t *testing.T) {
+	dispatcher := newDispatcher(newMockStore(), &dispatcherParams{maxBatchLength: 2})
+
+	resp, err := dispatcher.Handle([]byte(`[
+		{"id":1,"method":"web3_clientVersion"},
+		{"id":2,"method":"web3_clientVersion"}
+	]`))
+	assert.NoError(t, err)
+
+	var batch []*SuccessResponse
+	assert.NoError(t, json.Unmarshal(resp, &batch))
+	assert.Len(t, batch, 2)
+
+	resp, err = dispatcher.Handle([]byte(`[
+		{"id":1,"method":"web3_clientVersion"},
+		{"id":2,"method":"web3_clientVersion"},
+		{"id":3,"method":"web3_clientVersion"}
+	]`))
+	assert.NoError(t, err)
+
+	var obj ErrorResponse
+	assert.NoError(t, json.Unmarshal(resp, &obj))
+	assert.Equal(t, -32005, obj.Error.Code)
+}
+
+func TestHTTPHandler_Limits(t *testing.T) {
+	srv := &JSONRPC{
+		config: &Config{MaxRequestSize: 64},
+		dispatcher: newDispatcher(newMockStore(), &dispatcherParams{
+			metrics: metrics.NewRPCMetrics(),
+		}),
+	}
+
+	h := srv.newHTTPHandler(AccessConfig{VHosts: []string{"*"}, RateLimit: 1, RateLimitBurst: 2}, nil)
+	handler := h.guard(http.HandlerFunc(h.handle))
+
+	call := func(ip, body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.RemoteAddr = fmt.Sprintf("%s:1234", ip)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		return rec.Code
+	}
+
+	req := `{"id":1,"method":"web3_clientVersion"}`
+
+	// the body size is limited
+	assert.Equal(t, http.StatusOK, call("10.0.0.1", req))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, call("10.0.0.1", string(bytes.Repeat([]byte(" "), 65))))
+
+	// the burst of the client is exhausted
+	assert.Equal(t, http.StatusTooManyRequests, call("10.0.0.1", req))
+
+	// the other clients are not limited
+	assert.Equal(t, http.StatusOK, call("10.0.0.2", req))
+
+	// every call of a batch is charged
+	batch := `[{"id":1,"method":"web3_clientVersion"},{"id":2,"method":"web3_clientVersion"}]`
+
+	h.maxRequestSize = 0
+
+	assert.Equal(t, http.StatusOK, call("10.0.0.3", batch))
+	assert.Equal(t, http.StatusTooManyRequests, call("10.0.0.3", req))
+}
+
+func TestHTTPHandler_BatchRateLimit(t *testing.T) {
+	srv := &JSONRPC{
+		config: &Config{},
+		dispatcher: newDispatcher(newMockStore(), &dispatcherParams{
+			metrics: metrics.NewRPCMetrics(),
+		}),
+	}
+
+	h := srv.newHTTPHandler(AccessConfig{VHosts: []string{"*"}, RateLimit: 1, RateLimitBurst: 2}, nil)
+	handler := h.guard(http.HandlerFunc(h.handle))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`[
+		{"id":1,"method":"web3_clientVersion"},
+		{"id":2,"method":"web3_clientVersion"},
+		{"id":3,"method":"web3_clientVersion"}
+	]`))
+	req.RemoteAddr = "10.0.0.1:1234"
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	// the batch has more calls than the burst of the client
+	var obj ErrorResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &obj))
+	assert.Equal(t, -32005, obj.Error.Code)
+}
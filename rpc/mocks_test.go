@@ -2,8 +2,10 @@ package rpc
 
 import (
 	"errors"
+	"github.com/TIE-Tech/tie-core/common/progress"
 	"github.com/TIE-Tech/tie-core/core"
 	"github.com/TIE-Tech/tie-core/state"
+	"github.com/TIE-Tech/tie-core/storage"
 	"github.com/TIE-Tech/tie-core/types"
 	"math/big"
 	"sync"
@@ -52,6 +54,15 @@ type mockStore struct {
 	receiptsLock sync.Mutex
 	receipts     map[types.Hash][]*types.Receipt
 	accounts     map[types.Address]*state.Account
+
+	pendingTxCh chan types.Hash
+	pendingTxs  map[types.Hash]*types.Transaction
+
+	progressionLock sync.Mutex
+	progression     *progress.Progression
+
+	// bloomBits holds the bloom bits index, nil if the index is disabled
+	bloomBits storage.Storage
 }
 
 func newMockStore() *mockStore {
@@ -59,6 +70,8 @@ func newMockStore() *mockStore {
 		header:       &types.Header{Number: 0},
 		subscription: blockchain.NewMockSubscription(),
 		accounts:     map[types.Address]*state.Account{},
+		pendingTxCh:  make(chan types.Hash),
+		pendingTxs:   map[types.Hash]*types.Transaction{},
 	}
 }
 
@@ -73,11 +86,13 @@ func (m *mockStore) emitEvent(evnt *mockEvent) {
 	}
 
 	for _, i := range evnt.NewChain {
+		i.header.LogsBloom = types.CreateBloom(i.receipts)
 		m.receipts[i.header.Hash] = i.receipts
 		bEvnt.NewChain = append(bEvnt.NewChain, i.header)
 	}
 
 	for _, i := range evnt.OldChain {
+		i.header.LogsBloom = types.CreateBloom(i.receipts)
 		m.receipts[i.header.Hash] = i.receipts
 		bEvnt.OldChain = append(bEvnt.OldChain, i.header)
 	}
@@ -114,6 +129,48 @@ func (m *mockStore) SubscribeEvents() blockchain.Subscription {
 	return m.subscription
 }
 
+func (m *mockStore) SubscribePendingTxs() (<-chan types.Hash, func()) {
+	return m.pendingTxCh, func() {}
+}
+
+// emitPendingTx adds a transaction to the pool and signals its promotion
+func (m *mockStore) emitPendingTx(txn *types.Transaction) {
+	m.pendingTxs[txn.Hash] = txn
+	m.pendingTxCh <- txn.Hash
+}
+
+func (m *mockStore) GetPendingTx(txHash types.Hash) (*types.Transaction, bool) {
+	txn, ok := m.pendingTxs[txHash]
+
+	return txn, ok
+}
+
+func (m *mockStore) setSyncProgression(progression *progress.Progression) {
+	m.progressionLock.Lock()
+	defer m.progressionLock.Unlock()
+
+	m.progression = progression
+}
+
+func (m *mockStore) GetSyncProgression() *progress.Progression {
+	m.progressionLock.Lock()
+	defer m.progressionLock.Unlock()
+
+	return m.progression
+}
+
+func (m *mockStore) BloomBitsSections() uint64 {
+	if m.bloomBits == nil {
+		return 0
+	}
+
+	return m.bloomBits.ReadBloomBitsSections()
+}
+
+func (m *mockStore) MatchBloomBits(section uint64, groups [][][]byte) ([]byte, error) {
+	return storage.MatchBloomBits(m.bloomBits, section, groups)
+}
+
 func (m *mockStore) GetBlockByNumber(num uint64, full bool) (*types.Block, bool) {
 	return nil, false
 }
@@ -128,3 +185,15 @@ func (m *mockStore) GetTxs(inclQueued bool) (
 func (m *mockStore) GetCapacity() (uint64, uint64) {
 	return 0, 0
 }
+
+func (m *mockStore) ReadTxLookup(hash types.Hash) (types.Hash, bool) {
+	return types.ZeroHash, false
+}
+
+func (m *mockStore) GetTransactionStatus(hash types.Hash) (string, string) {
+	if _, ok := m.pendingTxs[hash]; ok {
+		return "pending", ""
+	}
+
+	return "unknown", ""
+}
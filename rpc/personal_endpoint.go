@@ -0,0 +1,203 @@
+package rpc
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/TIE-Tech/tie-core/accounts/keystore"
+	"github.com/TIE-Tech/tie-core/common/crypto"
+	"github.com/TIE-Tech/tie-core/types"
+)
+
+// defaultUnlockDuration is the duration of personal_unlockAccount when it is not set
+const defaultUnlockDuration = 300 * time.Second
+
+var (
+	ErrKeystoreDisabled = errors.New("the keystore is disabled")
+)
+
+// accountStore provides the accounts of the keys of the node
+type accountStore interface {
+	// Accounts returns the addresses of the keys, sorted
+	Accounts() []types.Address
+
+	// NewAccount generates a key encrypted with the passphrase, and returns its account
+	NewAccount(passphrase string) (types.Address, error)
+
+	// ImportKey stores the key encrypted with the passphrase, and returns its account
+	ImportKey(key *ecdsa.PrivateKey, passphrase string) (types.Address, error)
+
+	// Unlock decrypts the key of the account for the duration, 0 until it is locked
+	Unlock(address types.Address, passphrase string, duration time.Duration) error
+
+	// Lock removes the decrypted key of the account from memory
+	Lock(address types.Address) error
+
+	// SignHash signs the hash with the key of an unlocked account
+	SignHash(address types.Address, hash []byte) ([]byte, error)
+
+	// SignHashWithPassphrase signs the hash with the key of an account decrypted with the passphrase
+	SignHashWithPassphrase(address types.Address, passphrase string, hash []byte) ([]byte, error)
+
+	// SignTx signs the transaction with the key of an unlocked account
+	SignTx(address types.Address, tx *types.Transaction, signer keystore.TxSigner) (*types.Transaction, error)
+
+	// SignTxWithPassphrase signs the transaction with the key of an account decrypted with the passphrase
+	SignTxWithPassphrase(
+		address types.Address,
+		passphrase string,
+		tx *types.Transaction,
+		signer keystore.TxSigner,
+	) (*types.Transaction, error)
+}
+
+// Personal is the personal jsonrpc endpoint, managing the accounts of the keystore of the node
+type Personal struct {
+	eth      *Eth
+	accounts accountStore
+}
+
+// ListAccounts returns the accounts of the keystore
+func (p *Personal) ListAccounts() (interface{}, error) {
+	return p.accounts.Accounts(), nil
+}
+
+// NewAccount generates a key encrypted with the passphrase, and returns its account
+func (p *Personal) NewAccount(passphrase string) (interface{}, error) {
+	return p.accounts.NewAccount(passphrase)
+}
+
+// ImportRawKey stores the hex encoded key encrypted with the passphrase, and returns its account
+func (p *Personal) ImportRawKey(rawKey string, passphrase string) (interface{}, error) {
+	key, err := crypto.HexToPrvKey(strings.TrimPrefix(rawKey, "0x"))
+	if err != nil {
+		return nil, err
+	}
+
+	return p.accounts.ImportKey(key, passphrase)
+}
+
+// UnlockAccount unlocks the account for the duration in seconds, 300 if not set
+// and 0 until it is locked
+func (p *Personal) UnlockAccount(address types.Address, passphrase string, duration *uint64) (interface{}, error) {
+	unlockDuration := defaultUnlockDuration
+
+	if duration != nil {
+		if *duration > math.MaxInt64/uint64(time.Second) {
+			return nil, fmt.Errorf("unlock duration too large")
+		}
+
+		unlockDuration = time.Duration(*duration) * time.Second
+	}
+
+	if err := p.accounts.Unlock(address, passphrase, unlockDuration); err != nil {
+		return nil, err
+	}
+
+	return true, nil
+}
+
+// LockAccount locks the account
+func (p *Personal) LockAccount(address types.Address) (interface{}, error) {
+	if err := p.accounts.Lock(address); err != nil {
+		return nil, err
+	}
+
+	return true, nil
+}
+
+// Sign signs the message like eth_sign, with the key of the account decrypted with the passphrase
+func (p *Personal) Sign(data argBytes, address types.Address, passphrase string) (interface{}, error) {
+	sig, err := p.accounts.SignHashWithPassphrase(address, passphrase, signHash(data))
+	if err != nil {
+		return nil, err
+	}
+
+	return toMessageSignature(sig), nil
+}
+
+// EcRecover returns the account which signed the message with eth_sign or personal_sign
+func (p *Personal) EcRecover(data argBytes, sig argBytes) (interface{}, error) {
+	if len(sig) != 65 {
+		return nil, fmt.Errorf("signature must be 65 bytes long")
+	}
+
+	if sig[64] != 27 && sig[64] != 28 {
+		return nil, fmt.Errorf("invalid signature recovery id %d, expected 27 or 28", sig[64])
+	}
+
+	// the recovery id of the signature is 0 or 1
+	buf := make([]byte, 65)
+	copy(buf, sig)
+	buf[64] -= 27
+
+	pub, err := crypto.SigToPub(signHash(data), buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return crypto.PubKeyToAddress(pub), nil
+}
+
+// SignTransaction signs the transaction like eth_signTransaction, with the key of the
+// sender decrypted with the passphrase
+func (p *Personal) SignTransaction(arg *txnArgs, passphrase string) (interface{}, error) {
+	txn, err := p.eth.signTxnArgs(arg, func(txn *types.Transaction) (*types.Transaction, error) {
+		return p.accounts.SignTxWithPassphrase(txn.From, passphrase, txn, p.eth.signer())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toSignTransactionResult(txn), nil
+}
+
+// SendTransaction signs the transaction with the key of the sender decrypted with
+// the passphrase, and adds it to the pool
+func (p *Personal) SendTransaction(arg *txnArgs, passphrase string) (interface{}, error) {
+	txn, err := p.eth.signTxnArgs(arg, func(txn *types.Transaction) (*types.Transaction, error) {
+		return p.accounts.SignTxWithPassphrase(txn.From, passphrase, txn, p.eth.signer())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.eth.store.AddTx(txn); err != nil {
+		return nil, err
+	}
+
+	return txn.Hash.String(), nil
+}
+
+// signHash returns the hash of a message signed with eth_sign, prefixed so that
+// the signature is not the one of a transaction:
+// keccak256("\x19Ethereum Signed Message:\n" + len(message) + message)
+func signHash(data []byte) []byte {
+	prefix := fmt.Sprintf("\x19Ethereum Signed Message:\n%d", len(data))
+
+	return crypto.Keccak256([]byte(prefix), data)
+}
+
+// toMessageSignature returns the signature of a message with a recovery id of 27 or 28
+func toMessageSignature(sig []byte) argBytes {
+	sig[64] += 27
+
+	return sig
+}
+
+// signTransactionResult is the result of eth_signTransaction
+type signTransactionResult struct {
+	Raw argBytes     `json:"raw"`
+	Tx  *transaction `json:"tx"`
+}
+
+func toSignTransactionResult(txn *types.Transaction) *signTransactionResult {
+	return &signTransactionResult{
+		Raw: txn.MarshalRLP(),
+		Tx:  toPendingTransaction(txn),
+	}
+}
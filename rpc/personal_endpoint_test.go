@@ -0,0 +1,210 @@
+package rpc
+
+import (
+	"testing"
+
+	"github.com/TIE-Tech/tie-core/accounts/keystore"
+	"github.com/TIE-Tech/tie-core/state"
+	"github.com/TIE-Tech/tie-core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestKeyStore(t *testing.T) *keystore.KeyStore {
+	t.Helper()
+
+	ks, err := keystore.NewKeyStore(t.TempDir(), keystore.LightScryptN, keystore.LightScryptP)
+	assert.NoError(t, err)
+
+	return ks
+}
+
+func TestPersonal_Accounts(t *testing.T) {
+	ks := newTestKeyStore(t)
+	personal := &Personal{eth: newTestEthEndpoint(&mockStoreTxn{}), accounts: ks}
+
+	res, err := personal.NewAccount("secret")
+	assert.NoError(t, err)
+
+	address, ok := res.(types.Address)
+	assert.True(t, ok)
+
+	res, err = personal.ListAccounts()
+	assert.NoError(t, err)
+	assert.Equal(t, []types.Address{address}, res)
+
+	// a wrong passphrase does not unlock the account
+	_, err = personal.UnlockAccount(address, "wrong", nil)
+	assert.ErrorIs(t, err, keystore.ErrDecrypt)
+
+	// a duration of 0 unlocks the account until it is locked
+	duration := uint64(0)
+
+	_, err = personal.UnlockAccount(address, "secret", &duration)
+	assert.NoError(t, err)
+
+	// eth_sign signs with the unlocked account, the signature is recovered by personal_ecRecover
+	eth := &Eth{accounts: ks}
+	message := argBytes("hello")
+
+	sig, err := eth.Sign(address, message)
+	assert.NoError(t, err)
+
+	signer, err := personal.EcRecover(message, sig.(argBytes))
+	assert.NoError(t, err)
+	assert.Equal(t, address, signer)
+
+	_, err = personal.LockAccount(address)
+	assert.NoError(t, err)
+
+	_, err = eth.Sign(address, message)
+	assert.ErrorIs(t, err, keystore.ErrLocked)
+
+	// personal_sign decrypts the key without unlocking the account
+	sig, err = personal.Sign(message, address, "secret")
+	assert.NoError(t, err)
+
+	signer, err = personal.EcRecover(message, sig.(argBytes))
+	assert.NoError(t, err)
+	assert.Equal(t, address, signer)
+
+	_, err = eth.Sign(address, message)
+	assert.ErrorIs(t, err, keystore.ErrLocked)
+}
+
+func TestPersonal_ImportRawKey(t *testing.T) {
+	personal := &Personal{accounts: newTestKeyStore(t)}
+
+	res, err := personal.ImportRawKey("0x7a28b5ba57c53603b0b07b56bba752f7784bf506fa95edc395f5cf6c7514fe9d", "secret")
+	assert.NoError(t, err)
+	assert.Equal(t, types.StringToAddress("0x008aeeda4d805471df9b2a5b0f38a0c3bcba786b"), res)
+
+	_, err = personal.ImportRawKey("0x7a28b5ba57c53603b0b07b56bba752f7784bf506fa95edc395f5cf6c7514fe9d", "secret")
+	assert.ErrorIs(t, err, keystore.ErrExists)
+}
+
+func TestEth_SignTransaction(t *testing.T) {
+	ks := newTestKeyStore(t)
+
+	address, err := ks.NewAccount("secret")
+	assert.NoError(t, err)
+
+	store := &mockStoreTxn{}
+	store.AddAccount(address)
+
+	eth := newTestEthEndpoint(store)
+	eth.accounts = ks
+
+	arg := func() *txnArgs {
+		return &txnArgs{
+			From:     argAddrPtr(address),
+			To:       argAddrPtr(addr0),
+			Gas:      argUintPtr(21000),
+			GasPrice: argBytesPtr([]byte{0x1}),
+		}
+	}
+
+	// the sender must be unlocked
+	_, err = eth.SignTransaction(arg())
+	assert.ErrorIs(t, err, keystore.ErrLocked)
+
+	assert.NoError(t, ks.Unlock(address, "secret", 0))
+
+	res, err := eth.SignTransaction(arg())
+	assert.NoError(t, err)
+
+	result, ok := res.(*signTransactionResult)
+	assert.True(t, ok)
+
+	txn := new(types.Transaction)
+	assert.NoError(t, txn.UnmarshalRLP(result.Raw))
+
+	// the nonce is the next one of the sender in the pool
+	assert.Equal(t, uint64(1), txn.Nonce)
+
+	from, err := state.NewSigner(100).Sender(txn)
+	assert.NoError(t, err)
+	assert.Equal(t, address, from)
+
+	// eth_signTransaction does not add the transaction to the pool
+	assert.Nil(t, store.txn)
+
+	res, err = eth.SendTransaction(arg())
+	assert.NoError(t, err)
+	assert.NotNil(t, store.txn)
+	assert.Equal(t, store.txn.Hash.String(), res)
+
+	from, err = state.NewSigner(100).Sender(store.txn)
+	assert.NoError(t, err)
+	assert.Equal(t, address, from)
+}
+
+func TestPersonal_SendTransaction(t *testing.T) {
+	ks := newTestKeyStore(t)
+
+	address, err := ks.NewAccount("secret")
+	assert.NoError(t, err)
+
+	store := &mockStoreTxn{}
+	store.AddAccount(address)
+
+	personal := &Personal{eth: newTestEthEndpoint(store), accounts: ks}
+
+	arg := &txnArgs{
+		From:     argAddrPtr(address),
+		To:       argAddrPtr(addr0),
+		Gas:      argUintPtr(21000),
+		GasPrice: argBytesPtr([]byte{0x1}),
+	}
+
+	_, err = personal.SendTransaction(arg, "wrong")
+	assert.ErrorIs(t, err, keystore.ErrDecrypt)
+	assert.Nil(t, store.txn)
+
+	_, err = personal.SendTransaction(arg, "secret")
+	assert.NoError(t, err)
+	assert.NotNil(t, store.txn)
+
+	from, err := state.NewSigner(100).Sender(store.txn)
+	assert.NoError(t, err)
+	assert.Equal(t, address, from)
+}
+
+func TestDispatcher_AccountMethods(t *testing.T) {
+	ks := newTestKeyStore(t)
+
+	dispatcher := newDispatcher(newMockStore(), &dispatcherParams{accounts: ks})
+
+	var accounts []types.Address
+
+	// the account methods are only served by the authenticated and the ipc listeners
+	for _, method := range []string{"eth_accounts", "eth_sign", "personal_listAccounts"} {
+		resp, err := dispatcher.Handle([]byte(`{"id":1,"method":"` + method + `"}`))
+		assert.NoError(t, err)
+		assert.Error(t, expectJSONResult(resp, &accounts), method)
+	}
+
+	resp, err := dispatcher.withAccountMethods().Handle([]byte(`{"id":1,"method":"personal_listAccounts"}`))
+	assert.NoError(t, err)
+	assert.NoError(t, expectJSONResult(resp, &accounts))
+	assert.Empty(t, accounts)
+
+	// the personal methods are not registered without the keystore
+	dispatcher = newDispatcher(newMockStore(), &dispatcherParams{})
+
+	resp, err = dispatcher.withAccountMethods().Handle([]byte(`{"id":1,"method":"personal_listAccounts"}`))
+	assert.NoError(t, err)
+	assert.Error(t, expectJSONResult(resp, &accounts))
+
+	resp, err = dispatcher.withAccountMethods().Handle([]byte(`{"id":1,"method":"eth_accounts"}`))
+	assert.NoError(t, err)
+	assert.NoError(t, expectJSONResult(resp, &accounts))
+	assert.Empty(t, accounts)
+}
+
+func TestJSONRPC_KeystoreListener(t *testing.T) {
+	_, err := NewJSONRPC(&Config{
+		Store:    newMockStore(),
+		Keystore: newTestKeyStore(t),
+	})
+	assert.Error(t, err)
+}
@@ -213,3 +213,59 @@ func (l *LogFilter) Match(log *types.Log) bool {
 
 	return true
 }
+
+// bloomGroups returns the values of the filter for the bloom bits index, the addresses
+// and the topics of each position. It is empty if the filter matches all the logs
+func (l *LogFilter) bloomGroups() [][][]byte {
+	groups := [][][]byte{}
+
+	if len(l.Addresses) > 0 {
+		group := make([][]byte, 0, len(l.Addresses))
+		for _, addr := range l.Addresses {
+			group = append(group, addr.Bytes())
+		}
+
+		groups = append(groups, group)
+	}
+
+	for _, sub := range l.Topics {
+		if len(sub) == 0 {
+			continue
+		}
+
+		group := make([][]byte, 0, len(sub))
+		for _, topic := range sub {
+			group = append(group, topic.Bytes())
+		}
+
+		groups = append(groups, group)
+	}
+
+	return groups
+}
+
+// MatchBloom returns whether the bloom of a block possibly includes logs for this filter
+func (l *LogFilter) MatchBloom(bloom *types.Bloom) bool {
+	// the block has no logs
+	if *bloom == (types.Bloom{}) {
+		return false
+	}
+
+	for _, group := range l.bloomGroups() {
+		match := false
+
+		for _, value := range group {
+			if bloom.Test(value) {
+				match = true
+
+				break
+			}
+		}
+
+		if !match {
+			return false
+		}
+	}
+
+	return true
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/TIE-Tech/tie-core/types"
+	"github.com/stretchr/testify/assert"
 )
 
 var (
@@ -228,3 +229,36 @@ func TestFilterMatch(t *testing.T) {
 		}
 	}
 }
+
+func TestFilterMatchBloom(t *testing.T) {
+	bloom := types.CreateBloom([]*types.Receipt{
+		{
+			Logs: []*types.Log{
+				{
+					Address: addr1,
+					Topics:  []types.Hash{hash1, hash2},
+				},
+			},
+		},
+	})
+
+	cases := []struct {
+		filter *LogFilter
+		match  bool
+	}{
+		{&LogFilter{}, true},
+		{&LogFilter{Addresses: []types.Address{addr1}}, true},
+		{&LogFilter{Addresses: []types.Address{addr2}}, false},
+		{&LogFilter{Addresses: []types.Address{addr2, addr1}}, true},
+		{&LogFilter{Topics: [][]types.Hash{{}, {hash2}}}, true},
+		{&LogFilter{Topics: [][]types.Hash{{hash1}, {hash3}}}, false},
+		{&LogFilter{Addresses: []types.Address{addr1}, Topics: [][]types.Hash{{hash3, hash1}}}, true},
+	}
+
+	for indx, c := range cases {
+		assert.Equalf(t, c.match, c.filter.MatchBloom(&bloom), "case %d", indx)
+	}
+
+	// the blocks without logs never match
+	assert.False(t, (&LogFilter{}).MatchBloom(&types.Bloom{}))
+}
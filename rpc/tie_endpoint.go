@@ -0,0 +1,317 @@
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/TIE-Tech/tie-core/storage"
+	"github.com/TIE-Tech/tie-core/types"
+)
+
+const (
+	// defaultPageSize is the number of entries of a page of an address index
+	defaultPageSize = 25
+
+	// maxPageSize is the maximum number of entries of a page of an address index
+	maxPageSize = 100
+)
+
+// tieStore provides access to the methods needed by the tie endpoint
+type tieStore interface {
+	// GetAddressIndex returns a page of the entries of the index of an address,
+	// the newest first, and the total number of entries
+	GetAddressIndex(
+		index storage.AddressIndex,
+		addr types.Address,
+		offset,
+		limit uint64,
+	) ([]*storage.AddressIndexEntry, uint64, error)
+
+	// GetBlockByNumber returns a block using the provided number
+	GetBlockByNumber(num uint64, full bool) (*types.Block, bool)
+
+	// GetReceiptsByHash returns the receipts for a block hash
+	GetReceiptsByHash(hash types.Hash) ([]*types.Receipt, error)
+
+	// GetInternalTransfers returns the internal transfers of a block
+	GetInternalTransfers(hash types.Hash) ([]*types.InternalTransfer, error)
+
+	// Header returns the current header of the chain (genesis if empty)
+	Header() *types.Header
+
+	// GetBlockByHash gets a block using the provided hash
+	GetBlockByHash(hash types.Hash, full bool) (*types.Block, bool)
+}
+
+// Tie is the tie jsonrpc endpoint, with the chain queries for the explorers
+type Tie struct {
+	store tieStore
+}
+
+type addressTransactions struct {
+	Total        argUint64      `json:"total"`
+	Page         argUint64      `json:"page"`
+	PageSize     argUint64      `json:"pageSize"`
+	Transactions []*transaction `json:"transactions"`
+}
+
+type tokenTransfers struct {
+	Total     argUint64 `json:"total"`
+	Page      argUint64 `json:"page"`
+	PageSize  argUint64 `json:"pageSize"`
+	Transfers []*Log    `json:"transfers"`
+}
+
+type internalTransaction struct {
+	BlockNumber argUint64     `json:"blockNumber"`
+	BlockHash   types.Hash    `json:"blockHash"`
+	TxHash      types.Hash    `json:"transactionHash"`
+	TxIndex     argUint64     `json:"transactionIndex"`
+	Type        string        `json:"type"`
+	From        types.Address `json:"from"`
+	To          types.Address `json:"to"`
+	Value       argBig        `json:"value"`
+	Depth       argUint64     `json:"depth"`
+}
+
+// GetInternalTransactions returns the value transfers of a block which are not the value of
+// its transactions: the calls and creations made by the contracts, the self destructs and
+// the system transactions
+func (t *Tie) GetInternalTransactions(filter BlockNumberOrHash) (interface{}, error) {
+	block, err := t.getBlock(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	transfers, err := t.store.GetInternalTransfers(block.Hash())
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, fmt.Errorf("the internal transfers of block %d are not stored", block.Number())
+		}
+
+		return nil, err
+	}
+
+	txIndexes := make(map[types.Hash]int, len(block.Transactions))
+	for i, txn := range block.Transactions {
+		txIndexes[txn.Hash] = i
+	}
+
+	res := make([]*internalTransaction, 0, len(transfers))
+
+	for _, transfer := range transfers {
+		res = append(res, &internalTransaction{
+			BlockNumber: argUint64(block.Number()),
+			BlockHash:   block.Hash(),
+			TxHash:      transfer.TxHash,
+			TxIndex:     argUint64(txIndexes[transfer.TxHash]),
+			Type:        transfer.Type.String(),
+			From:        transfer.From,
+			To:          transfer.To,
+			Value:       argBig(*transfer.Value),
+			Depth:       argUint64(transfer.Depth),
+		})
+	}
+
+	return res, nil
+}
+
+// getBlock returns the full block of a number or hash, the latest one if none is set
+func (t *Tie) getBlock(filter BlockNumberOrHash) (*types.Block, error) {
+	if filter.BlockHash != nil {
+		block, ok := t.store.GetBlockByHash(*filter.BlockHash, true)
+		if !ok {
+			return nil, fmt.Errorf("could not find block referenced by the hash %s", filter.BlockHash.String())
+		}
+
+		return block, nil
+	}
+
+	var number uint64
+
+	if filter.BlockNumber == nil {
+		number = t.store.Header().Number
+	} else {
+		switch *filter.BlockNumber {
+		case LatestBlockNumber:
+			number = t.store.Header().Number
+		case EarliestBlockNumber:
+			number = 0
+		case PendingBlockNumber:
+			return nil, fmt.Errorf("fetching the pending header is not supported")
+		default:
+			number = uint64(*filter.BlockNumber)
+		}
+	}
+
+	block, ok := t.store.GetBlockByNumber(number, true)
+	if !ok {
+		return nil, fmt.Errorf("block %d not found", number)
+	}
+
+	return block, nil
+}
+
+// GetTransactionsByAddress returns a page of the transactions sent or received by an
+// address, or creating it, the newest first
+func (t *Tie) GetTransactionsByAddress(address types.Address, page, pageSize argUint64) (interface{}, error) {
+	entries, total, err := t.readPage(storage.AddressTransactions, address, page, &pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &addressTransactions{
+		Total:        argUint64(total),
+		Page:         page,
+		PageSize:     pageSize,
+		Transactions: make([]*transaction, 0, len(entries)),
+	}
+
+	blocks := newBlockCache(t.store)
+
+	for _, entry := range entries {
+		block, err := blocks.getBlock(entry.BlockNumber)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, txn := range block.Transactions {
+			if txn.Hash == entry.TxHash {
+				txIndex := i
+				res.Transactions = append(
+					res.Transactions,
+					toTransaction(txn, argUintPtr(block.Number()), &block.Header.Hash, &txIndex),
+				)
+
+				break
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// GetTokenTransfers returns a page of the Transfer events emitted by a token contract,
+// the newest first
+func (t *Tie) GetTokenTransfers(contract types.Address, page, pageSize argUint64) (interface{}, error) {
+	entries, total, err := t.readPage(storage.TokenTransfers, contract, page, &pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &tokenTransfers{
+		Total:     argUint64(total),
+		Page:      page,
+		PageSize:  pageSize,
+		Transfers: make([]*Log, 0, len(entries)),
+	}
+
+	blocks := newBlockCache(t.store)
+
+	for _, entry := range entries {
+		block, err := blocks.getBlock(entry.BlockNumber)
+		if err != nil {
+			return nil, err
+		}
+
+		receipts, err := blocks.getReceipts(block)
+		if err != nil {
+			return nil, err
+		}
+
+		logIndex := uint64(0)
+
+		for txIndex, receipt := range receipts {
+			if entry.LogIndex >= logIndex+uint64(len(receipt.Logs)) {
+				logIndex += uint64(len(receipt.Logs))
+
+				continue
+			}
+
+			log := receipt.Logs[entry.LogIndex-logIndex]
+			res.Transfers = append(res.Transfers, &Log{
+				Address:     log.Address,
+				Topics:      log.Topics,
+				Data:        argBytes(log.Data),
+				BlockNumber: argUint64(block.Number()),
+				TxHash:      entry.TxHash,
+				TxIndex:     argUint64(txIndex),
+				BlockHash:   block.Hash(),
+				LogIndex:    argUint64(entry.LogIndex),
+			})
+
+			break
+		}
+	}
+
+	return res, nil
+}
+
+// readPage reads a page of the index of an address, the page size is set to the default if empty
+func (t *Tie) readPage(
+	index storage.AddressIndex,
+	addr types.Address,
+	page argUint64,
+	pageSize *argUint64,
+) ([]*storage.AddressIndexEntry, uint64, error) {
+	if *pageSize == 0 {
+		*pageSize = defaultPageSize
+	}
+
+	if *pageSize > maxPageSize {
+		return nil, 0, NewInvalidParamsError(fmt.Sprintf("page size too large, the maximum is %d", maxPageSize))
+	}
+
+	// the offset of the page must not wrap around
+	if uint64(page) > math.MaxUint64/uint64(*pageSize) {
+		return nil, 0, NewInvalidParamsError(fmt.Sprintf("page %d out of range", page))
+	}
+
+	return t.store.GetAddressIndex(index, addr, uint64(page)*uint64(*pageSize), uint64(*pageSize))
+}
+
+// blockCache reads the blocks and the receipts of the entries of a page once
+type blockCache struct {
+	store    tieStore
+	blocks   map[uint64]*types.Block
+	receipts map[uint64][]*types.Receipt
+}
+
+func newBlockCache(store tieStore) *blockCache {
+	return &blockCache{
+		store:    store,
+		blocks:   map[uint64]*types.Block{},
+		receipts: map[uint64][]*types.Receipt{},
+	}
+}
+
+func (c *blockCache) getBlock(number uint64) (*types.Block, error) {
+	if block, ok := c.blocks[number]; ok {
+		return block, nil
+	}
+
+	block, ok := c.store.GetBlockByNumber(number, true)
+	if !ok {
+		return nil, fmt.Errorf("block %d not found", number)
+	}
+
+	c.blocks[number] = block
+
+	return block, nil
+}
+
+func (c *blockCache) getReceipts(block *types.Block) ([]*types.Receipt, error) {
+	if receipts, ok := c.receipts[block.Number()]; ok {
+		return receipts, nil
+	}
+
+	receipts, err := c.store.GetReceiptsByHash(block.Hash())
+	if err != nil {
+		return nil, err
+	}
+
+	c.receipts[block.Number()] = receipts
+
+	return receipts, nil
+}
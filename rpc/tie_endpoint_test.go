@@ -0,0 +1,233 @@
+package rpc
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/TIE-Tech/tie-core/storage"
+	"github.com/TIE-Tech/tie-core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockTieStore struct {
+	entries   map[storage.AddressIndex][]*storage.AddressIndexEntry
+	blocks    map[uint64]*types.Block
+	receipts  map[types.Hash][]*types.Receipt
+	transfers map[types.Hash][]*types.InternalTransfer
+}
+
+func (m *mockTieStore) GetAddressIndex(
+	index storage.AddressIndex,
+	addr types.Address,
+	offset,
+	limit uint64,
+) ([]*storage.AddressIndexEntry, uint64, error) {
+	entries := m.entries[index]
+	total := uint64(len(entries))
+
+	if offset >= total {
+		return []*storage.AddressIndexEntry{}, total, nil
+	}
+
+	if offset+limit > total {
+		limit = total - offset
+	}
+
+	return entries[offset : offset+limit], total, nil
+}
+
+func (m *mockTieStore) GetBlockByNumber(num uint64, full bool) (*types.Block, bool) {
+	block, ok := m.blocks[num]
+
+	return block, ok
+}
+
+func (m *mockTieStore) GetReceiptsByHash(hash types.Hash) ([]*types.Receipt, error) {
+	return m.receipts[hash], nil
+}
+
+func (m *mockTieStore) GetInternalTransfers(hash types.Hash) ([]*types.InternalTransfer, error) {
+	transfers, ok := m.transfers[hash]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+
+	return transfers, nil
+}
+
+func (m *mockTieStore) Header() *types.Header {
+	return m.blocks[uint64(len(m.blocks))].Header
+}
+
+func (m *mockTieStore) GetBlockByHash(hash types.Hash, full bool) (*types.Block, bool) {
+	for _, block := range m.blocks {
+		if block.Hash() == hash {
+			return block, true
+		}
+	}
+
+	return nil, false
+}
+
+func newTieTestTransaction(nonce uint64) *types.Transaction {
+	return &types.Transaction{
+		Nonce:    nonce,
+		GasPrice: big.NewInt(1),
+		Value:    big.NewInt(0),
+		V:        big.NewInt(0),
+		R:        big.NewInt(0),
+		S:        big.NewInt(0),
+	}
+}
+
+func newMockTieStore() *mockTieStore {
+	store := &mockTieStore{
+		entries:   map[storage.AddressIndex][]*storage.AddressIndexEntry{},
+		blocks:    map[uint64]*types.Block{},
+		receipts:  map[types.Hash][]*types.Receipt{},
+		transfers: map[types.Hash][]*types.InternalTransfer{},
+	}
+
+	token := types.StringToAddress("1")
+
+	// the blocks 3 to 1 have two transactions, the second one emitting a transfer
+	for i := uint64(3); i > 0; i-- {
+		block := &types.Block{
+			Header: &types.Header{Number: i},
+			Transactions: []*types.Transaction{
+				newTieTestTransaction(0),
+				newTieTestTransaction(1),
+			},
+		}
+		block.Header.ComputeHash()
+
+		for j, txn := range block.Transactions {
+			txn.Hash = types.StringToHash(fmt.Sprintf("%d%d", i, j))
+		}
+
+		store.blocks[i] = block
+		store.receipts[block.Hash()] = []*types.Receipt{
+			{Logs: []*types.Log{{Address: token}}},
+			{Logs: []*types.Log{{Address: token, Topics: []types.Hash{types.StringToHash("1")}}}},
+		}
+
+		store.entries[storage.AddressTransactions] = append(
+			store.entries[storage.AddressTransactions],
+			&storage.AddressIndexEntry{BlockNumber: i, TxHash: block.Transactions[1].Hash},
+		)
+		store.entries[storage.TokenTransfers] = append(
+			store.entries[storage.TokenTransfers],
+			&storage.AddressIndexEntry{BlockNumber: i, TxHash: block.Transactions[1].Hash, LogIndex: 1},
+		)
+	}
+
+	return store
+}
+
+func TestTie_GetTransactionsByAddress(t *testing.T) {
+	store := newMockTieStore()
+	tie := &Tie{store}
+
+	res, err := tie.GetTransactionsByAddress(types.StringToAddress("1"), 0, 2)
+	assert.NoError(t, err)
+
+	page, ok := res.(*addressTransactions)
+	assert.True(t, ok)
+	assert.Equal(t, argUint64(3), page.Total)
+	assert.Len(t, page.Transactions, 2)
+
+	for i, txn := range page.Transactions {
+		block := store.blocks[uint64(3-i)]
+
+		assert.Equal(t, block.Transactions[1].Hash, txn.Hash)
+		assert.Equal(t, block.Hash(), *txn.BlockHash)
+		assert.Equal(t, argUint64(1), *txn.TxIndex)
+	}
+
+	res, err = tie.GetTransactionsByAddress(types.StringToAddress("1"), 1, 2)
+	assert.NoError(t, err)
+	assert.Len(t, res.(*addressTransactions).Transactions, 1)
+
+	// the page size is the default one if not set
+	res, err = tie.GetTransactionsByAddress(types.StringToAddress("1"), 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, argUint64(defaultPageSize), res.(*addressTransactions).PageSize)
+
+	_, err = tie.GetTransactionsByAddress(types.StringToAddress("1"), 0, maxPageSize+1)
+	assert.Error(t, err)
+
+	// the offset of the page overflows
+	_, err = tie.GetTransactionsByAddress(types.StringToAddress("1"), math.MaxUint64/2, 2)
+	assert.NoError(t, err)
+
+	_, err = tie.GetTransactionsByAddress(types.StringToAddress("1"), math.MaxUint64/2+1, 2)
+	assert.Error(t, err)
+}
+
+func TestTie_GetTokenTransfers(t *testing.T) {
+	store := newMockTieStore()
+	tie := &Tie{store}
+
+	res, err := tie.GetTokenTransfers(types.StringToAddress("1"), 0, 0)
+	assert.NoError(t, err)
+
+	page, ok := res.(*tokenTransfers)
+	assert.True(t, ok)
+	assert.Equal(t, argUint64(3), page.Total)
+	assert.Len(t, page.Transfers, 3)
+
+	for i, log := range page.Transfers {
+		block := store.blocks[uint64(3-i)]
+
+		assert.Equal(t, []types.Hash{types.StringToHash("1")}, log.Topics)
+		assert.Equal(t, block.Transactions[1].Hash, log.TxHash)
+		assert.Equal(t, argUint64(1), log.TxIndex)
+		assert.Equal(t, argUint64(1), log.LogIndex)
+	}
+}
+
+func TestTie_GetInternalTransactions(t *testing.T) {
+	store := newMockTieStore()
+	tie := &Tie{store}
+
+	block := store.blocks[3]
+	store.transfers[block.Hash()] = []*types.InternalTransfer{
+		{
+			TxHash: block.Transactions[1].Hash,
+			Type:   types.InternalTransferCall,
+			From:   types.StringToAddress("1"),
+			To:     types.StringToAddress("2"),
+			Value:  big.NewInt(10),
+			Depth:  2,
+		},
+	}
+
+	// the latest block by default
+	res, err := tie.GetInternalTransactions(BlockNumberOrHash{})
+	assert.NoError(t, err)
+	assert.Equal(t, []*internalTransaction{
+		{
+			BlockNumber: 3,
+			BlockHash:   block.Hash(),
+			TxHash:      block.Transactions[1].Hash,
+			TxIndex:     1,
+			Type:        "call",
+			From:        types.StringToAddress("1"),
+			To:          types.StringToAddress("2"),
+			Value:       argBig(*big.NewInt(10)),
+			Depth:       2,
+		},
+	}, res)
+
+	hash := block.Hash()
+	res, err = tie.GetInternalTransactions(BlockNumberOrHash{BlockHash: &hash})
+	assert.NoError(t, err)
+	assert.Len(t, res, 1)
+
+	// the transfers of the block 2 are not stored
+	number := BlockNumber(2)
+	_, err = tie.GetInternalTransactions(BlockNumberOrHash{BlockNumber: &number})
+	assert.Error(t, err)
+}
@@ -14,6 +14,13 @@ type txPoolStore interface {
 
 	// GetCapacity returns the current and max capacity of the pool
 	GetCapacity() (uint64, uint64)
+
+	// GetTransactionStatus returns the status of a transaction in the pool
+	// (unknown, queued, pending or dropped), and the reason of a dropped transaction
+	GetTransactionStatus(hash types.Hash) (string, string)
+
+	// ReadTxLookup returns a block hash in which a given txn was mined
+	ReadTxLookup(txnHash types.Hash) (types.Hash, bool)
 }
 
 // TxPool is the txpool rpc endpoint
@@ -38,6 +45,15 @@ type StatusResponse struct {
 	Queued  uint64 `json:"queued"`
 }
 
+type TransactionStatusResponse struct {
+	Status    string      `json:"status"`
+	Reason    string      `json:"reason,omitempty"`
+	BlockHash *types.Hash `json:"blockHash,omitempty"`
+}
+
+// the status of a transaction written to a block
+const txStatusIncluded = "included"
+
 type txpoolTransaction struct {
 	Nonce       argUint64      `json:"nonce"`
 	GasPrice    argBig         `json:"gasPrice"`
@@ -175,3 +191,21 @@ func (t *TxPool) Status() (interface{}, error) {
 
 	return resp, nil
 }
+
+// Create response for txpool_getTransactionStatus request.
+// The status is included, queued, pending, dropped with its reason or unknown
+func (t *TxPool) GetTransactionStatus(hash types.Hash) (interface{}, error) {
+	if blockHash, ok := t.store.ReadTxLookup(hash); ok {
+		return TransactionStatusResponse{
+			Status:    txStatusIncluded,
+			BlockHash: &blockHash,
+		}, nil
+	}
+
+	status, reason := t.store.GetTransactionStatus(hash)
+
+	return TransactionStatusResponse{
+		Status: status,
+		Reason: reason,
+	}, nil
+}
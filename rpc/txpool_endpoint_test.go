@@ -3,11 +3,12 @@ package rpc
 import (
 	"testing"
 
+	"github.com/TIE-Tech/tie-core/types"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestContentEndpoint(t *testing.T) {
-	dispatcher := newDispatcher(newMockStore(), 0)
+	dispatcher := newDispatcher(newMockStore(), &dispatcherParams{})
 
 	resp, err := dispatcher.Handle([]byte(`{
 		"method": "txpool_content",
@@ -23,7 +24,7 @@ func TestContentEndpoint(t *testing.T) {
 }
 
 func TestInspectEndpoint(t *testing.T) {
-	dispatcher := newDispatcher(newMockStore(), 0)
+	dispatcher := newDispatcher(newMockStore(), &dispatcherParams{})
 
 	resp, err := dispatcher.Handle([]byte(`{
 		"method": "txpool_inspect",
@@ -41,7 +42,7 @@ func TestInspectEndpoint(t *testing.T) {
 }
 
 func TestStatusEndpoint(t *testing.T) {
-	dispatcher := newDispatcher(newMockStore(), 0)
+	dispatcher := newDispatcher(newMockStore(), &dispatcherParams{})
 
 	resp, err := dispatcher.Handle([]byte(`{
 		"method": "txpool_status",
@@ -55,3 +56,56 @@ func TestStatusEndpoint(t *testing.T) {
 	assert.Equal(t, res.Pending, uint64(0))
 	assert.Equal(t, res.Queued, uint64(0))
 }
+
+type mockTxStatusStore struct {
+	*mockStore
+
+	included map[types.Hash]types.Hash
+	dropped  map[types.Hash]string
+}
+
+func (m *mockTxStatusStore) ReadTxLookup(hash types.Hash) (types.Hash, bool) {
+	blockHash, ok := m.included[hash]
+
+	return blockHash, ok
+}
+
+func (m *mockTxStatusStore) GetTransactionStatus(hash types.Hash) (string, string) {
+	if reason, ok := m.dropped[hash]; ok {
+		return "dropped", reason
+	}
+
+	return m.mockStore.GetTransactionStatus(hash)
+}
+
+func TestGetTransactionStatusEndpoint(t *testing.T) {
+	store := &mockTxStatusStore{
+		mockStore: newMockStore(),
+		included:  map[types.Hash]types.Hash{hash1: types.StringToHash("4")},
+		dropped:   map[types.Hash]string{hash2: "nonce too low"},
+	}
+	store.pendingTxs[hash3] = &types.Transaction{Hash: hash3}
+
+	dispatcher := newDispatcher(store, &dispatcherParams{})
+
+	blockHash := types.StringToHash("4")
+	cases := map[types.Hash]TransactionStatusResponse{
+		hash1:                   {Status: "included", BlockHash: &blockHash},
+		hash2:                   {Status: "dropped", Reason: "nonce too low"},
+		hash3:                   {Status: "pending"},
+		types.StringToHash("5"): {Status: "unknown"},
+	}
+
+	for hash, expected := range cases {
+		resp, err := dispatcher.Handle([]byte(`{
+			"method": "txpool_getTransactionStatus",
+			"params": ["` + hash.String() + `"]
+		}`))
+		assert.NoError(t, err)
+
+		var res TransactionStatusResponse
+
+		assert.NoError(t, expectJSONResult(resp, &res))
+		assert.Equal(t, expected, res, hash.String())
+	}
+}
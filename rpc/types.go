@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/TIE-Tech/tie-core/common/hex"
+	"github.com/TIE-Tech/tie-core/state"
 	"github.com/TIE-Tech/tie-core/types"
 )
 
@@ -166,9 +167,71 @@ type receipt struct {
 	BlockHash         types.Hash     `json:"blockHash"`
 	BlockNumber       argUint64      `json:"blockNumber"`
 	GasUsed           argUint64      `json:"gasUsed"`
+	EffectiveGasPrice argBig         `json:"effectiveGasPrice"`
 	ContractAddress   types.Address  `json:"contractAddress"`
 	FromAddr          types.Address  `json:"from"`
 	ToAddr            *types.Address `json:"to"`
+	RevertReason      string         `json:"revertReason,omitempty"`
+}
+
+// toReceipt returns the receipt of the transaction of a block at an index. The logs are
+// indexed in the block from the index of the first one
+func toReceipt(
+	raw *types.Receipt,
+	txn *types.Transaction,
+	txIndex int,
+	header *types.Header,
+	logIndex uint64,
+) *receipt {
+	logs := make([]*Log, len(raw.Logs))
+	for i, elem := range raw.Logs {
+		logs[i] = &Log{
+			Address:     elem.Address,
+			Topics:      elem.Topics,
+			Data:        argBytes(elem.Data),
+			BlockHash:   header.Hash,
+			BlockNumber: argUint64(header.Number),
+			TxHash:      txn.Hash,
+			TxIndex:     argUint64(txIndex),
+			LogIndex:    argUint64(logIndex + uint64(i)),
+			Removed:     false,
+		}
+	}
+
+	return &receipt{
+		Root:              raw.Root,
+		CumulativeGasUsed: argUint64(raw.CumulativeGasUsed),
+		LogsBloom:         raw.LogsBloom,
+		Status:            argUint64(*raw.Status),
+		TxHash:            txn.Hash,
+		TxIndex:           argUint64(txIndex),
+		BlockHash:         header.Hash,
+		BlockNumber:       argUint64(header.Number),
+		GasUsed:           argUint64(raw.GasUsed),
+		EffectiveGasPrice: argBig(*txn.GasPrice),
+		ContractAddress:   raw.ContractAddress,
+		FromAddr:          txn.From,
+		ToAddr:            txn.To,
+		Logs:              logs,
+		RevertReason:      raw.RevertReason,
+	}
+}
+
+// firstLogIndex returns the index in the block of the first log of the receipt at an index
+func firstLogIndex(receipts []*types.Receipt, txIndex int) uint64 {
+	index := uint64(0)
+	for _, raw := range receipts[:txIndex] {
+		index += uint64(len(raw.Logs))
+	}
+
+	return index
+}
+
+type feeHistory struct {
+	OldestBlock   argUint64  `json:"oldestBlock"`
+	BaseFeePerGas []argBig   `json:"baseFeePerGas"`
+	GasUsedRatio  []float64  `json:"gasUsedRatio"`
+	Reward        [][]argBig `json:"reward,omitempty"`
 }
 
 type Log struct {
@@ -304,6 +367,104 @@ type txnArgs struct {
 	Nonce    *argUint64
 }
 
+// accountOverride is the override of an account for the call endpoints
+type accountOverride struct {
+	Nonce     *argUint64                 `json:"nonce"`
+	Code      *argBytes                  `json:"code"`
+	Balance   *argBig                    `json:"balance"`
+	State     *map[types.Hash]types.Hash `json:"state"`
+	StateDiff *map[types.Hash]types.Hash `json:"stateDiff"`
+}
+
+// stateOverride is the set of the account overrides for the call endpoints
+type stateOverride map[types.Address]accountOverride
+
+func (s stateOverride) toState() state.StateOverride {
+	if s == nil {
+		return nil
+	}
+
+	override := state.StateOverride{}
+
+	for addr, account := range s {
+		o := &state.AccountOverride{}
+
+		if account.Nonce != nil {
+			nonce := uint64(*account.Nonce)
+			o.Nonce = &nonce
+		}
+
+		if account.Code != nil {
+			o.Code = append([]byte{}, *account.Code...)
+		}
+
+		if account.Balance != nil {
+			o.Balance = new(big.Int).Set((*big.Int)(account.Balance))
+		}
+
+		if account.State != nil {
+			o.State = *account.State
+			if o.State == nil {
+				o.State = map[types.Hash]types.Hash{}
+			}
+		}
+
+		if account.StateDiff != nil {
+			o.StateDiff = *account.StateDiff
+		}
+
+		override[addr] = o
+	}
+
+	return override
+}
+
+// fillNonce sets the overridden nonce of the sender if the call has none
+func (s stateOverride) fillNonce(arg *txnArgs) {
+	if arg.From == nil || arg.Nonce != nil {
+		return
+	}
+
+	if account, ok := s[*arg.From]; ok && account.Nonce != nil {
+		arg.Nonce = account.Nonce
+	}
+}
+
+// blockOverride is the override of the block context for the call endpoints
+type blockOverride struct {
+	Number   *argUint64     `json:"number"`
+	Time     *argUint64     `json:"time"`
+	Coinbase *types.Address `json:"coinbase"`
+	GasLimit *argUint64     `json:"gasLimit"`
+}
+
+func (b *blockOverride) toState() *state.BlockOverride {
+	if b == nil {
+		return nil
+	}
+
+	override := &state.BlockOverride{
+		Coinbase: b.Coinbase,
+	}
+
+	if b.Number != nil {
+		number := uint64(*b.Number)
+		override.Number = &number
+	}
+
+	if b.Time != nil {
+		timestamp := uint64(*b.Time)
+		override.Timestamp = &timestamp
+	}
+
+	if b.GasLimit != nil {
+		gasLimit := uint64(*b.GasLimit)
+		override.GasLimit = &gasLimit
+	}
+
+	return override
+}
+
 type progression struct {
 	Type          string `json:"type"`
 	StartingBlock string `json:"startingBlock"`
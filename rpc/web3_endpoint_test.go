@@ -7,7 +7,7 @@ import (
 )
 
 func TestWeb3EndpointSha3(t *testing.T) {
-	dispatcher := newDispatcher(newMockStore(), 0)
+	dispatcher := newDispatcher(newMockStore(), &dispatcherParams{})
 
 	resp, err := dispatcher.Handle([]byte(`{
 		"method": "web3_sha3",
@@ -3,8 +3,11 @@ package server
 import (
 	"github.com/TIE-Tech/tie-core/core/nodekey"
 	"github.com/TIE-Tech/tie-core/params"
+	"github.com/TIE-Tech/tie-core/rpc"
+	itrie "github.com/TIE-Tech/tie-core/state/trie"
 	"github.com/TIE-Tech/tie-core/types"
 	"net"
+	"time"
 
 	"github.com/TIE-Tech/tie-core/p2p"
 )
@@ -13,32 +16,86 @@ import (
 type Config struct {
 	Chain *params.Chain
 
-	JSONRPCAddr    *net.TCPAddr
-	GRPCAddr       *net.TCPAddr
-	LibP2PAddr     *net.TCPAddr
-	Telemetry      *Telemetry
-	Network        *p2p.Config
-	DataDir        string
-	Seal           bool
-	PriceLimit     uint64
-	MaxSlots       uint64
-	SecretsManager *nodekey.SecretsManagerConfig
-	RestoreFile    *string
-	BlockTime      uint64
+	JSONRPCAddr       *net.TCPAddr
+	JSONRPC           *JSONRPC
+	GRPCAddr          *net.TCPAddr
+	LibP2PAddr        *net.TCPAddr
+	Telemetry         *Telemetry
+	Network           *p2p.Config
+	DataDir           string
+	Seal              bool
+	AddressIndex      bool
+	InternalTransfers bool
+	ParallelExecution uint64
+	PendingBlock      bool
+	PriceLimit        uint64
+	MaxSlots          uint64
+	SecretsManager    *nodekey.SecretsManagerConfig
+	RestoreFile       *string
+	BlockTime         uint64
+	Pruning           *itrie.PruningConfig
 }
 
 // DefaultConfig returns the default config for JSON-RPC, GRPC (ports) and Networking
 func DefaultConfig() *Config {
 	return &Config{
-		JSONRPCAddr:    &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: types.DefaultJSONRPCPort},
+		JSONRPCAddr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: types.DefaultJSONRPCPort},
+		JSONRPC: &JSONRPC{
+			MaxBlockRange:  rpc.DefaultMaxBlockRange,
+			MaxLogs:        rpc.DefaultMaxLogs,
+			Access:         rpc.AccessConfig{CorsOrigins: []string{"*"}, VHosts: []string{"*"}},
+			MaxRequestSize: rpc.DefaultMaxRequestSize,
+			MaxBatchLength: rpc.DefaultMaxBatchLength,
+			CallTimeout:    rpc.DefaultCallTimeout,
+		},
 		GRPCAddr:       &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: types.DefaultGRPCPort},
 		Network:        p2p.DefaultConfig(),
 		Telemetry:      &Telemetry{PrometheusAddr: nil},
 		SecretsManager: nil,
 		BlockTime:      types.DefaultBlockTime,
+		Pruning:        itrie.DefaultPruningConfig(),
 	}
 }
 
+// JSONRPC holds the limits of the json rpc queries and the config of its listeners
+type JSONRPC struct {
+	MaxBlockRange uint64
+	MaxLogs       uint64
+
+	// IPCPath is the path of the unix socket of the ipc server, empty if disabled
+	IPCPath string
+
+	// Access restricts the methods and the clients of the http listener
+	Access rpc.AccessConfig
+
+	// AuthAddr is the address of the listener authenticated with the jwt secret, nil if disabled
+	AuthAddr *net.TCPAddr
+
+	// JWTSecretPath is the path of the jwt secret, generated if it does not exist
+	JWTSecretPath string
+
+	// AuthAccess restricts the methods and the clients of the authenticated listener
+	AuthAccess rpc.AccessConfig
+
+	// MaxRequestSize is the maximum size of a request, 0 for no limit
+	MaxRequestSize int64
+
+	// MaxBatchLength is the maximum number of requests of a batch, 0 for no limit
+	MaxBatchLength uint64
+
+	// CallTimeout is the maximum execution time of eth_call, eth_estimateGas and eth_simulateV1, 0 for no limit
+	CallTimeout time.Duration
+
+	// SlowRequestThreshold is the duration above which the requests are logged, 0 to disable
+	SlowRequestThreshold time.Duration
+
+	// GraphQL enables the graphql endpoint of the http listeners
+	GraphQL bool
+
+	// KeystoreDir is the directory of the encrypted keys of the account methods, empty if disabled
+	KeystoreDir string
+}
+
 // Telemetry holds the config details for metric services
 type Telemetry struct {
 	PrometheusAddr *net.TCPAddr
@@ -16,6 +16,7 @@ import (
 	"time"
 
 	"github.com/TIE-Tech/go-logger"
+	"github.com/TIE-Tech/tie-core/accounts/keystore"
 	"github.com/TIE-Tech/tie-core/archive"
 	"github.com/TIE-Tech/tie-core/common/common"
 	"github.com/TIE-Tech/tie-core/common/progress"
@@ -30,6 +31,7 @@ import (
 	"github.com/TIE-Tech/tie-core/tievm/evm/execute"
 	"github.com/TIE-Tech/tie-core/tievm/evm/precompiled"
 	"github.com/TIE-Tech/tie-core/txpool"
+	txpoolProto "github.com/TIE-Tech/tie-core/txpool/proto"
 	"github.com/TIE-Tech/tie-core/types"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -72,6 +74,10 @@ type Server struct {
 
 	// restore
 	restoreProgression *progress.ProgressionWrapper
+
+	// state pruning
+	prunerSub  blockchain.Subscription
+	prunerDone chan struct{}
 }
 
 var dirPaths = []string{
@@ -137,6 +143,7 @@ func NewServer(config *Config) (*Server, error) {
 	m.executor = state.NewExecutor(config.Chain.Params, st)
 	m.executor.SetRuntime(precompiled.NewPrecompiled())
 	m.executor.SetRuntime(execute.NewEVM())
+	m.executor.SetParallelism(int(m.config.ParallelExecution))
 
 	// compute the genesis root state
 	genesisRoot := m.executor.WriteGenesis(config.Chain.Genesis.Alloc)
@@ -166,6 +173,8 @@ func NewServer(config *Config) (*Server, error) {
 				Sealing:    m.config.Seal,
 				MaxSlots:   m.config.MaxSlots,
 				PriceLimit: m.config.PriceLimit,
+
+				SystemTxFork: m.chain.Params.Forks.SystemTx,
 			},
 		)
 		if err != nil {
@@ -192,11 +201,32 @@ func NewServer(config *Config) (*Server, error) {
 		return nil, err
 	}
 
+	m.blockchain.EnableBloomBitsIndex()
+
+	if m.config.AddressIndex {
+		m.blockchain.EnableAddressIndex()
+	}
+
+	if m.config.InternalTransfers {
+		m.blockchain.EnableInternalTransfers()
+	}
+
+	// make sure the flat state snapshot covers the head state
+	m.setupStateSnapshot(st)
+
+	// prune the historic states if the node is not an archive node
+	m.setupStatePruner(st)
+
 	// initialize data in consensus layer
 	if err := m.consensus.Initialize(); err != nil {
 		return nil, err
 	}
 
+	// build the pending block served by the rpc
+	if m.config.PendingBlock {
+		m.setupPendingBlock()
+	}
+
 	// setup and start rpc server
 	if err := m.setupJSONRPC(); err != nil {
 		return nil, err
@@ -226,6 +256,82 @@ func NewServer(config *Config) (*Server, error) {
 	return m, nil
 }
 
+// setupStateSnapshot generates the flat state snapshot in the background when
+// it does not match the head state, the reads use the trie meanwhile
+func (s *Server) setupStateSnapshot(st *itrie.State) {
+	root := s.blockchain.Header().StateRoot
+	if st.HasSnapshot(root) {
+		return
+	}
+
+	go func() {
+		logger.Info("[SVR] generating state snapshot", "root", root.String())
+
+		if err := st.GenerateSnapshot(root); err != nil {
+			logger.Error("[SVR] failed to generate state snapshot", "root", root.String(), "err", err)
+
+			return
+		}
+
+		logger.Info("[SVR] state snapshot generated", "root", root.String())
+	}()
+}
+
+// setupStatePruner removes the states outside of the retention window on every new head
+func (s *Server) setupStatePruner(st *itrie.State) {
+	if s.config.Pruning == nil || s.config.Pruning.Mode != itrie.PruningPruned {
+		return
+	}
+
+	pruner := itrie.NewPruner(st, s.config.Pruning, s.blockchain.GetHeaderByNumber)
+
+	s.prunerSub = s.blockchain.SubscribeEvents()
+	s.prunerDone = make(chan struct{})
+
+	logger.Info(
+		"[SVR] state pruning enabled",
+		"retention", s.config.Pruning.Retention,
+		"checkpoint", s.config.Pruning.CheckpointInterval,
+	)
+
+	go func() {
+		defer close(s.prunerDone)
+
+		pruner.HandleHead(s.blockchain.Header().Number)
+
+		for {
+			evnt := s.prunerSub.GetEvent()
+			if evnt == nil {
+				return
+			}
+
+			if evnt.Type == blockchain.EventFork || len(evnt.NewChain) == 0 {
+				continue
+			}
+
+			pruner.HandleHead(evnt.Header().Number)
+		}
+	}()
+}
+
+// setupPendingBlock builds the pending block on every new head and after the changes of the executables of the pool
+func (s *Server) setupPendingBlock() {
+	s.blockchain.EnablePendingBlock(s.executor, s.txpool)
+
+	// the subscription is closed with the pool
+	eventCh, _ := s.txpool.SubscribeTxEvents([]txpoolProto.EventType{
+		txpoolProto.EventType_PROMOTED,
+		txpoolProto.EventType_DEMOTED,
+		txpoolProto.EventType_DROPPED,
+	})
+
+	go func() {
+		for range eventCh {
+			s.blockchain.NotifyPendingTxs()
+		}
+	}()
+}
+
 func (s *Server) restoreChain() error {
 	if s.config.RestoreFile == nil {
 		return nil
@@ -385,6 +491,13 @@ func (j *jsonRPCHub) GetPeers() int {
 	return len(j.Server.Peers())
 }
 
+// GetTransactionStatus returns the status of a transaction in the pool, and the reason of a dropped transaction
+func (j *jsonRPCHub) GetTransactionStatus(hash types.Hash) (string, string) {
+	status, reason := j.TxPool.GetTxStatus(hash)
+
+	return status.String(), reason
+}
+
 func (j *jsonRPCHub) getState(root types.Hash, slot []byte) ([]byte, error) {
 	// the values in the trie are the hashed objects of the keys
 	key := keccak.Keccak256(nil, slot)
@@ -449,21 +562,75 @@ func (j *jsonRPCHub) GetCode(hash types.Hash) ([]byte, error) {
 }
 
 func (j *jsonRPCHub) ApplyTxn(
+	ctx context.Context,
 	header *types.Header,
 	txn *types.Transaction,
+	stateOverride state.StateOverride,
+	blockOverride *state.BlockOverride,
 ) (result *evm.ExecutionResult, err error) {
-	blockCreator, err := j.GetConsensus().GetBlockCreator(header)
+	transition, err := j.BeginSimulation(header)
 	if err != nil {
 		return nil, err
 	}
 
-	transition, err := j.BeginTxn(header.StateRoot, header, blockCreator)
-	if err != nil {
-		return
+	return applySimulatedTxn(ctx, transition, txn, stateOverride, blockOverride)
+}
+
+// ApplyPendingTxn applies a transaction on the state of the pending block, the changes are discarded
+func (j *jsonRPCHub) ApplyPendingTxn(
+	ctx context.Context,
+	txn *types.Transaction,
+	stateOverride state.StateOverride,
+	blockOverride *state.BlockOverride,
+) (result *evm.ExecutionResult, err error) {
+	err = j.Blockchain.PendingState(func(_ *types.Header, transition *state.Transition) error {
+		result, err = applySimulatedTxn(ctx, transition, txn, stateOverride, blockOverride)
+
+		return err
+	})
+
+	return result, err
+}
+
+// applySimulatedTxn applies a transaction with the overrides on a transition which is never committed
+func applySimulatedTxn(
+	ctx context.Context,
+	transition *state.Transition,
+	txn *types.Transaction,
+	stateOverride state.StateOverride,
+	blockOverride *state.BlockOverride,
+) (*evm.ExecutionResult, error) {
+	// the overrides are only written in the transition, which is never committed
+	if err := transition.ApplyOverrides(stateOverride, blockOverride); err != nil {
+		return nil, err
+	}
+
+	// cancel the execution when the context is done
+	if ctx.Done() != nil {
+		done := make(chan struct{})
+		defer close(done)
+
+		go func() {
+			select {
+			case <-ctx.Done():
+				transition.Cancel()
+			case <-done:
+			}
+		}()
 	}
+
 	return transition.Apply(txn)
 }
 
+func (j *jsonRPCHub) BeginSimulation(header *types.Header) (*state.Transition, error) {
+	blockCreator, err := j.GetConsensus().GetBlockCreator(header)
+	if err != nil {
+		return nil, err
+	}
+
+	return j.BeginTxn(header.StateRoot, header, blockCreator)
+}
+
 func (j *jsonRPCHub) GetSyncProgression() *progress.Progression {
 	// restore progression
 	if restoreProg := j.restoreProgression.GetProgression(); restoreProg != nil {
@@ -478,6 +645,31 @@ func (j *jsonRPCHub) GetSyncProgression() *progress.Progression {
 	return nil
 }
 
+// SubscribePendingTxs subscribes for the hashes of the transactions promoted in the pool
+func (j *jsonRPCHub) SubscribePendingTxs() (<-chan types.Hash, func()) {
+	eventCh, cancel := j.TxPool.SubscribeTxEvents([]txpoolProto.EventType{txpoolProto.EventType_PROMOTED})
+
+	hashCh := make(chan types.Hash)
+	doneCh := make(chan struct{})
+
+	go func() {
+		defer close(hashCh)
+
+		for event := range eventCh {
+			select {
+			case hashCh <- types.StringToHash(event.TxHash):
+			case <-doneCh:
+				return
+			}
+		}
+	}()
+
+	return hashCh, func() {
+		close(doneCh)
+		cancel()
+	}
+}
+
 // SETUP //
 
 // setupJSONRCP sets up the JSONRPC server, using the set configuration
@@ -493,9 +685,43 @@ func (s *Server) setupJSONRPC() error {
 	}
 
 	conf := &rpc.Config{
-		Store:   hub,
-		Addr:    s.config.JSONRPCAddr,
-		ChainID: uint64(s.config.Chain.Params.ChainID),
+		Store:         hub,
+		Addr:          s.config.JSONRPCAddr,
+		ChainID:       uint64(s.config.Chain.Params.ChainID),
+		MaxBlockRange: s.config.JSONRPC.MaxBlockRange,
+		MaxLogs:       s.config.JSONRPC.MaxLogs,
+		IPCPath:       s.config.JSONRPC.IPCPath,
+		Access:        s.config.JSONRPC.Access,
+		AuthAddr:      s.config.JSONRPC.AuthAddr,
+		AuthAccess:    s.config.JSONRPC.AuthAccess,
+
+		MaxRequestSize: s.config.JSONRPC.MaxRequestSize,
+		MaxBatchLength: s.config.JSONRPC.MaxBatchLength,
+		CallTimeout:    s.config.JSONRPC.CallTimeout,
+		Metrics:        s.serverMetrics.JSONRPC,
+
+		SlowRequestThreshold: s.config.JSONRPC.SlowRequestThreshold,
+		GraphQL:              s.config.JSONRPC.GraphQL,
+	}
+
+	if conf.AuthAddr != nil {
+		secret, err := rpc.ObtainJWTSecret(s.config.JSONRPC.JWTSecretPath)
+		if err != nil {
+			return err
+		}
+
+		conf.JWTSecret = secret
+	}
+
+	if dir := s.config.JSONRPC.KeystoreDir; dir != "" {
+		ks, err := keystore.NewKeyStore(dir, keystore.StandardScryptN, keystore.StandardScryptP)
+		if err != nil {
+			return err
+		}
+
+		logger.Info("[SVR] keystore enabled", "dir", dir, "accounts", len(ks.Accounts()))
+
+		conf.Keystore = ks
 	}
 
 	srv, err := rpc.NewJSONRPC(conf)
@@ -536,6 +762,15 @@ func (s *Server) Join(addr0 string, dur time.Duration) error {
 
 // Close closes the Minimal server (blockchain, networking, consensus)
 func (s *Server) Close() {
+	head := s.blockchain.Header()
+
+	// Close the ipc server of the JSON-RPC and remove its socket
+	if s.jsonrpcServer != nil {
+		if err := s.jsonrpcServer.Close(); err != nil {
+			logger.Error("failed to close the JSON-RPC server", "err", err.Error())
+		}
+	}
+
 	// Close the blockchain layer
 	if err := s.blockchain.Close(); err != nil {
 		logger.Error("failed to close blockchain", "err", err.Error())
@@ -551,6 +786,19 @@ func (s *Server) Close() {
 		logger.Error("failed to close consensus", "err", err.Error())
 	}
 
+	// Stop the state pruner before closing its storage
+	if s.prunerSub != nil {
+		s.prunerSub.Close()
+		<-s.prunerDone
+	}
+
+	// Persist the state snapshot of the head
+	if st, ok := s.state.(*itrie.State); ok {
+		if err := st.FlushSnapshot(head.StateRoot); err != nil {
+			logger.Error("failed to flush state snapshot", "err", err.Error())
+		}
+	}
+
 	// Close the state storage
 	if err := s.stateStorage.Close(); err != nil {
 		logger.Error("failed to close storage for trie", "err", err.Error())
@@ -8,6 +8,7 @@ import (
 	"github.com/TIE-Tech/tie-core/params"
 	"math"
 	"math/big"
+	"sync/atomic"
 
 	"github.com/TIE-Tech/tie-core/tievm/evm"
 	"github.com/TIE-Tech/tie-core/types"
@@ -27,6 +28,12 @@ type GetHashByNumber = func(i uint64) types.Hash
 
 type GetHashByNumberHelper = func(*types.Header) GetHashByNumber
 
+// GetVrfValueHelper returns the vrf output of a block header
+type GetVrfValueHelper = func(*types.Header) []byte
+
+// GetFixedRewardHelper returns the fixed reward paid to the creator of a block header
+type GetFixedRewardHelper = func(*types.Header) *big.Int
+
 // Executor is the main entity
 type Executor struct {
 	config   *params.Params
@@ -34,7 +41,17 @@ type Executor struct {
 	state    State
 	GetHash  GetHashByNumberHelper
 
+	// GetVrfValue is set by the consensus that writes a vrf output in the headers
+	GetVrfValue GetVrfValueHelper
+
+	// GetFixedReward is set by the consensus that pays a fixed reward to the block creator,
+	// the value of the fixed reward transactions is checked against it after the SystemTx fork
+	GetFixedReward GetFixedRewardHelper
+
 	PostHook func(txn *Transition)
+
+	// parallelism is the number of transactions of a block executed in parallel
+	parallelism int
 }
 
 // NewExecutor creates a new executor
@@ -79,9 +96,10 @@ func (e *Executor) SetRuntime(r evm.Runtime) {
 }
 
 type BlockResult struct {
-	Root     types.Hash
-	Receipts []*types.Receipt
-	TotalGas uint64
+	Root              types.Hash
+	Receipts          []*types.Receipt
+	InternalTransfers []*types.InternalTransfer
+	TotalGas          uint64
 }
 
 // ProcessBlock already does all the handling of the whole process
@@ -96,27 +114,36 @@ func (e *Executor) ProcessBlock(
 	}
 
 	txn.block = block
-	for _, t := range block.Transactions {
-		if t.ExceedsBlockGasLimit(block.Header.GasLimit) {
-			if err := txn.WriteFailedReceipt(t); err != nil {
+	for txns := block.Transactions; len(txns) > 0; {
+		// the transactions up to the next one exceeding the block gas limit are written as a batch
+		n := 0
+		for n < len(txns) && !txns[n].ExceedsBlockGasLimit(block.Header.GasLimit) {
+			n++
+		}
+
+		if _, err := txn.WriteBatch(txns[:n]); err != nil {
+			return nil, err
+		}
+
+		if n < len(txns) {
+			if err := txn.WriteFailedReceipt(txns[n]); err != nil {
 				return nil, err
 			}
 
-			continue
+			n++
 		}
 
-		if err := txn.Write(t); err != nil {
-			return nil, err
-		}
+		txns = txns[n:]
 	}
 	NewLock().CleanTag(block.Number())
 
 	_, root := txn.Commit()
 
 	res := &BlockResult{
-		Root:     root,
-		Receipts: txn.Receipts(),
-		TotalGas: txn.TotalGas(),
+		Root:              root,
+		Receipts:          txn.Receipts(),
+		InternalTransfers: txn.InternalTransfers(),
+		TotalGas:          txn.TotalGas(),
 	}
 
 	return res, nil
@@ -158,6 +185,15 @@ func (e *Executor) BeginTxn(
 		ChainID:    int64(e.config.ChainID),
 	}
 
+	if e.GetVrfValue != nil {
+		env2.VrfValue = e.GetVrfValue(header)
+	}
+
+	var fixedReward *big.Int
+	if config.SystemTx && e.GetFixedReward != nil {
+		fixedReward = e.GetFixedReward(header)
+	}
+
 	transaction := &Transition{
 		r:        e,
 		ctx:      env2,
@@ -169,6 +205,8 @@ func (e *Executor) BeginTxn(
 
 		receipts: []*types.Receipt{},
 		totalGas: 0,
+
+		fixedReward: fixedReward,
 	}
 
 	return transaction, nil
@@ -189,9 +227,24 @@ type Transition struct {
 	ctx     evm.TxContext
 	gasPool uint64
 
+	// fixedReward is the value of the fixed reward transactions, nil if it is not checked
+	fixedReward *big.Int
+
 	// result
 	receipts []*types.Receipt
 	totalGas uint64
+
+	// transfers are the internal transfers of the written transactions,
+	// txTransfers the ones of the transaction being applied
+	transfers   []*types.InternalTransfer
+	txTransfers []*types.InternalTransfer
+
+	// fees accumulates the fees of a speculative execution, which are
+	// credited to the fee pool when the transaction is committed
+	fees *big.Int
+
+	// cancelled is set to stop the execution
+	cancelled int32
 }
 
 func (t *Transition) TotalGas() uint64 {
@@ -202,6 +255,16 @@ func (t *Transition) Receipts() []*types.Receipt {
 	return t.receipts
 }
 
+// InternalTransfers returns the internal transfers of the written transactions
+func (t *Transition) InternalTransfers() []*types.InternalTransfer {
+	return t.transfers
+}
+
+// LastInternalTransfers returns the internal transfers of the last applied transaction
+func (t *Transition) LastInternalTransfers() []*types.InternalTransfer {
+	return t.txTransfers
+}
+
 var emptyFrom = types.Address{}
 
 func (t *Transition) WriteFailedReceipt(txn *types.Transaction) error {
@@ -257,9 +320,19 @@ func (t *Transition) Write(txn *types.Transaction) error {
 		return e
 	}
 
-	t.totalGas += result.GasUsed
+	t.writeResult(txn, msg, result, t.state.Logs())
 
-	logs := t.state.Logs()
+	return nil
+}
+
+// writeResult writes the receipt and the internal transfers of an applied transaction
+func (t *Transition) writeResult(
+	txn *types.Transaction,
+	msg *types.Transaction,
+	result *evm.ExecutionResult,
+	logs []*types.Log,
+) {
+	t.totalGas += result.GasUsed
 
 	var root []byte
 
@@ -290,12 +363,21 @@ func (t *Transition) Write(txn *types.Transaction) error {
 		receipt.ContractAddress = crypto.CreateAddress(msg.From, txn.Nonce)
 	}
 
+	// keep the reason of a revert for the clients
+	if result.Reverted() {
+		receipt.RevertReason, _ = evm.UnpackRevertReason(result.ReturnValue)
+	}
+
 	// Set the receipt logs and create a bloom for filtering
 	receipt.Logs = logs
 	receipt.LogsBloom = types.CreateBloom([]*types.Receipt{receipt})
 	t.receipts = append(t.receipts, receipt)
 
-	return nil
+	for _, transfer := range t.txTransfers {
+		transfer.TxHash = txn.Hash
+	}
+
+	t.transfers = append(t.transfers, t.txTransfers...)
 }
 
 // Commit commits the final result
@@ -305,6 +387,29 @@ func (t *Transition) Commit() (Snapshot, types.Hash) {
 	return s2, types.BytesToHash(root)
 }
 
+// Fork returns a transition on the current state of the transition, with a full gas pool
+// and no receipts. The writes of the fork do not change the transition. Forking is not
+// thread-safe, but once the transition is no longer written its forks can be used by
+// different goroutines, each fork by one at a time. Neither of them must be committed
+func (t *Transition) Fork() *Transition {
+	txnState := newTxn(t.state.state, t.state.snapshot)
+	txnState.txn = t.state.txn.CommitOnly().Txn()
+
+	return &Transition{
+		auxState: t.auxState,
+		block:    t.block,
+		r:        t.r,
+		config:   t.config,
+		state:    txnState,
+		getHash:  t.getHash,
+		ctx:      t.ctx,
+		gasPool:  uint64(t.ctx.GasLimit),
+		receipts: []*types.Receipt{},
+
+		fixedReward: t.fixedReward,
+	}
+}
+
 func (t *Transition) subGasPool(amount uint64) error {
 	if t.gasPool < amount {
 		return ErrBlockLimitReached
@@ -334,9 +439,11 @@ func (t *Transition) GetTxnHash() types.Hash {
 // Apply applies a new transaction
 func (t *Transition) Apply(msg *types.Transaction) (*evm.ExecutionResult, error) {
 	s := t.state.Snapshot() //nolint:ifshort //nolint:nolintlint
+	t.txTransfers = nil
+
 	result, err := t.apply(msg)
 	if err != nil {
-		t.state.RevertToSnapshot(s)
+		t.revertToSnapshot(s, 0)
 	}
 
 	if t.r.PostHook != nil {
@@ -345,6 +452,17 @@ func (t *Transition) Apply(msg *types.Transaction) (*evm.ExecutionResult, error)
 	return result, err
 }
 
+// Cancel stops the execution of the transaction being applied, which fails with
+// evm.ErrExecutionCancelled. It can be called from any goroutine
+func (t *Transition) Cancel() {
+	atomic.StoreInt32(&t.cancelled, 1)
+}
+
+// Cancelled returns true if the execution is cancelled
+func (t *Transition) Cancelled() bool {
+	return atomic.LoadInt32(&t.cancelled) == 1
+}
+
 // ContextPtr returns reference of context
 // This method is called only by test
 func (t *Transition) ContextPtr() *evm.TxContext {
@@ -386,6 +504,10 @@ var (
 	ErrIntrinsicGasOverflow  = fmt.Errorf("overflow in intrinsic gas calculation")
 	ErrNotEnoughIntrinsicGas = fmt.Errorf("not enough gas supplied for intrinsic gas costs")
 	ErrNotEnoughFunds        = fmt.Errorf("not enough funds for transfer with given value")
+	ErrSystemTxSender        = fmt.Errorf("fixed reward transaction not sent by the block creator")
+	ErrSystemTxGasPrice      = fmt.Errorf("fixed reward transaction with non zero gas price")
+	ErrSystemTxReward        = fmt.Errorf("fixed reward transaction with invalid value")
+	ErrReservedAddress       = fmt.Errorf("transaction to a reserved address is not a system transaction")
 )
 
 type TransitionApplicationError struct {
@@ -426,6 +548,17 @@ func (t *Transition) apply(msg *types.Transaction) (*evm.ExecutionResult, error)
 	// 6. caller has enough balance to cover asset transfer for **topmost** call
 	txn := t.state
 
+	// 0. system transactions follow the rules of the block, after the SystemTx fork
+	// the reserved addresses only accept them
+	systemTx, err := t.systemTxType(msg)
+	if err != nil {
+		return nil, NewTransitionApplicationError(err, false)
+	}
+
+	if t.config.SystemTx && systemTx == types.SystemTxNone && msg.To != nil && types.IsReservedAddress(*msg.To) {
+		return nil, NewTransitionApplicationError(ErrReservedAddress, false)
+	}
+
 	// 1. the nonce of the message caller is correct
 	if err := t.nonceCheck(msg); err != nil {
 		return nil, NewTransitionApplicationError(err, true)
@@ -451,13 +584,13 @@ func (t *Transition) apply(msg *types.Transaction) (*evm.ExecutionResult, error)
 	gasLeft := msg.Gas - intrinsicGasCost
 
 	// Because we are working with unsigned integers for gas, the `>` operator is used instead of the more intuitive `<`
-	if !msg.IsFixedRewardTx() && gasLeft > msg.Gas {
+	if systemTx != types.SystemTxFixedReward && gasLeft > msg.Gas {
 		return nil, NewTransitionApplicationError(ErrNotEnoughIntrinsicGas, false)
 	}
 
 	// 6. caller has enough balance to cover asset transfer for **topmost** call
 	balance := txn.GetBalance(msg.From)
-	if !msg.IsFixedRewardTx() && balance.Cmp(msg.Value) < 0 {
+	if systemTx != types.SystemTxFixedReward && balance.Cmp(msg.Value) < 0 {
 		return nil, NewTransitionApplicationError(ErrNotEnoughFunds, true)
 	}
 
@@ -471,13 +604,13 @@ func (t *Transition) apply(msg *types.Transaction) (*evm.ExecutionResult, error)
 	var result *evm.ExecutionResult
 	if msg.IsContractCreation() {
 		result = t.Create2(msg.From, msg.Input, value, gasLeft)
-	} else if msg.IsWithdrawFee() {
+	} else if systemTx == types.SystemTxWithdrawFee {
 		result, err = t.WithdrawTxFee(msg.From, *msg.To, value)
 		if err != nil {
 			return nil, err
 		}
 		txn.IncrNonce(msg.From)
-	} else if msg.IsFixedRewardTx() {
+	} else if systemTx == types.SystemTxFixedReward {
 		result, err = t.SendFixedReward(msg.From, *msg.To, value)
 		if err != nil {
 			return nil, err
@@ -497,7 +630,7 @@ func (t *Transition) apply(msg *types.Transaction) (*evm.ExecutionResult, error)
 
 	// pay the coinbase
 	coinbaseFee := new(big.Int).Mul(new(big.Int).SetUint64(result.GasUsed), gasPrice)
-	txn.AddBalance(FeePool, coinbaseFee)
+	t.payFee(coinbaseFee)
 
 	// return gas to the pool
 	t.addGasPool(result.GasLeft)
@@ -505,6 +638,62 @@ func (t *Transition) apply(msg *types.Transaction) (*evm.ExecutionResult, error)
 	return result, nil
 }
 
+// payFee credits the fee of a transaction to the fee pool, or to the fees of a speculative execution
+func (t *Transition) payFee(fee *big.Int) {
+	if t.fees != nil {
+		t.fees.Add(t.fees, fee)
+
+		return
+	}
+
+	t.state.AddBalance(FeePool, fee)
+}
+
+// IsSystemTx returns true if msg is a system transaction with the rules of the block,
+// including the invalid ones
+func (t *Transition) IsSystemTx(msg *types.Transaction) bool {
+	systemTx, err := t.systemTxType(msg)
+
+	return err != nil || systemTx != types.SystemTxNone
+}
+
+// systemTxType returns the kind of system transaction of msg. Before the SystemTx fork
+// the method selectors are matched anywhere in the input so that historic blocks replay,
+// after it only the calls to the reserved addresses with the exact selector are accepted
+func (t *Transition) systemTxType(msg *types.Transaction) (types.SystemTxType, error) {
+	if !t.config.SystemTx {
+		if msg.IsWithdrawFee() {
+			return types.SystemTxWithdrawFee, nil
+		} else if msg.IsFixedRewardTx() {
+			return types.SystemTxFixedReward, nil
+		}
+
+		return types.SystemTxNone, nil
+	}
+
+	systemTx, err := msg.SystemTxType()
+	if err != nil {
+		return types.SystemTxNone, err
+	}
+
+	if systemTx == types.SystemTxFixedReward {
+		// only the block creator pays itself the reward
+		if msg.From != t.ctx.Coinbase {
+			return types.SystemTxNone, ErrSystemTxSender
+		}
+
+		if msg.GasPrice.Sign() != 0 {
+			return types.SystemTxNone, ErrSystemTxGasPrice
+		}
+
+		if t.fixedReward != nil && msg.Value.Cmp(t.fixedReward) != 0 {
+			return types.SystemTxNone, ErrSystemTxReward
+		}
+	}
+
+	return systemTx, nil
+}
+
 // Create2
 func (t *Transition) Create2(
 	caller types.Address,
@@ -550,7 +739,7 @@ func (t *Transition) WithdrawTxFee(caller types.Address, to types.Address, value
 		return result, result.Err
 	}
 
-	err := t.transfer(FeePool, caller, value)
+	err := t.transfer(types.InternalTransferWithdrawFee, 0, FeePool, caller, value)
 	if err != nil {
 		result.Err = err
 		return result, err
@@ -568,7 +757,7 @@ func (t *Transition) SendFixedReward(caller types.Address, to types.Address, val
 		result.Err = errors.New("Invalid transaction")
 		return result, result.Err
 	}
-	if err := t.transfer(to, caller, value); err != nil {
+	if err := t.transfer(types.InternalTransferFixedReward, 0, to, caller, value); err != nil {
 		result.Err = err
 		return result, err
 	}
@@ -590,7 +779,14 @@ func (t *Transition) run(contract *evm.Contract, host evm.Host) *evm.ExecutionRe
 	}
 }
 
-func (t *Transition) transfer(from, to types.Address, amount *big.Int) error {
+// transfer moves the value of an operation at a call depth, and records it as an internal transfer
+func (t *Transition) transfer(
+	typ types.InternalTransferType,
+	depth int,
+	from,
+	to types.Address,
+	amount *big.Int,
+) error {
 	if amount == nil {
 		return nil
 	}
@@ -603,10 +799,44 @@ func (t *Transition) transfer(from, to types.Address, amount *big.Int) error {
 	}
 
 	t.state.AddBalance(to, amount)
+	t.recordTransfer(typ, depth, from, to, amount)
 
 	return nil
 }
 
+// recordTransfer records an internal transfer of the transaction being applied. The value
+// of the topmost call or creation is the value of the transaction, which is not recorded
+func (t *Transition) recordTransfer(
+	typ types.InternalTransferType,
+	depth int,
+	from,
+	to types.Address,
+	amount *big.Int,
+) {
+	if amount.Sign() == 0 {
+		return
+	}
+
+	if (typ == types.InternalTransferCall || typ == types.InternalTransferCreate) && depth <= 1 {
+		return
+	}
+
+	t.txTransfers = append(t.txTransfers, &types.InternalTransfer{
+		Type:  typ,
+		From:  from,
+		To:    to,
+		Value: new(big.Int).Set(amount),
+		Depth: uint64(depth),
+	})
+}
+
+// revertToSnapshot reverts the state to a snapshot, and drops the internal
+// transfers recorded after it
+func (t *Transition) revertToSnapshot(snapshot, transfers int) {
+	t.state.RevertToSnapshot(snapshot)
+	t.txTransfers = t.txTransfers[:transfers]
+}
+
 func (t *Transition) applyCall(
 	c *evm.Contract,
 	callType evm.CallType,
@@ -620,11 +850,12 @@ func (t *Transition) applyCall(
 	}
 
 	snapshot := t.state.Snapshot() //nolint:ifshort
+	transfers := len(t.txTransfers)
 	t.state.TouchAccount(c.Address)
 
 	if callType == evm.Call {
 		// Transfers only allowed on calls
-		if err := t.transfer(c.Caller, c.Address, c.Value); err != nil {
+		if err := t.transfer(types.InternalTransferCall, c.Depth, c.Caller, c.Address, c.Value); err != nil {
 			return &evm.ExecutionResult{
 				GasLeft: c.Gas,
 				Err:     err,
@@ -634,7 +865,7 @@ func (t *Transition) applyCall(
 
 	result := t.run(c, host)
 	if result.Failed() {
-		t.state.RevertToSnapshot(snapshot)
+		t.revertToSnapshot(snapshot, transfers)
 	}
 
 	return result
@@ -679,6 +910,7 @@ func (t *Transition) applyCreate(c *evm.Contract, host evm.Host) *evm.ExecutionR
 
 	// Take snapshot of the current state
 	snapshot := t.state.Snapshot()
+	transfers := len(t.txTransfers)
 
 	if t.config.EIP158 {
 		// Force the creation of the account
@@ -687,7 +919,7 @@ func (t *Transition) applyCreate(c *evm.Contract, host evm.Host) *evm.ExecutionR
 	}
 
 	// Transfer the value
-	if err := t.transfer(c.Caller, c.Address, c.Value); err != nil {
+	if err := t.transfer(types.InternalTransferCreate, c.Depth, c.Caller, c.Address, c.Value); err != nil {
 		return &evm.ExecutionResult{
 			GasLeft: gasLimit,
 			Err:     err,
@@ -696,13 +928,13 @@ func (t *Transition) applyCreate(c *evm.Contract, host evm.Host) *evm.ExecutionR
 
 	result := t.run(c, host)
 	if result.Failed() {
-		t.state.RevertToSnapshot(snapshot)
+		t.revertToSnapshot(snapshot, transfers)
 		return result
 	}
 
 	if t.config.EIP158 && len(result.ReturnValue) > spuriousDragonMaxCodeSize {
 		// Contract size exceeds 'SpuriousDragon' size limit
-		t.state.RevertToSnapshot(snapshot)
+		t.revertToSnapshot(snapshot, transfers)
 
 		return &evm.ExecutionResult{
 			GasLeft: 0,
@@ -717,7 +949,7 @@ func (t *Transition) applyCreate(c *evm.Contract, host evm.Host) *evm.ExecutionR
 
 		// Out of gas creating the contract
 		if t.config.Homestead {
-			t.state.RevertToSnapshot(snapshot)
+			t.revertToSnapshot(snapshot, transfers)
 
 			result.GasLeft = 0
 		}
@@ -782,12 +1014,15 @@ func (t *Transition) GetNonce(addr types.Address) uint64 {
 	return t.state.GetNonce(addr)
 }
 
-func (t *Transition) Selfdestruct(addr types.Address, beneficiary types.Address) {
+func (t *Transition) Selfdestruct(addr types.Address, beneficiary types.Address, depth int) {
 	if !t.state.HasSuicided(addr) {
 		t.state.AddRefund(24000)
 	}
 
-	t.state.AddBalance(beneficiary, t.state.GetBalance(addr))
+	balance := t.state.GetBalance(addr)
+
+	t.state.AddBalance(beneficiary, balance)
+	t.recordTransfer(types.InternalTransferSelfdestruct, depth, addr, beneficiary, balance)
 	t.state.Suicide(addr)
 }
 
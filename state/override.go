@@ -0,0 +1,99 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/TIE-Tech/tie-core/types"
+)
+
+var (
+	ErrOverrideStateAndDiff = errors.New("both state and stateDiff are overridden")
+)
+
+// AccountOverride is the override of an account for a call, the nil fields are left untouched
+type AccountOverride struct {
+	Nonce   *uint64
+	Code    []byte
+	Balance *big.Int
+
+	// State replaces the whole storage of the account
+	State map[types.Hash]types.Hash
+
+	// StateDiff replaces the given slots of the storage of the account
+	StateDiff map[types.Hash]types.Hash
+}
+
+// StateOverride are the account overrides of a call by address
+type StateOverride map[types.Address]*AccountOverride
+
+// BlockOverride is the override of the block context of a call, the nil fields are left untouched
+type BlockOverride struct {
+	Number    *uint64
+	Timestamp *uint64
+	Coinbase  *types.Address
+	GasLimit  *uint64
+}
+
+// ApplyOverrides applies the state and block overrides to the transition. They are
+// only written in the pending state of the transition, which must not be committed
+func (t *Transition) ApplyOverrides(state StateOverride, block *BlockOverride) error {
+	for addr, account := range state {
+		if account == nil {
+			continue
+		}
+
+		if account.State != nil && account.StateDiff != nil {
+			return fmt.Errorf("account %s: %w", addr, ErrOverrideStateAndDiff)
+		}
+
+		if account.Nonce != nil {
+			t.state.SetNonce(addr, *account.Nonce)
+		}
+
+		if account.Code != nil {
+			t.state.SetCode(addr, account.Code)
+		}
+
+		if account.Balance != nil {
+			t.state.SetBalance(addr, account.Balance)
+		}
+
+		if account.State != nil {
+			t.state.ResetStorage(addr)
+
+			for key, value := range account.State {
+				t.state.SetState(addr, key, value)
+			}
+		}
+
+		for key, value := range account.StateDiff {
+			t.state.SetState(addr, key, value)
+		}
+	}
+
+	if block == nil {
+		return nil
+	}
+
+	if block.Number != nil {
+		t.ctx.Number = int64(*block.Number)
+		t.config = t.r.config.Forks.At(*block.Number)
+	}
+
+	if block.Timestamp != nil {
+		t.ctx.Timestamp = int64(*block.Timestamp)
+	}
+
+	if block.Coinbase != nil {
+		t.ctx.Coinbase = *block.Coinbase
+	}
+
+	if block.GasLimit != nil {
+		t.ctx.GasLimit = int64(*block.GasLimit)
+		t.gasPool = *block.GasLimit
+	}
+
+	return nil
+}
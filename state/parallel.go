@@ -0,0 +1,318 @@
+package state
+
+import (
+	"math"
+	"math/big"
+	"sync"
+
+	"github.com/TIE-Tech/go-logger"
+	iradix "github.com/hashicorp/go-immutable-radix"
+
+	"github.com/TIE-Tech/tie-core/tievm/evm"
+	"github.com/TIE-Tech/tie-core/types"
+)
+
+// The parallel execution of a batch of transactions follows Block-STM:
+//
+//  1. every transaction of the batch is executed speculatively by a pool of workers, on its
+//     own Txn on top of the state before the batch. The Txn records the accounts and the
+//     storage slots read and written by the transaction, and journals its changes
+//  2. the speculations are validated in the order of the batch. A speculation which read a
+//     value written by a previous transaction of the batch is executed again, on the state
+//     committed so far
+//  3. the journal of the changes of a valid speculation is replayed on the committed state
+//
+// The results are the ones of the sequential execution. The fees are credited to the fee pool
+// when a transaction is committed so that the transactions do not conflict on its balance,
+// and the system transactions, which update the fee and reward bookkeeping, are executed on
+// the committed state
+
+// accessKey is a piece of the state accessed by a transaction: the fields of an account,
+// or one of its storage slots
+type accessKey struct {
+	addr    types.Address
+	slot    types.Hash
+	storage bool
+}
+
+func accountKey(addr types.Address) accessKey {
+	return accessKey{addr: addr}
+}
+
+func storageKey(addr types.Address, slot types.Hash) accessKey {
+	return accessKey{addr: addr, slot: slot, storage: true}
+}
+
+// accessSet records the reads and the changes of a speculative execution
+type accessSet struct {
+	// shared serializes the reads of the objects and the tries shared by the speculations
+	shared *sync.Mutex
+
+	reads  map[accessKey]struct{}
+	writes map[accessKey]struct{}
+
+	// ops are the changes of the state, snapshots the number of ops at each snapshot of the Txn
+	ops       []func(txn *Txn)
+	snapshots []int
+}
+
+func newAccessSet(shared *sync.Mutex) *accessSet {
+	return &accessSet{
+		shared: shared,
+		reads:  map[accessKey]struct{}{},
+		writes: map[accessKey]struct{}{},
+	}
+}
+
+func (a *accessSet) read(k accessKey) {
+	a.reads[k] = struct{}{}
+}
+
+func (a *accessSet) write(k accessKey) {
+	a.writes[k] = struct{}{}
+}
+
+func (a *accessSet) journal(op func(txn *Txn)) {
+	a.ops = append(a.ops, op)
+}
+
+// upsert journals a change of an account. The fields of the account are written if they
+// changed, or if the account is new or empty since it is then removed at the end of the transaction
+func (a *accessSet) upsert(
+	addr types.Address,
+	create bool,
+	f func(object *StateObject),
+	exists bool,
+	prev,
+	object *StateObject,
+) {
+	a.journal(func(txn *Txn) {
+		txn.upsertAccount(addr, create, f)
+	})
+
+	if object == nil {
+		return
+	}
+
+	if !exists || object.Empty() || accountChanged(prev, object) {
+		a.write(accountKey(addr))
+	}
+}
+
+// conflicts returns true if the execution read a value of the written keys
+func (a *accessSet) conflicts(written map[accessKey]struct{}) bool {
+	for k := range a.reads {
+		if _, ok := written[k]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// accountChanged returns true if the fields of an account, or its storage trie, changed
+func accountChanged(prev, object *StateObject) bool {
+	return prev.Account.Nonce != object.Account.Nonce ||
+		prev.Account.Balance.Cmp(object.Account.Balance) != 0 ||
+		string(prev.Account.CodeHash) != string(object.Account.CodeHash) ||
+		prev.Account.Root != object.Account.Root ||
+		prev.Account.Trie != object.Account.Trie ||
+		prev.Suicide != object.Suicide ||
+		prev.Deleted != object.Deleted ||
+		prev.DirtyCode != object.DirtyCode
+}
+
+// retain copies a value kept by the journal of a speculative execution, which may be reused by the caller
+func (txn *Txn) retain(v *big.Int) *big.Int {
+	if txn.access == nil {
+		return v
+	}
+
+	return new(big.Int).Set(v)
+}
+
+// speculation is the result of the speculative execution of a transaction
+type speculation struct {
+	msg       *types.Transaction
+	result    *evm.ExecutionResult
+	err       error
+	logs      []*types.Log
+	transfers []*types.InternalTransfer
+	fees      *big.Int
+	access    *accessSet
+}
+
+// SetParallelism sets the number of transactions of a block executed in parallel,
+// the transactions are executed sequentially if it is lower than 2
+func (e *Executor) SetParallelism(workers int) {
+	e.parallelism = workers
+}
+
+// Parallel returns true if the batches of transactions are executed in parallel
+func (t *Transition) Parallel() bool {
+	// the receipts before byzantium hold the intermediate roots
+	return t.r.parallelism > 1 && t.config.Byzantium && t.r.PostHook == nil
+}
+
+// WriteBatch writes the transactions in order, with the results of consecutive calls to Write.
+// It stops at the first transaction which cannot be written, and returns the number of written
+// transactions and the error
+func (t *Transition) WriteBatch(txns []*types.Transaction) (int, error) {
+	if !t.Parallel() || len(txns) < 2 {
+		for i, txn := range txns {
+			if err := t.Write(txn); err != nil {
+				return i, err
+			}
+		}
+
+		return len(txns), nil
+	}
+
+	shared := new(sync.Mutex)
+	specs := t.speculateBatch(txns, shared)
+
+	// written are the keys written by the committed transactions, stale is set
+	// once a transaction is committed without recording them
+	written := map[accessKey]struct{}{}
+	stale := false
+
+	for i, txn := range txns {
+		spec := specs[i]
+
+		if spec == nil {
+			if err := t.Write(txn); err != nil {
+				return i, err
+			}
+
+			stale = true
+
+			continue
+		}
+
+		if stale || spec.err != nil || t.gasPool < spec.msg.Gas || spec.access.conflicts(written) {
+			spec = t.speculate(txn, t.state.txn.CommitOnly(), shared, t.gasPool)
+			if spec.err != nil {
+				logger.Error("failed to apply tx", "err", spec.err)
+
+				return i, spec.err
+			}
+		}
+
+		t.commit(txn, spec)
+
+		for k := range spec.access.writes {
+			written[k] = struct{}{}
+		}
+	}
+
+	return len(txns), nil
+}
+
+// speculateBatch executes the transactions of a batch in parallel on the current state. The
+// system transactions are not executed, their speculation is nil
+func (t *Transition) speculateBatch(txns []*types.Transaction, shared *sync.Mutex) []*speculation {
+	specs := make([]*speculation, len(txns))
+	base := t.state.txn.CommitOnly()
+
+	indexes := make(chan int, len(txns))
+
+	for i, txn := range txns {
+		if typ, err := t.systemTxType(txn); err == nil && typ == types.SystemTxNone {
+			indexes <- i
+		}
+	}
+
+	close(indexes)
+
+	workers := t.r.parallelism
+	if workers > len(txns) {
+		workers = len(txns)
+	}
+
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range indexes {
+				// the gas pool is checked when the transaction is committed
+				specs[i] = t.speculate(txns[i], base, shared, math.MaxUint64)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return specs
+}
+
+// speculate executes a transaction on top of a state, without changing the transition
+func (t *Transition) speculate(
+	txn *types.Transaction,
+	base *iradix.Tree,
+	shared *sync.Mutex,
+	gasPool uint64,
+) *speculation {
+	spec := &speculation{
+		access: newAccessSet(shared),
+	}
+
+	if txn.From == emptyFrom {
+		from, err := NewSigner(uint64(t.r.config.ChainID)).Sender(txn)
+		if err != nil {
+			spec.err = NewTransitionApplicationError(err, false)
+
+			return spec
+		}
+
+		txn.From = from
+	}
+
+	txnState := newTxn(t.state.state, t.state.snapshot)
+	txnState.txn = base.Txn()
+	txnState.access = spec.access
+
+	transition := &Transition{
+		auxState: t.auxState,
+		block:    t.block,
+		r:        t.r,
+		config:   t.config,
+		state:    txnState,
+		getHash:  t.getHash,
+		ctx:      t.ctx,
+		gasPool:  gasPool,
+		fees:     new(big.Int),
+	}
+
+	spec.msg = txn.Copy()
+
+	spec.result, spec.err = transition.apply(spec.msg)
+	if spec.err != nil {
+		return spec
+	}
+
+	spec.logs = txnState.Logs()
+	spec.transfers = transition.txTransfers
+	spec.fees = transition.fees
+
+	return spec
+}
+
+// commit replays the changes of a speculation on the state and writes the receipt of the transaction
+func (t *Transition) commit(txn *types.Transaction, spec *speculation) {
+	for _, op := range spec.access.ops {
+		op(t.state)
+	}
+
+	// the fees are paid at the end of the transaction
+	t.state.AddBalance(FeePool, spec.fees)
+	spec.access.write(accountKey(FeePool))
+
+	t.gasPool = t.gasPool - spec.msg.Gas + spec.result.GasLeft
+	t.txTransfers = spec.transfers
+
+	t.writeResult(txn, spec.msg, spec.result, spec.logs)
+}
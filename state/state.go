@@ -28,6 +28,41 @@ type accountTrie interface {
 	Get(k []byte) ([]byte, bool)
 }
 
+// flatReader is implemented by the snapshots which keep a flat copy of the
+// state next to the trie. Reads fall back to the trie when it returns an error
+type flatReader interface {
+	GetAccount(hash []byte) ([]byte, bool, error)
+	GetStorage(account, slot []byte) ([]byte, bool, error)
+}
+
+// flatStorage serves the storage of an account from the flat snapshot and
+// only opens the storage trie once the snapshot cannot serve a read
+type flatStorage struct {
+	reader  flatReader
+	state   State
+	account types.Hash
+	root    types.Hash
+	trie    accountTrie
+}
+
+func (f *flatStorage) Get(k []byte) ([]byte, bool) {
+	if f.trie == nil {
+		val, ok, err := f.reader.GetStorage(f.account.Bytes(), k)
+		if err == nil {
+			return val, ok
+		}
+
+		trie, err := f.state.NewSnapshotAt(f.root)
+		if err != nil {
+			return nil, false
+		}
+
+		f.trie = trie
+	}
+
+	return f.trie.Get(k)
+}
+
 // Account is the account reference in the ethereum state
 type Account struct {
 	Nonce    uint64
@@ -4,6 +4,7 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/TIE-Tech/tie-core/params"
 	"github.com/TIE-Tech/tie-core/tievm/evm"
 	"github.com/TIE-Tech/tie-core/types"
 	"github.com/stretchr/testify/assert"
@@ -129,7 +130,7 @@ func TestTransfer(t *testing.T) {
 			transition := newTestTransition(tt.preState)
 
 			amount := big.NewInt(tt.amount)
-			err := transition.transfer(tt.from, tt.to, amount)
+			err := transition.transfer(types.InternalTransferCall, 2, tt.from, tt.to, amount)
 
 			assert.Equal(t, tt.expectedErr, err)
 			if err == nil {
@@ -147,3 +148,323 @@ func TestTransfer(t *testing.T) {
 		})
 	}
 }
+
+func TestInternalTransfers(t *testing.T) {
+	transition := newTestTransition(map[types.Address]*PreState{
+		addr1: {
+			Balance: 1000,
+		},
+		addr2: {
+			Balance: 1000,
+		},
+	})
+
+	// the value of the transaction is not an internal transfer
+	assert.NoError(t, transition.transfer(types.InternalTransferCall, 1, addr1, addr2, big.NewInt(10)))
+	assert.Len(t, transition.LastInternalTransfers(), 0)
+
+	assert.NoError(t, transition.transfer(types.InternalTransferCall, 2, addr2, addr1, big.NewInt(20)))
+	assert.NoError(t, transition.transfer(types.InternalTransferCall, 2, addr2, addr1, big.NewInt(0)))
+
+	// the transfers of a reverted call are dropped
+	snapshot, transfers := transition.state.Snapshot(), len(transition.txTransfers)
+
+	assert.NoError(t, transition.transfer(types.InternalTransferCreate, 3, addr1, addr2, big.NewInt(30)))
+	transition.revertToSnapshot(snapshot, transfers)
+
+	transition.Selfdestruct(addr2, addr1, 2)
+
+	assert.Equal(t, []*types.InternalTransfer{
+		{
+			Type:  types.InternalTransferCall,
+			From:  addr2,
+			To:    addr1,
+			Value: big.NewInt(20),
+			Depth: 2,
+		},
+		{
+			Type:  types.InternalTransferSelfdestruct,
+			From:  addr2,
+			To:    addr1,
+			Value: big.NewInt(990),
+			Depth: 2,
+		},
+	}, transition.LastInternalTransfers())
+}
+
+func TestSystemTxType(t *testing.T) {
+	withdrawFee := []byte{0x07, 0x0f, 0x46, 0x8d}
+	fixedReward := []byte{0x57, 0x30, 0x59, 0x20}
+
+	// user calldata embedding the selector of the fixed reward
+	calldata := append([]byte{0xa9, 0x05, 0x9c, 0xbb}, fixedReward...)
+
+	tests := []struct {
+		name        string
+		systemTx    bool
+		from        types.Address
+		to          types.Address
+		input       []byte
+		gasPrice    int64
+		value       int64
+		fixedReward *big.Int
+		expected    types.SystemTxType
+		expectedErr error
+	}{
+		{
+			name:     "legacy rules match the selector anywhere in the input",
+			to:       addr2,
+			input:    calldata,
+			expected: types.SystemTxFixedReward,
+		},
+		{
+			name:     "legacy rules match the fee withdrawal first",
+			to:       addr2,
+			input:    append(withdrawFee, fixedReward...),
+			expected: types.SystemTxWithdrawFee,
+		},
+		{
+			name:     "strict rules ignore the selector in user calldata",
+			systemTx: true,
+			to:       types.RewardPoolAddress,
+			input:    calldata,
+			expected: types.SystemTxNone,
+		},
+		{
+			name:     "strict rules ignore the selector sent to other addresses",
+			systemTx: true,
+			to:       addr2,
+			input:    withdrawFee,
+			expected: types.SystemTxNone,
+		},
+		{
+			name:     "strict rules accept a fee withdrawal",
+			systemTx: true,
+			to:       types.TxFeePoolAddress,
+			input:    withdrawFee,
+			expected: types.SystemTxWithdrawFee,
+		},
+		{
+			name:        "strict rules reject arguments",
+			systemTx:    true,
+			to:          types.TxFeePoolAddress,
+			input:       append(withdrawFee, 0x01),
+			expectedErr: types.ErrInvalidSystemTx,
+		},
+		{
+			name:     "strict rules accept the reward of the block creator",
+			systemTx: true,
+			from:     addr1,
+			to:       types.RewardPoolAddress,
+			input:    fixedReward,
+			expected: types.SystemTxFixedReward,
+		},
+		{
+			name:        "strict rules reject the reward of another sender",
+			systemTx:    true,
+			from:        addr2,
+			to:          types.RewardPoolAddress,
+			input:       fixedReward,
+			expectedErr: ErrSystemTxSender,
+		},
+		{
+			name:        "strict rules reject a reward paying gas",
+			systemTx:    true,
+			from:        addr1,
+			to:          types.RewardPoolAddress,
+			input:       fixedReward,
+			gasPrice:    1,
+			expectedErr: ErrSystemTxGasPrice,
+		},
+		{
+			name:        "strict rules accept the fixed reward of the block",
+			systemTx:    true,
+			from:        addr1,
+			to:          types.RewardPoolAddress,
+			input:       fixedReward,
+			value:       100,
+			fixedReward: big.NewInt(100),
+			expected:    types.SystemTxFixedReward,
+		},
+		{
+			name:        "strict rules reject an inflated reward",
+			systemTx:    true,
+			from:        addr1,
+			to:          types.RewardPoolAddress,
+			input:       fixedReward,
+			value:       101,
+			fixedReward: big.NewInt(100),
+			expectedErr: ErrSystemTxReward,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transition := newTestTransition(nil)
+			transition.config.SystemTx = tt.systemTx
+			transition.ctx.Coinbase = addr1
+			transition.fixedReward = tt.fixedReward
+
+			to := tt.to
+			msg := &types.Transaction{
+				From:     tt.from,
+				To:       &to,
+				Input:    tt.input,
+				GasPrice: big.NewInt(tt.gasPrice),
+				Value:    big.NewInt(tt.value),
+			}
+
+			systemTx, err := transition.systemTxType(msg)
+
+			assert.ErrorIs(t, err, tt.expectedErr)
+			assert.Equal(t, tt.expected, systemTx)
+		})
+	}
+}
+
+func TestApplyReservedAddress(t *testing.T) {
+	rewardPool := types.RewardPoolAddress
+	msg := &types.Transaction{
+		From:     addr1,
+		To:       &rewardPool,
+		GasPrice: big.NewInt(1),
+		Value:    big.NewInt(1),
+	}
+
+	transition := newTestTransition(nil)
+	transition.config.SystemTx = true
+
+	_, err := transition.apply(msg)
+
+	var appErr *TransitionApplicationError
+	assert.ErrorAs(t, err, &appErr)
+	assert.Equal(t, ErrReservedAddress, appErr.Err)
+	assert.False(t, appErr.IsRecoverable)
+}
+
+func TestApplyOverrides(t *testing.T) {
+	preState := map[types.Address]*PreState{
+		addr1: {
+			Nonce:   1,
+			Balance: 100,
+		},
+	}
+
+	newTransition := func() *Transition {
+		transition := newTestTransition(preState)
+		transition.r = &Executor{config: &params.Params{Forks: params.AllForksEnabled}}
+
+		transition.state.SetState(addr1, hash1, hash1)
+		transition.state.SetState(addr1, hash2, hash2)
+
+		return transition
+	}
+
+	t.Run("should override the account fields", func(t *testing.T) {
+		transition := newTransition()
+		nonce := uint64(5)
+
+		err := transition.ApplyOverrides(StateOverride{
+			addr1: {
+				Nonce:   &nonce,
+				Balance: big.NewInt(1000),
+				Code:    []byte{0x1},
+			},
+		}, nil)
+		assert.NoError(t, err)
+
+		assert.Equal(t, nonce, transition.GetNonce(addr1))
+		assert.Equal(t, big.NewInt(1000), transition.GetBalance(addr1))
+		assert.Equal(t, []byte{0x1}, transition.GetCode(addr1))
+		assert.Equal(t, hash1, transition.GetStorage(addr1, hash1))
+	})
+
+	t.Run("should replace the whole storage", func(t *testing.T) {
+		transition := newTransition()
+
+		err := transition.ApplyOverrides(StateOverride{
+			addr1: {
+				State: map[types.Hash]types.Hash{hash2: hash1},
+			},
+		}, nil)
+		assert.NoError(t, err)
+
+		assert.Equal(t, types.Hash{}, transition.GetStorage(addr1, hash1))
+		assert.Equal(t, hash1, transition.GetStorage(addr1, hash2))
+	})
+
+	t.Run("should replace the given storage slots", func(t *testing.T) {
+		transition := newTransition()
+
+		err := transition.ApplyOverrides(StateOverride{
+			addr1: {
+				StateDiff: map[types.Hash]types.Hash{hash2: hash1},
+			},
+		}, nil)
+		assert.NoError(t, err)
+
+		assert.Equal(t, hash1, transition.GetStorage(addr1, hash1))
+		assert.Equal(t, hash1, transition.GetStorage(addr1, hash2))
+	})
+
+	t.Run("should fail if both state and stateDiff are set", func(t *testing.T) {
+		transition := newTransition()
+
+		err := transition.ApplyOverrides(StateOverride{
+			addr1: {
+				State:     map[types.Hash]types.Hash{},
+				StateDiff: map[types.Hash]types.Hash{},
+			},
+		}, nil)
+		assert.ErrorIs(t, err, ErrOverrideStateAndDiff)
+	})
+
+	t.Run("should override the block context", func(t *testing.T) {
+		transition := newTransition()
+		number, timestamp, gasLimit := uint64(10), uint64(20), uint64(30)
+
+		err := transition.ApplyOverrides(nil, &BlockOverride{
+			Number:    &number,
+			Timestamp: &timestamp,
+			Coinbase:  &addr2,
+			GasLimit:  &gasLimit,
+		})
+		assert.NoError(t, err)
+
+		ctx := transition.GetTxContext()
+		assert.Equal(t, int64(number), ctx.Number)
+		assert.Equal(t, int64(timestamp), ctx.Timestamp)
+		assert.Equal(t, addr2, ctx.Coinbase)
+		assert.Equal(t, int64(gasLimit), ctx.GasLimit)
+		assert.Equal(t, gasLimit, transition.gasPool)
+	})
+}
+
+func TestFork(t *testing.T) {
+	transition := newTestTransition(map[types.Address]*PreState{
+		addr1: {
+			Nonce:   1,
+			Balance: 100,
+		},
+	})
+	transition.ctx.GasLimit = 1000
+	transition.gasPool = 10
+
+	transition.state.SetState(addr1, hash1, hash1)
+
+	fork := transition.Fork()
+	assert.Equal(t, uint64(1000), fork.gasPool)
+	assert.Equal(t, hash1, fork.GetStorage(addr1, hash1))
+
+	// the writes of the fork are not seen by the transition
+	fork.state.SetBalance(addr1, big.NewInt(5))
+	fork.state.SetState(addr1, hash1, hash2)
+	fork.state.SetNonce(addr2, 3)
+
+	assert.Equal(t, big.NewInt(100), transition.GetBalance(addr1))
+	assert.Equal(t, hash1, transition.GetStorage(addr1, hash1))
+	assert.Equal(t, uint64(0), transition.GetNonce(addr2))
+
+	assert.Equal(t, big.NewInt(5), fork.GetBalance(addr1))
+	assert.Equal(t, hash2, fork.GetStorage(addr1, hash1))
+}
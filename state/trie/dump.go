@@ -0,0 +1,578 @@
+package itrie
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/TIE-Tech/tie-core/common/crypto"
+	"github.com/TIE-Tech/tie-core/common/hex"
+	"github.com/TIE-Tech/tie-core/params"
+	"github.com/TIE-Tech/tie-core/state"
+	"github.com/TIE-Tech/tie-core/types"
+	"github.com/umbracle/fastrlp"
+)
+
+var emptyCodeHash = types.BytesToHash(crypto.Keccak256(nil))
+
+// hasCode returns false for the code hashes of accounts without code
+func hasCode(codeHash types.Hash) bool {
+	return codeHash != emptyCodeHash && codeHash != types.ZeroHash
+}
+
+// importBatchSize is the number of leaves inserted in a trie during an import
+// before its nodes are written to the storage and released from memory
+var importBatchSize = 10000
+
+// DumpAccount is an account of the state with its code, one line of a dump. The
+// storage slots of the account are the lines following it
+type DumpAccount struct {
+	Hash     types.Hash     `json:"hash"`
+	Address  *types.Address `json:"address,omitempty"`
+	Nonce    uint64         `json:"nonce"`
+	Balance  string         `json:"balance"`
+	Root     types.Hash     `json:"root"`
+	CodeHash types.Hash     `json:"codeHash"`
+	Code     string         `json:"code,omitempty"`
+
+	// Storage is only filled when the whole account is read, see GenesisAlloc
+	Storage []*DumpSlot `json:"-"`
+}
+
+// DumpSlot is a storage slot of a dumped account
+type DumpSlot struct {
+	Hash  types.Hash  `json:"hash"`
+	Key   *types.Hash `json:"key,omitempty"`
+	Value types.Hash  `json:"value"`
+}
+
+// dumpLine is a line of a dump, either an account or a storage slot of the last account
+type dumpLine struct {
+	*DumpAccount
+	Slot *DumpSlot `json:"slot,omitempty"`
+}
+
+// GenesisAccount converts the dumped account to a genesis allocation, which
+// requires the preimages of the address and of every storage key
+func (d *DumpAccount) GenesisAccount() (types.Address, *params.GenesisAccount, error) {
+	if d.Address == nil {
+		return types.Address{}, nil, fmt.Errorf("account %s has no address preimage", d.Hash)
+	}
+
+	code, err := hex.DecodeHex(d.Code)
+	if err != nil {
+		return types.Address{}, nil, fmt.Errorf("invalid code of account %s: %w", d.Hash, err)
+	}
+
+	balance, err := types.ParseUint256orHex(&d.Balance)
+	if err != nil {
+		return types.Address{}, nil, fmt.Errorf("invalid balance of account %s: %w", d.Hash, err)
+	}
+
+	account := &params.GenesisAccount{
+		Balance: balance,
+		Nonce:   d.Nonce,
+	}
+
+	if len(code) != 0 {
+		account.Code = code
+	}
+
+	if len(d.Storage) != 0 {
+		account.Storage = map[types.Hash]types.Hash{}
+	}
+
+	for _, slot := range d.Storage {
+		if slot.Key == nil {
+			return types.Address{}, nil, fmt.Errorf("slot %s of account %s has no key preimage", slot.Hash, d.Hash)
+		}
+
+		account.Storage[*slot.Key] = slot.Value
+	}
+
+	return *d.Address, account, nil
+}
+
+// Preimages resolves the hashed keys of the tries to addresses and storage keys
+type Preimages struct {
+	Addresses map[types.Hash]types.Address
+	Keys      map[types.Hash]types.Hash
+}
+
+// NewPreimages collects the preimages of the accounts and the storage keys of a genesis allocation
+func NewPreimages(alloc map[types.Address]*params.GenesisAccount) *Preimages {
+	p := &Preimages{
+		Addresses: map[types.Hash]types.Address{},
+		Keys:      map[types.Hash]types.Hash{},
+	}
+
+	for addr, account := range alloc {
+		p.Addresses[types.BytesToHash(hashit(addr.Bytes()))] = addr
+
+		for key := range account.Storage {
+			p.Keys[types.BytesToHash(hashit(key.Bytes()))] = key
+		}
+	}
+
+	return p
+}
+
+// Dump writes every account of the state at root as a JSON line followed by a line per
+// storage slot, and returns the number of accounts
+func Dump(s *State, root types.Hash, preimages *Preimages, w io.Writer) (int, error) {
+	count := 0
+	enc := json.NewEncoder(w)
+
+	err := iterateLeaves(root, s.storage, func(key, value []byte) error {
+		var account state.Account
+		if err := account.UnmarshalRlp(value); err != nil {
+			return fmt.Errorf("failed to decode account %s: %w", types.BytesToHash(key), err)
+		}
+
+		dump := &DumpAccount{
+			Hash:     types.BytesToHash(key),
+			Nonce:    account.Nonce,
+			Balance:  hex.EncodeBig(account.Balance),
+			Root:     account.Root,
+			CodeHash: types.BytesToHash(account.CodeHash),
+		}
+
+		if preimages != nil {
+			if addr, ok := preimages.Addresses[dump.Hash]; ok {
+				dump.Address = &addr
+			}
+		}
+
+		if hasCode(dump.CodeHash) {
+			code, ok := s.GetCode(dump.CodeHash)
+			if !ok {
+				return fmt.Errorf("missing code %s of account %s", dump.CodeHash, dump.Hash)
+			}
+
+			dump.Code = hex.EncodeToHex(code)
+		}
+
+		if err := enc.Encode(&dumpLine{DumpAccount: dump}); err != nil {
+			return err
+		}
+
+		count++
+
+		return iterateLeaves(account.Root, s.storage, func(key, value []byte) error {
+			slot := &DumpSlot{
+				Hash: types.BytesToHash(key),
+			}
+
+			val, err := decodeSlotValue(value)
+			if err != nil {
+				return fmt.Errorf("failed to decode slot %s of account %s: %w", slot.Hash, dump.Hash, err)
+			}
+
+			slot.Value = val
+
+			if preimages != nil {
+				if k, ok := preimages.Keys[slot.Hash]; ok {
+					slot.Key = &k
+				}
+			}
+
+			return enc.Encode(&dumpLine{Slot: slot})
+		})
+	})
+
+	return count, err
+}
+
+// readDump calls onAccount for every account line of a dump and onSlot for
+// every storage slot line, which belongs to the last account
+func readDump(r io.Reader, onAccount func(*DumpAccount) error, onSlot func(*DumpSlot) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64*1024*1024)
+
+	accounts := 0
+
+	for num := 1; scanner.Scan(); num++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var dump dumpLine
+		if err := json.Unmarshal(line, &dump); err != nil {
+			return fmt.Errorf("failed to decode line %d: %w", num, err)
+		}
+
+		switch {
+		case dump.Slot != nil:
+			if accounts == 0 {
+				return fmt.Errorf("storage slot before any account at line %d", num)
+			}
+
+			if err := onSlot(dump.Slot); err != nil {
+				return err
+			}
+
+		case dump.DumpAccount != nil:
+			accounts++
+
+			if err := onAccount(dump.DumpAccount); err != nil {
+				return err
+			}
+
+		default:
+			return fmt.Errorf("neither an account nor a storage slot at line %d", num)
+		}
+	}
+
+	return scanner.Err()
+}
+
+func decodeSlotValue(value []byte) (types.Hash, error) {
+	p := parserPool.Get()
+	defer parserPool.Put(p)
+
+	v, err := p.Parse(value)
+	if err != nil {
+		return types.Hash{}, err
+	}
+
+	buf, err := v.GetBytes(nil)
+	if err != nil {
+		return types.Hash{}, err
+	}
+
+	return types.BytesToHash(buf), nil
+}
+
+// importTrie builds a trie from its leaves. Every importBatchSize leaves its nodes are
+// written to the storage and only its root is kept, the nodes on the path of the next
+// leaves are read back from the storage
+type importTrie struct {
+	storage Storage
+	txn     *Txn
+	pending int
+}
+
+func newImportTrie(storage Storage) *importTrie {
+	txn := NewTrie().Txn()
+	txn.storage = storage
+
+	return &importTrie{
+		storage: storage,
+		txn:     txn,
+	}
+}
+
+func (t *importTrie) insert(key, value []byte) error {
+	t.txn.Insert(key, value)
+	t.pending++
+
+	if t.pending < importBatchSize {
+		return nil
+	}
+
+	_, err := t.commit()
+
+	return err
+}
+
+// commit writes the nodes of the trie to the storage and returns its root
+func (t *importTrie) commit() (types.Hash, error) {
+	if t.txn.root == nil {
+		return types.EmptyRootHash, nil
+	}
+
+	batch := t.storage.Batch()
+	t.txn.batch = batch
+
+	root, err := t.txn.Hash()
+	if err != nil {
+		return types.Hash{}, err
+	}
+
+	batch.Write()
+
+	t.txn.root = &ValueNode{hash: true, buf: root}
+	t.pending = 0
+
+	return types.BytesToHash(root), nil
+}
+
+// Import builds the state of a dump in the storage of the state and returns its
+// root. The roots of the dumped accounts are checked
+func Import(s *State, r io.Reader) (types.Hash, int, error) {
+	accounts := newImportTrie(s.storage)
+	arena := &fastrlp.Arena{}
+	count := 0
+
+	var (
+		current *DumpAccount
+		storage *importTrie
+	)
+
+	// flush inserts the last account once all its storage slots are read
+	flush := func() error {
+		if current == nil {
+			return nil
+		}
+
+		dump := current
+
+		storageRoot, err := storage.commit()
+		if err != nil {
+			return err
+		}
+
+		if storageRoot != dump.Root {
+			return fmt.Errorf("storage root mismatch for account %s, expected %s but got %s",
+				dump.Hash, dump.Root, storageRoot)
+		}
+
+		if dump.Code != "" {
+			code, err := hex.DecodeHex(dump.Code)
+			if err != nil {
+				return fmt.Errorf("invalid code of account %s: %w", dump.Hash, err)
+			}
+
+			if hash := types.BytesToHash(crypto.Keccak256(code)); hash != dump.CodeHash {
+				return fmt.Errorf("code hash mismatch for account %s", dump.Hash)
+			}
+
+			s.SetCode(dump.CodeHash, code)
+		}
+
+		balance, err := types.ParseUint256orHex(&dump.Balance)
+		if err != nil {
+			return fmt.Errorf("invalid balance of account %s: %w", dump.Hash, err)
+		}
+
+		account := &state.Account{
+			Nonce:    dump.Nonce,
+			Balance:  balance,
+			Root:     dump.Root,
+			CodeHash: dump.CodeHash.Bytes(),
+		}
+
+		err = accounts.insert(dump.Hash.Bytes(), account.MarshalWith(arena).MarshalTo(nil))
+		arena.Reset()
+
+		if err != nil {
+			return err
+		}
+
+		count++
+
+		return nil
+	}
+
+	err := readDump(r, func(dump *DumpAccount) error {
+		if err := flush(); err != nil {
+			return err
+		}
+
+		current = dump
+		storage = newImportTrie(s.storage)
+
+		return nil
+	}, func(slot *DumpSlot) error {
+		val := arena.NewBytes(bytes.TrimLeft(slot.Value.Bytes(), "\x00"))
+		err := storage.insert(slot.Hash.Bytes(), val.MarshalTo(nil))
+		arena.Reset()
+
+		return err
+	})
+	if err != nil {
+		return types.Hash{}, count, err
+	}
+
+	if err := flush(); err != nil {
+		return types.Hash{}, count, err
+	}
+
+	root, err := accounts.commit()
+	if err != nil {
+		return types.Hash{}, count, err
+	}
+
+	return root, count, nil
+}
+
+// VerifyResult is the outcome of a state verification
+type VerifyResult struct {
+	Nodes           int               `json:"nodes"`
+	Accounts        int               `json:"accounts"`
+	Missing         []types.Hash      `json:"missing,omitempty"`
+	Corrupt         []types.Hash      `json:"corrupt,omitempty"`
+	CorruptAccounts []*CorruptAccount `json:"corrupt_accounts,omitempty"`
+	MissingCode     []types.Hash      `json:"missing_code,omitempty"`
+}
+
+// CorruptAccount is an account leaf which cannot be decoded, with the stored node holding it
+type CorruptAccount struct {
+	Node    types.Hash `json:"node"`
+	Account types.Hash `json:"account"`
+}
+
+// Valid returns true if no node, account nor code is missing or corrupt
+func (v *VerifyResult) Valid() bool {
+	return len(v.Missing) == 0 && len(v.Corrupt) == 0 && len(v.CorruptAccounts) == 0 && len(v.MissingCode) == 0
+}
+
+// Verify walks the trie at root with its storage tries, re-hashes every
+// node and reports the nodes which are missing or do not match their hash
+func Verify(s *State, root types.Hash) *VerifyResult {
+	v := &verifier{
+		state:   s,
+		visited: map[types.Hash]struct{}{},
+		res:     &VerifyResult{},
+	}
+
+	v.verify(root, nil, true)
+
+	return v.res
+}
+
+type verifier struct {
+	state   *State
+	visited map[types.Hash]struct{}
+	res     *VerifyResult
+}
+
+// verify checks the stored node of the hash, path is the key of the node in an account trie
+func (v *verifier) verify(hash types.Hash, path []byte, accounts bool) {
+	if hash == types.EmptyRootHash {
+		return
+	}
+
+	if _, ok := v.visited[hash]; ok {
+		return
+	}
+
+	v.visited[hash] = struct{}{}
+
+	data, ok := v.state.storage.Get(hash.Bytes())
+	if !ok {
+		v.res.Missing = append(v.res.Missing, hash)
+
+		return
+	}
+
+	v.res.Nodes++
+
+	if types.BytesToHash(crypto.Keccak256(data)) != hash {
+		v.res.Corrupt = append(v.res.Corrupt, hash)
+
+		return
+	}
+
+	n, ok, err := GetNode(hash.Bytes(), v.state.storage)
+	if err != nil || !ok {
+		v.res.Corrupt = append(v.res.Corrupt, hash)
+
+		return
+	}
+
+	v.verifyNode(n, hash, path, accounts)
+}
+
+// verifyNode checks a node embedded in the stored node of the hash
+func (v *verifier) verifyNode(node Node, hash types.Hash, path []byte, accounts bool) {
+	switch n := node.(type) {
+	case *ValueNode:
+		if n.hash {
+			v.verify(types.BytesToHash(n.buf), path, accounts)
+
+			return
+		}
+
+		if !accounts {
+			return
+		}
+
+		v.res.Accounts++
+
+		var account state.Account
+		if err := account.UnmarshalRlp(n.buf); err != nil {
+			v.res.CorruptAccounts = append(v.res.CorruptAccounts, &CorruptAccount{
+				Node:    hash,
+				Account: types.BytesToHash(hexNibblesToBytes(path)),
+			})
+
+			return
+		}
+
+		if codeHash := types.BytesToHash(account.CodeHash); hasCode(codeHash) {
+			if _, ok := v.state.GetCode(codeHash); !ok {
+				v.res.MissingCode = append(v.res.MissingCode, codeHash)
+			}
+		}
+
+		v.verify(account.Root, nil, false)
+
+	case *ShortNode:
+		key := n.key
+		if hasTerminator(key) {
+			key = key[:len(key)-1]
+		}
+
+		v.verifyNode(n.child, hash, concat(path, key), accounts)
+
+	case *FullNode:
+		for i, child := range n.children {
+			if child != nil {
+				v.verifyNode(child, hash, concat(path, []byte{byte(i)}), accounts)
+			}
+		}
+
+		if n.value != nil {
+			v.verifyNode(n.value, hash, path, accounts)
+		}
+	}
+}
+
+// GenesisAlloc reads a dump and returns the genesis allocation of its accounts
+func GenesisAlloc(r io.Reader) (map[types.Address]*params.GenesisAccount, error) {
+	alloc := map[types.Address]*params.GenesisAccount{}
+
+	var current *DumpAccount
+
+	flush := func() error {
+		if current == nil {
+			return nil
+		}
+
+		addr, account, err := current.GenesisAccount()
+		if err != nil {
+			return err
+		}
+
+		alloc[addr] = account
+
+		return nil
+	}
+
+	err := readDump(r, func(dump *DumpAccount) error {
+		if err := flush(); err != nil {
+			return err
+		}
+
+		current = dump
+
+		return nil
+	}, func(slot *DumpSlot) error {
+		current.Storage = append(current.Storage, slot)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return alloc, nil
+}
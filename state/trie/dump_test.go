@@ -0,0 +1,171 @@
+package itrie
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/TIE-Tech/tie-core/params"
+	"github.com/TIE-Tech/tie-core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDump_ImportRoundTrip(t *testing.T) {
+	st := NewState(NewMemoryStorage())
+	roots := buildChainStates(t, st, 3)
+	root := roots[len(roots)-1]
+
+	var buf bytes.Buffer
+
+	count, err := Dump(st, root, nil, &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	// a line per account and per storage slot
+	assert.Equal(t, 4, strings.Count(buf.String(), "\n"))
+
+	imported := NewState(NewMemoryStorage())
+
+	importedRoot, count, err := Import(imported, &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, root, importedRoot)
+
+	res := Verify(imported, importedRoot)
+	assert.True(t, res.Valid())
+	assert.Equal(t, 2, res.Accounts)
+}
+
+func TestDump_ImportBatches(t *testing.T) {
+	batchSize := importBatchSize
+	importBatchSize = 7
+
+	t.Cleanup(func() {
+		importBatchSize = batchSize
+	})
+
+	st := NewState(NewMemoryStorage())
+	_, root := st.NewSnapshot().Commit(buildCommitObjects(20, 30))
+
+	var buf bytes.Buffer
+
+	count, err := Dump(st, types.BytesToHash(root), nil, &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 20, count)
+	assert.Equal(t, 20+20*30, strings.Count(buf.String(), "\n"))
+
+	imported := NewState(NewMemoryStorage())
+
+	importedRoot, count, err := Import(imported, &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 20, count)
+	assert.Equal(t, types.BytesToHash(root), importedRoot)
+	assert.True(t, Verify(imported, importedRoot).Valid())
+}
+
+func TestDump_ImportSlotWithoutAccount(t *testing.T) {
+	slot := `{"slot":{"hash":"` + types.ZeroHash.String() + `","value":"` + types.ZeroHash.String() + `"}}`
+
+	_, _, err := Import(NewState(NewMemoryStorage()), strings.NewReader(slot))
+	assert.Error(t, err)
+}
+
+func TestDump_ImportRootMismatch(t *testing.T) {
+	st := NewState(NewMemoryStorage())
+	roots := buildChainStates(t, st, 1)
+
+	var buf bytes.Buffer
+
+	_, err := Dump(st, roots[0], nil, &buf)
+	assert.NoError(t, err)
+
+	// tamper the storage value of the first account
+	tampered := strings.Replace(buf.String(), types.BytesToHash([]byte{1}).String(), types.BytesToHash([]byte{2}).String(), 1)
+
+	_, _, err = Import(NewState(NewMemoryStorage()), strings.NewReader(tampered))
+	assert.Error(t, err)
+}
+
+func TestDump_GenesisAlloc(t *testing.T) {
+	st := NewState(NewMemoryStorage())
+	roots := buildChainStates(t, st, 1)
+
+	var buf bytes.Buffer
+
+	// without preimages the addresses are unknown
+	_, err := Dump(st, roots[0], nil, &buf)
+	assert.NoError(t, err)
+
+	_, err = GenesisAlloc(bytes.NewReader(buf.Bytes()))
+	assert.Error(t, err)
+
+	preimages := NewPreimages(map[types.Address]*params.GenesisAccount{
+		pruneAddr1: {Storage: map[types.Hash]types.Hash{pruneSlot: {}}},
+		pruneAddr2: {},
+	})
+
+	buf.Reset()
+
+	_, err = Dump(st, roots[0], preimages, &buf)
+	assert.NoError(t, err)
+
+	alloc, err := GenesisAlloc(&buf)
+	assert.NoError(t, err)
+	assert.Len(t, alloc, 2)
+	assert.Equal(t, types.BytesToHash([]byte{1}), alloc[pruneAddr1].Storage[pruneSlot])
+	assert.Equal(t, types.BytesToHash([]byte{1}), alloc[pruneAddr2].Storage[pruneSlot])
+}
+
+func TestVerify(t *testing.T) {
+	storage := NewMemoryStorage()
+	st := NewState(storage)
+	roots := buildChainStates(t, st, 2)
+	root := roots[len(roots)-1]
+
+	res := Verify(st, root)
+	assert.True(t, res.Valid())
+	assert.Equal(t, 2, res.Accounts)
+	assert.NotZero(t, res.Nodes)
+
+	// corrupt the root node
+	data, ok := storage.Get(root.Bytes())
+	assert.True(t, ok)
+
+	storage.Put(root.Bytes(), append([]byte{}, data[:len(data)-1]...))
+
+	res = Verify(st, root)
+	assert.False(t, res.Valid())
+	assert.Equal(t, []types.Hash{root}, res.Corrupt)
+
+	// drop the root node
+	storage.Delete(root.Bytes())
+
+	res = Verify(st, root)
+	assert.False(t, res.Valid())
+	assert.Equal(t, []types.Hash{root}, res.Missing)
+}
+
+func TestVerify_CorruptAccount(t *testing.T) {
+	storage := NewMemoryStorage()
+	st := NewState(storage)
+
+	// a leaf of the account trie which is not an account
+	key := types.BytesToHash(hashit(pruneAddr1.Bytes()))
+
+	txn := NewTrie().Txn()
+	txn.Insert(key.Bytes(), []byte{0x1, 0x2})
+	txn.batch = storage
+
+	root, err := txn.Hash()
+	assert.NoError(t, err)
+
+	res := Verify(st, types.BytesToHash(root))
+	assert.False(t, res.Valid())
+	assert.Empty(t, res.Corrupt)
+	assert.Equal(t, []*CorruptAccount{
+		{
+			Node:    types.BytesToHash(root),
+			Account: key,
+		},
+	}, res.CorruptAccounts)
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"hash"
+	"runtime"
 	"sync"
 
 	"github.com/TIE-Tech/tie-core/types"
@@ -13,6 +14,15 @@ import (
 
 var arenaPool fastrlp.ArenaPool
 
+var (
+	// parallelHashThreshold is the minimum number of children of a full node
+	// without a cached hash to hash them concurrently
+	parallelHashThreshold = 4
+
+	// parallelHashDepth is the depth up to which the full nodes are hashed concurrently
+	parallelHashDepth = 1
+)
+
 var (
 	emptyRoot = types.StringToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421").Bytes()
 )
@@ -160,6 +170,10 @@ func (t *Txn) hash(node Node, h *hasher, a *fastrlp.Arena, d int) *fastrlp.Value
 
 		aa, idx = h.AcquireArena()
 
+		if d < parallelHashDepth && runtime.GOMAXPROCS(0) > 1 && countDirtyChildren(n) >= parallelHashThreshold {
+			t.hashChildren(n, d)
+		}
+
 		for _, i := range n.children {
 			if i == nil {
 				val.Set(a.NewNull())
@@ -200,3 +214,71 @@ func (t *Txn) hash(node Node, h *hasher, a *fastrlp.Arena, d int) *fastrlp.Value
 
 	return a.NewCopyBytes(hh)
 }
+
+// countDirtyChildren returns the number of children of the node without a cached hash
+func countDirtyChildren(n *FullNode) int {
+	count := 0
+
+	for _, child := range n.children {
+		if isDirty(child) {
+			count++
+		}
+	}
+
+	return count
+}
+
+func isDirty(node Node) bool {
+	if node == nil {
+		return false
+	}
+
+	if _, ok := node.(*ValueNode); ok {
+		return false
+	}
+
+	_, ok := node.Hash()
+
+	return !ok
+}
+
+// hashChildren hashes the children of the full node concurrently. The hashes are
+// cached in the children, so the encoding of the node afterwards reuses them
+func (t *Txn) hashChildren(n *FullNode, d int) {
+	tt := &Txn{root: t.root, epoch: t.epoch, storage: t.storage}
+
+	if t.batch != nil {
+		if putter, ok := t.batch.(*syncPutter); ok {
+			tt.batch = putter
+		} else {
+			tt.batch = &syncPutter{putter: t.batch}
+		}
+	}
+
+	var wg sync.WaitGroup
+
+	for _, child := range n.children {
+		if !isDirty(child) {
+			continue
+		}
+
+		wg.Add(1)
+
+		go func(child Node) {
+			defer wg.Done()
+
+			h, ok := hasherPool.Get().(*hasher)
+			if !ok {
+				return
+			}
+
+			a, _ := h.AcquireArena()
+			tt.hash(child, h, a, d+1)
+
+			h.ReleaseArenas(0)
+			hasherPool.Put(h)
+		}(child)
+	}
+
+	wg.Wait()
+}
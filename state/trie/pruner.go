@@ -0,0 +1,329 @@
+package itrie
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/TIE-Tech/go-logger"
+	"github.com/TIE-Tech/tie-core/state"
+	"github.com/TIE-Tech/tie-core/types"
+)
+
+const (
+	// PruningArchive keeps every historic state
+	PruningArchive = "archive"
+	// PruningPruned keeps the recent states and the checkpoints only
+	PruningPruned = "pruned"
+
+	// sweepBatchSize is the number of keys deleted per batch during a sweep
+	sweepBatchSize = 4096
+)
+
+// prunedHeadKey holds the head of the last prune, the state reports
+// the missing roots with ErrStatePruned once it is set
+var prunedHeadKey = []byte("pruned-head")
+
+// PruningConfig is the state retention configuration
+type PruningConfig struct {
+	// Mode is either archive or pruned
+	Mode string
+	// Retention is the number of recent block states kept in pruned mode
+	Retention uint64
+	// CheckpointInterval keeps the state of every block multiple of it, 0 disables checkpoints
+	CheckpointInterval uint64
+}
+
+// DefaultPruningConfig returns the archive configuration
+func DefaultPruningConfig() *PruningConfig {
+	return &PruningConfig{
+		Mode:               PruningArchive,
+		Retention:          128,
+		CheckpointInterval: 0,
+	}
+}
+
+// Validate checks the pruning configuration
+func (c *PruningConfig) Validate() error {
+	switch c.Mode {
+	case PruningArchive:
+		return nil
+	case PruningPruned:
+		if c.Retention == 0 {
+			return fmt.Errorf("state retention must be greater than zero in %s mode", PruningPruned)
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("unknown state pruning mode '%s', expected %s or %s", c.Mode, PruningArchive, PruningPruned)
+	}
+}
+
+// HeaderByNumber returns the canonical header at the given height
+type HeaderByNumber func(uint64) (*types.Header, bool)
+
+// Pruner removes the trie nodes which are not reachable from the
+// retained states with a mark-and-sweep over the trie storage
+type Pruner struct {
+	state     *State
+	config    *PruningConfig
+	getHeader HeaderByNumber
+
+	lock       sync.Mutex
+	lastPruned uint64
+}
+
+// NewPruner creates a pruner for the state. The state reports pruned
+// roots with ErrStatePruned from now on
+func NewPruner(s *State, config *PruningConfig, getHeader HeaderByNumber) *Pruner {
+	s.pruned = true
+
+	lastPruned, _ := readPrunedHead(s.storage)
+
+	return &Pruner{
+		state:      s,
+		config:     config,
+		getHeader:  getHeader,
+		lastPruned: lastPruned,
+	}
+}
+
+// readPrunedHead returns the head of the last prune of the storage, false if it was never pruned
+func readPrunedHead(storage Storage) (uint64, bool) {
+	buf, ok := storage.Get(prunedHeadKey)
+	if !ok || len(buf) != 8 {
+		return 0, false
+	}
+
+	return binary.BigEndian.Uint64(buf), true
+}
+
+// writePrunedHead records the head of a prune in the storage
+func writePrunedHead(storage Storage, head uint64) {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, head)
+
+	storage.Put(prunedHeadKey, buf)
+}
+
+// HandleHead prunes the state once the head moved a full retention window since the last prune
+func (p *Pruner) HandleHead(head uint64) {
+	p.lock.Lock()
+	due := head >= p.lastPruned+p.config.Retention
+	p.lock.Unlock()
+
+	if !due {
+		return
+	}
+
+	deleted, err := p.Prune(head)
+	if err != nil {
+		logger.Error("[TRIE] failed to prune state", "head", head, "err", err)
+
+		return
+	}
+
+	logger.Info("[TRIE] pruned state", "head", head, "deleted", deleted)
+}
+
+// Prune deletes every node which is not part of the retained states at the given head
+// and returns the number of deleted nodes. Contract code is never pruned
+func (p *Pruner) Prune(head uint64) (int, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	s := p.state
+
+	// from now on every committed node is protected from the sweep
+	s.commitLock.Lock()
+	s.protected = map[types.Hash]struct{}{}
+	roots := append([]types.Hash{}, s.recentRoots...)
+	s.commitLock.Unlock()
+
+	defer func() {
+		s.commitLock.Lock()
+		s.protected = nil
+		s.commitLock.Unlock()
+	}()
+
+	retained, err := p.retainedRoots(head)
+	if err != nil {
+		return 0, err
+	}
+
+	roots = append(roots, retained...)
+
+	marked := map[types.Hash]struct{}{}
+	for _, root := range roots {
+		if err := p.mark(root, true, marked); err != nil {
+			return 0, err
+		}
+	}
+
+	// recorded before the sweep so an interrupted prune still reports the missing roots
+	writePrunedHead(s.storage, head)
+
+	// cached tries may reference the nodes about to be swept
+	s.cache.Purge()
+
+	deleted := p.sweep(marked)
+	p.lastPruned = head
+
+	return deleted, nil
+}
+
+// retainedRoots returns the state roots of the recent blocks, the checkpoints and the genesis
+func (p *Pruner) retainedRoots(head uint64) ([]types.Hash, error) {
+	from := uint64(0)
+	if head >= p.config.Retention {
+		from = head - p.config.Retention + 1
+	}
+
+	roots := []types.Hash{}
+
+	add := func(num uint64) error {
+		header, ok := p.getHeader(num)
+		if !ok {
+			return fmt.Errorf("header %d not found", num)
+		}
+
+		roots = append(roots, header.StateRoot)
+
+		return nil
+	}
+
+	// genesis is always kept
+	if err := add(0); err != nil {
+		return nil, err
+	}
+
+	if interval := p.config.CheckpointInterval; interval != 0 {
+		for num := interval; num < from; num += interval {
+			if err := add(num); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for num := from; num <= head; num++ {
+		if err := add(num); err != nil {
+			return nil, err
+		}
+	}
+
+	return roots, nil
+}
+
+// mark walks the trie at root and marks every stored node. Leaves of
+// account tries are decoded to follow the storage tries
+func (p *Pruner) mark(root types.Hash, accounts bool, marked map[types.Hash]struct{}) error {
+	if root == types.EmptyRootHash {
+		return nil
+	}
+
+	if _, ok := marked[root]; ok {
+		// nodes are content addressed, the subtree is already marked
+		return nil
+	}
+
+	n, ok, err := GetNode(root.Bytes(), p.state.storage)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		// sweeping without the whole retained states would delete the rest of their nodes
+		return fmt.Errorf("%w %s while marking the retained states", ErrMissingNode, root)
+	}
+
+	marked[root] = struct{}{}
+
+	return p.markNode(n, accounts, marked)
+}
+
+func (p *Pruner) markNode(node Node, accounts bool, marked map[types.Hash]struct{}) error {
+	switch n := node.(type) {
+	case nil:
+		return nil
+
+	case *ValueNode:
+		if n.hash {
+			return p.mark(types.BytesToHash(n.buf), accounts, marked)
+		}
+
+		if !accounts {
+			return nil
+		}
+
+		var account state.Account
+		if err := account.UnmarshalRlp(n.buf); err != nil {
+			return err
+		}
+
+		return p.mark(account.Root, false, marked)
+
+	case *ShortNode:
+		return p.markNode(n.child, accounts, marked)
+
+	case *FullNode:
+		for _, child := range n.children {
+			if err := p.markNode(child, accounts, marked); err != nil {
+				return err
+			}
+		}
+
+		return p.markNode(n.value, accounts, marked)
+
+	default:
+		return fmt.Errorf("unknown node type %T", n)
+	}
+}
+
+// sweep deletes the nodes which were not marked nor written during the prune
+func (p *Pruner) sweep(marked map[types.Hash]struct{}) int {
+	s := p.state
+	deleted := 0
+	keys := make([]types.Hash, 0, sweepBatchSize)
+
+	flush := func() {
+		s.commitLock.Lock()
+		defer s.commitLock.Unlock()
+
+		batch := s.storage.Batch()
+
+		for _, k := range keys {
+			if _, ok := s.protected[k]; ok {
+				continue
+			}
+
+			batch.Delete(k.Bytes())
+			deleted++
+		}
+
+		batch.Write()
+
+		keys = keys[:0]
+	}
+
+	s.storage.Iterate(nil, func(k []byte) bool {
+		// code and snapshot entries are stored with a prefix, nodes are keyed by their hash
+		if len(k) != types.HashLength {
+			return true
+		}
+
+		key := types.BytesToHash(k)
+		if _, ok := marked[key]; !ok {
+			keys = append(keys, key)
+		}
+
+		if len(keys) == sweepBatchSize {
+			flush()
+		}
+
+		return true
+	})
+
+	flush()
+
+	return deleted
+}
@@ -0,0 +1,210 @@
+package itrie
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/TIE-Tech/tie-core/state"
+	"github.com/TIE-Tech/tie-core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	pruneAddr1 = types.StringToAddress("1")
+	pruneAddr2 = types.StringToAddress("2")
+	pruneSlot  = types.StringToHash("1")
+)
+
+// buildChainStates commits a state per block and returns the state roots
+func buildChainStates(t *testing.T, st *State, blocks int) []types.Hash {
+	t.Helper()
+
+	roots := []types.Hash{}
+	storageRoots := map[types.Address]types.Hash{}
+
+	snap := st.NewSnapshot()
+
+	for i := 0; i < blocks; i++ {
+		objs := []*state.Object{}
+
+		for _, addr := range []types.Address{pruneAddr1, pruneAddr2} {
+			root, ok := storageRoots[addr]
+			if !ok {
+				root = types.EmptyRootHash
+			}
+
+			objs = append(objs, &state.Object{
+				Address: addr,
+				Balance: big.NewInt(int64(i)),
+				Nonce:   uint64(i),
+				Root:    root,
+				Storage: []*state.StorageObject{
+					{Key: pruneSlot.Bytes(), Val: types.BytesToHash(big.NewInt(int64(i + 1)).Bytes()).Bytes()},
+				},
+			})
+		}
+
+		var root []byte
+		snap, root = snap.Commit(objs)
+		roots = append(roots, types.BytesToHash(root))
+
+		for _, addr := range []types.Address{pruneAddr1, pruneAddr2} {
+			account := readAccount(t, snap, addr)
+			storageRoots[addr] = account.Root
+		}
+	}
+
+	return roots
+}
+
+func readAccount(t *testing.T, snap state.Snapshot, addr types.Address) *state.Account {
+	t.Helper()
+
+	data, ok := snap.Get(hashit(addr.Bytes()))
+	assert.True(t, ok)
+
+	var account state.Account
+	assert.NoError(t, account.UnmarshalRlp(data))
+
+	return &account
+}
+
+func TestPruner_Prune(t *testing.T) {
+	st := NewState(NewMemoryStorage())
+	roots := buildChainStates(t, st, 10)
+
+	getHeader := func(num uint64) (*types.Header, bool) {
+		if num >= uint64(len(roots)) {
+			return nil, false
+		}
+
+		return &types.Header{Number: num, StateRoot: roots[num]}, true
+	}
+
+	pruner := NewPruner(st, &PruningConfig{
+		Mode:               PruningPruned,
+		Retention:          3,
+		CheckpointInterval: 4,
+	}, getHeader)
+
+	// drop the roots which are always retained as recent commits
+	st.recentRoots = nil
+
+	deleted, err := pruner.Prune(9)
+	assert.NoError(t, err)
+	assert.NotZero(t, deleted)
+
+	retained := map[int]bool{0: true, 4: true, 7: true, 8: true, 9: true}
+
+	for num, root := range roots {
+		snap, err := st.NewSnapshotAt(root)
+
+		if !retained[num] {
+			assert.True(t, errors.Is(err, ErrStatePruned), "block %d", num)
+
+			continue
+		}
+
+		assert.NoError(t, err, "block %d", num)
+
+		for _, addr := range []types.Address{pruneAddr1, pruneAddr2} {
+			account := readAccount(t, snap, addr)
+			assert.Equal(t, uint64(num), account.Nonce)
+
+			storage, err := st.NewSnapshotAt(account.Root)
+			assert.NoError(t, err)
+
+			_, ok := storage.Get(hashit(pruneSlot.Bytes()))
+			assert.True(t, ok)
+		}
+	}
+}
+
+func TestPruner_PrunedAfterRestart(t *testing.T) {
+	storage := NewMemoryStorage()
+	st := NewState(storage)
+	roots := buildChainStates(t, st, 5)
+
+	getHeader := func(num uint64) (*types.Header, bool) {
+		return &types.Header{Number: num, StateRoot: roots[num]}, true
+	}
+
+	pruner := NewPruner(st, &PruningConfig{Mode: PruningPruned, Retention: 1}, getHeader)
+	st.recentRoots = nil
+
+	_, err := pruner.Prune(4)
+	assert.NoError(t, err)
+
+	// reopened in archive mode, the pruned states are still reported as pruned
+	st = NewState(storage)
+
+	_, err = st.NewSnapshotAt(roots[2])
+	assert.True(t, errors.Is(err, ErrStatePruned))
+
+	// the pruner resumes from the last prune
+	pruner = NewPruner(st, &PruningConfig{Mode: PruningPruned, Retention: 1}, getHeader)
+	assert.Equal(t, uint64(4), pruner.lastPruned)
+
+	// a storage never pruned reports a generic error
+	_, err = NewState(NewMemoryStorage()).NewSnapshotAt(roots[2])
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrStatePruned))
+}
+
+func TestPruner_KeepsRecentCommits(t *testing.T) {
+	st := NewState(NewMemoryStorage())
+	roots := buildChainStates(t, st, 5)
+
+	// only the genesis is canonical, the other states are recent commits
+	getHeader := func(num uint64) (*types.Header, bool) {
+		return &types.Header{Number: num, StateRoot: roots[0]}, true
+	}
+
+	pruner := NewPruner(st, &PruningConfig{Mode: PruningPruned, Retention: 1}, getHeader)
+
+	_, err := pruner.Prune(0)
+	assert.NoError(t, err)
+
+	for _, root := range roots {
+		_, err := st.NewSnapshotAt(root)
+		assert.NoError(t, err)
+	}
+}
+
+func TestPruner_AbortsOnMissingNode(t *testing.T) {
+	storage := NewMemoryStorage()
+	st := NewState(storage)
+	roots := buildChainStates(t, st, 5)
+
+	getHeader := func(num uint64) (*types.Header, bool) {
+		return &types.Header{Number: num, StateRoot: roots[num]}, true
+	}
+
+	pruner := NewPruner(st, &PruningConfig{Mode: PruningPruned, Retention: 1}, getHeader)
+	st.recentRoots = nil
+
+	// the storage trie of a retained state is missing
+	snap, err := st.NewSnapshotAt(roots[4])
+	assert.NoError(t, err)
+	storage.Delete(readAccount(t, snap, pruneAddr1).Root.Bytes())
+
+	_, err = pruner.Prune(4)
+	assert.ErrorIs(t, err, ErrMissingNode)
+
+	// nothing was swept
+	_, pruned := readPrunedHead(storage)
+	assert.False(t, pruned)
+
+	for _, root := range roots {
+		_, err := st.NewSnapshotAt(root)
+		assert.NoError(t, err)
+	}
+}
+
+func TestPruningConfig_Validate(t *testing.T) {
+	assert.NoError(t, DefaultPruningConfig().Validate())
+	assert.NoError(t, (&PruningConfig{Mode: PruningPruned, Retention: 1}).Validate())
+	assert.Error(t, (&PruningConfig{Mode: PruningPruned}).Validate())
+	assert.Error(t, (&PruningConfig{Mode: "full"}).Validate())
+}
@@ -0,0 +1,467 @@
+package itrie
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/TIE-Tech/tie-core/state"
+	"github.com/TIE-Tech/tie-core/types"
+)
+
+var (
+	// snapshotAccountPrefix is the prefix of the flat accounts, keyed by the account hash
+	snapshotAccountPrefix = []byte("sa")
+	// snapshotStoragePrefix is the prefix of the flat storage, keyed by the account hash and the slot hash
+	snapshotStoragePrefix = []byte("ss")
+	// snapshotRootKey holds the state root of the flat entries on disk
+	snapshotRootKey = []byte("snapshot-root")
+)
+
+// maxDiffLayers is the number of in-memory layers kept before
+// the oldest one is flattened to disk
+const maxDiffLayers = 128
+
+// snapshotBatchSize is the number of flat entries written per batch during the generation
+const snapshotBatchSize = 4096
+
+var (
+	errSnapshotUnavailable = errors.New("snapshot not available")
+	errGenerationAborted   = errors.New("snapshot generation aborted")
+)
+
+// diffLayer holds the flat changes of one committed state on top of its parent
+type diffLayer struct {
+	root   types.Hash
+	parent *diffLayer // nil when the layer is on top of the disk layer
+	depth  int
+
+	// accounts holds the rlp of the changed accounts, nil for deleted ones
+	accounts map[types.Hash][]byte
+	// storage holds the changed slots of the accounts, nil for deleted ones
+	storage map[types.Hash]map[types.Hash][]byte
+	// destructs are the accounts whose previous storage is wiped
+	destructs map[types.Hash]struct{}
+}
+
+func newDiffLayer(root types.Hash) *diffLayer {
+	return &diffLayer{
+		root:      root,
+		accounts:  map[types.Hash][]byte{},
+		storage:   map[types.Hash]map[types.Hash][]byte{},
+		destructs: map[types.Hash]struct{}{},
+	}
+}
+
+func (d *diffLayer) bottom() *diffLayer {
+	l := d
+	for l.parent != nil {
+		l = l.parent
+	}
+
+	return l
+}
+
+// snapshotTree is a flat key-value copy of the recent states. The disk layer holds
+// the accounts and slots of one state, and the diff layers the changes of the
+// states committed on top of it, so forks of the recent blocks are kept apart
+type snapshotTree struct {
+	lock    sync.RWMutex
+	storage Storage
+
+	diskRoot   types.Hash
+	generating bool
+	layers     map[types.Hash]*diffLayer
+
+	// genAbort stops the running generation which closes genDone when it returns
+	genAbort chan struct{}
+	genDone  chan struct{}
+}
+
+func newSnapshotTree(storage Storage) *snapshotTree {
+	s := &snapshotTree{
+		storage:  storage,
+		diskRoot: types.EmptyRootHash,
+		layers:   map[types.Hash]*diffLayer{},
+	}
+
+	// without a root the disk layer is empty which matches the empty state
+	if root, ok := storage.Get(snapshotRootKey); ok {
+		s.diskRoot = types.BytesToHash(root)
+	}
+
+	return s
+}
+
+func snapshotAccountKey(account types.Hash) []byte {
+	return append(append([]byte{}, snapshotAccountPrefix...), account.Bytes()...)
+}
+
+func snapshotStorageKey(account, slot types.Hash) []byte {
+	return append(snapshotStorageAccountPrefix(account), slot.Bytes()...)
+}
+
+func snapshotStorageAccountPrefix(account types.Hash) []byte {
+	return append(append([]byte{}, snapshotStoragePrefix...), account.Bytes()...)
+}
+
+// available returns true if the snapshot can serve the state at root
+func (s *snapshotTree) available(root types.Hash) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if _, ok := s.layers[root]; ok {
+		return true
+	}
+
+	return s.onDisk(root) && !s.generating
+}
+
+// onDisk returns true if root is the state of the disk layer
+func (s *snapshotTree) onDisk(root types.Hash) bool {
+	// the zero root marks a disk layer which has to be generated again
+	return root == s.diskRoot && root != types.ZeroHash
+}
+
+// update adds the layer of a state committed on top of the parent state
+func (s *snapshotTree) update(parent, root types.Hash, layer *diffLayer) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.layers[root]; ok || root == s.diskRoot {
+		// the same state was already committed
+		return
+	}
+
+	if !s.onDisk(parent) {
+		p, ok := s.layers[parent]
+		if !ok {
+			// the parent is not covered by the snapshot
+			return
+		}
+
+		layer.parent = p
+		layer.depth = p.depth
+	}
+
+	layer.depth++
+	s.layers[root] = layer
+
+	if layer.depth > maxDiffLayers && !s.generating {
+		s.flatten(layer.bottom())
+	}
+}
+
+// account returns the rlp of the account in the state at root
+func (s *snapshotTree) account(root, account types.Hash) ([]byte, bool, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	layer, ok := s.layers[root]
+	if !ok && !s.onDisk(root) {
+		return nil, false, errSnapshotUnavailable
+	}
+
+	for l := layer; l != nil; l = l.parent {
+		if data, ok := l.accounts[account]; ok {
+			return data, data != nil, nil
+		}
+	}
+
+	if s.generating {
+		return nil, false, errSnapshotUnavailable
+	}
+
+	data, ok := s.storage.Get(snapshotAccountKey(account))
+
+	return data, ok, nil
+}
+
+// slot returns the value of the storage slot of the account in the state at root
+func (s *snapshotTree) slot(root, account, slot types.Hash) ([]byte, bool, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	layer, ok := s.layers[root]
+	if !ok && !s.onDisk(root) {
+		return nil, false, errSnapshotUnavailable
+	}
+
+	for l := layer; l != nil; l = l.parent {
+		if slots, ok := l.storage[account]; ok {
+			if data, ok := slots[slot]; ok {
+				return data, data != nil, nil
+			}
+		}
+
+		if _, ok := l.destructs[account]; ok {
+			return nil, false, nil
+		}
+	}
+
+	if s.generating {
+		return nil, false, errSnapshotUnavailable
+	}
+
+	data, ok := s.storage.Get(snapshotStorageKey(account, slot))
+
+	return data, ok, nil
+}
+
+// flatten writes the bottom layer into the disk layer and drops
+// the layers which are not built on top of it anymore
+func (s *snapshotTree) flatten(bottom *diffLayer) {
+	batch := s.storage.Batch()
+
+	for account := range bottom.destructs {
+		s.storage.Iterate(snapshotStorageAccountPrefix(account), func(k []byte) bool {
+			batch.Delete(append([]byte{}, k...))
+
+			return true
+		})
+	}
+
+	for account, data := range bottom.accounts {
+		if data == nil {
+			batch.Delete(snapshotAccountKey(account))
+		} else {
+			batch.Put(snapshotAccountKey(account), data)
+		}
+	}
+
+	for account, slots := range bottom.storage {
+		for slot, data := range slots {
+			if data == nil {
+				batch.Delete(snapshotStorageKey(account, slot))
+			} else {
+				batch.Put(snapshotStorageKey(account, slot), data)
+			}
+		}
+	}
+
+	batch.Put(snapshotRootKey, bottom.root.Bytes())
+	batch.Write()
+
+	s.diskRoot = bottom.root
+
+	keep := map[types.Hash]bool{}
+	for root, layer := range s.layers {
+		keep[root] = layer.bottom() == bottom && layer != bottom
+	}
+
+	for root, layer := range s.layers {
+		if !keep[root] {
+			delete(s.layers, root)
+
+			continue
+		}
+
+		if layer.parent == bottom {
+			layer.parent = nil
+		}
+
+		layer.depth--
+	}
+}
+
+// flush flattens all the layers of the state at root to disk, a running generation is aborted
+func (s *snapshotTree) flush(root types.Hash) error {
+	s.lock.Lock()
+	if s.generating {
+		if s.genAbort != nil {
+			close(s.genAbort)
+			s.genAbort = nil
+		}
+
+		done := s.genDone
+		s.lock.Unlock()
+
+		<-done
+
+		return errGenerationAborted
+	}
+	defer s.lock.Unlock()
+
+	for root != s.diskRoot {
+		layer, ok := s.layers[root]
+		if !ok {
+			return errSnapshotUnavailable
+		}
+
+		s.flatten(layer.bottom())
+	}
+
+	return nil
+}
+
+// disable drops the layers and invalidates the disk layer until it is generated again
+func (s *snapshotTree) disable() {
+	s.layers = map[types.Hash]*diffLayer{}
+	s.diskRoot = types.ZeroHash
+	s.storage.Put(snapshotRootKey, types.ZeroHash.Bytes())
+}
+
+// generate rebuilds the disk layer from the trie at root. The layers committed
+// during the generation are kept on top of the generated state
+func (s *snapshotTree) generate(root types.Hash) error {
+	s.lock.Lock()
+	if s.generating {
+		s.lock.Unlock()
+
+		return fmt.Errorf("snapshot generation already running")
+	}
+
+	s.generating = true
+	s.diskRoot = root
+	s.layers = map[types.Hash]*diffLayer{}
+	s.storage.Put(snapshotRootKey, types.ZeroHash.Bytes())
+	s.genAbort = make(chan struct{})
+	s.genDone = make(chan struct{})
+	abort, done := s.genAbort, s.genDone
+	s.lock.Unlock()
+
+	defer close(done)
+
+	err := s.generateEntries(root, abort)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.generating = false
+
+	if err != nil {
+		s.disable()
+
+		return err
+	}
+
+	s.storage.Put(snapshotRootKey, root.Bytes())
+
+	return nil
+}
+
+func (s *snapshotTree) generateEntries(root types.Hash, abort chan struct{}) error {
+	// wipe the previous entries
+	for _, prefix := range [][]byte{snapshotAccountPrefix, snapshotStoragePrefix} {
+		batch := s.storage.Batch()
+
+		s.storage.Iterate(prefix, func(k []byte) bool {
+			batch.Delete(append([]byte{}, k...))
+
+			return true
+		})
+		batch.Write()
+	}
+
+	batch := s.storage.Batch()
+	count := 0
+
+	put := func(k, v []byte) {
+		batch.Put(k, v)
+
+		if count++; count%snapshotBatchSize == 0 {
+			batch.Write()
+			batch = s.storage.Batch()
+		}
+	}
+
+	err := iterateLeaves(root, s.storage, func(key, value []byte) error {
+		select {
+		case <-abort:
+			return errGenerationAborted
+		default:
+		}
+
+		account := types.BytesToHash(key)
+		put(snapshotAccountKey(account), value)
+
+		var acc state.Account
+		if err := acc.UnmarshalRlp(value); err != nil {
+			return err
+		}
+
+		return iterateLeaves(acc.Root, s.storage, func(key, value []byte) error {
+			put(snapshotStorageKey(account, types.BytesToHash(key)), value)
+
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	batch.Write()
+
+	return nil
+}
+
+// iterateLeaves calls fn with the key and the value of every leaf of the trie at root
+func iterateLeaves(root types.Hash, storage Storage, fn func(key, value []byte) error) error {
+	if root == types.EmptyRootHash {
+		return nil
+	}
+
+	n, ok, err := GetNode(root.Bytes(), storage)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return fmt.Errorf("missing trie node %s", root)
+	}
+
+	return iterateNode(n, nil, storage, fn)
+}
+
+func iterateNode(node Node, path []byte, storage Storage, fn func(key, value []byte) error) error {
+	switch n := node.(type) {
+	case nil:
+		return nil
+
+	case *ValueNode:
+		if n.hash {
+			child, ok, err := GetNode(n.buf, storage)
+			if err != nil {
+				return err
+			}
+
+			if !ok {
+				return fmt.Errorf("missing trie node %s", types.BytesToHash(n.buf))
+			}
+
+			return iterateNode(child, path, storage, fn)
+		}
+
+		return fn(hexNibblesToBytes(path), n.buf)
+
+	case *ShortNode:
+		key := n.key
+		if hasTerminator(key) {
+			key = key[:len(key)-1]
+		}
+
+		return iterateNode(n.child, concat(path, key), storage, fn)
+
+	case *FullNode:
+		for i, child := range n.children {
+			if err := iterateNode(child, concat(path, []byte{byte(i)}), storage, fn); err != nil {
+				return err
+			}
+		}
+
+		return iterateNode(n.value, path, storage, fn)
+
+	default:
+		return fmt.Errorf("unknown node type %T", n)
+	}
+}
+
+// hexNibblesToBytes packs a sequence of nibbles without terminator into bytes
+func hexNibblesToBytes(nibbles []byte) []byte {
+	buf := make([]byte, len(nibbles)/2)
+	for i := range buf {
+		buf[i] = nibbles[2*i]<<4 | nibbles[2*i+1]
+	}
+
+	return buf
+}
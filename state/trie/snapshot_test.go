@@ -0,0 +1,135 @@
+package itrie
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/TIE-Tech/tie-core/state"
+	"github.com/TIE-Tech/tie-core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// assertFlatMatchesTrie checks the flat snapshot reads of the state at root against the trie
+func assertFlatMatchesTrie(t *testing.T, st *State, root types.Hash) {
+	t.Helper()
+
+	snap, err := st.NewSnapshotAt(root)
+	assert.NoError(t, err)
+
+	trie, ok := snap.(*Trie)
+	assert.True(t, ok)
+
+	for _, addr := range []types.Address{pruneAddr1, pruneAddr2} {
+		key := hashit(addr.Bytes())
+
+		expected, found := trie.Get(key)
+
+		data, ok, err := trie.GetAccount(key)
+		assert.NoError(t, err)
+		assert.Equal(t, found, ok)
+		assert.Equal(t, expected, data)
+
+		if !found {
+			continue
+		}
+
+		var account state.Account
+		assert.NoError(t, account.UnmarshalRlp(expected))
+
+		storage, err := st.NewSnapshotAt(account.Root)
+		assert.NoError(t, err)
+
+		slot := hashit(pruneSlot.Bytes())
+		expected, found = storage.Get(slot)
+
+		data, ok, err = trie.GetStorage(key, slot)
+		assert.NoError(t, err)
+		assert.Equal(t, found, ok)
+		assert.Equal(t, expected, data)
+	}
+}
+
+func TestSnapshot_ReadsMatchTrie(t *testing.T) {
+	st := NewState(NewMemoryStorage())
+	roots := buildChainStates(t, st, 5)
+
+	for _, root := range roots {
+		assert.True(t, st.HasSnapshot(root))
+		assertFlatMatchesTrie(t, st, root)
+	}
+}
+
+func TestSnapshot_Forks(t *testing.T) {
+	st := NewState(NewMemoryStorage())
+	roots := buildChainStates(t, st, 3)
+
+	parent, err := st.NewSnapshotAt(roots[1])
+	assert.NoError(t, err)
+
+	// delete one account in a sibling of the last state
+	_, root := parent.Commit([]*state.Object{
+		{Address: pruneAddr1, Deleted: true},
+	})
+	fork := types.BytesToHash(root)
+
+	assert.True(t, st.HasSnapshot(fork))
+	assertFlatMatchesTrie(t, st, fork)
+	assertFlatMatchesTrie(t, st, roots[2])
+
+	snap, err := st.NewSnapshotAt(fork)
+	assert.NoError(t, err)
+
+	trie, ok := snap.(*Trie)
+	assert.True(t, ok)
+
+	_, ok, err = trie.GetStorage(hashit(pruneAddr1.Bytes()), hashit(pruneSlot.Bytes()))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestSnapshot_Flatten(t *testing.T) {
+	st := NewState(NewMemoryStorage())
+	roots := buildChainStates(t, st, maxDiffLayers+5)
+
+	// the oldest states were written to disk
+	assert.Equal(t, roots[4], st.snaps.diskRoot)
+	assert.False(t, st.HasSnapshot(roots[3]))
+	assert.Len(t, st.snaps.layers, maxDiffLayers)
+
+	assertFlatMatchesTrie(t, st, roots[4])
+	assertFlatMatchesTrie(t, st, roots[len(roots)-1])
+
+	// flush the head and reload the snapshot from disk
+	head := roots[len(roots)-1]
+	assert.NoError(t, st.FlushSnapshot(head))
+
+	reloaded := NewState(st.storage)
+	assert.True(t, reloaded.HasSnapshot(head))
+	assertFlatMatchesTrie(t, reloaded, head)
+}
+
+func TestSnapshot_Generate(t *testing.T) {
+	storage := NewMemoryStorage()
+	roots := buildChainStates(t, NewState(storage), 5)
+	head := roots[len(roots)-1]
+
+	// drop the flat entries, the snapshot is not available anymore
+	st := NewState(storage)
+	st.snaps.disable()
+	assert.False(t, st.HasSnapshot(head))
+
+	assert.NoError(t, st.GenerateSnapshot(head))
+	assert.True(t, st.HasSnapshot(head))
+	assertFlatMatchesTrie(t, st, head)
+
+	// the states committed on top of the generated one are tracked
+	snap, err := st.NewSnapshotAt(head)
+	assert.NoError(t, err)
+
+	_, root := snap.Commit([]*state.Object{
+		{Address: pruneAddr2, Balance: big.NewInt(100), Root: types.EmptyRootHash},
+	})
+
+	assert.True(t, st.HasSnapshot(types.BytesToHash(root)))
+	assertFlatMatchesTrie(t, st, types.BytesToHash(root))
+}
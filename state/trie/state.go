@@ -3,6 +3,7 @@ package itrie
 import (
 	"errors"
 	"fmt"
+	"sync"
 
 	lru "github.com/hashicorp/golang-lru"
 
@@ -10,9 +11,27 @@ import (
 	"github.com/TIE-Tech/tie-core/types"
 )
 
+// ErrStatePruned is returned when the requested state has been removed by the pruner
+var ErrStatePruned = errors.New("state has been pruned, use an archive node to query historic state")
+
+// recentRootsLimit is the number of recently committed roots always kept by the pruner
+const recentRootsLimit = 16
+
 type State struct {
 	storage Storage
 	cache   *lru.Cache
+	snaps   *snapshotTree
+
+	// pruned is set once a pruner removes historic states from the storage,
+	// it is restored from the storage on open whatever the pruning mode
+	pruned bool
+
+	// commitLock serializes batch writes with the sweep phase of the pruner
+	commitLock sync.Mutex
+	// protected holds the nodes written while a prune is running
+	protected map[types.Hash]struct{}
+	// recentRoots are the last committed roots, which may not be canonical yet
+	recentRoots []types.Hash
 }
 
 func NewState(storage Storage) *State {
@@ -21,8 +40,11 @@ func NewState(storage Storage) *State {
 	s := &State{
 		storage: storage,
 		cache:   cache,
+		snaps:   newSnapshotTree(storage),
 	}
 
+	_, s.pruned = readPrunedHead(storage)
+
 	return s
 }
 
@@ -30,6 +52,7 @@ func (s *State) NewSnapshot() state.Snapshot {
 	t := NewTrie()
 	t.state = s
 	t.storage = s.storage
+	t.hash = types.EmptyRootHash
 
 	return t
 }
@@ -55,11 +78,13 @@ func (s *State) NewSnapshotAt(root types.Hash) (state.Snapshot, error) {
 			return nil, errors.New("invalid type assertion")
 		}
 
-		t.state = s
-
-		trie, ok := tt.(*Trie)
-		if !ok {
-			return nil, errors.New("invalid type assertion")
+		// cached tries are shared between concurrent commits, do not modify them
+		trie := &Trie{
+			root:    t.root,
+			hash:    root,
+			epoch:   t.epoch,
+			state:   s,
+			storage: s.storage,
 		}
 
 		return trie, nil
@@ -72,11 +97,16 @@ func (s *State) NewSnapshotAt(root types.Hash) (state.Snapshot, error) {
 	}
 
 	if !ok {
+		if s.pruned {
+			return nil, fmt.Errorf("state not found at hash %s: %w", root, ErrStatePruned)
+		}
+
 		return nil, fmt.Errorf("state not found at hash %s", root)
 	}
 
 	t := &Trie{
 		root:    n,
+		hash:    root,
 		state:   s,
 		storage: s.storage,
 	}
@@ -84,6 +114,57 @@ func (s *State) NewSnapshotAt(root types.Hash) (state.Snapshot, error) {
 	return t, nil
 }
 
+// HasSnapshot returns true if the flat snapshot can serve the reads of the state at root
+func (s *State) HasSnapshot(root types.Hash) bool {
+	return s.snaps.available(root)
+}
+
+// GenerateSnapshot rebuilds the flat snapshot from the trie at root
+func (s *State) GenerateSnapshot(root types.Hash) error {
+	return s.snaps.generate(root)
+}
+
+// FlushSnapshot writes the in-memory layers of the state at root to disk
+// so the flat snapshot is still available after a restart
+func (s *State) FlushSnapshot(root types.Hash) error {
+	return s.snaps.flush(root)
+}
+
 func (s *State) AddState(root types.Hash, t *Trie) {
 	s.cache.Add(root, t)
 }
+
+// commitBatch records the keys written by a commit so a running
+// prune does not sweep nodes it did not see during the mark phase
+type commitBatch struct {
+	Batch
+	keys []types.Hash
+}
+
+func (b *commitBatch) Put(k, v []byte) {
+	b.keys = append(b.keys, types.BytesToHash(k))
+	b.Batch.Put(k, v)
+}
+
+func (s *State) newBatch() *commitBatch {
+	return &commitBatch{Batch: s.storage.Batch()}
+}
+
+// writeBatch flushes the batch of a commit with the given state root
+func (s *State) writeBatch(b *commitBatch, root types.Hash) {
+	s.commitLock.Lock()
+	defer s.commitLock.Unlock()
+
+	b.Write()
+
+	if s.protected != nil {
+		for _, k := range b.keys {
+			s.protected[k] = struct{}{}
+		}
+	}
+
+	s.recentRoots = append(s.recentRoots, root)
+	if len(s.recentRoots) > recentRootsLimit {
+		s.recentRoots = s.recentRoots[1:]
+	}
+}
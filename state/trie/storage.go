@@ -1,16 +1,23 @@
 package itrie
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/TIE-Tech/tie-core/common/hex"
 	"github.com/TIE-Tech/tie-core/types"
 	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
 	"github.com/umbracle/fastrlp"
 )
 
 var parserPool fastrlp.ParserPool
 
+// ErrMissingNode is returned when a node referenced by a trie is not in the storage
+var ErrMissingNode = errors.New("missing trie node")
+
 var (
 	// codePrefix is the code prefix for leveldb
 	codePrefix = []byte("code")
@@ -18,6 +25,7 @@ var (
 
 type Batch interface {
 	Put(k, v []byte)
+	Delete(k []byte)
 	Write()
 }
 
@@ -25,6 +33,9 @@ type Batch interface {
 type Storage interface {
 	Put(k, v []byte)
 	Get(k []byte) ([]byte, bool)
+	Delete(k []byte)
+	// Iterate calls fn for every key with the prefix until fn returns false
+	Iterate(prefix []byte, fn func(k []byte) bool)
 	Batch() Batch
 	SetCode(hash types.Hash, code []byte)
 	GetCode(hash types.Hash) ([]byte, bool)
@@ -47,6 +58,10 @@ func (b *KVBatch) Put(k, v []byte) {
 	b.batch.Put(k, v)
 }
 
+func (b *KVBatch) Delete(k []byte) {
+	b.batch.Delete(k)
+}
+
 func (b *KVBatch) Write() {
 	_ = b.db.Write(b.batch, nil)
 }
@@ -80,6 +95,21 @@ func (kv *KVStorage) Get(k []byte) ([]byte, bool) {
 	return data, true
 }
 
+func (kv *KVStorage) Delete(k []byte) {
+	_ = kv.db.Delete(k, nil)
+}
+
+func (kv *KVStorage) Iterate(prefix []byte, fn func(k []byte) bool) {
+	iter := kv.db.NewIterator(util.BytesPrefix(prefix), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		if !fn(iter.Key()) {
+			return
+		}
+	}
+}
+
 func (kv *KVStorage) Close() error {
 	return kv.db.Close()
 }
@@ -94,12 +124,13 @@ func NewLevelDBStorage(path string) (Storage, error) {
 }
 
 type memStorage struct {
+	lock sync.RWMutex
 	db   map[string][]byte
 	code map[string][]byte
 }
 
 type memBatch struct {
-	db *map[string][]byte
+	m *memStorage
 }
 
 // NewMemoryStorage creates an inmemory trie storage
@@ -110,11 +141,17 @@ func NewMemoryStorage() Storage {
 func (m *memStorage) Put(p []byte, v []byte) {
 	buf := make([]byte, len(v))
 	copy(buf[:], v[:])
+
+	m.lock.Lock()
 	m.db[hex.EncodeToHex(p)] = buf
+	m.lock.Unlock()
 }
 
 func (m *memStorage) Get(p []byte) ([]byte, bool) {
+	m.lock.RLock()
 	v, ok := m.db[hex.EncodeToHex(p)]
+	m.lock.RUnlock()
+
 	if !ok {
 		return []byte{}, false
 	}
@@ -122,18 +159,49 @@ func (m *memStorage) Get(p []byte) ([]byte, bool) {
 	return v, true
 }
 
+func (m *memStorage) Delete(p []byte) {
+	m.lock.Lock()
+	delete(m.db, hex.EncodeToHex(p))
+	m.lock.Unlock()
+}
+
+func (m *memStorage) Iterate(prefix []byte, fn func(k []byte) bool) {
+	keys := [][]byte{}
+
+	m.lock.RLock()
+	for k := range m.db {
+		buf, err := hex.DecodeHex(k)
+		if err != nil || !bytes.HasPrefix(buf, prefix) {
+			continue
+		}
+
+		keys = append(keys, buf)
+	}
+	m.lock.RUnlock()
+
+	for _, k := range keys {
+		if !fn(k) {
+			return
+		}
+	}
+}
+
 func (m *memStorage) SetCode(hash types.Hash, code []byte) {
+	m.lock.Lock()
 	m.code[hash.String()] = code
+	m.lock.Unlock()
 }
 
 func (m *memStorage) GetCode(hash types.Hash) ([]byte, bool) {
+	m.lock.RLock()
 	code, ok := m.code[hash.String()]
+	m.lock.RUnlock()
 
 	return code, ok
 }
 
 func (m *memStorage) Batch() Batch {
-	return &memBatch{db: &m.db}
+	return &memBatch{m: m}
 }
 
 func (m *memStorage) Close() error {
@@ -141,9 +209,11 @@ func (m *memStorage) Close() error {
 }
 
 func (m *memBatch) Put(p, v []byte) {
-	buf := make([]byte, len(v))
-	copy(buf[:], v[:])
-	(*m.db)[hex.EncodeToHex(p)] = buf
+	m.m.Put(p, v)
+}
+
+func (m *memBatch) Delete(p []byte) {
+	m.m.Delete(p)
 }
 
 func (m *memBatch) Write() {
@@ -3,6 +3,8 @@ package itrie
 import (
 	"bytes"
 	"fmt"
+	"runtime"
+	"sync"
 
 	"github.com/TIE-Tech/tie-core/state"
 	"github.com/TIE-Tech/tie-core/types"
@@ -92,6 +94,7 @@ func (f *FullNode) get(idx byte) Node {
 type Trie struct {
 	state   *State
 	root    Node
+	hash    types.Hash
 	epoch   uint32
 	storage Storage
 }
@@ -102,11 +105,34 @@ func NewTrie() *Trie {
 
 func (t *Trie) Get(k []byte) ([]byte, bool) {
 	txn := t.Txn()
-	res := txn.Lookup(k)
+
+	res, err := txn.Lookup(k)
+	if err != nil {
+		// the state is corrupted or pruned, reading it as empty would diverge
+		panic(err)
+	}
 
 	return res, res != nil
 }
 
+// GetAccount returns the account with the hashed address from the flat snapshot
+func (t *Trie) GetAccount(hash []byte) ([]byte, bool, error) {
+	if t.state == nil {
+		return nil, false, errSnapshotUnavailable
+	}
+
+	return t.state.snaps.account(t.hash, types.BytesToHash(hash))
+}
+
+// GetStorage returns the storage slot of the account from the flat snapshot
+func (t *Trie) GetStorage(account, slot []byte) ([]byte, bool, error) {
+	if t.state == nil {
+		return nil, false, errSnapshotUnavailable
+	}
+
+	return t.state.snaps.slot(t.hash, types.BytesToHash(account), types.BytesToHash(slot))
+}
+
 func hashit(k []byte) []byte {
 	h := sha3.NewLegacyKeccak256()
 	h.Write(k)
@@ -120,20 +146,29 @@ var stateArenaPool fastrlp.ArenaPool
 
 func (t *Trie) Commit(objs []*state.Object) (state.Snapshot, []byte) {
 	// Create an insertion batch for all the entries
-	batch := t.storage.Batch()
+	batch := t.state.newBatch()
+	putter := &syncPutter{putter: batch}
 
 	tt := t.Txn()
-	tt.batch = batch
+	tt.batch = putter
+
+	// flat changes of the new state
+	layer := newDiffLayer(types.ZeroHash)
 
 	arena := accountArenaPool.Get()
 	defer accountArenaPool.Put(arena)
 
-	ar1 := stateArenaPool.Get()
-	defer stateArenaPool.Put(ar1)
+	// the storage tries of the accounts are independent, build them first
+	storages := t.commitStorages(objs, putter)
+
+	for i, obj := range objs {
+		accountHash := types.BytesToHash(hashit(obj.Address.Bytes()))
 
-	for _, obj := range objs {
 		if obj.Deleted {
-			tt.Delete(hashit(obj.Address.Bytes()))
+			tt.Delete(accountHash.Bytes())
+
+			layer.accounts[accountHash] = nil
+			layer.destructs[accountHash] = struct{}{}
 		} else {
 			account := state.Account{
 				Balance:  obj.Balance,
@@ -142,37 +177,14 @@ func (t *Trie) Commit(objs []*state.Object) (state.Snapshot, []byte) {
 				Root:     obj.Root, // old root
 			}
 
-			if len(obj.Storage) != 0 {
-				localSnapshot, err := t.state.NewSnapshotAt(obj.Root)
-				if err != nil {
-					panic(err)
-				}
-
-				trie, ok := localSnapshot.(*Trie)
-				if !ok {
-					panic("invalid type assertion")
-				}
-
-				localTxn := trie.Txn()
-				localTxn.batch = batch
-
-				for _, entry := range obj.Storage {
-					k := hashit(entry.Key)
-					if entry.Deleted {
-						localTxn.Delete(k)
-					} else {
-						vv := ar1.NewBytes(bytes.TrimLeft(entry.Val, "\x00"))
-						localTxn.Insert(k, vv.MarshalTo(nil))
-					}
-				}
-
-				accountStateRoot, _ := localTxn.Hash()
-				accountStateTrie := localTxn.Commit()
-
-				// Add this to the cache
-				t.state.AddState(types.BytesToHash(accountStateRoot), accountStateTrie)
+			if obj.Root == types.EmptyRootHash {
+				// new or recreated account, nothing from the previous storage is left
+				layer.destructs[accountHash] = struct{}{}
+			}
 
-				account.Root = types.BytesToHash(accountStateRoot)
+			if storage := storages[i]; storage != nil {
+				layer.storage[accountHash] = storage.slots
+				account.Root = storage.root
 			}
 
 			if obj.DirtyCode {
@@ -182,8 +194,10 @@ func (t *Trie) Commit(objs []*state.Object) (state.Snapshot, []byte) {
 			vv := account.MarshalWith(arena)
 			data := vv.MarshalTo(nil)
 
-			tt.Insert(hashit(obj.Address.Bytes()), data)
+			tt.Insert(accountHash.Bytes(), data)
 			arena.Reset()
+
+			layer.accounts[accountHash] = data
 		}
 	}
 
@@ -192,15 +206,126 @@ func (t *Trie) Commit(objs []*state.Object) (state.Snapshot, []byte) {
 	nTrie := tt.Commit()
 	nTrie.state = t.state
 	nTrie.storage = t.storage
+	nTrie.hash = types.BytesToHash(root)
 
 	// Write all the entries to db
-	batch.Write()
+	t.state.writeBatch(batch, nTrie.hash)
+
+	// Keep the flat snapshot in sync with the trie
+	layer.root = nTrie.hash
+	t.state.snaps.update(t.hash, nTrie.hash, layer)
 
-	t.state.AddState(types.BytesToHash(root), nTrie)
+	t.state.AddState(nTrie.hash, nTrie)
 
 	return nTrie, root
 }
 
+// parallelCommitThreshold is the minimum number of storage tries in a commit
+// to build them concurrently
+var parallelCommitThreshold = 2
+
+// storageCommit is the result of the commit of a storage trie
+type storageCommit struct {
+	root  types.Hash
+	slots map[types.Hash][]byte
+}
+
+// commitStorages builds the storage tries of the objects. The result has the
+// index of the object, or nil if the storage of the object did not change
+func (t *Trie) commitStorages(objs []*state.Object, putter Putter) []*storageCommit {
+	res := make([]*storageCommit, len(objs))
+
+	idxs := []int{}
+
+	for i, obj := range objs {
+		if !obj.Deleted && len(obj.Storage) != 0 {
+			idxs = append(idxs, i)
+		}
+	}
+
+	workers := runtime.GOMAXPROCS(0)
+
+	if len(idxs) < parallelCommitThreshold || workers == 1 {
+		for _, i := range idxs {
+			res[i] = t.commitStorage(objs[i], putter)
+		}
+
+		return res
+	}
+
+	var wg sync.WaitGroup
+
+	sem := make(chan struct{}, workers)
+
+	for _, i := range idxs {
+		wg.Add(1)
+
+		sem <- struct{}{}
+
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			res[i] = t.commitStorage(objs[i], putter)
+		}(i)
+	}
+
+	wg.Wait()
+
+	return res
+}
+
+// commitStorage applies the storage changes of the object on top of its storage trie
+func (t *Trie) commitStorage(obj *state.Object, putter Putter) *storageCommit {
+	ar := stateArenaPool.Get()
+	defer stateArenaPool.Put(ar)
+
+	localSnapshot, err := t.state.NewSnapshotAt(obj.Root)
+	if err != nil {
+		panic(err)
+	}
+
+	trie, ok := localSnapshot.(*Trie)
+	if !ok {
+		panic("invalid type assertion")
+	}
+
+	localTxn := trie.Txn()
+	localTxn.batch = putter
+
+	slots := map[types.Hash][]byte{}
+
+	for _, entry := range obj.Storage {
+		k := hashit(entry.Key)
+		if entry.Deleted {
+			localTxn.Delete(k)
+
+			slots[types.BytesToHash(k)] = nil
+		} else {
+			vv := ar.NewBytes(bytes.TrimLeft(entry.Val, "\x00"))
+			val := vv.MarshalTo(nil)
+			localTxn.Insert(k, val)
+
+			slots[types.BytesToHash(k)] = val
+		}
+	}
+
+	ar.Reset()
+
+	accountStateRoot, _ := localTxn.Hash()
+	accountStateTrie := localTxn.Commit()
+
+	// Add this to the cache
+	t.state.AddState(types.BytesToHash(accountStateRoot), accountStateTrie)
+
+	return &storageCommit{
+		root:  types.BytesToHash(accountStateRoot),
+		slots: slots,
+	}
+}
+
 // Hash returns the root hash of the trie. It does not write to the
 // database and can be used even if the trie doesn't have one.
 func (t *Trie) Hash() types.Hash {
@@ -247,6 +372,19 @@ type Putter interface {
 	Put(k, v []byte)
 }
 
+// syncPutter serializes the writes of concurrent hashing to a putter
+type syncPutter struct {
+	lock   sync.Mutex
+	putter Putter
+}
+
+func (s *syncPutter) Put(k, v []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.putter.Put(k, v)
+}
+
 type Txn struct {
 	root    Node
 	epoch   uint32
@@ -258,16 +396,18 @@ func (t *Txn) Commit() *Trie {
 	return &Trie{epoch: t.epoch, root: t.root, storage: t.storage}
 }
 
-func (t *Txn) Lookup(key []byte) []byte {
-	_, res := t.lookup(t.root, bytesToHexNibbles(key))
+// Lookup returns the value of the key, nil if it is not in the trie. A node
+// of the path missing from the storage is reported with ErrMissingNode
+func (t *Txn) Lookup(key []byte) ([]byte, error) {
+	_, res, err := t.lookup(t.root, bytesToHexNibbles(key))
 
-	return res
+	return res, err
 }
 
-func (t *Txn) lookup(node interface{}, key []byte) (Node, []byte) {
+func (t *Txn) lookup(node interface{}, key []byte) (Node, []byte, error) {
 	switch n := node.(type) {
 	case nil:
-		return nil, nil
+		return nil, nil, nil
 
 	case *ValueNode:
 		if n.hash {
@@ -277,46 +417,46 @@ func (t *Txn) lookup(node interface{}, key []byte) (Node, []byte) {
 			}
 
 			if !ok {
-				return nil, nil
+				return nil, nil, fmt.Errorf("%w %s", ErrMissingNode, types.BytesToHash(n.buf))
 			}
 
-			_, res := t.lookup(nc, key)
+			_, res, err := t.lookup(nc, key)
 
-			return nc, res
+			return nc, res, err
 		}
 
 		if len(key) == 0 {
-			return nil, n.buf
+			return nil, n.buf, nil
 		} else {
-			return nil, nil
+			return nil, nil, nil
 		}
 
 	case *ShortNode:
 		plen := len(n.key)
 		if plen > len(key) || !bytes.Equal(key[:plen], n.key) {
-			return nil, nil
+			return nil, nil, nil
 		}
 
-		child, res := t.lookup(n.child, key[plen:])
+		child, res, err := t.lookup(n.child, key[plen:])
 
 		if child != nil {
 			n.child = child
 		}
 
-		return nil, res
+		return nil, res, err
 
 	case *FullNode:
 		if len(key) == 0 {
 			return t.lookup(n.value, key)
 		}
 
-		child, res := t.lookup(n.get(key[0]), key[1:])
+		child, res, err := t.lookup(n.get(key[0]), key[1:])
 
 		if child != nil {
 			n.children[key[0]] = child
 		}
 
-		return nil, res
+		return nil, res, err
 
 	default:
 		panic(fmt.Sprintf("unknown node type %v", n))
@@ -0,0 +1,192 @@
+package itrie
+
+import (
+	"encoding/binary"
+	"math/big"
+	"runtime"
+	"testing"
+
+	"github.com/TIE-Tech/tie-core/state"
+	"github.com/TIE-Tech/tie-core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type mapPutter map[types.Hash][]byte
+
+func (m mapPutter) Put(k, v []byte) {
+	m[types.BytesToHash(k)] = append([]byte{}, v...)
+}
+
+func uintKey(i int) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(i))
+
+	return hashit(buf)
+}
+
+// buildTxn inserts n keys in a new trie
+func buildTxn(n int) *Txn {
+	txn := NewTrie().Txn()
+	for i := 0; i < n; i++ {
+		txn.Insert(uintKey(i), uintKey(i+n))
+	}
+
+	return txn
+}
+
+// withSequentialHashing disables the concurrent hashing and commits until the test ends
+func withSequentialHashing(tb testing.TB) {
+	tb.Helper()
+
+	hashThreshold, commitThreshold := parallelHashThreshold, parallelCommitThreshold
+	parallelHashThreshold, parallelCommitThreshold = 17, int(^uint(0)>>1)
+
+	tb.Cleanup(func() {
+		parallelHashThreshold, parallelCommitThreshold = hashThreshold, commitThreshold
+	})
+}
+
+// withParallelism makes sure the concurrent code paths run on single core machines
+func withParallelism(tb testing.TB) {
+	tb.Helper()
+
+	procs := runtime.GOMAXPROCS(4)
+
+	tb.Cleanup(func() {
+		runtime.GOMAXPROCS(procs)
+	})
+}
+
+func buildCommitObjects(accounts, slots int) []*state.Object {
+	objs := make([]*state.Object, 0, accounts)
+
+	for i := 0; i < accounts; i++ {
+		obj := &state.Object{
+			Address: types.BytesToAddress(uintKey(i)),
+			Balance: big.NewInt(int64(i)),
+			Root:    types.EmptyRootHash,
+		}
+
+		for j := 0; j < slots; j++ {
+			obj.Storage = append(obj.Storage, &state.StorageObject{
+				Key: uintKey(i*slots + j),
+				Val: uintKey(j),
+			})
+		}
+
+		objs = append(objs, obj)
+	}
+
+	return objs
+}
+
+func TestTxn_ParallelHash(t *testing.T) {
+	hashTxn := func() ([]byte, mapPutter) {
+		txn := buildTxn(1000)
+		nodes := mapPutter{}
+		txn.batch = nodes
+
+		root, err := txn.Hash()
+		assert.NoError(t, err)
+
+		return root, nodes
+	}
+
+	withParallelism(t)
+
+	parallelRoot, parallelNodes := hashTxn()
+
+	withSequentialHashing(t)
+
+	root, nodes := hashTxn()
+
+	assert.Equal(t, root, parallelRoot)
+	assert.Equal(t, nodes, parallelNodes)
+}
+
+func TestTxn_LookupMissingNode(t *testing.T) {
+	storage := NewMemoryStorage()
+
+	txn := buildTxn(100)
+	txn.batch = storage
+
+	root, err := txn.Hash()
+	assert.NoError(t, err)
+
+	n, ok, err := GetNode(root, storage)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// drop the child of the root on the path of the key
+	key := uintKey(0)
+	child, _ := n.(*FullNode).children[key[0]>>4].Hash()
+	storage.Delete(child)
+
+	txn = &Txn{root: n, storage: storage}
+
+	_, err = txn.Lookup(key)
+	assert.ErrorIs(t, err, ErrMissingNode)
+}
+
+func TestTrie_ParallelCommit(t *testing.T) {
+	commit := func() types.Hash {
+		st := NewState(NewMemoryStorage())
+		_, root := st.NewSnapshot().Commit(buildCommitObjects(50, 10))
+
+		return types.BytesToHash(root)
+	}
+
+	withParallelism(t)
+
+	parallelRoot := commit()
+
+	withSequentialHashing(t)
+
+	assert.Equal(t, commit(), parallelRoot)
+}
+
+func benchmarkTxnHash(b *testing.B) {
+	b.Helper()
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		txn := buildTxn(10000)
+		b.StartTimer()
+
+		if _, err := txn.Hash(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTxnHash_Sequential(b *testing.B) {
+	withSequentialHashing(b)
+	benchmarkTxnHash(b)
+}
+
+func BenchmarkTxnHash_Parallel(b *testing.B) {
+	benchmarkTxnHash(b)
+}
+
+func benchmarkCommit(b *testing.B) {
+	b.Helper()
+
+	objs := buildCommitObjects(200, 20)
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		st := NewState(NewMemoryStorage())
+		b.StartTimer()
+
+		st.NewSnapshot().Commit(objs)
+	}
+}
+
+func BenchmarkCommit_Sequential(b *testing.B) {
+	withSequentialHashing(b)
+	benchmarkCommit(b)
+}
+
+func BenchmarkCommit_Parallel(b *testing.B) {
+	benchmarkCommit(b)
+}
@@ -32,6 +32,9 @@ type Txn struct {
 	txn       *iradix.Txn
 	codeCache *lru.Cache
 	hash      *keccak.Keccak
+
+	// access records the reads and the changes of a speculative execution, nil otherwise
+	access *accessSet
 }
 
 func NewTxn(state State, snapshot Snapshot) *Txn {
@@ -68,6 +71,10 @@ func (txn *Txn) Snapshot() int {
 	id := len(txn.snapshots)
 	txn.snapshots = append(txn.snapshots, t)
 
+	if txn.access != nil {
+		txn.access.snapshots = append(txn.access.snapshots, len(txn.access.ops))
+	}
+
 	// fmt.Printf("take snapshot ========> %d\n", id)
 
 	return id
@@ -81,6 +88,10 @@ func (txn *Txn) RevertToSnapshot(id int) {
 
 	tree := txn.snapshots[id]
 	txn.txn = tree.Txn()
+
+	if txn.access != nil {
+		txn.access.ops = txn.access.ops[:txn.access.snapshots[id]]
+	}
 }
 
 // GetAccount returns an account
@@ -94,6 +105,14 @@ func (txn *Txn) GetAccount(addr types.Address) (*Account, bool) {
 }
 
 func (txn *Txn) getStateObject(addr types.Address) (*StateObject, bool) {
+	if txn.access != nil {
+		txn.access.read(accountKey(addr))
+
+		// the objects and the tries are shared with the other speculative executions
+		txn.access.shared.Lock()
+		defer txn.access.shared.Unlock()
+	}
+
 	// Try to get state from radix tree which holds transient states during block processing first
 	val, exists := txn.txn.Get(addr.Bytes())
 	if exists {
@@ -105,7 +124,9 @@ func (txn *Txn) getStateObject(addr types.Address) (*StateObject, bool) {
 		return obj.Copy(), true
 	}
 
-	data, ok := txn.snapshot.Get(txn.hashit(addr.Bytes()))
+	accountHash := types.BytesToHash(txn.hashit(addr.Bytes()))
+
+	data, ok := txn.getAccountData(accountHash)
 	if !ok {
 		return nil, false
 	}
@@ -120,6 +141,14 @@ func (txn *Txn) getStateObject(addr types.Address) (*StateObject, bool) {
 	// Load trie from memory if there is some state
 	if account.Root == emptyStateHash {
 		account.Trie = txn.state.NewSnapshot()
+	} else if reader, ok := txn.snapshot.(flatReader); ok {
+		// the storage trie is opened only if the flat snapshot misses
+		account.Trie = &flatStorage{
+			reader:  reader,
+			state:   txn.state,
+			account: accountHash,
+			root:    account.Root,
+		}
 	} else {
 		account.Trie, err = txn.state.NewSnapshotAt(account.Root)
 		if err != nil {
@@ -134,6 +163,17 @@ func (txn *Txn) getStateObject(addr types.Address) (*StateObject, bool) {
 	return obj, true
 }
 
+// getAccountData reads the account from the flat snapshot if possible, otherwise from the trie
+func (txn *Txn) getAccountData(hash types.Hash) ([]byte, bool) {
+	if reader, ok := txn.snapshot.(flatReader); ok {
+		if data, ok, err := reader.GetAccount(hash.Bytes()); err == nil {
+			return data, ok
+		}
+	}
+
+	return txn.snapshot.Get(hash.Bytes())
+}
+
 func (txn *Txn) upsertAccount(addr types.Address, create bool, f func(object *StateObject)) {
 	object, exists := txn.getStateObject(addr)
 	if !exists && create {
@@ -147,15 +187,26 @@ func (txn *Txn) upsertAccount(addr types.Address, create bool, f func(object *St
 		}
 	}
 
+	var prev *StateObject
+	if txn.access != nil && object != nil {
+		prev = object.Copy()
+	}
+
 	// run the callback to modify the account
 	f(object)
 
 	if object != nil {
 		txn.txn.Insert(addr.Bytes(), object)
 	}
+
+	if txn.access != nil {
+		txn.access.upsert(addr, create, f, exists, prev, object)
+	}
 }
 
 func (txn *Txn) AddSealingReward(addr types.Address, balance *big.Int) {
+	balance = txn.retain(balance)
+
 	txn.upsertAccount(addr, true, func(object *StateObject) {
 		if object.Suicide {
 			*object = *newStateObject(txn)
@@ -168,6 +219,8 @@ func (txn *Txn) AddSealingReward(addr types.Address, balance *big.Int) {
 
 // AddBalance adds balance
 func (txn *Txn) AddBalance(addr types.Address, balance *big.Int) {
+	balance = txn.retain(balance)
+
 	txn.upsertAccount(addr, true, func(object *StateObject) {
 		object.Account.Balance.Add(object.Account.Balance, balance)
 	})
@@ -186,6 +239,8 @@ func (txn *Txn) SubBalance(addr types.Address, amount *big.Int) error {
 		return evm.ErrNotEnoughFunds
 	}
 
+	amount = txn.retain(amount)
+
 	txn.upsertAccount(addr, true, func(object *StateObject) {
 		object.Account.Balance.Sub(object.Account.Balance, amount)
 	})
@@ -195,6 +250,8 @@ func (txn *Txn) SubBalance(addr types.Address, amount *big.Int) error {
 // SetBalance sets the balance
 func (txn *Txn) SetBalance(addr types.Address, balance *big.Int) {
 	//fmt.Printf("SET BALANCE: %s %s\n", addr.String(), balance.String())
+	balance = txn.retain(balance)
+
 	txn.upsertAccount(addr, true, func(object *StateObject) {
 		object.Account.Balance.SetBytes(balance.Bytes())
 	})
@@ -327,6 +384,11 @@ func (txn *Txn) SetState(
 	key,
 	value types.Hash,
 ) {
+	if txn.access != nil {
+		txn.access.read(storageKey(addr, key))
+		txn.access.write(storageKey(addr, key))
+	}
+
 	txn.upsertAccount(addr, true, func(object *StateObject) {
 		if object.Txn == nil {
 			object.Txn = iradix.New().Txn()
@@ -340,8 +402,21 @@ func (txn *Txn) SetState(
 	})
 }
 
+// ResetStorage clears the storage of an address
+func (txn *Txn) ResetStorage(addr types.Address) {
+	txn.upsertAccount(addr, true, func(object *StateObject) {
+		object.Account.Trie = txn.state.NewSnapshot()
+		object.Account.Root = emptyStateHash
+		object.Txn = nil
+	})
+}
+
 // GetState returns the state of the address at a given key
 func (txn *Txn) GetState(addr types.Address, key types.Hash) types.Hash {
+	if txn.access != nil {
+		txn.access.read(storageKey(addr, key))
+	}
+
 	object, exists := txn.getStateObject(addr)
 	if !exists {
 		return types.Hash{}
@@ -361,8 +436,18 @@ func (txn *Txn) GetState(addr types.Address, key types.Hash) types.Hash {
 	}
 
 	// If the object was not found in the radix trie due to no state update, we fetch it from the trie tre
+	return txn.committedState(object, key)
+}
+
+// committedState reads a slot of an object from its storage trie
+func (txn *Txn) committedState(object *StateObject, key types.Hash) types.Hash {
 	k := txn.hashit(key.Bytes())
 
+	if txn.access != nil {
+		txn.access.shared.Lock()
+		defer txn.access.shared.Unlock()
+	}
+
 	return object.GetCommitedState(types.BytesToHash(k))
 }
 
@@ -496,12 +581,16 @@ func (txn *Txn) GetRefund() uint64 {
 
 // GetCommittedState returns the state of the address in the trie
 func (txn *Txn) GetCommittedState(addr types.Address, key types.Hash) types.Hash {
+	if txn.access != nil {
+		txn.access.read(storageKey(addr, key))
+	}
+
 	obj, ok := txn.getStateObject(addr)
 	if !ok {
 		return types.Hash{}
 	}
 
-	return obj.GetCommitedState(types.BytesToHash(txn.hashit(key.Bytes())))
+	return txn.committedState(obj, key)
 }
 
 func (txn *Txn) TouchAccount(addr types.Address) {
@@ -552,6 +641,13 @@ func (txn *Txn) CreateAccount(addr types.Address) {
 	}
 
 	txn.txn.Insert(addr.Bytes(), obj)
+
+	if txn.access != nil {
+		txn.access.write(accountKey(addr))
+		txn.access.journal(func(txn *Txn) {
+			txn.CreateAccount(addr)
+		})
+	}
 }
 
 func (txn *Txn) CleanDeleteObjects(deleteEmptyObjects bool) {
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"github.com/TIE-Tech/tie-core/types"
+)
+
+// AddressIndex is a secondary index of the chain by address
+type AddressIndex byte
+
+const (
+	// AddressTransactions indexes the transactions sent, received or creating a contract by address
+	AddressTransactions AddressIndex = iota
+
+	// TokenTransfers indexes the token transfer events emitted by a contract
+	TokenTransfers
+)
+
+// AddressIndexEntry is an entry of an address index
+type AddressIndexEntry struct {
+	BlockNumber uint64
+	TxHash      types.Hash
+
+	// LogIndex is the index of the log in the block, only set for the token transfers
+	LogIndex uint64
+}
+
+// AppendAddressIndex appends an entry to the index of an address
+func AppendAddressIndex(s Storage, index AddressIndex, addr types.Address, entry *AddressIndexEntry) error {
+	count := s.ReadAddressIndexCount(index, addr)
+
+	if err := s.WriteAddressIndexEntry(index, addr, count, entry); err != nil {
+		return err
+	}
+
+	return s.WriteAddressIndexCount(index, addr, count+1)
+}
+
+// TruncateAddressIndex removes the entries of the index of an address that are
+// after the given block. The entries are appended in block order, so only the
+// last entries are removed
+func TruncateAddressIndex(s Storage, index AddressIndex, addr types.Address, number uint64) error {
+	count := s.ReadAddressIndexCount(index, addr)
+	newCount := count
+
+	for newCount > 0 {
+		entry, ok := s.ReadAddressIndexEntry(index, addr, newCount-1)
+		if ok && entry.BlockNumber <= number {
+			break
+		}
+
+		newCount--
+	}
+
+	if newCount == count {
+		return nil
+	}
+
+	return s.WriteAddressIndexCount(index, addr, newCount)
+}
+
+// ReadAddressIndexPage returns a page of the entries of the index of an address,
+// the newest first, and the total number of entries
+func ReadAddressIndexPage(
+	s Storage,
+	index AddressIndex,
+	addr types.Address,
+	offset,
+	limit uint64,
+) ([]*AddressIndexEntry, uint64) {
+	count := s.ReadAddressIndexCount(index, addr)
+	if offset >= count {
+		return []*AddressIndexEntry{}, count
+	}
+
+	if limit > count-offset {
+		limit = count - offset
+	}
+
+	entries := make([]*AddressIndexEntry, 0, limit)
+
+	for i := uint64(0); i < limit; i++ {
+		entry, ok := s.ReadAddressIndexEntry(index, addr, count-1-offset-i)
+		if !ok {
+			break
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, count
+}
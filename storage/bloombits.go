@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"github.com/TIE-Tech/tie-core/types"
+)
+
+const (
+	// BloomBitsSectionSize is the number of blocks of a section of the bloom bits index
+	BloomBitsSectionSize = 4096
+
+	// BloomBitLength is the number of bits of a bloom
+	BloomBitLength = types.BloomByteLength * 8
+)
+
+// GenerateBloomBits rotates the blooms of the blocks of a section into one bit set per
+// bit of the bloom. The bit i of the set of a bloom bit is the bit of the block i of
+// the section, the most significant bit of the first byte being the first block
+func GenerateBloomBits(blooms []types.Bloom) [][]byte {
+	sets := make([][]byte, BloomBitLength)
+	for bit := range sets {
+		sets[bit] = make([]byte, BloomBitsSectionSize/8)
+	}
+
+	for i := range blooms {
+		for bit := uint(0); bit < BloomBitLength; bit++ {
+			if blooms[i].IsBitSet(bit) {
+				sets[bit][i/8] |= 1 << (7 - i%8)
+			}
+		}
+	}
+
+	return sets
+}
+
+// MatchBloomBits returns the bit set of the blocks of a section whose bloom possibly
+// contains a value of every group. The empty groups match all the blocks
+func MatchBloomBits(s Storage, section uint64, groups [][][]byte) ([]byte, error) {
+	match := make([]byte, BloomBitsSectionSize/8)
+	for i := range match {
+		match[i] = 0xff
+	}
+
+	for _, group := range groups {
+		if len(group) == 0 {
+			continue
+		}
+
+		groupMatch := make([]byte, BloomBitsSectionSize/8)
+
+		for _, value := range group {
+			// the blocks with the 3 bits of the value
+			valueMatch := append([]byte{}, match...)
+
+			for _, bit := range types.BloomBitIndexes(value) {
+				bits, err := s.ReadBloomBits(bit, section)
+				if err != nil {
+					return nil, err
+				}
+
+				for i := range valueMatch {
+					valueMatch[i] &= bits[i]
+				}
+			}
+
+			for i := range groupMatch {
+				groupMatch[i] |= valueMatch[i]
+			}
+		}
+
+		match = groupMatch
+	}
+
+	return match, nil
+}
@@ -2,6 +2,7 @@
 package storage
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"math/big"
@@ -38,6 +39,15 @@ var (
 
 	// TX_LOOKUP_PREFIX is the prefix for transaction lookups
 	TX_LOOKUP_PREFIX = []byte("l")
+
+	// BLOOM_BITS is the prefix for the bloom bits index
+	BLOOM_BITS = []byte("x")
+
+	// ADDRESS_INDEX is the prefix of the address indexes
+	ADDRESS_INDEX = []byte("i")
+
+	// INTERNAL_TRANSFERS is the prefix for the internal transfers of the blocks
+	INTERNAL_TRANSFERS = []byte("t")
 )
 
 // Sub-prefixes
@@ -54,6 +64,13 @@ type KV interface {
 	Close() error
 	Set(p []byte, v []byte) error
 	Get(p []byte) ([]byte, bool, error)
+	NewBatch() Batch
+}
+
+// Batch is a set of writes of a kv storage applied at once
+type Batch interface {
+	Set(p []byte, v []byte)
+	Write() error
 }
 
 // KeyValueStorage is a generic storage for kv databases
@@ -253,6 +270,23 @@ func (s *KeyValueStorage) ReadReceipts(hash types.Hash) ([]*types.Receipt, error
 	return *receipts, err
 }
 
+// INTERNAL TRANSFERS //
+
+// WriteInternalTransfers writes the internal transfers of a block
+func (s *KeyValueStorage) WriteInternalTransfers(hash types.Hash, transfers []*types.InternalTransfer) error {
+	tt := types.InternalTransfers(transfers)
+
+	return s.writeRLP(INTERNAL_TRANSFERS, hash.Bytes(), &tt)
+}
+
+// ReadInternalTransfers reads the internal transfers of a block
+func (s *KeyValueStorage) ReadInternalTransfers(hash types.Hash) ([]*types.InternalTransfer, error) {
+	transfers := &types.InternalTransfers{}
+	err := s.readRLP(INTERNAL_TRANSFERS, hash.Bytes(), transfers)
+
+	return *transfers, err
+}
+
 // TX LOOKUP //
 
 // WriteTxLookup maps the transaction hash to the block hash
@@ -282,6 +316,168 @@ func (s *KeyValueStorage) ReadTxLookup(hash types.Hash) (types.Hash, bool) {
 	return types.BytesToHash(blockHash), true
 }
 
+// BLOOM BITS //
+
+// WriteBloomBitsSection writes the bloom bits of a section for every bit of the bloom and
+// marks it as the last indexed section in one batch, so a section is never partially written.
+// The empty bit sets are not stored, unless they replace the set of a reorged section
+func (s *KeyValueStorage) WriteBloomBitsSection(section uint64, sets [][]byte) error {
+	batch := s.db.NewBatch()
+
+	for bit, bits := range sets {
+		key := append(append([]byte{}, BLOOM_BITS...), s.bloomBitsKey(uint(bit), section)...)
+
+		if bytes.Count(bits, []byte{0}) == len(bits) {
+			if _, ok, _ := s.db.Get(key); !ok {
+				continue
+			}
+
+			bits = []byte{}
+		}
+
+		batch.Set(key, bits)
+	}
+
+	batch.Set(append(append([]byte{}, BLOOM_BITS...), NUMBER...), s.encodeUint(section+1))
+
+	return batch.Write()
+}
+
+// ReadBloomBits reads the bloom bits of a section for a bit of the bloom
+func (s *KeyValueStorage) ReadBloomBits(bit uint, section uint64) ([]byte, error) {
+	p := append(append([]byte{}, BLOOM_BITS...), s.bloomBitsKey(bit, section)...)
+
+	data, ok, err := s.db.Get(p)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok || len(data) == 0 {
+		return make([]byte, BloomBitsSectionSize/8), nil
+	}
+
+	return data, nil
+}
+
+// WriteBloomBitsSections writes the number of indexed sections
+func (s *KeyValueStorage) WriteBloomBitsSections(n uint64) error {
+	return s.set(BLOOM_BITS, NUMBER, s.encodeUint(n))
+}
+
+// ReadBloomBitsSections reads the number of indexed sections
+func (s *KeyValueStorage) ReadBloomBitsSections() uint64 {
+	data, ok := s.get(BLOOM_BITS, NUMBER)
+	if !ok || len(data) != 8 {
+		return 0
+	}
+
+	return s.decodeUint(data)
+}
+
+func (s *KeyValueStorage) bloomBitsKey(bit uint, section uint64) []byte {
+	key := make([]byte, 10)
+	binary.BigEndian.PutUint16(key[:2], uint16(bit))
+	binary.BigEndian.PutUint64(key[2:], section)
+
+	return key
+}
+
+// ADDRESS INDEX //
+
+// WriteAddressIndexEntry writes the n-th entry of the index of an address
+func (s *KeyValueStorage) WriteAddressIndexEntry(
+	index AddressIndex,
+	addr types.Address,
+	n uint64,
+	entry *AddressIndexEntry,
+) error {
+	ar := &fastrlp.Arena{}
+
+	vv := ar.NewArray()
+	vv.Set(ar.NewUint(entry.BlockNumber))
+	vv.Set(ar.NewBytes(entry.TxHash.Bytes()))
+	vv.Set(ar.NewUint(entry.LogIndex))
+
+	return s.write2(ADDRESS_INDEX, s.addressIndexKey(index, addr, &n), vv)
+}
+
+// ReadAddressIndexEntry reads the n-th entry of the index of an address
+func (s *KeyValueStorage) ReadAddressIndexEntry(
+	index AddressIndex,
+	addr types.Address,
+	n uint64,
+) (*AddressIndexEntry, bool) {
+	parser := &fastrlp.Parser{}
+
+	v := s.read2(ADDRESS_INDEX, s.addressIndexKey(index, addr, &n), parser)
+	if v == nil {
+		return nil, false
+	}
+
+	elems, err := v.GetElems()
+	if err != nil || len(elems) != 3 {
+		return nil, false
+	}
+
+	entry := &AddressIndexEntry{}
+
+	if entry.BlockNumber, err = elems[0].GetUint64(); err != nil {
+		return nil, false
+	}
+
+	if err = elems[1].GetHash(entry.TxHash[:]); err != nil {
+		return nil, false
+	}
+
+	if entry.LogIndex, err = elems[2].GetUint64(); err != nil {
+		return nil, false
+	}
+
+	return entry, true
+}
+
+// WriteAddressIndexCount writes the number of entries of the index of an address
+func (s *KeyValueStorage) WriteAddressIndexCount(index AddressIndex, addr types.Address, count uint64) error {
+	return s.set(ADDRESS_INDEX, s.addressIndexKey(index, addr, nil), s.encodeUint(count))
+}
+
+// ReadAddressIndexCount reads the number of entries of the index of an address
+func (s *KeyValueStorage) ReadAddressIndexCount(index AddressIndex, addr types.Address) uint64 {
+	data, ok := s.get(ADDRESS_INDEX, s.addressIndexKey(index, addr, nil))
+	if !ok || len(data) != 8 {
+		return 0
+	}
+
+	return s.decodeUint(data)
+}
+
+// WriteAddressIndexHead writes the number of the last indexed block
+func (s *KeyValueStorage) WriteAddressIndexHead(n uint64) error {
+	return s.set(ADDRESS_INDEX, NUMBER, s.encodeUint(n))
+}
+
+// ReadAddressIndexHead reads the number of the last indexed block
+func (s *KeyValueStorage) ReadAddressIndexHead() (uint64, bool) {
+	data, ok := s.get(ADDRESS_INDEX, NUMBER)
+	if !ok || len(data) != 8 {
+		return 0, false
+	}
+
+	return s.decodeUint(data), true
+}
+
+// addressIndexKey returns the key of the n-th entry of the index of an address,
+// or the key of its number of entries if n is nil
+func (s *KeyValueStorage) addressIndexKey(index AddressIndex, addr types.Address, n *uint64) []byte {
+	key := append([]byte{byte(index)}, addr.Bytes()...)
+
+	if n != nil {
+		key = append(key, s.encodeUint(*n)...)
+	}
+
+	return key
+}
+
 // WRITE OPERATIONS //
 
 func (s *KeyValueStorage) writeRLP(p, k []byte, raw types.RLPMarshaler) error {
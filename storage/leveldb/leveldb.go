@@ -58,6 +58,27 @@ func (l *levelDBKV) Get(p []byte) ([]byte, bool, error) {
 	return data, true, nil
 }
 
+// NewBatch creates a batch of writes applied atomically to the leveldb storage
+func (l *levelDBKV) NewBatch() storage.Batch {
+	return &levelDBBatch{db: l.db, batch: &leveldb.Batch{}}
+}
+
+// levelDBBatch is the leveldb implementation of the batch of the kv storage
+type levelDBBatch struct {
+	db    *leveldb.DB
+	batch *leveldb.Batch
+}
+
+// Set adds the key-value pair to the batch
+func (b *levelDBBatch) Set(p []byte, v []byte) {
+	b.batch.Put(p, v)
+}
+
+// Write writes the key-value pairs of the batch to the leveldb storage
+func (b *levelDBBatch) Write() error {
+	return b.db.Write(b.batch, nil)
+}
+
 // Close closes the leveldb storage instance
 func (l *levelDBKV) Close() error {
 	return l.db.Close()
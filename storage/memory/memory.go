@@ -1,29 +1,38 @@
 package memory
 
 import (
+	"sync"
+
 	"github.com/TIE-Tech/tie-core/common/hex"
 	"github.com/TIE-Tech/tie-core/storage"
 )
 
 // NewMemoryStorage creates the new storage reference with inmemory
 func NewMemoryStorage() (storage.Storage, error) {
-	db := &memoryKV{map[string][]byte{}}
+	db := &memoryKV{db: map[string][]byte{}}
 	return storage.NewKeyValueStorage(db), nil
 }
 
 // memoryKV is an in memory implementation of the kv storage
 type memoryKV struct {
-	db map[string][]byte
+	// lock guards the map, the indexers write to the storage in the background
+	lock sync.RWMutex
+	db   map[string][]byte
 }
 
 func (m *memoryKV) Set(p []byte, v []byte) error {
+	m.lock.Lock()
 	m.db[hex.EncodeToHex(p)] = v
+	m.lock.Unlock()
 
 	return nil
 }
 
 func (m *memoryKV) Get(p []byte) ([]byte, bool, error) {
+	m.lock.RLock()
 	v, ok := m.db[hex.EncodeToHex(p)]
+	m.lock.RUnlock()
+
 	if !ok {
 		return nil, false, nil
 	}
@@ -31,6 +40,31 @@ func (m *memoryKV) Get(p []byte) ([]byte, bool, error) {
 	return v, true, nil
 }
 
+func (m *memoryKV) NewBatch() storage.Batch {
+	return &memoryBatch{m: m, db: map[string][]byte{}}
+}
+
 func (m *memoryKV) Close() error {
 	return nil
 }
+
+// memoryBatch is an in memory implementation of the batch of the kv storage
+type memoryBatch struct {
+	m  *memoryKV
+	db map[string][]byte
+}
+
+func (b *memoryBatch) Set(p []byte, v []byte) {
+	b.db[hex.EncodeToHex(p)] = v
+}
+
+func (b *memoryBatch) Write() error {
+	b.m.lock.Lock()
+	defer b.m.lock.Unlock()
+
+	for k, v := range b.db {
+		b.m.db[k] = v
+	}
+
+	return nil
+}
@@ -36,9 +36,24 @@ type Storage interface {
 	WriteReceipts(hash types.Hash, receipts []*types.Receipt) error
 	ReadReceipts(hash types.Hash) ([]*types.Receipt, error)
 
+	WriteInternalTransfers(hash types.Hash, transfers []*types.InternalTransfer) error
+	ReadInternalTransfers(hash types.Hash) ([]*types.InternalTransfer, error)
+
 	WriteTxLookup(hash types.Hash, blockHash types.Hash) error
 	ReadTxLookup(hash types.Hash) (types.Hash, bool)
 
+	WriteBloomBitsSection(section uint64, sets [][]byte) error
+	ReadBloomBits(bit uint, section uint64) ([]byte, error)
+	WriteBloomBitsSections(n uint64) error
+	ReadBloomBitsSections() uint64
+
+	WriteAddressIndexEntry(index AddressIndex, addr types.Address, n uint64, entry *AddressIndexEntry) error
+	ReadAddressIndexEntry(index AddressIndex, addr types.Address, n uint64) (*AddressIndexEntry, bool)
+	WriteAddressIndexCount(index AddressIndex, addr types.Address, count uint64) error
+	ReadAddressIndexCount(index AddressIndex, addr types.Address) uint64
+	WriteAddressIndexHead(n uint64) error
+	ReadAddressIndexHead() (uint64, bool)
+
 	Close() error
 }
 
@@ -49,6 +49,15 @@ func TestStorage(t *testing.T, m MockStorage) {
 	t.Run("", func(t *testing.T) {
 		testReceipts(t, m)
 	})
+	t.Run("", func(t *testing.T) {
+		testBloomBits(t, m)
+	})
+	t.Run("", func(t *testing.T) {
+		testAddressIndex(t, m)
+	})
+	t.Run("", func(t *testing.T) {
+		testInternalTransfers(t, m)
+	})
 }
 
 func testCanonicalChain(t *testing.T, m MockStorage) {
@@ -451,3 +460,148 @@ func testWriteCanonicalHeader(t *testing.T, m MockStorage) {
 		t.Fatal("canonical hash not correct")
 	}
 }
+
+func testBloomBits(t *testing.T, m MockStorage) {
+	t.Helper()
+
+	s, closeFn := m(t)
+	defer closeFn()
+
+	bloom := func(addr types.Address, topics ...types.Hash) types.Bloom {
+		return types.CreateBloom([]*types.Receipt{
+			{Logs: []*types.Log{{Address: addr, Topics: topics}}},
+		})
+	}
+
+	// the blocks 1 and 4 have logs
+	blooms := make([]types.Bloom, BloomBitsSectionSize)
+	blooms[1] = bloom(addr1, hash1)
+	blooms[4] = bloom(addr2, hash2)
+
+	assert.Equal(t, uint64(0), s.ReadBloomBitsSections())
+
+	assert.NoError(t, s.WriteBloomBitsSection(0, GenerateBloomBits(blooms)))
+	assert.Equal(t, uint64(1), s.ReadBloomBitsSections())
+
+	blocks := func(groups ...[][]byte) []int {
+		bits, err := MatchBloomBits(s, 0, groups)
+		assert.NoError(t, err)
+
+		found := []int{}
+
+		for i := 0; i < BloomBitsSectionSize; i++ {
+			if bits[i/8]&(1<<(7-i%8)) != 0 {
+				found = append(found, i)
+			}
+		}
+
+		return found
+	}
+
+	assert.Equal(t, []int{1}, blocks([][]byte{addr1.Bytes()}))
+	assert.Equal(t, []int{1, 4}, blocks([][]byte{addr1.Bytes(), addr2.Bytes()}))
+	assert.Equal(t, []int{4}, blocks([][]byte{addr1.Bytes(), addr2.Bytes()}, [][]byte{hash2.Bytes()}))
+	assert.Equal(t, []int{}, blocks([][]byte{addr1.Bytes()}, [][]byte{hash2.Bytes()}))
+
+	// the section is indexed again without the logs of the block 4
+	blooms[4] = types.Bloom{}
+
+	assert.NoError(t, s.WriteBloomBitsSections(0))
+	assert.NoError(t, s.WriteBloomBitsSection(0, GenerateBloomBits(blooms)))
+	assert.Equal(t, uint64(1), s.ReadBloomBitsSections())
+
+	assert.Equal(t, []int{}, blocks([][]byte{addr2.Bytes()}))
+}
+
+func testAddressIndex(t *testing.T, m MockStorage) {
+	t.Helper()
+
+	s, closeFn := m(t)
+	defer closeFn()
+
+	_, ok := s.ReadAddressIndexHead()
+	assert.False(t, ok)
+
+	// the blocks 1 to 5 have a transaction of the address
+	for i := uint64(1); i <= 5; i++ {
+		entry := &AddressIndexEntry{BlockNumber: i, TxHash: types.StringToHash(fmt.Sprint(i))}
+		assert.NoError(t, AppendAddressIndex(s, AddressTransactions, addr1, entry))
+	}
+
+	assert.NoError(t, AppendAddressIndex(s, TokenTransfers, addr1, &AddressIndexEntry{BlockNumber: 3, LogIndex: 2}))
+
+	assert.NoError(t, s.WriteAddressIndexHead(5))
+
+	head, ok := s.ReadAddressIndexHead()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(5), head)
+
+	blocks := func(index AddressIndex, addr types.Address, offset, limit uint64) ([]uint64, uint64) {
+		entries, total := ReadAddressIndexPage(s, index, addr, offset, limit)
+
+		found := []uint64{}
+		for _, entry := range entries {
+			found = append(found, entry.BlockNumber)
+		}
+
+		return found, total
+	}
+
+	found, total := blocks(AddressTransactions, addr1, 0, 2)
+	assert.Equal(t, []uint64{5, 4}, found)
+	assert.Equal(t, uint64(5), total)
+
+	found, _ = blocks(AddressTransactions, addr1, 4, 2)
+	assert.Equal(t, []uint64{1}, found)
+
+	found, _ = blocks(AddressTransactions, addr1, 5, 2)
+	assert.Equal(t, []uint64{}, found)
+
+	found, total = blocks(AddressTransactions, addr2, 0, 2)
+	assert.Equal(t, []uint64{}, found)
+	assert.Equal(t, uint64(0), total)
+
+	entries, _ := ReadAddressIndexPage(s, TokenTransfers, addr1, 0, 10)
+	assert.Equal(t, []*AddressIndexEntry{{BlockNumber: 3, LogIndex: 2}}, entries)
+
+	// the blocks after 3 are reorged
+	assert.NoError(t, TruncateAddressIndex(s, AddressTransactions, addr1, 3))
+
+	found, total = blocks(AddressTransactions, addr1, 0, 10)
+	assert.Equal(t, []uint64{3, 2, 1}, found)
+	assert.Equal(t, uint64(3), total)
+}
+
+func testInternalTransfers(t *testing.T, m MockStorage) {
+	t.Helper()
+
+	s, closeFn := m(t)
+	defer closeFn()
+
+	_, err := s.ReadInternalTransfers(hash1)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	transfers := []*types.InternalTransfer{
+		{
+			TxHash: hash2,
+			Type:   types.InternalTransferSelfdestruct,
+			From:   addr1,
+			To:     addr2,
+			Value:  big.NewInt(10),
+			Depth:  3,
+		},
+	}
+
+	assert.NoError(t, s.WriteInternalTransfers(hash1, transfers))
+
+	found, err := s.ReadInternalTransfers(hash1)
+	assert.NoError(t, err)
+	assert.Equal(t, transfers, found)
+
+	// the blocks without internal transfers are stored as well
+	assert.NoError(t, s.WriteInternalTransfers(hash2, nil))
+
+	found, err = s.ReadInternalTransfers(hash2)
+	assert.NoError(t, err)
+	assert.Len(t, found, 0)
+}
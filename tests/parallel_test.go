@@ -0,0 +1,283 @@
+package tests
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/TIE-Tech/tie-core/common/hex"
+	"github.com/TIE-Tech/tie-core/params"
+	"github.com/TIE-Tech/tie-core/state"
+	itrie "github.com/TIE-Tech/tie-core/state/trie"
+	"github.com/TIE-Tech/tie-core/tievm/evm/execute"
+	"github.com/TIE-Tech/tie-core/tievm/evm/precompiled"
+	"github.com/TIE-Tech/tie-core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	parallelCoinbase = types.StringToAddress("0x5000")
+
+	// tokenAddr increments the slot of the caller and emits a log
+	tokenAddr = types.StringToAddress("0x4000")
+	// counterAddr increments the slot 0, all its calls conflict
+	counterAddr = types.StringToAddress("0x4001")
+	// forwarderAddr forwards the value of the call to 0x1000
+	forwarderAddr = types.StringToAddress("0x4002")
+	// destructAddr self destructs to the caller
+	destructAddr = types.StringToAddress("0x4003")
+	// revertAddr reverts
+	revertAddr = types.StringToAddress("0x4004")
+	// feeReaderAddr stores the balance of the fee pool in the slot 0
+	feeReaderAddr = types.StringToAddress("0x4005")
+)
+
+func parallelSender(i int) types.Address {
+	return types.BytesToAddress(big.NewInt(int64(0x100 + i)).Bytes())
+}
+
+func parallelGenesis() map[types.Address]*params.GenesisAccount {
+	ether := new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+
+	feeReader := append([]byte{0x73}, state.FeePool.Bytes()...)
+	feeReader = append(feeReader, 0x31, 0x60, 0x00, 0x55, 0x00)
+
+	alloc := map[types.Address]*params.GenesisAccount{
+		tokenAddr:               {Balance: big.NewInt(0), Code: hex.MustDecodeHex("0x3354600101335560006000a000")},
+		counterAddr:             {Balance: big.NewInt(0), Code: hex.MustDecodeHex("0x60005460010160005500")},
+		forwarderAddr:           {Balance: big.NewInt(0), Code: hex.MustDecodeHex("0x6000600060006000346110005af100")},
+		destructAddr:            {Balance: big.NewInt(100), Code: hex.MustDecodeHex("0x33ff")},
+		revertAddr:              {Balance: big.NewInt(0), Code: hex.MustDecodeHex("0x60006000fd")},
+		feeReaderAddr:           {Balance: big.NewInt(0), Code: feeReader},
+		types.RewardPoolAddress: {Balance: ether},
+	}
+
+	for i := 0; i < 8; i++ {
+		alloc[parallelSender(i)] = &params.GenesisAccount{Balance: ether}
+	}
+
+	return alloc
+}
+
+// parallelTx is a transaction of a sender, the nonces are set in the order of the block
+type parallelTx struct {
+	from  types.Address
+	to    *types.Address
+	value int64
+	input string
+	gas   uint64
+
+	// skipNonce sets the nonce after the next one of the sender
+	skipNonce bool
+}
+
+func parallelTxns(txs []parallelTx) []*types.Transaction {
+	nonces := map[types.Address]uint64{}
+	txns := make([]*types.Transaction, 0, len(txs))
+
+	for _, tx := range txs {
+		gas := tx.gas
+		if gas == 0 {
+			gas = 100000
+		}
+
+		nonce := nonces[tx.from]
+		if tx.skipNonce {
+			nonce++
+		}
+
+		txn := &types.Transaction{
+			Nonce:    nonce,
+			From:     tx.from,
+			To:       tx.to,
+			Value:    big.NewInt(tx.value),
+			Input:    hex.MustDecodeHex(tx.input),
+			Gas:      gas,
+			GasPrice: big.NewInt(1),
+		}
+
+		if tx.from == parallelCoinbase {
+			txn.GasPrice = big.NewInt(0)
+		}
+
+		txn.ComputeHash()
+
+		nonces[tx.from]++
+
+		txns = append(txns, txn)
+	}
+
+	return txns
+}
+
+// processParallelBlock processes a block on a new chain, with the given number of workers
+func processParallelBlock(t *testing.T, txns []*types.Transaction, gasLimit uint64, workers int) (*state.BlockResult, error) {
+	t.Helper()
+
+	executor := state.NewExecutor(&params.Params{
+		Forks:   params.AllForksEnabled,
+		ChainID: chainID,
+	}, itrie.NewState(itrie.NewMemoryStorage()))
+
+	executor.SetRuntime(precompiled.NewPrecompiled())
+	executor.SetRuntime(execute.NewEVM())
+	executor.SetParallelism(workers)
+
+	executor.GetHash = func(*types.Header) state.GetHashByNumber {
+		return vmTestBlockHash
+	}
+
+	root := executor.WriteGenesis(parallelGenesis())
+
+	block := &types.Block{
+		Header: &types.Header{
+			Number:    1,
+			GasLimit:  gasLimit,
+			Timestamp: 1000,
+		},
+		Transactions: txns,
+	}
+
+	return executor.ProcessBlock(root, block, parallelCoinbase)
+}
+
+func TestParallelExecution(t *testing.T) {
+	addr := func(s string) *types.Address {
+		a := types.StringToAddress(s)
+
+		return &a
+	}
+
+	sender := func(i int) types.Address {
+		return parallelSender(i)
+	}
+
+	to := func(i int) *types.Address {
+		a := parallelSender(i)
+
+		return &a
+	}
+
+	cases := []struct {
+		name     string
+		txs      []parallelTx
+		gasLimit uint64
+		err      bool
+	}{
+		{
+			name: "independent transfers",
+			txs: []parallelTx{
+				{from: sender(0), to: addr("0x3000"), value: 1},
+				{from: sender(1), to: addr("0x3001"), value: 2},
+				{from: sender(2), to: addr("0x3002"), value: 3},
+				{from: sender(3), to: addr("0x3003"), value: 4},
+				{from: sender(4), to: addr("0x3004"), value: 5},
+				{from: sender(5), to: addr("0x3005"), value: 6},
+			},
+		},
+		{
+			name: "token transfers",
+			txs: []parallelTx{
+				{from: sender(0), to: &tokenAddr},
+				{from: sender(1), to: &tokenAddr},
+				{from: sender(2), to: &tokenAddr},
+				{from: sender(3), to: &tokenAddr},
+				{from: sender(4), to: &tokenAddr},
+				{from: sender(0), to: &tokenAddr},
+			},
+		},
+		{
+			name: "transactions of the same sender",
+			txs: []parallelTx{
+				{from: sender(0), to: addr("0x3000"), value: 1},
+				{from: sender(0), to: &tokenAddr},
+				{from: sender(0), to: addr("0x3000"), value: 1},
+				{from: sender(0), to: &counterAddr},
+				{from: sender(0), to: to(1), value: 10},
+			},
+		},
+		{
+			name: "conflicting storage",
+			txs: []parallelTx{
+				{from: sender(0), to: &counterAddr},
+				{from: sender(1), to: &counterAddr},
+				{from: sender(2), to: &counterAddr},
+				{from: sender(3), to: &tokenAddr},
+				{from: sender(4), to: &counterAddr},
+			},
+		},
+		{
+			name: "transfers between the senders",
+			txs: []parallelTx{
+				{from: sender(0), to: to(1), value: 100},
+				{from: sender(1), to: to(2), value: 100},
+				{from: sender(2), to: to(0), value: 100},
+				{from: sender(3), to: &forwarderAddr, value: 50},
+				{from: sender(4), to: addr("0x1000"), value: 50},
+			},
+		},
+		{
+			name: "mixed",
+			txs: []parallelTx{
+				{from: sender(0), to: &forwarderAddr, value: 7},
+				{from: sender(1), input: "0x600160005500"},
+				{from: sender(2), to: &destructAddr},
+				{from: sender(3), to: &destructAddr, value: 5},
+				{from: sender(4), to: &revertAddr},
+				{from: sender(5), to: &tokenAddr, gas: 20000000},
+				{from: sender(6), to: &feeReaderAddr},
+				{from: sender(7), to: &tokenAddr},
+				{from: parallelCoinbase, to: &types.RewardPoolAddress, value: 1000, input: "0x57305920"},
+				{from: sender(1), to: &feeReaderAddr},
+				{from: sender(2), to: &tokenAddr},
+			},
+		},
+		{
+			name: "block gas limit reached",
+			txs: []parallelTx{
+				{from: sender(0), to: &tokenAddr},
+				{from: sender(1), to: &tokenAddr},
+				{from: sender(2), to: &tokenAddr},
+			},
+			gasLimit: 180000,
+			err:      true,
+		},
+		{
+			name: "invalid nonce",
+			txs: []parallelTx{
+				{from: sender(0), to: &tokenAddr},
+				{from: sender(1), to: &tokenAddr},
+				{from: sender(0), to: &tokenAddr, skipNonce: true},
+			},
+			err: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			gasLimit := c.gasLimit
+			if gasLimit == 0 {
+				gasLimit = 10000000
+			}
+
+			expected, expectedErr := processParallelBlock(t, parallelTxns(c.txs), gasLimit, 0)
+
+			for _, workers := range []int{2, 4, 16} {
+				res, err := processParallelBlock(t, parallelTxns(c.txs), gasLimit, workers)
+				if c.err {
+					assert.Error(t, expectedErr)
+					assert.Equal(t, expectedErr, err)
+
+					continue
+				}
+
+				assert.NoError(t, expectedErr)
+				assert.NoError(t, err)
+
+				assert.Equal(t, expected.Root, res.Root)
+				assert.Equal(t, expected.TotalGas, res.TotalGas)
+				assert.Equal(t, expected.Receipts, res.Receipts)
+				assert.Equal(t, expected.InternalTransfers, res.InternalTransfers)
+			}
+		})
+	}
+}
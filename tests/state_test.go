@@ -0,0 +1,264 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"testing"
+
+	"github.com/TIE-Tech/tie-core/common/crypto"
+	"github.com/TIE-Tech/tie-core/state"
+	"github.com/TIE-Tech/tie-core/types"
+)
+
+const (
+	stateTests = "GeneralStateTests"
+
+	// legacyStateTests are the upstream state tests in the format preceding the
+	// GeneralStateTests, with a single transaction and post state
+	legacyStateTests = "StateTests"
+
+	// legacyStateTestsFork is the fork of the legacy state tests after the
+	// Spurious Dragon block, the only ones supported
+	legacyStateTestsFork = "EIP158"
+	spuriousDragonBlock  = 2675000
+
+	// regressionStateTests are the state tests recorded from this implementation
+	regressionStateTests = "regression/GeneralStateTests"
+)
+
+// legacyStateSkips are the legacy state tests which are not run, with the reason
+var legacyStateSkips = map[string]string{
+	// the creations on an existing contract were later made to fail on every
+	// fork by EIP-684, the legacy fixtures overwrite the contract
+	"CreateHashCollision":      "address collisions fail since EIP-684",
+	"createJS_ExampleContract": "address collisions fail since EIP-684",
+}
+
+type stateCase struct {
+	Env         env                        `json:"env"`
+	Pre         map[types.Address]*account `json:"pre"`
+	Transaction stTransaction              `json:"transaction"`
+	Post        map[string][]*stPostState  `json:"post"`
+}
+
+type stPostState struct {
+	Hash    types.Hash  `json:"hash"`
+	Logs    fixtureLogs `json:"logs"`
+	Indexes struct {
+		Data  int `json:"data"`
+		Gas   int `json:"gas"`
+		Value int `json:"value"`
+	} `json:"indexes"`
+}
+
+type stTransaction struct {
+	Data      []hexBytes  `json:"data"`
+	GasLimit  []hexNumber `json:"gasLimit"`
+	Value     []hexNumber `json:"value"`
+	GasPrice  hexNumber   `json:"gasPrice"`
+	Nonce     hexNumber   `json:"nonce"`
+	SecretKey hexBytes    `json:"secretKey"`
+	To        string      `json:"to"`
+}
+
+// legacyStateCase is a state test in the legacy format
+type legacyStateCase struct {
+	Env           env                        `json:"env"`
+	Pre           map[types.Address]*account `json:"pre"`
+	Transaction   legacyTransaction          `json:"transaction"`
+	PostStateRoot types.Hash                 `json:"postStateRoot"`
+	Logs          fixtureLogs                `json:"logs"`
+}
+
+type legacyTransaction struct {
+	Data      hexBytes  `json:"data"`
+	GasLimit  hexNumber `json:"gasLimit"`
+	Value     hexNumber `json:"value"`
+	GasPrice  hexNumber `json:"gasPrice"`
+	Nonce     hexNumber `json:"nonce"`
+	SecretKey hexBytes  `json:"secretKey"`
+	To        string    `json:"to"`
+}
+
+// stateCase converts the legacy test to a state test with a single post state
+func (l *legacyStateCase) stateCase() (*stateCase, *stPostState) {
+	c := &stateCase{
+		Env: l.Env,
+		Pre: l.Pre,
+		Transaction: stTransaction{
+			Data:      []hexBytes{l.Transaction.Data},
+			GasLimit:  []hexNumber{l.Transaction.GasLimit},
+			Value:     []hexNumber{l.Transaction.Value},
+			GasPrice:  l.Transaction.GasPrice,
+			Nonce:     l.Transaction.Nonce,
+			SecretKey: l.Transaction.SecretKey,
+			To:        l.Transaction.To,
+		},
+	}
+
+	return c, &stPostState{Hash: l.PostStateRoot, Logs: l.Logs}
+}
+
+// message builds the transaction of the indexes of a post state
+func (s *stTransaction) message(post *stPostState) (*types.Transaction, error) {
+	key, err := crypto.ParsePrivateKey(s.SecretKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret key: %w", err)
+	}
+
+	msg := &types.Transaction{
+		From:  crypto.PubKeyToAddress(&key.PublicKey),
+		Input: s.Data[post.Indexes.Data],
+	}
+
+	if msg.Gas, err = s.GasLimit[post.Indexes.Gas].Uint64(); err != nil {
+		return nil, fmt.Errorf("invalid gas limit: %w", err)
+	}
+
+	if msg.Value, err = s.Value[post.Indexes.Value].Big(); err != nil {
+		return nil, fmt.Errorf("invalid value: %w", err)
+	}
+
+	if msg.GasPrice, err = s.GasPrice.Big(); err != nil {
+		return nil, fmt.Errorf("invalid gas price: %w", err)
+	}
+
+	if msg.Nonce, err = s.Nonce.Uint64(); err != nil {
+		return nil, fmt.Errorf("invalid nonce: %w", err)
+	}
+
+	if s.To != "" {
+		to := types.StringToAddress(s.To)
+		msg.To = &to
+	}
+
+	return msg, nil
+}
+
+// runStateCase applies the transaction of a post state on the pre state and
+// returns the resulting state root and logs hash
+func runStateCase(c *stateCase, fork string, post *stPostState) (types.Hash, types.Hash, error) {
+	executor, root, err := newExecutor(Forks[fork], c.Pre)
+	if err != nil {
+		return types.Hash{}, types.Hash{}, err
+	}
+
+	header, err := c.Env.header()
+	if err != nil {
+		return types.Hash{}, types.Hash{}, err
+	}
+
+	msg, err := c.Transaction.message(post)
+	if err != nil {
+		return types.Hash{}, types.Hash{}, err
+	}
+
+	transition, err := executor.BeginTxn(root, header, c.Env.Coinbase)
+	if err != nil {
+		return types.Hash{}, types.Hash{}, err
+	}
+
+	txn := transition.Txn()
+	fees := new(big.Int).Set(txn.GetBalance(state.FeePool))
+
+	// invalid transactions are reverted and leave the state untouched
+	_, _ = transition.Apply(msg)
+
+	// the fees are paid to the fee pool instead of the coinbase,
+	// move them to the coinbase as the fixtures expect
+	fees.Sub(txn.GetBalance(state.FeePool), fees)
+
+	if fees.Sign() > 0 {
+		if err := txn.SubBalance(state.FeePool, fees); err != nil {
+			return types.Hash{}, types.Hash{}, err
+		}
+	}
+
+	// the coinbase is touched even without fees
+	txn.AddBalance(c.Env.Coinbase, fees)
+
+	logs := logsHash(txn.Logs())
+	_, root = transition.Commit()
+
+	return root, logs, nil
+}
+
+// checkStateCase runs the transaction of a post state and compares the results
+func checkStateCase(t *testing.T, c *stateCase, fork string, post *stPostState) {
+	t.Helper()
+
+	root, logs, err := runStateCase(c, fork, post)
+	if errors.Is(err, strconv.ErrRange) {
+		t.Skipf("values above 64 bits are not supported: %v", err)
+	} else if err != nil {
+		t.Fatal(err)
+	}
+
+	if root != post.Hash {
+		t.Errorf("post state root mismatch, expected %s but got %s", post.Hash, root)
+	}
+
+	if logs != types.Hash(post.Logs) {
+		t.Errorf("logs hash mismatch, expected %s but got %s", types.Hash(post.Logs), logs)
+	}
+}
+
+func runStateTests(t *testing.T, folder string) {
+	t.Helper()
+
+	for _, file := range listFixtures(t, folder) {
+		cases := map[string]*stateCase{}
+		readFixture(t, file, &cases)
+
+		for name, c := range cases {
+			for fork, posts := range c.Post {
+				if skipFork(t, fork) {
+					continue
+				}
+
+				for i, post := range posts {
+					t.Run(fmt.Sprintf("%s/%s/%d", name, fork, i), func(t *testing.T) {
+						checkStateCase(t, c, fork, post)
+					})
+				}
+			}
+		}
+	}
+}
+
+func TestState(t *testing.T) {
+	runStateTests(t, stateTests)
+}
+
+func TestStateRegression(t *testing.T) {
+	runStateTests(t, regressionStateTests)
+}
+
+func TestLegacyState(t *testing.T) {
+	for _, file := range listFixtures(t, legacyStateTests) {
+		cases := map[string]*legacyStateCase{}
+		readFixture(t, file, &cases)
+
+		for name, l := range cases {
+			t.Run(name, func(t *testing.T) {
+				if reason, ok := legacyStateSkips[name]; ok {
+					t.Skip(reason)
+				}
+
+				number, err := l.Env.Number.Uint64()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if number < spuriousDragonBlock {
+					t.Skip("the rules before the Spurious Dragon block are not supported")
+				}
+
+				c, post := l.stateCase()
+				checkStateCase(t, c, legacyStateTestsFork, post)
+			})
+		}
+	}
+}
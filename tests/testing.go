@@ -0,0 +1,357 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TIE-Tech/tie-core/common/crypto"
+	"github.com/TIE-Tech/tie-core/common/hex"
+	"github.com/TIE-Tech/tie-core/params"
+	"github.com/TIE-Tech/tie-core/state"
+	itrie "github.com/TIE-Tech/tie-core/state/trie"
+	"github.com/TIE-Tech/tie-core/tievm/evm/execute"
+	"github.com/TIE-Tech/tie-core/tievm/evm/precompiled"
+	"github.com/TIE-Tech/tie-core/types"
+	"github.com/umbracle/fastrlp"
+)
+
+const (
+	testDataDir = "testdata"
+
+	// chainID is the chain id of the consensus tests
+	chainID = 1
+)
+
+// Forks are the fork configurations of the consensus tests by fork name
+var Forks = map[string]*params.Forks{
+	"Frontier": {},
+	"Homestead": {
+		Homestead: params.NewFork(0),
+	},
+	"EIP150": {
+		Homestead: params.NewFork(0),
+		EIP150:    params.NewFork(0),
+	},
+	"EIP158": {
+		Homestead: params.NewFork(0),
+		EIP150:    params.NewFork(0),
+		EIP155:    params.NewFork(0),
+		EIP158:    params.NewFork(0),
+	},
+	"Byzantium": {
+		Homestead: params.NewFork(0),
+		EIP150:    params.NewFork(0),
+		EIP155:    params.NewFork(0),
+		EIP158:    params.NewFork(0),
+		Byzantium: params.NewFork(0),
+	},
+	"Constantinople": {
+		Homestead:      params.NewFork(0),
+		EIP150:         params.NewFork(0),
+		EIP155:         params.NewFork(0),
+		EIP158:         params.NewFork(0),
+		Byzantium:      params.NewFork(0),
+		Constantinople: params.NewFork(0),
+	},
+	"ConstantinopleFix": {
+		Homestead:      params.NewFork(0),
+		EIP150:         params.NewFork(0),
+		EIP155:         params.NewFork(0),
+		EIP158:         params.NewFork(0),
+		Byzantium:      params.NewFork(0),
+		Constantinople: params.NewFork(0),
+		Petersburg:     params.NewFork(0),
+	},
+	"Istanbul": {
+		Homestead:      params.NewFork(0),
+		EIP150:         params.NewFork(0),
+		EIP155:         params.NewFork(0),
+		EIP158:         params.NewFork(0),
+		Byzantium:      params.NewFork(0),
+		Constantinople: params.NewFork(0),
+		Petersburg:     params.NewFork(0),
+		Istanbul:       params.NewFork(0),
+	},
+}
+
+// unsupportedForks are the forks whose post states are not checked, with the reason
+var unsupportedForks = map[string]string{
+	// the transaction fees are paid to the fee pool, which is left empty in
+	// the state when the empty accounts are not removed
+	"Frontier":  "fee pool account is not removed before EIP158",
+	"Homestead": "fee pool account is not removed before EIP158",
+	"EIP150":    "fee pool account is not removed before EIP158",
+
+	"Berlin":            "access lists and EIP-2929 gas costs are not implemented",
+	"London":            "EIP-1559 is not implemented",
+	"Merge":             "the merge is not implemented",
+	"Shanghai":          "the merge is not implemented",
+	"BerlinToLondonAt5": "transition tests are not supported",
+}
+
+// skipFork returns true if the post states of the fork are not checked
+func skipFork(t *testing.T, fork string) bool {
+	t.Helper()
+
+	if reason, ok := unsupportedForks[fork]; ok {
+		t.Logf("skipping fork %s: %s", fork, reason)
+
+		return true
+	}
+
+	if _, ok := Forks[fork]; !ok {
+		t.Logf("skipping unknown fork %s", fork)
+
+		return true
+	}
+
+	return false
+}
+
+// listFixtures returns the json fixtures in the folder of the test data. A missing or
+// empty folder fails the test, so that a suite cannot pass without running a case
+func listFixtures(t *testing.T, folder string) []string {
+	t.Helper()
+
+	root := filepath.Join(testDataDir, folder)
+	if _, err := os.Stat(root); err != nil {
+		t.Fatalf("missing fixtures folder %s: %v", root, err)
+	}
+
+	var files []string
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() && strings.HasSuffix(path, ".json") {
+			files = append(files, path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) == 0 {
+		t.Fatalf("no fixtures in %s", root)
+	}
+
+	return files
+}
+
+// readFixture decodes the named cases of a fixture file
+func readFixture(t *testing.T, path string, cases interface{}) {
+	t.Helper()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := json.Unmarshal(data, cases); err != nil {
+		t.Fatalf("failed to decode %s: %v", path, err)
+	}
+}
+
+// hexNumber is a number encoded as a hex string
+type hexNumber string
+
+func (h hexNumber) Uint64() (uint64, error) {
+	s := string(h)
+
+	return types.ParseUint64orHex(&s)
+}
+
+func (h hexNumber) Big() (*big.Int, error) {
+	s := string(h)
+
+	return types.ParseUint256orHex(&s)
+}
+
+// hexBytes are bytes encoded as a hex string
+type hexBytes []byte
+
+func (h *hexBytes) UnmarshalText(input []byte) error {
+	buf, err := hex.DecodeHex(string(input))
+	if err != nil {
+		return err
+	}
+
+	*h = buf
+
+	return nil
+}
+
+// env is the block environment of a test
+type env struct {
+	Coinbase   types.Address `json:"currentCoinbase"`
+	Difficulty hexNumber     `json:"currentDifficulty"`
+	GasLimit   hexNumber     `json:"currentGasLimit"`
+	Number     hexNumber     `json:"currentNumber"`
+	Timestamp  hexNumber     `json:"currentTimestamp"`
+}
+
+func (e *env) header() (*types.Header, error) {
+	header := &types.Header{
+		Miner: e.Coinbase,
+	}
+
+	var err error
+
+	if header.Difficulty, err = e.Difficulty.Uint64(); err != nil {
+		return nil, fmt.Errorf("invalid difficulty: %w", err)
+	}
+
+	if header.GasLimit, err = e.GasLimit.Uint64(); err != nil {
+		return nil, fmt.Errorf("invalid gas limit: %w", err)
+	}
+
+	if header.Number, err = e.Number.Uint64(); err != nil {
+		return nil, fmt.Errorf("invalid number: %w", err)
+	}
+
+	if header.Timestamp, err = e.Timestamp.Uint64(); err != nil {
+		return nil, fmt.Errorf("invalid timestamp: %w", err)
+	}
+
+	return header, nil
+}
+
+// account is an account of the pre or post state of a test
+type account struct {
+	Balance hexNumber               `json:"balance"`
+	Code    hexBytes                `json:"code"`
+	Nonce   hexNumber               `json:"nonce"`
+	Storage map[hexNumber]hexNumber `json:"storage"`
+}
+
+func (a *account) storage() (map[types.Hash]types.Hash, error) {
+	storage := map[types.Hash]types.Hash{}
+
+	for k, v := range a.Storage {
+		key, err := k.Big()
+		if err != nil {
+			return nil, fmt.Errorf("invalid storage key: %w", err)
+		}
+
+		value, err := v.Big()
+		if err != nil {
+			return nil, fmt.Errorf("invalid storage value: %w", err)
+		}
+
+		storage[types.BytesToHash(key.Bytes())] = types.BytesToHash(value.Bytes())
+	}
+
+	return storage, nil
+}
+
+func (a *account) genesis() (*params.GenesisAccount, error) {
+	balance, err := a.Balance.Big()
+	if err != nil {
+		return nil, fmt.Errorf("invalid balance: %w", err)
+	}
+
+	nonce, err := a.Nonce.Uint64()
+	if err != nil {
+		return nil, fmt.Errorf("invalid nonce: %w", err)
+	}
+
+	storage, err := a.storage()
+	if err != nil {
+		return nil, err
+	}
+
+	return &params.GenesisAccount{
+		Balance: balance,
+		Nonce:   nonce,
+		Code:    a.Code,
+		Storage: storage,
+	}, nil
+}
+
+// newExecutor returns an executor for the forks with the pre state of a test written
+func newExecutor(forks *params.Forks, pre map[types.Address]*account) (*state.Executor, types.Hash, error) {
+	alloc := map[types.Address]*params.GenesisAccount{}
+
+	for addr, acc := range pre {
+		genesis, err := acc.genesis()
+		if err != nil {
+			return nil, types.Hash{}, fmt.Errorf("account %s: %w", addr, err)
+		}
+
+		alloc[addr] = genesis
+	}
+
+	executor := state.NewExecutor(&params.Params{
+		Forks:   forks,
+		ChainID: chainID,
+	}, itrie.NewState(itrie.NewMemoryStorage()))
+
+	executor.SetRuntime(precompiled.NewPrecompiled())
+	executor.SetRuntime(execute.NewEVM())
+
+	executor.GetHash = func(*types.Header) state.GetHashByNumber {
+		return vmTestBlockHash
+	}
+
+	return executor, executor.WriteGenesis(alloc), nil
+}
+
+// vmTestBlockHash is the hash of a block number in the consensus tests
+func vmTestBlockHash(n uint64) types.Hash {
+	return types.BytesToHash(crypto.Keccak256([]byte(big.NewInt(int64(n)).String())))
+}
+
+// fixtureLogs are the expected logs of a test. The filled fixtures hold the hash of the
+// rlp encoded logs, the legacy fixtures hold the list of logs which is hashed the same way
+type fixtureLogs types.Hash
+
+func (f *fixtureLogs) UnmarshalJSON(data []byte) error {
+	var entries []struct {
+		Address types.Address `json:"address"`
+		Topics  []types.Hash  `json:"topics"`
+		Data    hexBytes      `json:"data"`
+	}
+
+	if err := json.Unmarshal(data, &entries); err == nil {
+		logs := make([]*types.Log, 0, len(entries))
+
+		for _, entry := range entries {
+			logs = append(logs, &types.Log{
+				Address: entry.Address,
+				Topics:  entry.Topics,
+				Data:    entry.Data,
+			})
+		}
+
+		*f = fixtureLogs(logsHash(logs))
+
+		return nil
+	}
+
+	var hash types.Hash
+	if err := json.Unmarshal(data, &hash); err != nil {
+		return err
+	}
+
+	*f = fixtureLogs(hash)
+
+	return nil
+}
+
+// logsHash returns the hash of the rlp encoded logs
+func logsHash(logs []*types.Log) types.Hash {
+	ar := &fastrlp.Arena{}
+	receipt := &types.Receipt{Logs: logs}
+
+	return types.BytesToHash(crypto.Keccak256(receipt.MarshalLogsWith(ar).MarshalTo(nil)))
+}
@@ -0,0 +1,185 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/TIE-Tech/tie-core/types"
+)
+
+const (
+	vmTests = "VMTests"
+
+	// vmTestsFork is the fork of the upstream vm tests, filled with the rules of the
+	// first blocks of the mainnet
+	vmTestsFork = "Frontier"
+
+	// regressionVMTests are the vm tests recorded from this implementation
+	regressionVMTests = "regression/VMTests"
+
+	// regressionVMTestsFork is the fork the regression vm tests were recorded with
+	regressionVMTestsFork = "Istanbul"
+)
+
+type vmCase struct {
+	Env  env                        `json:"env"`
+	Exec vmExec                     `json:"exec"`
+	Pre  map[types.Address]*account `json:"pre"`
+
+	// Callcreates are the calls and creations of the upstream vm tests, which are
+	// recorded instead of executed by the filling client
+	Callcreates []json.RawMessage `json:"callcreates"`
+
+	// the expected results, missing if the execution fails
+	Gas  *hexNumber                 `json:"gas"`
+	Logs fixtureLogs                `json:"logs"`
+	Out  hexBytes                   `json:"out"`
+	Post map[types.Address]*account `json:"post"`
+}
+
+type vmExec struct {
+	Address  types.Address `json:"address"`
+	Caller   types.Address `json:"caller"`
+	Origin   types.Address `json:"origin"`
+	Data     hexBytes      `json:"data"`
+	Gas      hexNumber     `json:"gas"`
+	GasPrice hexNumber     `json:"gasPrice"`
+	Value    hexNumber     `json:"value"`
+}
+
+func runVMCase(t *testing.T, c *vmCase, fork string) {
+	t.Helper()
+
+	if len(c.Callcreates) > 0 {
+		t.Skip("the calls and creations are executed instead of recorded")
+	}
+
+	executor, root, err := newExecutor(Forks[fork], c.Pre)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header, err := c.Env.header()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gas, err := c.Exec.Gas.Uint64()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gasPrice, err := c.Exec.GasPrice.Big()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := c.Exec.Value.Big()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the call is made by the origin
+	if c.Exec.Origin != c.Exec.Caller {
+		t.Skip("calls from another caller than the origin are not supported")
+	}
+
+	transition, err := executor.BeginTxn(root, header, c.Env.Coinbase)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := transition.ContextPtr()
+	ctx.Origin = c.Exec.Origin
+	ctx.GasPrice = types.BytesToHash(gasPrice.Bytes())
+
+	txn := transition.Txn()
+
+	// the value is not transferred by the fixtures, it is moved from the address to
+	// the caller so that the transfer of the call leaves both balances untouched
+	if err := txn.SubBalance(c.Exec.Address, value); err != nil {
+		t.Skip("the value is higher than the balance of the address")
+	}
+
+	txn.AddBalance(c.Exec.Caller, value)
+
+	result := transition.Call2(c.Exec.Caller, c.Exec.Address, c.Exec.Data, value, gas)
+
+	if c.Gas == nil {
+		if !result.Failed() {
+			t.Fatal("expected the execution to fail")
+		}
+
+		return
+	}
+
+	if result.Failed() {
+		t.Fatalf("unexpected execution error: %v", result.Err)
+	}
+
+	expectedGas, err := c.Gas.Uint64()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.GasLeft != expectedGas {
+		t.Errorf("gas left mismatch, expected %d but got %d", expectedGas, result.GasLeft)
+	}
+
+	if !bytes.Equal(result.ReturnValue, c.Out) {
+		t.Errorf("output mismatch, expected 0x%x but got 0x%x", []byte(c.Out), result.ReturnValue)
+	}
+
+	if logs := logsHash(txn.Logs()); logs != types.Hash(c.Logs) {
+		t.Errorf("logs hash mismatch, expected %s but got %s", types.Hash(c.Logs), logs)
+	}
+
+	for addr, expected := range c.Post {
+		genesis, err := expected.genesis()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if balance := txn.GetBalance(addr); balance.Cmp(genesis.Balance) != 0 {
+			t.Errorf("balance mismatch of %s, expected %s but got %s", addr, genesis.Balance, balance)
+		}
+
+		if nonce := txn.GetNonce(addr); nonce != genesis.Nonce {
+			t.Errorf("nonce mismatch of %s, expected %d but got %d", addr, genesis.Nonce, nonce)
+		}
+
+		if code := txn.GetCode(addr); !bytes.Equal(code, genesis.Code) {
+			t.Errorf("code mismatch of %s", addr)
+		}
+
+		for key, value := range genesis.Storage {
+			if found := txn.GetState(addr, key); found != value {
+				t.Errorf("storage mismatch of %s at %s, expected %s but got %s", addr, key, value, found)
+			}
+		}
+	}
+}
+
+func runVMTests(t *testing.T, folder, fork string) {
+	t.Helper()
+
+	for _, file := range listFixtures(t, folder) {
+		cases := map[string]*vmCase{}
+		readFixture(t, file, &cases)
+
+		for name, c := range cases {
+			t.Run(name, func(t *testing.T) {
+				runVMCase(t, c, fork)
+			})
+		}
+	}
+}
+
+func TestVM(t *testing.T) {
+	runVMTests(t, vmTests, vmTestsFork)
+}
+
+func TestVMRegression(t *testing.T) {
+	runVMTests(t, regressionVMTests, regressionVMTestsFork)
+}
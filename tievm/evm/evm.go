@@ -18,6 +18,10 @@ type TxContext struct {
 	GasLimit   int64
 	ChainID    int64
 	Difficulty types.Hash
+
+	// VrfValue is the vrf output of the block, empty if the
+	// consensus does not provide one
+	VrfValue []byte
 }
 
 // StorageStatus is the status of the storage access
@@ -62,7 +66,7 @@ type Host interface {
 	GetCodeSize(addr types.Address) int
 	GetCodeHash(addr types.Address) types.Hash
 	GetCode(addr types.Address) []byte
-	Selfdestruct(addr types.Address, beneficiary types.Address)
+	Selfdestruct(addr types.Address, beneficiary types.Address, depth int)
 	GetTxContext() TxContext
 	GetBlockHash(number int64) types.Hash
 	EmitLog(addr types.Address, topics []types.Hash, data []byte)
@@ -107,8 +111,15 @@ var (
 	ErrDepth                    = errors.New("max call depth exceeded")
 	ErrExecutionReverted        = errors.New("execution was reverted")
 	ErrCodeStoreOutOfGas        = errors.New("contract creation code storage out of gas")
+	ErrExecutionCancelled       = errors.New("execution cancelled")
 )
 
+// Cancellable is a host whose execution can be cancelled, the runtimes stop
+// with ErrExecutionCancelled once it is cancelled
+type Cancellable interface {
+	Cancelled() bool
+}
+
 type CallType int
 
 const (
@@ -59,7 +59,7 @@ func (m *mockHost) GetCode(addr types.Address) []byte {
 	panic("Not implemented in tests")
 }
 
-func (m *mockHost) Selfdestruct(addr types.Address, beneficiary types.Address) {
+func (m *mockHost) Selfdestruct(addr types.Address, beneficiary types.Address, depth int) {
 	panic("Not implemented in tests")
 }
 
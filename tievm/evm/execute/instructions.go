@@ -931,7 +931,7 @@ func opSelfDestruct(c *state) {
 		return
 	}
 
-	c.host.Selfdestruct(c.msg.Address, address)
+	c.host.Selfdestruct(c.msg.Address, address, c.msg.Depth)
 	c.halt()
 }
 
@@ -212,8 +212,16 @@ func (c *state) resetReturnData() {
 func (c *state) Run() ([]byte, error) {
 	var vmerr error
 
+	cancellable, _ := c.host.(evm.Cancellable)
+
 	codeSize := len(c.code)
 	for !c.stop {
+		if cancellable != nil && cancellable.Cancelled() {
+			c.exit(evm.ErrExecutionCancelled)
+
+			break
+		}
+
 		if c.ip >= codeSize {
 			c.halt()
 
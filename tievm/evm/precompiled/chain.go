@@ -0,0 +1,117 @@
+package precompiled
+
+import (
+	"math"
+	"math/big"
+
+	"github.com/TIE-Tech/tie-core/common/crypto"
+	"github.com/TIE-Tech/tie-core/common/crypto/vrf"
+	"github.com/TIE-Tech/tie-core/contracts/staking"
+	"github.com/TIE-Tech/tie-core/params"
+	"github.com/TIE-Tech/tie-core/tievm/evm"
+)
+
+const (
+	// vrfPubKeyLength is the length of an uncompressed secp256k1 public key
+	vrfPubKeyLength = 65
+
+	// vrfValueLength is the length of the vrf output of a secp256k1 key
+	vrfValueLength = 65
+
+	// vrfProofLength is the length of the nizk proof of a secp256k1 key
+	vrfProofLength = 64
+
+	// storageReadGas is the cost of a storage read, as SLOAD since istanbul
+	storageReadGas = 800
+)
+
+var (
+	trueWord  = append(make([]byte, 31), 1)
+	falseWord = make([]byte, 32)
+)
+
+// vrfValue returns the keccak256 of the vrf output of the current block as a single 32 bytes
+// word, which contracts read as a uint256. It returns a zero word if the consensus provides no output
+type vrfValue struct {
+}
+
+func (v *vrfValue) gas(input []byte, _ evm.Host, config *params.ForksInTime) uint64 {
+	return 100
+}
+
+func (v *vrfValue) run(input []byte, host evm.Host) ([]byte, error) {
+	value := host.GetTxContext().VrfValue
+	if len(value) == 0 {
+		return falseWord, nil
+	}
+
+	return crypto.Keccak256(value), nil
+}
+
+// vrfVerify verifies a vrf output and its proof. The input is the uncompressed
+// public key (65 bytes), the vrf output (65 bytes), the proof (64 bytes) and the
+// message. It returns 1 as a 32 bytes word if the proof is valid, 0 otherwise
+type vrfVerify struct {
+	p *Precompiled
+}
+
+func (v *vrfVerify) gas(input []byte, _ evm.Host, config *params.ForksInTime) uint64 {
+	return baseGasCalc(input, 20000, 12)
+}
+
+func (v *vrfVerify) run(input []byte, _ evm.Host) ([]byte, error) {
+	buf, msg := v.p.get(input, vrfPubKeyLength+vrfValueLength+vrfProofLength)
+
+	pub, err := crypto.ParsePublicKey(buf[:vrfPubKeyLength])
+	if err != nil {
+		return falseWord, nil
+	}
+
+	value := append([]byte{}, buf[vrfPubKeyLength:vrfPubKeyLength+vrfValueLength]...)
+	proof := append([]byte{}, buf[vrfPubKeyLength+vrfValueLength:]...)
+
+	if ok, err := vrf.Verify(pub, msg, value, proof); err != nil || !ok {
+		return falseWord, nil
+	}
+
+	return trueWord, nil
+}
+
+// validatorSet returns the validators of the staking contract and their staked
+// amounts, abi encoded as (address[], uint256[])
+type validatorSet struct {
+	p *Precompiled
+}
+
+func (v *validatorSet) gas(input []byte, host evm.Host, config *params.ForksInTime) uint64 {
+	// the size of the set is read from the storage, it cannot be trusted
+	count := staking.ReadValidatorsCount(host)
+	if count > staking.MaxValidatorsCount {
+		return math.MaxUint64
+	}
+
+	// the size of the set plus the address and the stake of each validator
+	return storageReadGas + 2*storageReadGas*count
+}
+
+func (v *validatorSet) run(input []byte, host evm.Host) ([]byte, error) {
+	validators, stakes := staking.ReadValidators(host)
+	size := uint64(len(validators))
+
+	// head with the offsets of the two arrays
+	out := make([]byte, 0, 32*(4+2*size))
+	out = append(out, v.p.leftPad(big.NewInt(64).Bytes(), 32)...)
+	out = append(out, v.p.leftPad(new(big.Int).SetUint64(96+32*size).Bytes(), 32)...)
+
+	out = append(out, v.p.leftPad(new(big.Int).SetUint64(size).Bytes(), 32)...)
+	for _, validator := range validators {
+		out = append(out, v.p.leftPad(validator.Bytes(), 32)...)
+	}
+
+	out = append(out, v.p.leftPad(new(big.Int).SetUint64(size).Bytes(), 32)...)
+	for _, stake := range stakes {
+		out = append(out, v.p.leftPad(stake.Bytes(), 32)...)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,163 @@
+package precompiled
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/TIE-Tech/tie-core/common/crypto"
+	"github.com/TIE-Tech/tie-core/common/crypto/vrf"
+	"github.com/TIE-Tech/tie-core/contracts/staking"
+	"github.com/TIE-Tech/tie-core/params"
+	"github.com/TIE-Tech/tie-core/tievm/evm"
+	"github.com/TIE-Tech/tie-core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockHost struct {
+	evm.Host
+
+	ctx     evm.TxContext
+	storage map[types.Hash]types.Hash
+}
+
+func (m *mockHost) GetTxContext() evm.TxContext {
+	return m.ctx
+}
+
+func (m *mockHost) GetStorage(addr types.Address, key types.Hash) types.Hash {
+	if addr != staking.StakingSCAddress {
+		return types.Hash{}
+	}
+
+	return m.storage[key]
+}
+
+func TestChainPrecompilesFork(t *testing.T) {
+	p := NewPrecompiled()
+
+	for _, addr := range []string{"2001", "2002", "2003"} {
+		c := &evm.Contract{CodeAddress: types.StringToAddress(addr)}
+
+		assert.False(t, p.CanRun(c, nil, &params.ForksInTime{Istanbul: true}))
+		assert.True(t, p.CanRun(c, nil, &params.ForksInTime{ChainPrecompiles: true}))
+	}
+}
+
+func TestVrfValue(t *testing.T) {
+	host := &mockHost{
+		ctx: evm.TxContext{VrfValue: []byte{0x1, 0x2, 0x3}},
+	}
+
+	found, err := (&vrfValue{}).run(nil, host)
+	assert.NoError(t, err)
+	assert.Equal(t, crypto.Keccak256([]byte{0x1, 0x2, 0x3}), found)
+	assert.Len(t, found, 32)
+
+	// no vrf output from the consensus
+	found, err = (&vrfValue{}).run(nil, &mockHost{})
+	assert.NoError(t, err)
+	assert.Equal(t, make([]byte, 32), found)
+}
+
+func TestVrfVerify(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+
+	msg := []byte("block seed")
+
+	value, proof, err := vrf.Vrf(key, msg)
+	assert.NoError(t, err)
+
+	pub := crypto.MarshalPublicKey(&key.PublicKey)
+
+	input := func(pub, value, proof, msg []byte) []byte {
+		buf := append(append(append([]byte{}, pub...), value...), proof...)
+
+		return append(buf, msg...)
+	}
+
+	cases := []struct {
+		name     string
+		input    []byte
+		expected []byte
+	}{
+		{"valid", input(pub, value, proof, msg), trueWord},
+		{"wrong message", input(pub, value, proof, []byte("other seed")), falseWord},
+		{"wrong value", input(pub, proof[:vrfValueLength], proof, msg), falseWord},
+		{"invalid public key", input(make([]byte, vrfPubKeyLength), value, proof, msg), falseWord},
+		{"short input", pub, falseWord},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			found, err := (&vrfVerify{&Precompiled{}}).run(c.input, nil)
+
+			assert.NoError(t, err)
+			assert.Equal(t, c.expected, found)
+		})
+	}
+}
+
+func TestValidatorSet(t *testing.T) {
+	validators := []types.Address{
+		types.StringToAddress("1"),
+		types.StringToAddress("2"),
+	}
+
+	account, err := staking.PredeployStakingSC(validators)
+	assert.NoError(t, err)
+
+	host := &mockHost{storage: account.Storage}
+	p := &validatorSet{&Precompiled{}}
+
+	assert.Equal(t, uint64(5*storageReadGas), p.gas(nil, host, &params.ForksInTime{}))
+
+	found, err := p.run(nil, host)
+	assert.NoError(t, err)
+
+	word := func(b []byte) []byte {
+		return (&Precompiled{}).leftPad(b, 32)
+	}
+
+	stake, _ := new(big.Int).SetString("8AC7230489E80000", 16)
+
+	expected := append([]byte{}, word([]byte{64})...)
+	expected = append(expected, word([]byte{160})...)
+	expected = append(expected, word([]byte{2})...)
+	expected = append(expected, word(validators[0].Bytes())...)
+	expected = append(expected, word(validators[1].Bytes())...)
+	expected = append(expected, word([]byte{2})...)
+	expected = append(expected, word(stake.Bytes())...)
+	expected = append(expected, word(stake.Bytes())...)
+
+	assert.Equal(t, expected, found)
+}
+
+func TestValidatorSet_HugeCount(t *testing.T) {
+	count := func(size *big.Int) *mockHost {
+		return &mockHost{storage: map[types.Hash]types.Hash{
+			{}: types.BytesToHash(size.Bytes()),
+		}}
+	}
+
+	p := NewPrecompiled()
+	c := &evm.Contract{CodeAddress: types.StringToAddress("2003"), Gas: 10000000}
+
+	// the cost of the counts above the maximum does not wrap around
+	for _, size := range []*big.Int{
+		new(big.Int).Lsh(big.NewInt(1), 58),
+		new(big.Int).Lsh(big.NewInt(1), 64),
+		big.NewInt(staking.MaxValidatorsCount + 1),
+	} {
+		host := count(size)
+
+		assert.Equal(t, uint64(math.MaxUint64), (&validatorSet{}).gas(nil, host, &params.ForksInTime{}), size)
+		assert.Equal(t, evm.ErrOutOfGas, p.Run(c, host, &params.ForksInTime{ChainPrecompiles: true}).Err, size)
+	}
+
+	// the validators read are bounded
+	validators, stakes := staking.ReadValidators(count(new(big.Int).Lsh(big.NewInt(1), 58)))
+	assert.Len(t, validators, staking.MaxValidatorsCount)
+	assert.Len(t, stakes, staking.MaxValidatorsCount)
+}
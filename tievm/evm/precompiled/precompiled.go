@@ -15,10 +15,16 @@ type contract interface {
 	run(input []byte) ([]byte, error)
 }
 
+// hostContract is a precompiled contract reading the chain through the host
+type hostContract interface {
+	gas(input []byte, host evm.Host, config *params.ForksInTime) uint64
+	run(input []byte, host evm.Host) ([]byte, error)
+}
+
 // Precompiled is the runtime for the precompiled contracts
 type Precompiled struct {
-	buf       []byte
-	contracts map[types.Address]contract
+	contracts     map[types.Address]contract
+	hostContracts map[types.Address]hostContract
 }
 
 // NewPrecompiled creates a new runtime for the precompiled contracts
@@ -43,6 +49,11 @@ func (p *Precompiled) setupContracts() {
 
 	// Istanbul fork
 	p.register("9", &blake2f{p})
+
+	// chain precompiles fork
+	p.registerHost("2001", &vrfValue{})
+	p.registerHost("2002", &vrfVerify{p})
+	p.registerHost("2003", &validatorSet{p})
 }
 
 func (p *Precompiled) register(addrStr string, b contract) {
@@ -53,6 +64,14 @@ func (p *Precompiled) register(addrStr string, b contract) {
 	p.contracts[types.StringToAddress(addrStr)] = b
 }
 
+func (p *Precompiled) registerHost(addrStr string, b hostContract) {
+	if len(p.hostContracts) == 0 {
+		p.hostContracts = map[types.Address]hostContract{}
+	}
+
+	p.hostContracts[types.StringToAddress(addrStr)] = b
+}
+
 var (
 	five  = types.StringToAddress("5")
 	six   = types.StringToAddress("6")
@@ -63,6 +82,10 @@ var (
 
 // CanRun implements the runtime interface
 func (p *Precompiled) CanRun(c *evm.Contract, _ evm.Host, config *params.ForksInTime) bool {
+	if _, ok := p.hostContracts[c.CodeAddress]; ok {
+		return config.ChainPrecompiles
+	}
+
 	if _, ok := p.contracts[c.CodeAddress]; !ok {
 		return false
 	}
@@ -94,9 +117,24 @@ func (p *Precompiled) Name() string {
 }
 
 // Run runs an execution
-func (p *Precompiled) Run(c *evm.Contract, _ evm.Host, config *params.ForksInTime) *evm.ExecutionResult {
-	contract := p.contracts[c.CodeAddress]
-	gasCost := contract.gas(c.Input, config)
+func (p *Precompiled) Run(c *evm.Contract, host evm.Host, config *params.ForksInTime) *evm.ExecutionResult {
+	var (
+		gasCost uint64
+		run     func() ([]byte, error)
+	)
+
+	if contract, ok := p.hostContracts[c.CodeAddress]; ok {
+		gasCost = contract.gas(c.Input, host, config)
+		run = func() ([]byte, error) {
+			return contract.run(c.Input, host)
+		}
+	} else {
+		contract := p.contracts[c.CodeAddress]
+		gasCost = contract.gas(c.Input, config)
+		run = func() ([]byte, error) {
+			return contract.run(c.Input)
+		}
+	}
 
 	// In the case of not enough gas for precompiled execution we return ErrOutOfGas
 	if c.Gas < gasCost {
@@ -107,7 +145,7 @@ func (p *Precompiled) Run(c *evm.Contract, _ evm.Host, config *params.ForksInTim
 	}
 
 	c.Gas = c.Gas - gasCost
-	returnValue, err := contract.run(c.Input)
+	returnValue, err := run()
 
 	result := &evm.ExecutionResult{
 		ReturnValue: returnValue,
@@ -123,8 +161,6 @@ func (p *Precompiled) Run(c *evm.Contract, _ evm.Host, config *params.ForksInTim
 	return result
 }
 
-var zeroPadding = make([]byte, 64)
-
 func (p *Precompiled) leftPad(buf []byte, n int) []byte {
 	l := len(buf)
 	if l > n {
@@ -137,42 +173,18 @@ func (p *Precompiled) leftPad(buf []byte, n int) []byte {
 	return tmp
 }
 
+// get returns the first size bytes of the input, padded with zeros, and the rest of the input.
+// The runtime is shared by the transactions executed in parallel, so every call gets its own buffer
 func (p *Precompiled) get(input []byte, size int) ([]byte, []byte) {
-	p.buf = extendByteSlice(p.buf, size)
-	n := size
-
-	if len(input) < n {
-		n = len(input)
-	}
+	buf := make([]byte, size)
+	n := copy(buf, input)
 
-	// copy the part from the input
-	copy(p.buf[0:], input[:n])
-
-	// copy empty values
-	if n < size {
-		rest := size - n
-		if rest < 64 {
-			copy(p.buf[n:], zeroPadding[0:size-n])
-		} else {
-			copy(p.buf[n:], make([]byte, rest))
-		}
-	}
-
-	return p.buf, input[n:]
+	return buf, input[n:]
 }
 
 func (p *Precompiled) getUint64(input []byte) (uint64, []byte) {
-	p.buf, input = p.get(input, 32)
-	num := binary.BigEndian.Uint64(p.buf[24:32])
+	buf, input := p.get(input, 32)
+	num := binary.BigEndian.Uint64(buf[24:32])
 
 	return num, input
 }
-
-func extendByteSlice(b []byte, needLen int) []byte {
-	b = b[:cap(b)]
-	if n := needLen - cap(b); n > 0 {
-		b = append(b, make([]byte, n)...)
-	}
-
-	return b[:needLen]
-}
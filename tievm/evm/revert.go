@@ -0,0 +1,79 @@
+package evm
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+)
+
+var (
+	// revertSelector is the selector of Error(string), the reason of a revert or a require
+	revertSelector = []byte{0x08, 0xc3, 0x79, 0xa0}
+
+	// panicSelector is the selector of Panic(uint256), the reason of a failed assert or check
+	panicSelector = []byte{0x4e, 0x48, 0x7b, 0x71}
+)
+
+// panicReasons are the reasons of the panic codes of solidity
+var panicReasons = map[uint64]string{
+	0x00: "generic panic",
+	0x01: "assert(false)",
+	0x11: "arithmetic underflow or overflow",
+	0x12: "division or modulo by zero",
+	0x21: "enum overflow",
+	0x22: "invalid encoded storage byte array accessed",
+	0x31: "out-of-bounds array access; popping on an empty array",
+	0x32: "out-of-bounds access of an array or bytesN",
+	0x41: "out of memory",
+	0x51: "uninitialized function",
+}
+
+// UnpackRevertReason returns the reason of the data of a revert, encoded
+// as Error(string) or Panic(uint256)
+func UnpackRevertReason(data []byte) (string, bool) {
+	if len(data) < 4 {
+		return "", false
+	}
+
+	switch {
+	case bytes.Equal(data[:4], revertSelector):
+		return unpackErrorReason(data[4:])
+	case bytes.Equal(data[:4], panicSelector):
+		return unpackPanicReason(data[4:])
+	default:
+		return "", false
+	}
+}
+
+func unpackErrorReason(data []byte) (string, bool) {
+	if len(data) < 64 {
+		return "", false
+	}
+
+	offset := new(big.Int).SetBytes(data[:32])
+	if !offset.IsUint64() || offset.Uint64() > uint64(len(data)-32) {
+		return "", false
+	}
+
+	start := offset.Uint64() + 32
+
+	size := new(big.Int).SetBytes(data[start-32 : start])
+	if !size.IsUint64() || size.Uint64() > uint64(len(data))-start {
+		return "", false
+	}
+
+	return string(data[start : start+size.Uint64()]), true
+}
+
+func unpackPanicReason(data []byte) (string, bool) {
+	if len(data) != 32 {
+		return "", false
+	}
+
+	code := new(big.Int).SetBytes(data)
+	if reason, ok := panicReasons[code.Uint64()]; ok && code.IsUint64() {
+		return reason, true
+	}
+
+	return fmt.Sprintf("unknown panic code: %#x", code), true
+}
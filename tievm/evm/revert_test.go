@@ -0,0 +1,63 @@
+package evm
+
+import (
+	"testing"
+
+	"github.com/TIE-Tech/tie-core/common/hex"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnpackRevertReason(t *testing.T) {
+	cases := []struct {
+		name   string
+		data   string
+		reason string
+		ok     bool
+	}{
+		{
+			name: "error",
+			data: "0x08c379a0" +
+				"0000000000000000000000000000000000000000000000000000000000000020" +
+				"0000000000000000000000000000000000000000000000000000000000000012" +
+				"696e73756666696369656e742066756e64730000000000000000000000000000",
+			reason: "insufficient funds",
+			ok:     true,
+		},
+		{
+			name:   "panic",
+			data:   "0x4e487b71" + "0000000000000000000000000000000000000000000000000000000000000011",
+			reason: "arithmetic underflow or overflow",
+			ok:     true,
+		},
+		{
+			name:   "unknown panic",
+			data:   "0x4e487b71" + "0000000000000000000000000000000000000000000000000000000000000099",
+			reason: "unknown panic code: 0x99",
+			ok:     true,
+		},
+		{
+			name: "error with an out of range size",
+			data: "0x08c379a0" +
+				"0000000000000000000000000000000000000000000000000000000000000020" +
+				"0000000000000000000000000000000000000000000000000000000000000040" +
+				"696e73756666696369656e742066756e64730000000000000000000000000000",
+		},
+		{
+			name: "custom error",
+			data: "0xcf479181" + "0000000000000000000000000000000000000000000000000000000000000001",
+		},
+		{
+			name: "empty",
+			data: "0x",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			reason, ok := UnpackRevertReason(hex.MustDecodeHex(c.data))
+
+			assert.Equal(t, c.ok, ok)
+			assert.Equal(t, c.reason, reason)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package txpool
 
 import (
+	"sort"
 	"sync"
 	"sync/atomic"
 
@@ -66,6 +67,54 @@ func (m *accountsMap) getPrimaries() (primaries []*types.Transaction) {
 	return primaries
 }
 
+// executables returns the promoted transactions of all the accounts in the order they are
+// written to a block: the best priced head of the accounts first, followed by the next
+// transaction of its account. The queues are left untouched.
+func (m *accountsMap) executables() []*types.Transaction {
+	promoted := make(map[types.Address][]*types.Transaction)
+
+	m.Range(func(key, value interface{}) bool {
+		addr, _ := key.(types.Address)
+		account := m.get(addr)
+
+		account.promoted.lock(false)
+		defer account.promoted.unlock()
+
+		if account.promoted.length() != 0 {
+			txs := make([]*types.Transaction, len(account.promoted.queue))
+			copy(txs, account.promoted.queue)
+
+			sort.Slice(txs, func(i, j int) bool {
+				return txs[i].Nonce < txs[j].Nonce
+			})
+
+			promoted[addr] = txs
+		}
+
+		return true
+	})
+
+	heads := newPricedQueue()
+	for _, txs := range promoted {
+		heads.push(txs[0])
+	}
+
+	executables := make([]*types.Transaction, 0, len(promoted))
+
+	for tx := heads.pop(); tx != nil; tx = heads.pop() {
+		executables = append(executables, tx)
+
+		txs := promoted[tx.From][1:]
+		if len(txs) != 0 {
+			heads.push(txs[0])
+		}
+
+		promoted[tx.From] = txs
+	}
+
+	return executables
+}
+
 // get returns the account associated with the given address.
 func (m *accountsMap) get(addr types.Address) *account {
 	a, ok := m.Load(addr)
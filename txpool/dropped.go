@@ -0,0 +1,106 @@
+package txpool
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/TIE-Tech/tie-core/types"
+)
+
+const (
+	// maxDroppedTxs is the maximum number of dropped transactions remembered by the pool
+	maxDroppedTxs = 4096
+
+	// droppedTxTTL is how long a dropped transaction is remembered by the pool
+	droppedTxTTL = time.Hour
+)
+
+// Reasons of the dropped transactions
+const (
+	DropReasonFailed         = "failed to be written to a block"
+	DropReasonAccountDropped = "dropped with a failed transaction of the account"
+	DropReasonReplaced       = "replaced by an included transaction with the same nonce"
+	DropReasonNonceTooLow    = "nonce too low"
+)
+
+// droppedTx is the record of a transaction dropped from the pool
+type droppedTx struct {
+	hash   types.Hash
+	reason string
+	time   time.Time
+}
+
+// droppedTxs remembers the last transactions dropped from the pool and why,
+// a record is forgotten once it expires or the limit of records is reached
+type droppedTxs struct {
+	sync.Mutex
+
+	max int
+	ttl time.Duration
+
+	// txs are the elements of the records in order
+	txs map[types.Hash]*list.Element
+
+	// records in the order they were added, the oldest first. A transaction
+	// dropped again replaces its record at the end
+	order *list.List
+}
+
+func newDroppedTxs(max int, ttl time.Duration) *droppedTxs {
+	return &droppedTxs{
+		max:   max,
+		ttl:   ttl,
+		txs:   make(map[types.Hash]*list.Element),
+		order: list.New(),
+	}
+}
+
+// add records the dropped transactions with their reason. [thread-safe]
+func (d *droppedTxs) add(reason string, now time.Time, hashes ...types.Hash) {
+	d.Lock()
+	defer d.Unlock()
+
+	for _, hash := range hashes {
+		if elem, ok := d.txs[hash]; ok {
+			d.order.Remove(elem)
+		}
+
+		d.txs[hash] = d.order.PushBack(&droppedTx{
+			hash:   hash,
+			reason: reason,
+			time:   now,
+		})
+	}
+
+	d.evict(now)
+}
+
+// get returns the reason a transaction was dropped,
+// false if the transaction is not remembered. [thread-safe]
+func (d *droppedTxs) get(hash types.Hash, now time.Time) (string, bool) {
+	d.Lock()
+	defer d.Unlock()
+
+	d.evict(now)
+
+	elem, ok := d.txs[hash]
+	if !ok {
+		return "", false
+	}
+
+	return elem.Value.(*droppedTx).reason, true // nolint:forcetypeassert
+}
+
+// evict forgets the expired records and the oldest ones over the limit
+func (d *droppedTxs) evict(now time.Time) {
+	for elem := d.order.Front(); elem != nil; elem = d.order.Front() {
+		oldest := elem.Value.(*droppedTx) // nolint:forcetypeassert
+		if d.order.Len() <= d.max && now.Sub(oldest.time) < d.ttl {
+			break
+		}
+
+		d.order.Remove(elem)
+		delete(d.txs, oldest.hash)
+	}
+}
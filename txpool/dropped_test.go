@@ -0,0 +1,78 @@
+package txpool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TIE-Tech/tie-core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDroppedTxs(t *testing.T) {
+	hash1 := types.StringToHash("1")
+	hash2 := types.StringToHash("2")
+	hash3 := types.StringToHash("3")
+
+	t.Run("evict the oldest over the limit", func(t *testing.T) {
+		now := time.Now()
+		dropped := newDroppedTxs(2, time.Hour)
+
+		dropped.add(DropReasonFailed, now, hash1, hash2)
+		dropped.add(DropReasonReplaced, now, hash3)
+
+		_, ok := dropped.get(hash1, now)
+		assert.False(t, ok)
+
+		reason, ok := dropped.get(hash3, now)
+		assert.True(t, ok)
+		assert.Equal(t, DropReasonReplaced, reason)
+	})
+
+	t.Run("expire the old records", func(t *testing.T) {
+		now := time.Now()
+		dropped := newDroppedTxs(10, time.Minute)
+
+		dropped.add(DropReasonFailed, now, hash1)
+		dropped.add(DropReasonFailed, now.Add(30*time.Second), hash2)
+
+		_, ok := dropped.get(hash1, now.Add(time.Minute))
+		assert.False(t, ok)
+
+		_, ok = dropped.get(hash2, now.Add(time.Minute))
+		assert.True(t, ok)
+		assert.Len(t, dropped.txs, 1)
+	})
+
+	t.Run("keep the last reason of a transaction dropped again", func(t *testing.T) {
+		now := time.Now()
+		dropped := newDroppedTxs(10, time.Minute)
+
+		dropped.add(DropReasonFailed, now, hash1)
+		dropped.add(DropReasonNonceTooLow, now.Add(30*time.Second), hash1)
+
+		reason, ok := dropped.get(hash1, now.Add(time.Minute))
+		assert.True(t, ok)
+		assert.Equal(t, DropReasonNonceTooLow, reason)
+	})
+
+	t.Run("replace the record of a transaction dropped again", func(t *testing.T) {
+		now := time.Now()
+		dropped := newDroppedTxs(2, time.Hour)
+
+		for i := 0; i < 10; i++ {
+			dropped.add(DropReasonFailed, now, hash1)
+		}
+
+		assert.Equal(t, 1, dropped.order.Len())
+
+		// the record of hash1 moved after the one of hash2
+		dropped.add(DropReasonFailed, now, hash2, hash1)
+		dropped.add(DropReasonFailed, now, hash3)
+
+		_, ok := dropped.get(hash2, now)
+		assert.False(t, ok)
+
+		_, ok = dropped.get(hash1, now)
+		assert.True(t, ok)
+	})
+}
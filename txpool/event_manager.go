@@ -78,11 +78,19 @@ func (em *eventManager) Close() {
 		subscription.close()
 	}
 
+	// the subscriptions cancelled later are already closed
+	em.subscriptions = make(map[subscriptionID]*eventSubscription)
+
 	atomic.StoreInt64(&em.numSubscriptions, 0)
 }
 
 // signalEvent is a common method for alerting listeners of a new TxPool event
 func (em *eventManager) signalEvent(eventType proto.EventType, txHashes ...types.Hash) {
+	em.signalEventWithReason(eventType, "", txHashes...)
+}
+
+// signalEventWithReason alerts listeners of a new TxPool event, with the reason of the event
+func (em *eventManager) signalEventWithReason(eventType proto.EventType, reason string, txHashes ...types.Hash) {
 	if atomic.LoadInt64(&em.numSubscriptions) < 1 {
 		// No reason to lock the subscriptions map
 		// if no subscriptions exist
@@ -97,6 +105,7 @@ func (em *eventManager) signalEvent(eventType proto.EventType, txHashes ...types
 			subscription.pushEvent(&proto.TxPoolEvent{
 				Type:   eventType,
 				TxHash: txHash.String(),
+				Reason: reason,
 			})
 		}
 	}
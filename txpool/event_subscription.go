@@ -35,15 +35,17 @@ func (es *eventSubscription) eventSupported(eventType proto.EventType) bool {
 	return false
 }
 
-// close stops the event subscription
+// close stops the event subscription, the output channel is closed by the main loop
+// so an event being passed to the output is not sent on a closed channel
 func (es *eventSubscription) close() {
 	close(es.doneCh)
-	close(es.outputCh)
 	close(es.notifyCh)
 }
 
 // runLoop is the main loop that listens for notifications and handles the event / close signals
 func (es *eventSubscription) runLoop() {
+	defer close(es.outputCh)
+
 	for {
 		select {
 		case <-es.doneCh: // Break if a close signal has been received
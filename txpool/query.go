@@ -1,6 +1,10 @@
 package txpool
 
-import "github.com/TIE-Tech/tie-core/types"
+import (
+	"time"
+
+	"github.com/TIE-Tech/tie-core/types"
+)
 
 /* QUERY methods */
 // Used to query the pool for specific state info.
@@ -28,6 +32,12 @@ func (p *TxPool) GetCapacity() (uint64, uint64) {
 	return p.gauge.read(), p.gauge.max
 }
 
+// GetPriceLimit returns the minimum gas price of the
+// transactions accepted in the pool
+func (p *TxPool) GetPriceLimit() uint64 {
+	return p.priceLimit
+}
+
 // GetPendingTx returns the transaction by hash in the TxPool (pending txn) [Thread-safe]
 func (p *TxPool) GetPendingTx(txHash types.Hash) (*types.Transaction, bool) {
 	tx, ok := p.index.get(txHash)
@@ -46,3 +56,58 @@ func (p *TxPool) GetTxs(inclQueued bool) (
 
 	return
 }
+
+// GetExecutableTxs returns the promoted transactions in the order they are written
+// to a block, without removing them from the pool [Thread-safe]
+func (p *TxPool) GetExecutableTxs() []*types.Transaction {
+	return p.accounts.executables()
+}
+
+// TxStatus is the status of a transaction in the pool
+type TxStatus int
+
+const (
+	// TxStatusUnknown is the status of a transaction unknown to the pool
+	TxStatusUnknown TxStatus = iota
+
+	// TxStatusQueued is the status of an enqueued transaction, waiting for the previous nonces
+	TxStatusQueued
+
+	// TxStatusPending is the status of a promoted transaction, ready to be written to a block
+	TxStatusPending
+
+	// TxStatusDropped is the status of a transaction recently dropped from the pool
+	TxStatusDropped
+)
+
+func (s TxStatus) String() string {
+	switch s {
+	case TxStatusQueued:
+		return "queued"
+	case TxStatusPending:
+		return "pending"
+	case TxStatusDropped:
+		return "dropped"
+	default:
+		return "unknown"
+	}
+}
+
+// GetTxStatus returns the status of a transaction in the pool,
+// and the reason of a dropped transaction [Thread-safe]
+func (p *TxPool) GetTxStatus(txHash types.Hash) (TxStatus, string) {
+	if tx, ok := p.index.get(txHash); ok {
+		// the promoted transactions are below the next nonce of the account
+		if account := p.accounts.get(tx.From); account != nil && tx.Nonce < account.getNonce() {
+			return TxStatusPending, ""
+		}
+
+		return TxStatusQueued, ""
+	}
+
+	if reason, ok := p.dropped.get(txHash, time.Now()); ok {
+		return TxStatusDropped, reason
+	}
+
+	return TxStatusUnknown, ""
+}
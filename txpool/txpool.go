@@ -7,6 +7,7 @@ import (
 	"github.com/TIE-Tech/tie-core/metrics"
 	"github.com/TIE-Tech/tie-core/params"
 	"math/big"
+	"time"
 
 	"github.com/golang/protobuf/ptypes/any"
 	"google.golang.org/grpc"
@@ -79,6 +80,9 @@ type Config struct {
 	PriceLimit uint64
 	MaxSlots   uint64
 	Sealing    bool
+
+	// SystemTxFork is the block of the SystemTx fork, nil if it is not scheduled
+	SystemTxFork *params.Fork
 }
 
 /* All requests are passed to the main loop
@@ -123,6 +127,9 @@ type TxPool struct {
 	forks  params.ForksInTime
 	store  store
 
+	// block of the strict system transaction rules
+	systemTxFork *params.Fork
+
 	// map of all accounts registered by the pool
 	accounts accountsMap
 
@@ -163,6 +170,9 @@ type TxPool struct {
 	// Event manager for txpool events
 	eventManager *eventManager
 
+	// the last transactions dropped from the pool
+	dropped *droppedTxs
+
 	// indicates which txpool operator commands should be implemented
 	proto.UnimplementedTxnPoolOperatorServer
 }
@@ -183,9 +193,12 @@ func NewTxPool(
 		accounts:    accountsMap{},
 		executables: newPricedQueue(),
 		index:       lookupMap{all: make(map[types.Hash]*types.Transaction)},
+		dropped:     newDroppedTxs(maxDroppedTxs, droppedTxTTL),
 		gauge:       slotGauge{height: 0, max: config.MaxSlots},
 		priceLimit:  config.PriceLimit,
 		sealing:     config.Sealing,
+
+		systemTxFork: config.SystemTxFork,
 	}
 
 	// Attach the event manager
@@ -244,6 +257,16 @@ func (p *TxPool) Close() {
 	p.shutdownCh <- struct{}{}
 }
 
+// SubscribeTxEvents subscribes to the events of the given types in the pool.
+// The returned function cancels the subscription, closing the channel
+func (p *TxPool) SubscribeTxEvents(eventTypes []proto.EventType) (<-chan *proto.TxPoolEvent, func()) {
+	subscription := p.eventManager.subscribe(eventTypes)
+
+	return subscription.subscriptionChannel, func() {
+		p.eventManager.cancelSubscription(subscription.subscriptionID)
+	}
+}
+
 // SetSigner sets the signer the pool will use
 // to validate a transaction's signature.
 func (p *TxPool) SetSigner(s signer) {
@@ -349,11 +372,20 @@ func (p *TxPool) Drop(tx *types.Transaction) {
 	// num of all txs dropped
 	droppedCount := 0
 
+	// the other txs of the account are dropped with the failed one
+	var droppedWith []*types.Transaction
+
 	// pool resource cleanup
 	clearAccountQueue := func(txs []*types.Transaction) {
 		p.index.remove(txs...)
 		p.gauge.decrease(slotsRequired(txs...))
 
+		for _, dropped := range txs {
+			if dropped.Hash != tx.Hash {
+				droppedWith = append(droppedWith, dropped)
+			}
+		}
+
 		// increase counter
 		droppedCount += len(txs)
 	}
@@ -378,7 +410,8 @@ func (p *TxPool) Drop(tx *types.Transaction) {
 	dropped = account.enqueued.clear()
 	clearAccountQueue(dropped)
 
-	p.eventManager.signalEvent(proto.EventType_DROPPED, tx.Hash)
+	p.dropTxs(DropReasonFailed, tx)
+	p.dropTxs(DropReasonAccountDropped, droppedWith...)
 	logger.Debug("[TXP] dropped account txs",
 		"num", droppedCount,
 		"next_nonce", nextNonce,
@@ -386,6 +419,22 @@ func (p *TxPool) Drop(tx *types.Transaction) {
 	)
 }
 
+// dropTxs remembers the transactions dropped from the pool
+// with their reason and signals them to the subscribers
+func (p *TxPool) dropTxs(reason string, txs ...*types.Transaction) {
+	if len(txs) == 0 {
+		return
+	}
+
+	hashes := make([]types.Hash, len(txs))
+	for i, tx := range txs {
+		hashes[i] = tx.Hash
+	}
+
+	p.dropped.add(reason, time.Now(), hashes...)
+	p.eventManager.signalEventWithReason(proto.EventType_DROPPED, reason, hashes...)
+}
+
 func (p *TxPool) Demote(tx *types.Transaction) {
 	p.eventManager.signalEvent(proto.EventType_DEMOTED, tx.Hash)
 }
@@ -472,6 +521,30 @@ func (p *TxPool) processEvent(event *blockchain.Event) {
 	p.resetAccounts(stateNonces)
 }
 
+// validateSystemTx rejects the fixed reward transactions, which are only
+// written by the block creator, the malformed system transactions and, after
+// the SystemTx fork, the other transactions sent to the reserved addresses
+func (p *TxPool) validateSystemTx(tx *types.Transaction) error {
+	if p.systemTxFork == nil || !p.systemTxFork.Active(p.store.Header().Number+1) {
+		if tx.IsFixedRewardTx() {
+			return ErrInvalidTransaction
+		}
+
+		return nil
+	}
+
+	systemTx, err := tx.SystemTxType()
+	if err != nil || systemTx == types.SystemTxFixedReward {
+		return ErrInvalidTransaction
+	}
+
+	if systemTx == types.SystemTxNone && tx.To != nil && types.IsReservedAddress(*tx.To) {
+		return ErrInvalidTransaction
+	}
+
+	return nil
+}
+
 // validateTx ensures the transaction conforms to specific
 // constraints before entering the pool.
 func (p *TxPool) validateTx(tx *types.Transaction) error {
@@ -506,8 +579,8 @@ func (p *TxPool) validateTx(tx *types.Transaction) error {
 		return ErrUnderpriced
 	}
 
-	if tx.IsFixedRewardTx() {
-		return ErrInvalidTransaction
+	if err := p.validateSystemTx(tx); err != nil {
+		return err
 	}
 
 	// Grab the state root for the latest block
@@ -606,6 +679,8 @@ func (p *TxPool) handleEnqueueRequest(req enqueueRequest) {
 	// enqueue tx
 	if err := account.enqueue(tx); err != nil {
 		logger.Error("enqueue error", "err", err)
+		p.dropTxs(DropReasonNonceTooLow, tx)
+
 		return
 	}
 
@@ -690,8 +765,7 @@ func (p *TxPool) resetAccount(addr types.Address, nonce uint64) {
 	pruned := account.promoted.prune(nonce)
 
 	// update pool state
-	p.index.remove(pruned...)
-	p.gauge.decrease(slotsRequired(pruned...))
+	p.removePruned(pruned)
 
 	// update metrics
 	p.metrics.PendingTxs.Add(float64(-1 * len(pruned)))
@@ -709,8 +783,7 @@ func (p *TxPool) resetAccount(addr types.Address, nonce uint64) {
 	pruned = account.enqueued.prune(nonce)
 
 	// update pool state
-	p.index.remove(pruned...)
-	p.gauge.decrease(slotsRequired(pruned...))
+	p.removePruned(pruned)
 
 	// update next nonce
 	account.setNonce(nonce)
@@ -722,6 +795,24 @@ func (p *TxPool) resetAccount(addr types.Address, nonce uint64) {
 	}
 }
 
+// removePruned removes the pruned transactions from the pool.
+// The included transactions are already removed from the lookup map,
+// the ones left were replaced by included transactions with the same nonce
+func (p *TxPool) removePruned(pruned []*types.Transaction) {
+	var replaced []*types.Transaction
+
+	for _, tx := range pruned {
+		if _, ok := p.index.get(tx.Hash); ok {
+			replaced = append(replaced, tx)
+		}
+	}
+
+	p.index.remove(pruned...)
+	p.gauge.decrease(slotsRequired(pruned...))
+
+	p.dropTxs(DropReasonReplaced, replaced...)
+}
+
 // createAccountOnce creates an account and
 // ensures it is only initialized once.
 func (p *TxPool) createAccountOnce(newAddr types.Address) *account {
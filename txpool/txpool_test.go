@@ -2,6 +2,7 @@ package txpool
 
 import (
 	"crypto/rand"
+	"fmt"
 	"github.com/TIE-Tech/tie-core/metrics"
 	"github.com/TIE-Tech/tie-core/params"
 	"github.com/TIE-Tech/tie-core/state"
@@ -198,6 +199,46 @@ func TestAddTxErrors(t *testing.T) {
 		)
 	})
 
+	t.Run("ErrInvalidTransaction", func(t *testing.T) {
+		fixedReward := []byte{0x57, 0x30, 0x59, 0x20}
+
+		// user calldata embedding the fixed reward selector
+		tx := newTx(addr1, 0, 1)
+		tx.Input = append([]byte{0xa9, 0x05, 0x9c, 0xbb}, fixedReward...)
+
+		pool := setupPool(nil)
+
+		assert.ErrorIs(t,
+			pool.addTx(local, tx),
+			ErrInvalidTransaction,
+		)
+
+		// after the SystemTx fork only the exact reward call is rejected
+		pool = setupPool(nil)
+		pool.systemTxFork = params.NewFork(0)
+
+		assert.NoError(t, pool.validateTx(tx))
+
+		rewardPool := types.RewardPoolAddress
+		reward := newTx(addr2, 0, 1)
+		reward.To = &rewardPool
+		reward.Input = fixedReward
+
+		assert.ErrorIs(t,
+			pool.addTx(local, reward),
+			ErrInvalidTransaction,
+		)
+
+		// as well as the user transactions sent to the reserved addresses
+		transfer := newTx(addr2, 0, 1)
+		transfer.To = &rewardPool
+
+		assert.ErrorIs(t,
+			pool.addTx(local, transfer),
+			ErrInvalidTransaction,
+		)
+	})
+
 	t.Run("ErrIntrinsicGas", func(t *testing.T) {
 		pool := setupPool(nil)
 
@@ -986,6 +1027,81 @@ func TestDrop(t *testing.T) {
 	assert.Equal(t, uint64(0), pool.gauge.read())
 	assert.Equal(t, uint64(0), pool.accounts.get(addr1).getNonce())
 	assert.Equal(t, uint64(0), pool.accounts.get(addr1).promoted.length())
+
+	// the dropped tx is remembered
+	status, reason := pool.GetTxStatus(tx.Hash)
+	assert.Equal(t, TxStatusDropped, status)
+	assert.Equal(t, DropReasonFailed, reason)
+}
+
+func TestGetTxStatus(t *testing.T) {
+	pool, err := newTestPool(nil)
+	assert.NoError(t, err)
+	pool.SetSigner(&mockSigner{})
+	pool.EnableDev()
+
+	subscription := pool.eventManager.subscribe([]proto.EventType{proto.EventType_DROPPED})
+	defer pool.eventManager.cancelSubscription(subscription.subscriptionID)
+
+	// promote 2 txs and enqueue 1
+	txs := []*types.Transaction{
+		newTx(addr1, 0, 1),
+		newTx(addr1, 1, 1),
+		newTx(addr1, 3, 1),
+	}
+
+	for _, tx := range txs {
+		go func(tx *types.Transaction) {
+			assert.NoError(t, pool.addTx(local, tx))
+		}(tx)
+
+		if tx.Nonce == 3 {
+			pool.handleEnqueueRequest(<-pool.enqueueReqCh)
+
+			continue
+		}
+
+		go pool.handleEnqueueRequest(<-pool.enqueueReqCh)
+		pool.handlePromoteRequest(<-pool.promoteReqCh)
+	}
+
+	status, _ := pool.GetTxStatus(txs[0].Hash)
+	assert.Equal(t, TxStatusPending, status)
+
+	status, _ = pool.GetTxStatus(txs[2].Hash)
+	assert.Equal(t, TxStatusQueued, status)
+
+	status, _ = pool.GetTxStatus(types.StringToHash("unknown"))
+	assert.Equal(t, TxStatusUnknown, status)
+
+	// the first tx is included, the second one is replaced
+	// by an included tx with the same nonce
+	pool.index.remove(txs[0])
+	pool.resetAccount(addr1, 2)
+
+	status, _ = pool.GetTxStatus(txs[0].Hash)
+	assert.Equal(t, TxStatusUnknown, status)
+
+	status, reason := pool.GetTxStatus(txs[1].Hash)
+	assert.Equal(t, TxStatusDropped, status)
+	assert.Equal(t, DropReasonReplaced, reason)
+
+	select {
+	case event := <-subscription.subscriptionChannel:
+		assert.Equal(t, txs[1].Hash.String(), event.TxHash)
+		assert.Equal(t, DropReasonReplaced, event.Reason)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the dropped event was not signaled")
+	}
+
+	// a tx with a nonce too low is dropped
+	lowNonce := newTx(addr1, 1, 1)
+	lowNonce.ComputeHash()
+	pool.handleEnqueueRequest(enqueueRequest{tx: lowNonce})
+
+	status, reason = pool.GetTxStatus(lowNonce.Hash)
+	assert.Equal(t, TxStatusDropped, status)
+	assert.Equal(t, DropReasonNonceTooLow, reason)
 }
 
 func TestDemote(t *testing.T) {
@@ -1495,6 +1611,58 @@ type statusTx struct {
 	status status
 }
 
+func TestGetExecutableTxs(t *testing.T) {
+	pool, err := newTestPool(nil)
+	assert.NoError(t, err)
+	pool.SetSigner(&mockSigner{})
+
+	newPricedTx := func(addr types.Address, nonce, gasPrice uint64) *types.Transaction {
+		tx := newTx(addr, nonce, 1)
+		tx.GasPrice.SetUint64(gasPrice)
+
+		return tx
+	}
+
+	// the queues are filled out of order
+	promoted := map[types.Address][]*types.Transaction{
+		addr1: {
+			newPricedTx(addr1, 1, 5),
+			newPricedTx(addr1, 0, 9),
+			newPricedTx(addr1, 2, 3),
+		},
+		addr2: {
+			newPricedTx(addr2, 0, 7),
+			newPricedTx(addr2, 1, 1),
+		},
+	}
+
+	for addr, txs := range promoted {
+		account := pool.createAccountOnce(addr)
+		for _, tx := range txs {
+			account.promoted.push(tx)
+		}
+	}
+
+	executables := pool.GetExecutableTxs()
+
+	order := make([]string, len(executables))
+	for i, tx := range executables {
+		order[i] = fmt.Sprintf("%s/%d/%d", tx.From, tx.Nonce, tx.GasPrice.Uint64())
+	}
+
+	assert.Equal(t, []string{
+		fmt.Sprintf("%s/0/9", addr1),
+		fmt.Sprintf("%s/0/7", addr2),
+		fmt.Sprintf("%s/1/5", addr1),
+		fmt.Sprintf("%s/2/3", addr1),
+		fmt.Sprintf("%s/1/1", addr2),
+	}, order)
+
+	// the pool is left untouched
+	assert.Equal(t, uint64(3), pool.accounts.get(addr1).promoted.length())
+	assert.Equal(t, uint64(2), pool.accounts.get(addr2).promoted.length())
+}
+
 func TestRecovery(t *testing.T) {
 	testCases := []struct {
 		name     string
@@ -0,0 +1,58 @@
+package types
+
+import (
+	"math/big"
+)
+
+// InternalTransferType is the kind of operation moving the value of an internal transfer
+type InternalTransferType int
+
+const (
+	// InternalTransferCall is the value of a call made by a contract
+	InternalTransferCall InternalTransferType = iota
+
+	// InternalTransferCreate is the value of a contract created by a contract
+	InternalTransferCreate
+
+	// InternalTransferSelfdestruct is the balance of a destructed contract sent to its beneficiary
+	InternalTransferSelfdestruct
+
+	// InternalTransferWithdrawFee is the fee reward withdrawn by a validator from the fee pool
+	InternalTransferWithdrawFee
+
+	// InternalTransferFixedReward is the fixed block reward paid to the block creator
+	InternalTransferFixedReward
+)
+
+func (i InternalTransferType) String() string {
+	switch i {
+	case InternalTransferCall:
+		return "call"
+	case InternalTransferCreate:
+		return "create"
+	case InternalTransferSelfdestruct:
+		return "selfdestruct"
+	case InternalTransferWithdrawFee:
+		return "withdrawTxFee"
+	case InternalTransferFixedReward:
+		return "fixedReward"
+	default:
+		return "unknown"
+	}
+}
+
+// InternalTransfers are the internal transfers of a block
+type InternalTransfers []*InternalTransfer
+
+// InternalTransfer is a value transfer made during the execution of a transaction,
+// which is not the value of the transaction itself
+type InternalTransfer struct {
+	TxHash Hash
+	Type   InternalTransferType
+	From   Address
+	To     Address
+	Value  *big.Int
+
+	// Depth is the depth of the call making the transfer, 0 for the system transactions
+	Depth uint64
+}
@@ -31,6 +31,9 @@ type Receipt struct {
 	GasUsed         uint64
 	ContractAddress Address
 	TxHash          Hash
+
+	// RevertReason is the decoded reason of a reverted transaction
+	RevertReason string
 }
 
 func (r *Receipt) SetStatus(s ReceiptStatus) {
@@ -117,6 +120,34 @@ func (b *Bloom) setEncode(hasher *keccak.Keccak, h []byte) {
 	}
 }
 
+// BloomBitIndexes returns the indexes of the bits set by data in a bloom, from 0 to 2047
+func BloomBitIndexes(data []byte) [3]uint {
+	buf := keccak.Keccak256(nil, data)
+
+	var bits [3]uint
+	for i := 0; i < 6; i += 2 {
+		bits[i/2] = (uint(buf[i+1]) + (uint(buf[i]) << 8)) & 2047
+	}
+
+	return bits
+}
+
+// IsBitSet checks if the bit at the given index is set in the bloom
+func (b *Bloom) IsBitSet(bit uint) bool {
+	return b[BloomByteLength-1-bit/8]&(1<<(bit%8)) != 0
+}
+
+// Test checks if data is possibly present in the bloom
+func (b *Bloom) Test(data []byte) bool {
+	for _, bit := range BloomBitIndexes(data) {
+		if !b.IsBitSet(bit) {
+			return false
+		}
+	}
+
+	return true
+}
+
 // IsLogInBloom checks if the log has a possible presence in the bloom filter
 func (b *Bloom) IsLogInBloom(log *Log) bool {
 	hasher := keccak.DefaultKeccakPool.Get()
@@ -37,6 +37,51 @@ func TestRLPEncoding(t *testing.T) {
 	}
 }
 
+func TestRLPStoreReceipt_RevertReason(t *testing.T) {
+	receipt := &Receipt{GasUsed: 10}
+	receipt.SetStatus(ReceiptSuccess)
+
+	// the receipts without a revert reason keep the old format
+	found := &Receipt{}
+	assert.NoError(t, found.UnmarshalStoreRLP(receipt.MarshalStoreRLPTo(nil)))
+	assert.Equal(t, "", found.RevertReason)
+
+	receipt.SetStatus(ReceiptFailed)
+	receipt.RevertReason = "insufficient funds"
+
+	found = &Receipt{}
+	assert.NoError(t, found.UnmarshalStoreRLP(receipt.MarshalStoreRLPTo(nil)))
+	assert.Equal(t, receipt.RevertReason, found.RevertReason)
+	assert.Equal(t, receipt.GasUsed, found.GasUsed)
+
+	// the reason is not part of the consensus encoding
+	assert.Equal(t, (&Receipt{Status: receipt.Status}).MarshalRLPTo(nil), receipt.MarshalRLPTo(nil))
+}
+
+func TestRLPInternalTransfers(t *testing.T) {
+	transfers := InternalTransfers{
+		{
+			TxHash: StringToHash("1"),
+			Type:   InternalTransferCall,
+			From:   StringToAddress("2"),
+			To:     StringToAddress("3"),
+			Value:  big.NewInt(100),
+			Depth:  2,
+		},
+		{
+			TxHash: StringToHash("4"),
+			Type:   InternalTransferFixedReward,
+			From:   StringToAddress("5"),
+			To:     StringToAddress("6"),
+			Value:  big.NewInt(0),
+		},
+	}
+
+	found := InternalTransfers{}
+	assert.NoError(t, found.UnmarshalRLP(transfers.MarshalRLPTo(nil)))
+	assert.Equal(t, transfers, found)
+}
+
 func TestRLPMarshall_And_Unmarshall_Transaction(t *testing.T) {
 	addrTo := StringToAddress("11")
 	txn := &Transaction{
@@ -187,3 +187,29 @@ func (t *Transaction) MarshalRLPWith(arena *fastrlp.Arena) *fastrlp.Value {
 
 	return vv
 }
+
+func (i InternalTransfers) MarshalRLPTo(dst []byte) []byte {
+	return MarshalRLPTo(i.MarshalRLPWith, dst)
+}
+
+func (i *InternalTransfers) MarshalRLPWith(a *fastrlp.Arena) *fastrlp.Value {
+	vv := a.NewArray()
+	for _, tt := range *i {
+		vv.Set(tt.MarshalRLPWith(a))
+	}
+
+	return vv
+}
+
+// MarshalRLPWith marshals an internal transfer with a specific fastrlp.Arena
+func (i *InternalTransfer) MarshalRLPWith(a *fastrlp.Arena) *fastrlp.Value {
+	vv := a.NewArray()
+	vv.Set(a.NewBytes(i.TxHash.Bytes()))
+	vv.Set(a.NewUint(uint64(i.Type)))
+	vv.Set(a.NewBytes(i.From.Bytes()))
+	vv.Set(a.NewBytes(i.To.Bytes()))
+	vv.Set(a.NewBigInt(i.Value))
+	vv.Set(a.NewUint(i.Depth))
+
+	return vv
+}
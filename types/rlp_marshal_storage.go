@@ -80,5 +80,10 @@ func (r *Receipt) MarshalStoreRLPWith(a *fastrlp.Arena) *fastrlp.Value {
 	// gas used
 	vv.Set(a.NewUint(r.GasUsed))
 
+	// revert reason, only for the reverted transactions to keep the old format otherwise
+	if r.RevertReason != "" {
+		vv.Set(a.NewBytes([]byte(r.RevertReason)))
+	}
+
 	return vv
 }
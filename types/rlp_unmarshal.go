@@ -353,3 +353,67 @@ func (t *Transaction) UnmarshalRLPFrom(p *fastrlp.Parser, v *fastrlp.Value) erro
 
 	return nil
 }
+
+func (i *InternalTransfers) UnmarshalRLP(input []byte) error {
+	return UnmarshalRlp(i.UnmarshalRLPFrom, input)
+}
+
+func (i *InternalTransfers) UnmarshalRLPFrom(p *fastrlp.Parser, v *fastrlp.Value) error {
+	elems, err := v.GetElems()
+	if err != nil {
+		return err
+	}
+
+	for _, elem := range elems {
+		tt := &InternalTransfer{}
+		if err := tt.UnmarshalRLPFrom(p, elem); err != nil {
+			return err
+		}
+
+		(*i) = append(*i, tt)
+	}
+
+	return nil
+}
+
+// UnmarshalRLPFrom unmarshals an internal transfer in RLP format
+func (i *InternalTransfer) UnmarshalRLPFrom(p *fastrlp.Parser, v *fastrlp.Value) error {
+	elems, err := v.GetElems()
+	if err != nil {
+		return err
+	}
+
+	if len(elems) != 6 {
+		return fmt.Errorf("expected 6 elements")
+	}
+
+	if err = elems[0].GetHash(i.TxHash[:]); err != nil {
+		return err
+	}
+
+	typ, err := elems[1].GetUint64()
+	if err != nil {
+		return err
+	}
+
+	i.Type = InternalTransferType(typ)
+
+	if err = elems[2].GetAddr(i.From[:]); err != nil {
+		return err
+	}
+
+	if err = elems[3].GetAddr(i.To[:]); err != nil {
+		return err
+	}
+
+	i.Value = new(big.Int)
+	if err = elems[4].GetBigInt(i.Value); err != nil {
+		return err
+	}
+
+	if i.Depth, err = elems[5].GetUint64(); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -115,8 +115,8 @@ func (r *Receipt) UnmarshalStoreRLPFrom(p *fastrlp.Parser, v *fastrlp.Value) err
 		return err
 	}
 
-	if len(elems) != 3 {
-		return fmt.Errorf("expected 3 elements")
+	if len(elems) != 3 && len(elems) != 4 {
+		return fmt.Errorf("expected 3 or 4 elements")
 	}
 
 	if err := r.UnmarshalRLPFrom(p, elems[0]); err != nil {
@@ -140,5 +140,15 @@ func (r *Receipt) UnmarshalStoreRLPFrom(p *fastrlp.Parser, v *fastrlp.Value) err
 		return err
 	}
 
+	// revert reason
+	if len(elems) == 4 {
+		vv, err := elems[3].Bytes()
+		if err != nil {
+			return err
+		}
+
+		r.RevertReason = string(vv)
+	}
+
 	return nil
 }
@@ -0,0 +1,79 @@
+package types
+
+import (
+	"bytes"
+	"errors"
+)
+
+// SystemTxType is the kind of a system transaction
+type SystemTxType int
+
+const (
+	// SystemTxNone is a regular transaction
+	SystemTxNone SystemTxType = iota
+
+	// SystemTxWithdrawFee withdraws the fee reward of a validator from the fee pool
+	SystemTxWithdrawFee
+
+	// SystemTxFixedReward pays the fixed block reward from the reward pool to the block creator
+	SystemTxFixedReward
+)
+
+func (s SystemTxType) String() string {
+	switch s {
+	case SystemTxWithdrawFee:
+		return "withdrawTxFee"
+	case SystemTxFixedReward:
+		return "fixedReward"
+	default:
+		return "none"
+	}
+}
+
+var ErrInvalidSystemTx = errors.New("invalid system transaction input")
+
+var (
+	// RewardPoolAddress is the reserved address receiving the fixed reward transactions
+	RewardPoolAddress = StringToAddress(RewardPool)
+
+	// TxFeePoolAddress is the reserved address receiving the fee withdrawal transactions
+	TxFeePoolAddress = StringToAddress(TxFeePool)
+
+	// systemMethods are the method selectors accepted by the reserved addresses
+	systemMethods = map[Address]struct {
+		selector []byte
+		kind     SystemTxType
+	}{
+		RewardPoolAddress: {[]byte{0x57, 0x30, 0x59, 0x20}, SystemTxFixedReward},
+		TxFeePoolAddress:  {[]byte{0x07, 0x0f, 0x46, 0x8d}, SystemTxWithdrawFee},
+	}
+)
+
+// IsReservedAddress returns true if addr only accepts system transactions
+func IsReservedAddress(addr Address) bool {
+	_, ok := systemMethods[addr]
+
+	return ok
+}
+
+// SystemTxType decodes the kind of system transaction of t. A system transaction
+// is a call to a reserved address whose input is exactly the method selector of
+// that address, the amount is the value of the transaction. Any other input sent
+// to a reserved address with the method selector is rejected
+func (t *Transaction) SystemTxType() (SystemTxType, error) {
+	if t.To == nil {
+		return SystemTxNone, nil
+	}
+
+	method, ok := systemMethods[*t.To]
+	if !ok || !bytes.HasPrefix(t.Input, method.selector) {
+		return SystemTxNone, nil
+	}
+
+	// the methods take no arguments
+	if len(t.Input) != len(method.selector) {
+		return SystemTxNone, ErrInvalidSystemTx
+	}
+
+	return method.kind, nil
+}
@@ -34,10 +34,14 @@ func (t *Transaction) IsContractCreation() bool {
 	return t.To == nil
 }
 
+// IsWithdrawFee matches the withdrawTxFee selector anywhere in the input.
+// It is only used to replay the blocks before the SystemTx fork, see SystemTxType
 func (t *Transaction) IsWithdrawFee() bool {
 	return strings.Count(hex.EncodeToString(t.Input), WithdrawFeeMethod) > 0
 }
 
+// IsFixedRewardTx matches the fixedReward selector anywhere in the input.
+// It is only used to replay the blocks before the SystemTx fork, see SystemTxType
 func (t *Transaction) IsFixedRewardTx() bool {
 	return strings.Count(hex.EncodeToString(t.Input), FixedRewardMethod) > 0
 }